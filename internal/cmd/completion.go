@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// dbFileExtensions are the extensions the database argument is completed with, besides directories
+var dbFileExtensions = []string{"db", "sqlite", "sqlite3", "db3"}
+
+var idleActionCompletions = []string{
+	string(enums.IDLE_EXIT) + "\tExit the shell",
+	string(enums.IDLE_DISCONNECT) + "\tDisconnect, and reconnect on the next statement",
+}
+
+var logLevelCompletions = []string{
+	string(enums.LOG_INFO) + "\tEvery diagnostic, like progress and reconnection notices",
+	string(enums.LOG_NOTE) + "\tNotes, like slow statements, and warnings",
+	string(enums.LOG_WARNING) + "\tWarnings only",
+}
+
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate the completion script for a shell",
+		Long: `Generate the completion script for a shell, completing the flags, their values and the database file.
+
+To load the completions in the current bash session:
+  source <(libsql-shell completion bash)
+In zsh, with compinit loaded:
+  source <(libsql-shell completion zsh)
+In fish:
+  libsql-shell completion fish | source
+Add the same line to the startup file of the shell to load them in every session.`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rootCmd := cmd.Root()
+			outF := cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return rootCmd.GenBashCompletionV2(outF, true)
+			case "zsh":
+				return rootCmd.GenZshCompletion(outF)
+			case "fish":
+				return rootCmd.GenFishCompletion(outF, true)
+			case "powershell":
+				return rootCmd.GenPowerShellCompletionWithDesc(outF)
+			}
+			return fmt.Errorf("unsupported shell %q. Supported shells are %s", args[0], strings.Join(cmd.ValidArgs, ", "))
+		},
+	}
+}
+
+// registerCompletions completes the database argument with database files and the flags with their values, leaving
+// file completion out of the flags that don't take files
+func registerCompletions(rootCmd *cobra.Command) {
+	rootCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 || strings.Contains(toComplete, "://") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		// The names of the subcommands matching are completed before this function is called, and with the file
+		// extension filter they would be taken for extensions, so files are only filtered when none matches
+		for _, subCmd := range cmd.Commands() {
+			if strings.HasPrefix(subCmd.Name(), toComplete) {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+		}
+		return dbFileExtensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+
+	rootCmd.RegisterFlagCompletionFunc("idle-action", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return idleActionCompletions, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return logLevelCompletions, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("init", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"sql"}, cobra.ShellCompDirectiveFilterFileExt
+	})
+	rootCmd.RegisterFlagCompletionFunc("file-root", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	})
+	for _, flag := range []string{"exec", "auth", "cipher-key", "init-sql", "idle-timeout", "history-size", "cell-cache-size"} {
+		rootCmd.RegisterFlagCompletionFunc(flag, cobra.NoFileCompletions)
+	}
+}
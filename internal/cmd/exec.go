@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell"
+)
+
+// execStatements is the value of the repeatable -e flag. Each statement is recorded with the number of positional
+// arguments parsed before it, which pflag doesn't keep, to run it in its place among the script files
+type execStatements struct {
+	flags      *pflag.FlagSet
+	statements []string
+	positions  []int
+}
+
+func (e *execStatements) Set(value string) error {
+	e.statements = append(e.statements, value)
+	e.positions = append(e.positions, len(e.flags.Args()))
+	return nil
+}
+
+func (e *execStatements) String() string {
+	return strings.Join(e.statements, " ")
+}
+
+func (e *execStatements) Type() string {
+	return "string"
+}
+
+// inputs returns the -e statements and the script files in the order they were given. The database is the first
+// positional argument, so the statements given before the first script file were parsed after at most 1 of them
+func (e *execStatements) inputs(scriptFiles []string) ([]shell.ShellInput, error) {
+	inputs := make([]shell.ShellInput, 0, len(e.statements)+len(scriptFiles))
+	next := 0
+	addStatementsBefore := func(position int) error {
+		for ; next < len(e.statements) && e.positions[next] <= position; next++ {
+			if len(e.statements[next]) == 0 {
+				return fmt.Errorf("no SQL command to execute")
+			}
+			inputs = append(inputs, shell.ShellInput{Statements: e.statements[next], Name: fmt.Sprintf("-e %d (%s)", next+1, e.statements[next])})
+		}
+		return nil
+	}
+
+	for i, scriptFile := range scriptFiles {
+		if err := addStatementsBefore(i + 1); err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, shell.ShellInput{File: scriptFile, Name: "script " + scriptFile})
+	}
+	if err := addStatementsBefore(len(scriptFiles) + 1); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
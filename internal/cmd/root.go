@@ -1,30 +1,100 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"github.com/chzyer/readline"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
 
 	"github.com/libsql/libsql-shell-go/pkg/shell"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 )
 
+// Exit codes of the shell, telling apart the connection failures automation handles differently
+const (
+	EXIT_FAILURE               = 1
+	EXIT_AUTHENTICATION_FAILED = 2
+	EXIT_NETWORK_FAILURE       = 3
+	EXIT_DATABASE_NOT_FOUND    = 4
+)
+
+const inputOrderHelp = `Without -e nor SCRIPT files, the statements are read from the standard input, at an interactive prompt
+when it's a terminal. Otherwise the -e statements and the SCRIPT files, executed like .read, run in the order they're
+given on the command line, and the first one failing stops the others and is named in the error.`
+
+const exitCodesHelp = `Exit codes:
+  0  Success
+  1  Any other failure
+  2  Authentication failed: renew the token given with --auth
+  3  Network failure: the server couldn't be reached, retry later
+  4  Database not found`
+
 type RootArgs struct {
-	statements string
+	statements execStatements
 	quiet      bool
 	authToken  string
+	cipherKey  string
+
+	resultCacheSize int
+
+	initFile       string
+	initStatements []string
+
+	idleTimeout time.Duration
+	idleAction  string
+
+	bail         bool
+	jsonErrors   bool
+	summary      bool
+	sessionStats bool
+	failOnEmpty  bool
+
+	noPerDbSettings bool
+	noLockCheck     bool
+
+	fileRoot string
+
+	historyFile string
+	historySize int
+
+	logFile   string
+	logStderr bool
+	logLevel  string
 }
 
 func NewRootCmd() *cobra.Command {
 	var rootArgs RootArgs = RootArgs{}
 	var rootCmd = &cobra.Command{
 		SilenceUsage: true,
-		Use:          "libsql-shell <DB>",
+		Use:          "libsql-shell <DB> [SCRIPT...]",
 		Short:        "A cli for executing SQL statements on a libSQL or SQLite database",
-		Args:         cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Long:         "A cli for executing SQL statements on a libSQL or SQLite database\n\n" + inputOrderHelp + "\n\n" + exitCodesHelp,
+		Args:         cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			idleAction := enums.IdleAction(rootArgs.idleAction)
+			if idleAction != enums.IDLE_EXIT && idleAction != enums.IDLE_DISCONNECT {
+				return fmt.Errorf("invalid idle action %q. Valid actions are exit and disconnect", rootArgs.idleAction)
+			}
+			if rootArgs.historySize < 0 {
+				return fmt.Errorf("invalid history size %d. Give the number of entries to keep, or 0 for the default of %d", rootArgs.historySize, shell.DEFAULT_HISTORY_LIMIT)
+			}
+			logLevel := enums.LogLevel(rootArgs.logLevel)
+			if logLevel != enums.LOG_INFO && logLevel != enums.LOG_NOTE && logLevel != enums.LOG_WARNING {
+				return fmt.Errorf("invalid log level %q. Valid levels are info, note and warning", rootArgs.logLevel)
+			}
+			if rootArgs.logStderr && rootArgs.logFile == "" {
+				return fmt.Errorf("--log-stderr needs --log-file, as the diagnostics are written to stderr without it")
+			}
+			if cmd.Flag("cipher-key").Changed && !shell.CipherSupported() {
+				return &shellerrors.CipherUnsupportedError{}
+			}
+
 			shellConfig := shell.ShellConfig{
 				DbUri:       args[0],
 				InF:         cmd.InOrStdin(),
@@ -34,31 +104,103 @@ func NewRootCmd() *cobra.Command {
 				HistoryName: "libsql",
 				QuietMode:   rootArgs.quiet,
 				AuthToken:   rootArgs.authToken,
+				CipherKey:   rootArgs.cipherKey,
+
+				ResultCacheSize: rootArgs.resultCacheSize,
+
+				InitFile:       rootArgs.initFile,
+				InitStatements: rootArgs.initStatements,
+
+				IdleTimeout: rootArgs.idleTimeout,
+				IdleAction:  idleAction,
+
+				ContinueOnError: !rootArgs.bail,
+				JSONErrors:      rootArgs.jsonErrors,
+				// Batch runs only print the summary when asked, to keep their output quiet
+				ExecutionSummary: rootArgs.summary || (len(args) == 1 && !cmd.Flag("exec").Changed && isTerminal(cmd.InOrStdin())),
+				SessionStats:     rootArgs.sessionStats,
+				FailOnEmpty:      rootArgs.failOnEmpty,
+
+				PerDatabaseSettings: !rootArgs.noPerDbSettings,
+				LockCheck:           !rootArgs.noLockCheck,
+
+				FileRoot: rootArgs.fileRoot,
+
+				HistoryFile:  rootArgs.historyFile,
+				HistoryLimit: rootArgs.historySize,
+
+				LogFile:   rootArgs.logFile,
+				LogToErrF: rootArgs.logStderr,
+				LogLevel:  logLevel,
 			}
 
-			if cmd.Flag("exec").Changed {
-				if len(rootArgs.statements) == 0 {
-					return fmt.Errorf("no SQL command to execute")
+			if len(args) > 1 || cmd.Flag("exec").Changed {
+				inputs, err := rootArgs.statements.inputs(args[1:])
+				if err != nil {
+					return err
 				}
-
-				return shell.RunShellLine(shellConfig, rootArgs.statements)
+				return shell.RunShellInputs(shellConfig, inputs)
 			}
 
 			return shell.RunShell(shellConfig)
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&rootArgs.statements, "exec", "e", "", "SQL statements separated by ;")
+	rootArgs.statements.flags = rootCmd.Flags()
+	rootCmd.Flags().VarP(&rootArgs.statements, "exec", "e", "SQL statements separated by ;. Can be repeated, and mixed with SCRIPT files, run in the order given")
 	rootCmd.Flags().BoolVarP(&rootArgs.quiet, "quiet", "q", false, "Don't print welcome message")
 	rootCmd.Flags().StringVar(&rootArgs.authToken, "auth", "", "Add a JWT Token.")
+	rootCmd.Flags().StringVar(&rootArgs.cipherKey, "cipher-key", "", "Key of an encrypted local database, issued with PRAGMA key on connecting. Needs a build with cipher support, like SQLCipher's. Use .key to enter it without echo")
+	rootCmd.Flags().StringVar(&rootArgs.initFile, "init", "", "Execute the SQL statements of this file before any other input")
+	rootCmd.Flags().StringArrayVar(&rootArgs.initStatements, "init-sql", nil, "Execute this SQL statement before any other input, after --init. Can be repeated")
+	rootCmd.Flags().DurationVar(&rootArgs.idleTimeout, "idle-timeout", 0, "Take the idle action after this long without input at the prompt, like 30m")
+	rootCmd.Flags().StringVar(&rootArgs.idleAction, "idle-action", string(enums.IDLE_EXIT), "What to do when idle: exit, or disconnect and reconnect on the next statement")
+	rootCmd.Flags().BoolVar(&rootArgs.bail, "bail", true, "Stop a batch of statements at its first error. With --bail=false the following statements still run and the failures are summarized at the end")
+	rootCmd.Flags().BoolVar(&rootArgs.jsonErrors, "json-errors", false, "With --bail=false, write the failures to stderr as JSON lines as they happen")
+	rootCmd.Flags().BoolVar(&rootArgs.failOnEmpty, "fail-on-empty", false, "Fail the queries returning no rows, for pipelines treating an empty result as an error. Their header is still printed")
+	rootCmd.Flags().BoolVar(&rootArgs.summary, "summary", false, "Print a summary after inputs with several statements. Always on at an interactive prompt")
+	rootCmd.Flags().BoolVar(&rootArgs.sessionStats, "session-stats", false, "Print the statistics of the session on exit, like the statements executed and the slowest one. Always on at an interactive prompt")
+	rootCmd.Flags().BoolVar(&rootArgs.noPerDbSettings, "no-per-db-settings", false, "Don't load the settings saved for the database with .save-settings --per-db, nor save them on exit. The saved settings override the --init file, and --init-sql overrides them")
+	rootCmd.Flags().BoolVar(&rootArgs.noLockCheck, "no-lock-check", false, "Don't warn when another shell has the same local database file open, nor mark it open by this one")
+	rootCmd.Flags().StringVar(&rootArgs.fileRoot, "file-root", "", "Confine the files dot commands like .read and .import access to this directory, refusing the paths outside of it")
+	rootCmd.Flags().StringVar(&rootArgs.historyFile, "history-file", "", "Save the history of the interactive shell to this file instead of the one of the database in ~/.libsql")
+	rootCmd.Flags().IntVar(&rootArgs.historySize, "history-size", shell.DEFAULT_HISTORY_LIMIT, "Maximum number of entries kept in the history, the most recent ones")
+	rootCmd.Flags().StringVar(&rootArgs.logFile, "log-file", "", "Append the diagnostics, like progress, reconnection notices, slow statement notes and warnings, to this file with their time and level instead of stderr. Errors are still written to stderr")
+	rootCmd.Flags().BoolVar(&rootArgs.logStderr, "log-stderr", false, "With --log-file, write the diagnostics to stderr too")
+	rootCmd.Flags().StringVar(&rootArgs.logLevel, "log-level", string(enums.LOG_INFO), "Lowest level of the diagnostics written: info, note or warning")
+	rootCmd.Flags().IntVar(&rootArgs.resultCacheSize, "cell-cache-size", shell.DEFAULT_RESULT_CACHE_SIZE, "Maximum size in bytes of the last result kept for .cell")
+
+	rootCmd.AddCommand(newCompletionCmd())
+	registerCompletions(rootCmd)
 
 	return rootCmd
 }
 
+func isTerminal(inF io.Reader) bool {
+	file, ok := inF.(*os.File)
+	return ok && readline.IsTerminal(int(file.Fd()))
+}
+
+// ExitCode returns the exit code of the shell for the error it failed with
+func ExitCode(err error) int {
+	var authenticationErr *shellerrors.AuthenticationError
+	var networkErr *shellerrors.NetworkError
+	var notFoundErr *shellerrors.DatabaseNotFoundError
+	switch {
+	case errors.As(err, &authenticationErr):
+		return EXIT_AUTHENTICATION_FAILED
+	case errors.As(err, &networkErr):
+		return EXIT_NETWORK_FAILURE
+	case errors.As(err, &notFoundErr):
+		return EXIT_DATABASE_NOT_FOUND
+	}
+	return EXIT_FAILURE
+}
+
 func Execute() {
 	var rootCmd *cobra.Command = NewRootCmd()
 
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(ExitCode(err))
 	}
 }
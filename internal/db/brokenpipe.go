@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+// BrokenPipeWriter wraps an output whose reader may go away, like a pipe to `head` that is closed after a few lines.
+// Once a write fails because of that, onClose is called and every later write fails with OutputClosedError. It counts
+// the bytes written
+type BrokenPipeWriter struct {
+	w       io.Writer
+	closed  atomic.Bool
+	onClose func()
+	written atomic.Int64
+}
+
+func NewBrokenPipeWriter(w io.Writer, onClose func()) *BrokenPipeWriter {
+	return &BrokenPipeWriter{w: w, onClose: onClose}
+}
+
+func (b *BrokenPipeWriter) Write(p []byte) (int, error) {
+	if b.closed.Load() {
+		return 0, &shellerrors.OutputClosedError{}
+	}
+
+	n, err := b.w.Write(p)
+	b.written.Add(int64(n))
+	if err != nil && isBrokenPipeError(err) {
+		if !b.closed.Swap(true) && b.onClose != nil {
+			b.onClose()
+		}
+		return n, &shellerrors.OutputClosedError{}
+	}
+	return n, err
+}
+
+func (b *BrokenPipeWriter) Closed() bool {
+	return b.closed.Load()
+}
+
+func (b *BrokenPipeWriter) Written() int64 {
+	return b.written.Load()
+}
+
+func isBrokenPipeError(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) || errors.Is(err, io.ErrClosedPipe)
+}
+
+// IsOutputClosed reports whether the output is a BrokenPipeWriter whose reader went away, or a writer wrapping one and
+// reporting it with a Closed method of its own
+func IsOutputClosed(outF io.Writer) bool {
+	closer, ok := outF.(interface{ Closed() bool })
+	return ok && closer.Closed()
+}
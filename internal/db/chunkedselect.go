@@ -0,0 +1,61 @@
+package db
+
+// ChunkQuery returns the query selecting the chunk of rows following the one whose last row had lastKey, or the first
+// chunk when lastKey is nil. Its first column is the key the rows are ordered by, which is left out of the result
+type ChunkQuery func(lastKey interface{}) (string, error)
+
+// SelectInChunks runs the queries of chunks of at most chunkSize rows one after the other, until one returns fewer,
+// and streams their rows as the result of a single query. This keeps a server or driver buffering whole results from
+// holding more than a chunk at a time
+func (db *Db) SelectInChunks(chunkQuery ChunkQuery, chunkSize int) (StatementResult, error) {
+	first, err := db.selectChunk(chunkQuery, nil)
+	if err != nil {
+		return StatementResult{}, err
+	}
+
+	rowCh := make(chan rowResult)
+	go func() {
+		defer close(rowCh)
+		chunk := first
+		for {
+			rows := 0
+			var lastKey interface{}
+			for row := range chunk.RowCh {
+				if row.Err != nil {
+					rowCh <- row
+					return
+				}
+				rows++
+				lastKey = row.Row[0]
+				rowCh <- rowResult{Row: row.Row[1:]}
+			}
+			if rows < chunkSize {
+				return
+			}
+
+			chunk, err = db.selectChunk(chunkQuery, lastKey)
+			if err != nil {
+				rowCh <- rowResult{Err: err}
+				return
+			}
+		}
+	}()
+
+	return StatementResult{Statement: first.Statement, ColumnNames: first.ColumnNames[1:], RowCh: rowCh}, nil
+}
+
+func (db *Db) selectChunk(chunkQuery ChunkQuery, lastKey interface{}) (StatementResult, error) {
+	query, err := chunkQuery(lastKey)
+	if err != nil {
+		return StatementResult{}, err
+	}
+	result, err := db.ExecuteStatements(query)
+	if err != nil {
+		return StatementResult{}, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return StatementResult{}, statementResult.Err
+	}
+	return statementResult, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"sync"
+
+	sqlite3driver "github.com/mattn/go-sqlite3"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+var cipherSupport struct {
+	once      sync.Once
+	supported bool
+}
+
+// CipherSupported reports whether the SQLite driver the shell is built with can open encrypted databases, which only
+// builds linking SQLCipher or a compatible library can. They answer PRAGMA cipher_version with their version
+func CipherSupported() bool {
+	cipherSupport.once.Do(func() {
+		sqlDb, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			return
+		}
+		defer sqlDb.Close()
+		var version string
+		cipherSupport.supported = sqlDb.QueryRow("PRAGMA cipher_version;").Scan(&version) == nil && version != ""
+	})
+	return cipherSupport.supported
+}
+
+// cipherConnector opens connections to a local database issuing PRAGMA key before any other statement. The key is
+// given to each connection of the pool, as it only applies to the connection it's issued on
+type cipherConnector struct {
+	dsn          string
+	sqliteDriver *sqlite3driver.SQLiteDriver
+}
+
+func newCipherConnector(dsn string, key string) *cipherConnector {
+	keyPragma := fmt.Sprintf("PRAGMA key = '%s';", EscapeSingleQuotes(key))
+	return &cipherConnector{dsn: dsn, sqliteDriver: &sqlite3driver.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3driver.SQLiteConn) error {
+			_, err := conn.Exec(keyPragma, nil)
+			return err
+		},
+	}}
+}
+
+func (c *cipherConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	return c.sqliteDriver.Open(c.dsn)
+}
+
+func (c *cipherConnector) Driver() sqldriver.Driver {
+	return c.sqliteDriver
+}
+
+func openSqliteDb(dsn string, cipherKey string) (*sql.DB, error) {
+	if cipherKey == "" {
+		return sql.Open("sqlite3", dsn)
+	}
+	return sql.OpenDB(newCipherConnector(dsn, cipherKey)), nil
+}
+
+// SetCipherKey reconnects to the local database with the key of its encryption, issued with PRAGMA key on each
+// connection, and checks that the database can be read with it. Like Open, it replays the session statements, and the
+// current connection is kept when the new one can't be established
+func (db *Db) SetCipherKey(key string) ([]SessionReplayResult, error) {
+	if !CipherSupported() {
+		return nil, &shellerrors.CipherUnsupportedError{}
+	}
+	if db.driver != sqlite3 {
+		return nil, fmt.Errorf("a cipher key only applies to local databases")
+	}
+
+	db.mu.Lock()
+	previousKey := db.cipherKey
+	db.cipherKey = key
+	db.mu.Unlock()
+	replayResults, err := db.Open("", "")
+	if err != nil {
+		db.mu.Lock()
+		db.cipherKey = previousKey
+		db.mu.Unlock()
+		return nil, err
+	}
+	return replayResults, nil
+}
+
+// checkCipherKey reads the schema, which fails when the key is wrong or the database isn't encrypted, as a trivial
+// query like SELECT 1 doesn't read the file
+func checkCipherKey(sqlDb *sql.DB) error {
+	var count int
+	if err := sqlDb.QueryRow("SELECT count(*) FROM sqlite_master;").Scan(&count); err != nil {
+		return &shellerrors.InvalidCipherKeyError{Err: err}
+	}
+	return nil
+}
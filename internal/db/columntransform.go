@@ -0,0 +1,54 @@
+package db
+
+import sqldriver "database/sql/driver"
+
+// ColumnTransform replaces a value of a column with the one it returns along with true, before it's formatted. It's
+// given the raw value read from the database, like an int64, a float64, a string, a []byte or nil, and returning false
+// keeps it as it is
+type ColumnTransform func(column string, value interface{}) (interface{}, bool)
+
+// TransformRow returns the values of a row of the columns, replaced by the transform. The row itself isn't changed.
+// The values of the typed columns, read as the sql.Null types, are given to the transform as the value they hold
+func TransformRow(transform ColumnTransform, columnNames []string, row []interface{}) []interface{} {
+	transformed := make([]interface{}, len(row))
+	for i, value := range row {
+		if i < len(columnNames) {
+			if newValue, ok := transform(columnNames[i], getRawValue(value)); ok {
+				value = newValue
+			}
+		}
+		transformed[i] = value
+	}
+	return transformed
+}
+
+// transformColumns replaces the values of the rows with the ones given by the transform
+func transformColumns(statementResult StatementResult, transform ColumnTransform) StatementResult {
+	rowCh := statementResult.RowCh
+	transformedRowCh := make(chan rowResult)
+	go func() {
+		defer close(transformedRowCh)
+		for row := range rowCh {
+			if row.Err == nil {
+				// The row is copied, as it may be retained by the result cache
+				row.Row = TransformRow(transform, statementResult.ColumnNames, row.Row)
+			}
+			transformedRowCh <- row
+		}
+	}()
+
+	statementResult.RowCh = transformedRowCh
+	return statementResult
+}
+
+func getRawValue(value interface{}) interface{} {
+	valuer, ok := value.(sqldriver.Valuer)
+	if !ok {
+		return value
+	}
+	rawValue, err := valuer.Value()
+	if err != nil {
+		return value
+	}
+	return rawValue
+}
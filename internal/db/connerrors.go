@@ -0,0 +1,94 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	sqlite3driver "github.com/mattn/go-sqlite3"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+const connectionProbeTimeout = 10 * time.Second
+
+// classifyConnectionError returns the typed error of a failed connection, telling authentication, network and not
+// found failures apart, or err itself when it's none of them. The driver doesn't report the HTTP status of the
+// requests it makes, so servers are probed with a request of their own to learn why they refused the connection
+func (db *Db) classifyConnectionError(err error) error {
+	if db.driver == sqlite3 {
+		var sqliteErr sqlite3driver.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3driver.ErrCantOpen {
+			return &shellerrors.DatabaseNotFoundError{Database: db.Uri}
+		}
+		return err
+	}
+
+	probeUrl, authToken, parseErr := getConnectionProbeUrl(db.Uri)
+	if parseErr != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionProbeTimeout)
+	defer cancel()
+	request, requestErr := http.NewRequestWithContext(ctx, "POST", probeUrl.String(), bytes.NewReader([]byte(`{"statements":["SELECT 1"]}`)))
+	if requestErr != nil {
+		return err
+	}
+	if authToken != "" {
+		request.Header.Set("Authorization", "Bearer "+authToken)
+	}
+
+	response, probeErr := http.DefaultClient.Do(request)
+	if probeErr != nil {
+		var urlErr *url.Error
+		if errors.As(probeErr, &urlErr) {
+			probeErr = urlErr.Err
+		}
+		return &shellerrors.NetworkError{Host: probeUrl.Host, Err: probeErr}
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &shellerrors.AuthenticationError{StatusCode: response.StatusCode}
+	case http.StatusNotFound:
+		return &shellerrors.DatabaseNotFoundError{Database: probeUrl.String(), StatusCode: response.StatusCode}
+	}
+	return err
+}
+
+// getConnectionProbeUrl returns the HTTP URL of a database server, without the auth token, which is returned apart.
+// Servers reached through libsql:// and WebSocket URLs answer HTTP requests too
+func getConnectionProbeUrl(dbUri string) (probeUrl *url.URL, authToken string, err error) {
+	dbUrl, err := url.Parse(dbUri)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := dbUrl.Query()
+	for _, name := range []string{"authToken", "auth_token", "jwt"} {
+		if token := query.Get(name); token != "" {
+			authToken = token
+		}
+	}
+
+	probeUrl = &url.URL{Scheme: dbUrl.Scheme, Host: dbUrl.Host, Path: dbUrl.Path}
+	tls := query.Get("tls")
+	switch dbUrl.Scheme {
+	case "libsql", "wss":
+		probeUrl.Scheme = "https"
+		if tls == "0" {
+			probeUrl.Scheme = "http"
+		}
+	case "ws":
+		probeUrl.Scheme = "http"
+		if tls == "1" {
+			probeUrl.Scheme = "https"
+		}
+	}
+	return probeUrl, authToken, nil
+}
@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+func newStatusServer(t *testing.T, statusCode int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTestConnection_GivenServerRejectingTheToken_ExpectAuthenticationError(t *testing.T) {
+	c := qt.New(t)
+
+	var receivedAuthorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedAuthorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	serverDb, err := db.NewDb(server.URL, "expired")
+	c.Assert(err, qt.IsNil)
+	defer serverDb.Close()
+
+	err = serverDb.TestConnection()
+	var authenticationErr *shellerrors.AuthenticationError
+	c.Assert(errors.As(err, &authenticationErr), qt.IsTrue)
+	c.Assert(err.Error(), qt.Equals, "authentication failed (401): check the token given with --auth")
+	c.Assert(receivedAuthorization, qt.Equals, "Bearer expired")
+}
+
+func TestTestConnection_GivenServerWithoutTheDatabase_ExpectDatabaseNotFoundError(t *testing.T) {
+	c := qt.New(t)
+
+	server := newStatusServer(t, http.StatusNotFound)
+	serverDb, err := db.NewDb(server.URL, "token")
+	c.Assert(err, qt.IsNil)
+	defer serverDb.Close()
+
+	err = serverDb.TestConnection()
+	var notFoundErr *shellerrors.DatabaseNotFoundError
+	c.Assert(errors.As(err, &notFoundErr), qt.IsTrue)
+	c.Assert(err.Error(), qt.Equals, "database not found (404): check the database URL "+server.URL)
+}
+
+func TestTestConnection_GivenUnreachableServer_ExpectNetworkError(t *testing.T) {
+	c := qt.New(t)
+
+	server := newStatusServer(t, http.StatusOK)
+	server.Close()
+	serverDb, err := db.NewDb(strings.Replace(server.URL, "http://", "libsql://", 1)+"?tls=0", "")
+	c.Assert(err, qt.IsNil)
+	defer serverDb.Close()
+
+	err = serverDb.TestConnection()
+	var networkErr *shellerrors.NetworkError
+	c.Assert(errors.As(err, &networkErr), qt.IsTrue)
+	c.Assert(networkErr.Host, qt.Equals, strings.TrimPrefix(server.URL, "http://"))
+	c.Assert(err, qt.ErrorMatches, `unable to reach .*connection refused\. Retry later, or check the database URL`)
+}
+
+func TestTestConnection_GivenOtherServerFailure_ExpectGenericError(t *testing.T) {
+	c := qt.New(t)
+
+	server := newStatusServer(t, http.StatusInternalServerError)
+	serverDb, err := db.NewDb(server.URL, "")
+	c.Assert(err, qt.IsNil)
+	defer serverDb.Close()
+
+	err = serverDb.TestConnection()
+	c.Assert(err, qt.ErrorMatches, "(?s)failed to connect to database.*")
+}
+
+func TestTestConnection_GivenLocalDatabaseInMissingDirectory_ExpectDatabaseNotFoundError(t *testing.T) {
+	c := qt.New(t)
+
+	path := t.TempDir() + "/missing/test.sqlite"
+	localDb, err := db.NewDb(path, "")
+	c.Assert(err, qt.IsNil)
+	defer localDb.Close()
+
+	err = localDb.TestConnection()
+	var notFoundErr *shellerrors.DatabaseNotFoundError
+	c.Assert(errors.As(err, &notFoundErr), qt.IsTrue)
+	c.Assert(err.Error(), qt.Equals, "database not found: unable to open "+path)
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+)
+
+// ConnectionEvent describes a connection to the database being opened or closed
+type ConnectionEvent struct {
+	// Url is the database connected to, with its auth token redacted
+	Url string
+	// Duration is how long connecting took when the connection is opened, and how long it was open when it's closed
+	Duration time.Duration
+	// DB is the live connection when it's opened, nil when it's closed. It's shared with the shell, which still owns
+	// it: it must not be closed, nor used after the connection is closed
+	DB *sql.DB
+}
+
+// ConnectionHooks are called after each connection is opened or closed, including the reconnections after idle
+// disconnects and the connections made by .open
+type ConnectionHooks struct {
+	OnConnect    func(event ConnectionEvent)
+	OnDisconnect func(event ConnectionEvent)
+}
+
+// connectionNotice calls the hook of a connection event. Events are found with mu held and noticed after it's
+// released, so the hooks can use the Db
+type connectionNotice func()
+
+func noConnectionNotice() {}
+
+// SetConnectionHooks sets the hooks called when connections are opened or closed
+func (db *Db) SetConnectionHooks(hooks ConnectionHooks) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.hooks = hooks
+}
+
+// SqlDb returns the live connection, opening it again if it was disconnected. It's shared with the shell, which still
+// owns it: it must not be closed
+func (db *Db) SqlDb() (*sql.DB, error) {
+	return db.getSqlDb()
+}
+
+// connected must be called with mu held, once the connection is established
+func (db *Db) connected(startTime time.Time) connectionNotice {
+	db.connectedAt = time.Now()
+	if db.hooks.OnConnect == nil {
+		return noConnectionNotice
+	}
+	onConnect := db.hooks.OnConnect
+	event := ConnectionEvent{Url: RedactUri(db.Uri), Duration: db.connectedAt.Sub(startTime), DB: db.sqlDb}
+	return func() { onConnect(event) }
+}
+
+// disconnected must be called with mu held, once the connection is closed
+func (db *Db) disconnected() connectionNotice {
+	if db.connectedAt.IsZero() {
+		return noConnectionNotice
+	}
+	event := ConnectionEvent{Url: RedactUri(db.Uri), Duration: time.Since(db.connectedAt)}
+	db.previousConnectedTime += event.Duration
+	db.connectedAt = time.Time{}
+	if db.hooks.OnDisconnect == nil {
+		return noConnectionNotice
+	}
+	onDisconnect := db.hooks.OnDisconnect
+	return func() { onDisconnect(event) }
+}
+
+// ConnectedTime returns how long the database was connected to, adding up all the connections
+func (db *Db) ConnectedTime() time.Duration {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.connectedAt.IsZero() {
+		return db.previousConnectedTime
+	}
+	return db.previousConnectedTime + time.Since(db.connectedAt)
+}
+
+// RedactUri returns the database URI with the auth token given in its query, or the password of its user, replaced
+func RedactUri(uri string) string {
+	if !IsUrl(uri) {
+		return uri
+	}
+	dbUrl, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := dbUrl.Query()
+	for _, key := range []string{"authToken", "jwt"} {
+		if query.Has(key) {
+			query.Set(key, "xxxxx")
+		}
+	}
+	dbUrl.RawQuery = query.Encode()
+	return dbUrl.Redacted()
+}
@@ -6,11 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/url"
-	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/libsql/libsql-client-go/libsql"
-	"github.com/libsql/sqlite-antlr4-parser/sqliteparserutils"
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
@@ -31,26 +32,69 @@ type Db struct {
 	driver    driver
 	urlScheme string
 
-	cancelRunningQuery func()
+	// mu guards the fields below and the connection fields, so a Db can be shared between goroutines
+	mu                  sync.Mutex
+	closed              bool
+	runningQueryCancels map[uint64]context.CancelFunc
+	nextRunningQueryId  uint64
+	sessionStatements   []string
+	// unreportedReplayResults are the results of replaying the session statements on a reconnection that Reconnect
+	// hasn't returned yet
+	unreportedReplayResults []SessionReplayResult
+
+	// queryCount is how many queries were sent to the database, each a round trip to remote ones
+	queryCount uint64
+	// queryTrace, when set, is called with each query as it's sent to the database
+	queryTrace func(query string)
+	hooks      ConnectionHooks
+	// connectedAt is when the current connection was established, zero when there's none
+	connectedAt time.Time
+	// previousConnectedTime adds up how long the connections before the current one were open
+	previousConnectedTime time.Duration
+	// cipherKey is the key of the encrypted local database set by SetCipherKey, issued on each connection
+	cipherKey string
+	// transactionConn is the connection of the read transaction begun by BeginReadTransaction, nil when there's none
+	transactionConn *sql.Conn
+	// limits are the statement limits of the current connection, nil until StatementLimits reads them, and
+	// limitOverrides the ones set in their place by SetStatementLimit, 0 when unset
+	limits         *StatementLimits
+	limitOverrides StatementLimits
+}
+
+type SessionReplayResult struct {
+	Statement string
+	Err       error
 }
 
 type StatementsResult struct {
 	StatementResultCh chan StatementResult
+	// StatementCount is how many statements are executed, even when the database receives them all in a single query
+	StatementCount int
+	// EmptyStatements is how many empty statements, like the ones of ;;, were left out rather than sent
+	EmptyStatements int
 }
 
 type StatementResult struct {
+	// Statement is the query that produced the result
+	Statement   string
 	ColumnNames []string
 	RowCh       chan rowResult
-	Err         error
+	// RowsChanged is the number of rows changed by INSERT, UPDATE, DELETE and REPLACE statements, which ChangesCounted
+	// reports the statement is one of
+	RowsChanged    int64
+	ChangesCounted bool
+	// LastInsertRowid is the rowid of the last row inserted by INSERT and REPLACE statements, 0 for the others
+	LastInsertRowid int64
+	Err             error
 }
 
-func newStatementResult(columnNames []string, rowCh chan rowResult) *StatementResult {
-	return &StatementResult{ColumnNames: columnNames, RowCh: rowCh}
+func newStatementResult(statement string, columnNames []string, rowCh chan rowResult) *StatementResult {
+	return &StatementResult{Statement: statement, ColumnNames: columnNames, RowCh: rowCh}
 }
 
-func newStatementResultWithError(err error) *StatementResult {
+func newStatementResultWithError(statement string, err error) *StatementResult {
 	treatedErr := treatDbError(err)
-	return &StatementResult{Err: treatedErr}
+	return &StatementResult{Statement: statement, Err: treatedErr}
 }
 
 type rowResult struct {
@@ -82,25 +126,31 @@ func addAuthTokenAsQueryParameter(dbUri string, authToken string) (string, error
 }
 
 func NewDb(dbUri string, authToken string) (*Db, error) {
+	return newDb(dbUri, authToken, "")
+}
+
+func newDb(dbUri string, authToken string, cipherKey string) (*Db, error) {
 	var err error
 	dbUrl, err := addAuthTokenAsQueryParameter(dbUri, authToken)
 	if err != nil {
 		return nil, err
 	}
 
-	var db = Db{Uri: dbUrl}
+	var db = Db{Uri: dbUrl, cipherKey: cipherKey}
 
 	if IsUrl(dbUrl) {
-		var validSqldUrl bool
-		if validSqldUrl, db.urlScheme = IsValidSqldUrl(dbUrl); validSqldUrl {
-			db.driver = libsql
-			db.sqlDb, err = sql.Open("libsql", dbUrl)
-		} else {
-			return nil, &shellerrors.ProtocolError{}
+		var sqldUrl string
+		if sqldUrl, err = NormalizeSqldUrl(dbUrl); err != nil {
+			return nil, err
 		}
+		db.driver = libsql
+		db.urlScheme, _, _ = strings.Cut(sqldUrl, ":")
+		db.sqlDb, err = sql.Open("libsql", sqldUrl)
+	} else if looksLikeSqldHost(dbUri) {
+		return nil, &shellerrors.MissingProtocolError{Host: strings.TrimSuffix(dbUri, "/")}
 	} else {
 		db.driver = sqlite3
-		db.sqlDb, err = sql.Open("sqlite3", dbUri)
+		db.sqlDb, err = openSqliteDb(dbUri, cipherKey)
 	}
 	if err != nil {
 		return nil, err
@@ -109,45 +159,251 @@ func NewDb(dbUri string, authToken string) (*Db, error) {
 }
 
 func (db *Db) TestConnection() error {
-	_, err := db.sqlDb.Exec("SELECT 1;")
+	startTime := time.Now()
+	sqlDb, err := db.getSqlDb()
+	if err == nil {
+		_, err = sqlDb.Exec("SELECT 1;")
+	}
+	if err == nil && db.cipherKey != "" {
+		if err := checkCipherKey(sqlDb); err != nil {
+			return err
+		}
+	}
 	if err != nil {
+		if classifiedErr := db.classifyConnectionError(err); classifiedErr != err {
+			return classifiedErr
+		}
 		return fmt.Errorf("failed to connect to database. err: %v", err)
 	}
+
+	db.mu.Lock()
+	notice := noConnectionNotice
+	if db.connectedAt.IsZero() {
+		notice = db.connected(startTime)
+	}
+	db.mu.Unlock()
+	notice()
 	return nil
 }
 
+// IsRemote reports whether the database is reached through a server rather than opened as a local file
+func (db *Db) IsRemote() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.driver != sqlite3
+}
+
 func (db *Db) Close() {
-	db.sqlDb.Close()
+	db.mu.Lock()
+	db.closed = true
+	sqlDb, notice := db.detachConnection()
+	db.mu.Unlock()
+	closeSqlDb(sqlDb)
+	notice()
+}
+
+// Disconnect closes the connection. Unlike after Close, the Db can still be used: the connection is opened again, and
+// the session statements replayed, when it's needed
+func (db *Db) Disconnect() {
+	db.mu.Lock()
+	sqlDb, notice := db.detachConnection()
+	db.mu.Unlock()
+	closeSqlDb(sqlDb)
+	notice()
+}
+
+// detachConnection must be called with mu held. It returns the connection for the caller to close once mu is released,
+// as closing it waits for the queries running on it, which take mu to be tracked
+func (db *Db) detachConnection() (*sql.DB, connectionNotice) {
+	if db.sqlDb == nil {
+		return nil, noConnectionNotice
+	}
+	sqlDb := db.sqlDb
+	db.sqlDb = nil
+	db.limits = nil
+	return sqlDb, db.disconnected()
+}
+
+func closeSqlDb(sqlDb *sql.DB) {
+	if sqlDb != nil {
+		sqlDb.Close()
+	}
+}
+
+func (db *Db) getSqlDb() (*sql.DB, error) {
+	db.mu.Lock()
+	closed, sqlDb := db.closed, db.sqlDb
+	db.mu.Unlock()
+	if closed {
+		return nil, sql.ErrConnDone
+	}
+	if sqlDb != nil {
+		return sqlDb, nil
+	}
+	return db.reopenConnection()
+}
+
+// Reconnect opens the connection again if Disconnect closed it, and returns the results of replaying the session
+// statements that weren't returned yet, including the ones of connections reopened by statements
+func (db *Db) Reconnect() ([]SessionReplayResult, error) {
+	if _, err := db.getSqlDb(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	replayResults := db.unreportedReplayResults
+	db.unreportedReplayResults = nil
+	return replayResults, nil
+}
+
+// reopenConnection opens the connection again and replays the session statements on it without holding mu, so the
+// other goroutines aren't blocked meanwhile. When one of them reopened it first, its connection is kept. The
+// connection is only noticed once its session statements are replayed
+func (db *Db) reopenConnection() (*sql.DB, error) {
+	startTime := time.Now()
+	db.mu.Lock()
+	uri, cipherKey, statements := db.Uri, db.cipherKey, append([]string(nil), db.sessionStatements...)
+	db.mu.Unlock()
+
+	newDb, err := newDb(uri, "", cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	replayResults := replaySessionStatements(newDb.sqlDb, statements)
+
+	db.mu.Lock()
+	if db.closed || db.sqlDb != nil {
+		closed, sqlDb := db.closed, db.sqlDb
+		db.mu.Unlock()
+		newDb.sqlDb.Close()
+		if closed {
+			return nil, sql.ErrConnDone
+		}
+		return sqlDb, nil
+	}
+	db.sqlDb = newDb.sqlDb
+	db.unreportedReplayResults = append(db.unreportedReplayResults, replayResults...)
+	notice := db.connected(startTime)
+	db.mu.Unlock()
+	notice()
+	return newDb.sqlDb, nil
+}
+
+// Open connects to a new database, or reconnects to the current one when dbUri is empty, and replays the tracked session
+// statements on the new connection. The current connection is kept if the new one can't be established. The cipher key
+// is only kept when reconnecting. The new connection is set up without holding mu, and the current one closed once
+// it's replaced
+func (db *Db) Open(dbUri string, authToken string) ([]SessionReplayResult, error) {
+	cipherKey := ""
+	db.mu.Lock()
+	if dbUri == "" {
+		dbUri = db.Uri
+		cipherKey = db.cipherKey
+	}
+	statements := append([]string(nil), db.sessionStatements...)
+	db.mu.Unlock()
+
+	startTime := time.Now()
+	newDb, err := newDb(dbUri, authToken, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := newDb.TestConnection(); err != nil {
+		newDb.Close()
+		return nil, err
+	}
+	replayResults := replaySessionStatements(newDb.sqlDb, statements)
+
+	db.mu.Lock()
+	previousSqlDb, disconnectNotice := db.detachConnection()
+	db.Uri = newDb.Uri
+	db.sqlDb = newDb.sqlDb
+	db.driver = newDb.driver
+	db.urlScheme = newDb.urlScheme
+	db.cipherKey = newDb.cipherKey
+	connectNotice := db.connected(startTime)
+	db.mu.Unlock()
+	closeSqlDb(previousSqlDb)
+	disconnectNotice()
+	connectNotice()
+	return replayResults, nil
+}
+
+// replaySessionStatements executes the session statements on a new connection, which isn't shared yet, so neither mu
+// nor the tracking of the statements executed is involved
+func replaySessionStatements(sqlDb *sql.DB, statements []string) []SessionReplayResult {
+	results := make([]SessionReplayResult, 0, len(statements))
+	for _, statement := range statements {
+		_, err := sqlDb.Exec(statement)
+		results = append(results, SessionReplayResult{Statement: statement, Err: err})
+	}
+	return results
+}
+
+// SessionStatements returns the statements executed so far that change connection state, in execution order
+func (db *Db) SessionStatements() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.sessionStatements...)
+}
+
+func (db *Db) ClearSessionStatements() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.sessionStatements = nil
 }
 
 func (db *Db) ExecuteStatements(statementsString string) (StatementsResult, error) {
-	queries := db.prepareStatementsIntoQueries(statementsString)
+	return db.executeStatements(context.Background(), statementsString, false)
+}
+
+// ExecuteStatementsContext is like ExecuteStatements, but canceling ctx cancels the running query and stops the
+// statements after it, and ends the goroutine feeding the channels of the result, which is then closed without the
+// results left unread. Callers that may stop reading the results before their end cancel ctx once they're done
+func (db *Db) ExecuteStatementsContext(ctx context.Context, statementsString string) (StatementsResult, error) {
+	return db.executeStatements(ctx, statementsString, false)
+}
+
+// executeStatements runs the statements in the background, stopping at the first one that fails unless
+// continueOnError is set, or when ctx is canceled
+func (db *Db) executeStatements(ctx context.Context, statementsString string, continueOnError bool) (StatementsResult, error) {
+	queries, statementCount, emptyStatements := db.prepareStatementsIntoQueries(statementsString)
 
 	statementResultCh := make(chan StatementResult)
 
 	go func() {
 		defer close(statementResultCh)
-		db.executeQueriesAndPopulateChannel(queries, statementResultCh)
+		db.executeQueriesAndPopulateChannel(ctx, queries, statementResultCh, continueOnError)
 	}()
 
-	return StatementsResult{StatementResultCh: statementResultCh}, nil
+	return StatementsResult{StatementResultCh: statementResultCh, StatementCount: statementCount, EmptyStatements: emptyStatements}, nil
 }
 
-func (db *Db) executeQueriesAndPopulateChannel(queries []string, statementResultCh chan StatementResult) {
+func (db *Db) executeQueriesAndPopulateChannel(ctx context.Context, queries []string, statementResultCh chan StatementResult, continueOnError bool) {
 	for _, query := range queries {
-		if shouldContinue := db.executeQuery(query, statementResultCh); !shouldContinue {
+		if ctx.Err() != nil {
+			return
+		}
+		if shouldContinue := db.executeQuery(ctx, query, statementResultCh); !shouldContinue && !continueOnError {
 			return
 		}
 	}
 }
 
-func (db *Db) ExecuteAndPrintStatements(statementsString string, outF io.Writer, withoutHeader bool, printMode enums.PrintMode) error {
-	result, err := db.ExecuteStatements(statementsString)
+func (db *Db) ExecuteAndPrintStatements(statementsString string, outF io.Writer, options PrintOptions) error {
+	if options.Mode == enums.SQL_MODE {
+		options.limits = db.StatementLimits()
+	}
+	// The results left unread when printing stops early, like when the output is closed, are dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result, err := db.executeStatements(ctx, statementsString, options.ErrorLog != nil)
 	if err != nil {
 		return err
 	}
 
-	err = PrintStatementsResult(result, outF, withoutHeader, printMode)
+	err = PrintStatementsResult(result, outF, options)
 	if err != nil {
 		return err
 	}
@@ -155,41 +411,171 @@ func (db *Db) ExecuteAndPrintStatements(statementsString string, outF io.Writer,
 	return nil
 }
 
-func (db *Db) executeQuery(query string, statementResultCh chan StatementResult) (queryEndedWithoutError bool) {
+// SetQueryTrace sets the function called with each query as it's sent to the database, or removes it when nil
+func (db *Db) SetQueryTrace(trace func(query string)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queryTrace = trace
+}
+
+// traceQuery counts a query sent to the database and passes it to the query trace
+func (db *Db) traceQuery(query string) {
+	atomic.AddUint64(&db.queryCount, 1)
+	db.mu.Lock()
+	trace := db.queryTrace
+	db.mu.Unlock()
+	if trace != nil {
+		trace(query)
+	}
+}
+
+// QueryCount returns how many queries were sent to the database since it was created
+func (db *Db) QueryCount() uint64 {
+	return atomic.LoadUint64(&db.queryCount)
+}
+
+// executeQuery runs the query and sends its results, until ctx is canceled. The query itself is canceled by CancelQuery
+// too, which is reported by its result rather than ending the results
+func (db *Db) executeQuery(ctx context.Context, query string, statementResultCh chan StatementResult) (queryEndedWithoutError bool) {
 	if strings.TrimSpace(query) == "" {
 		return true
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	db.cancelRunningQuery = cancel
+	queryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runningQueryId := db.addRunningQuery(cancel)
+	defer db.removeRunningQuery(runningQueryId)
+
+	sqlDb, err := db.getQueryer()
+	if err != nil {
+		sendStatementResult(ctx, statementResultCh, *newStatementResultWithError(query, err))
+
+		return false
+	}
+	db.traceQuery(query)
+
+	if isChangeStatement(query) {
+		return executeChangeStatement(ctx, queryCtx, sqlDb, query, statementResultCh)
+	}
 
-	rows, err := db.sqlDb.QueryContext(ctx, query)
+	rows, err := sqlDb.QueryContext(queryCtx, query)
 	if err != nil {
-		statementResultCh <- *newStatementResultWithError(err)
+		sendStatementResult(ctx, statementResultCh, *newStatementResultWithError(query, err))
 
 		return false
 	}
 
 	defer rows.Close()
 
-	return readQueryResults(rows, statementResultCh)
+	queryEndedWithoutError = readQueryResults(ctx, rows, query, statementResultCh)
+	if queryEndedWithoutError {
+		db.trackSessionStatements(query)
+	}
+	return queryEndedWithoutError
+}
+
+// executeChangeStatement executes a statement that returns no rows with queryCtx, to report how many rows it changed
+func executeChangeStatement(ctx context.Context, queryCtx context.Context, sqlDb sqlQueryer, query string, statementResultCh chan StatementResult) (queryEndedWithoutError bool) {
+	result, err := sqlDb.ExecContext(queryCtx, query)
+	if err != nil {
+		sendStatementResult(ctx, statementResultCh, *newStatementResultWithError(query, err))
+		return false
+	}
+
+	// Drivers that can't tell report no changes rather than failing a statement that succeeded
+	rowsChanged, err := result.RowsAffected()
+	if err != nil {
+		rowsChanged = 0
+	}
+
+	var lastInsertRowid int64
+	if isInsertStatement(query) {
+		if lastInsertRowid, err = result.LastInsertId(); err != nil {
+			lastInsertRowid = 0
+		}
+	}
+
+	rowCh := make(chan rowResult)
+	close(rowCh)
+	return sendStatementResult(ctx, statementResultCh, StatementResult{Statement: query, RowCh: rowCh, RowsChanged: rowsChanged, ChangesCounted: true, LastInsertRowid: lastInsertRowid})
+}
+
+// sendStatementResult sends the result to be read, reporting false when ctx is canceled first, as nothing reads it then
+func sendStatementResult(ctx context.Context, statementResultCh chan StatementResult, result StatementResult) bool {
+	select {
+	case statementResultCh <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendRowResult is like sendStatementResult, for the rows of a result
+func sendRowResult(ctx context.Context, rowCh chan rowResult, row rowResult) bool {
+	select {
+	case rowCh <- row:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (db *Db) trackSessionStatements(query string) {
+	if !mayContainSessionStatement(query) {
+		return
+	}
+
+	// Queries sent to HTTP servers aren't split, so a single query may mix session and regular statements
+	statements, _ := splitStatements(query)
+	for _, statement := range statementTexts(statements) {
+		if isSessionStatement(statement) {
+			db.mu.Lock()
+			db.sessionStatements = append(db.sessionStatements, statement)
+			db.mu.Unlock()
+		}
+	}
+}
+
+func (db *Db) addRunningQuery(cancel context.CancelFunc) uint64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.runningQueryCancels == nil {
+		db.runningQueryCancels = make(map[uint64]context.CancelFunc)
+	}
+	db.nextRunningQueryId++
+	db.runningQueryCancels[db.nextRunningQueryId] = cancel
+	return db.nextRunningQueryId
+}
+
+func (db *Db) removeRunningQuery(id uint64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.runningQueryCancels, id)
 }
 
-func (db *Db) prepareStatementsIntoQueries(statementsString string) []string {
+// prepareStatementsIntoQueries returns the queries to send to the database, how many statements they hold and how many
+// empty statements were left out of them
+func (db *Db) prepareStatementsIntoQueries(statementsString string) (queries []string, statementCount int, emptyStatements int) {
 	// sqlite3 driver just run the first query that we send. So we must split the statements and send them one by one
 	// e.g If we execute query "select 1; select 2;" with it, just the first one ("select 1;") would be executed
 	//
 	// libsql driver doesn't accept multiple statements if using websocket connection
+	db.mu.Lock()
 	mustSplitStatementsIntoMultipleQueries :=
 		db.driver == sqlite3 ||
-			db.driver == libsql && (db.urlScheme == "libsql" || db.urlScheme == "wss" || db.urlScheme == "ws")
+			db.driver == libsql && (db.urlScheme == "wss" || db.urlScheme == "ws")
+	db.mu.Unlock()
 
+	statements, info := splitStatements(statementsString)
 	if mustSplitStatementsIntoMultipleQueries {
-		stmts, _ := sqliteparserutils.SplitStatement(statementsString)
-		return stmts
+		return statementTexts(statements), len(statements), info.emptyStatements
+	}
+	// The server may fail on the empty statements, so the script is only sent as written without any
+	if info.emptyStatements > 0 && len(statements) > 0 {
+		return []string{strings.Join(statementTexts(statements), ";\n")}, len(statements), info.emptyStatements
 	}
 
-	return []string{statementsString}
+	return []string{statementsString}, len(statements), info.emptyStatements
 }
 
 func getColumnNames(rows *sql.Rows) ([]string, error) {
@@ -206,24 +592,22 @@ func getColumnNames(rows *sql.Rows) ([]string, error) {
 	return columnNames, nil
 }
 
-func getColumnTypes(rows *sql.Rows) ([]reflect.Type, error) {
-	columnTypes, err := rows.ColumnTypes()
-	if err != nil {
-		return nil, err
-	}
-	types := make([]reflect.Type, len(columnTypes))
-
-	for i, ct := range columnTypes {
-		types[i] = ct.ScanType()
+// normalizeValue returns a value read from a driver the way every driver reads it. The local one reads the integers of
+// BOOLEAN columns as booleans, which are returned as the 1 and 0 they're stored as, like the remote ones read them
+func normalizeValue(value interface{}) interface{} {
+	if boolean, ok := value.(bool); ok {
+		if boolean {
+			return int64(1)
+		}
+		return int64(0)
 	}
-
-	return types, nil
+	return value
 }
 
-func readQueryResults(queryRows *sql.Rows, statementResultCh chan StatementResult) (shouldContinue bool) {
+func readQueryResults(ctx context.Context, queryRows *sql.Rows, query string, statementResultCh chan StatementResult) (shouldContinue bool) {
 	hasResultSetToRead := true
 	for hasResultSetToRead {
-		if shouldContinue := readQueryResultSet(queryRows, statementResultCh); !shouldContinue {
+		if shouldContinue := readQueryResultSet(ctx, queryRows, query, statementResultCh); !shouldContinue {
 			return false
 		}
 
@@ -231,67 +615,64 @@ func readQueryResults(queryRows *sql.Rows, statementResultCh chan StatementResul
 	}
 
 	if err := queryRows.Err(); err != nil {
-		statementResultCh <- *newStatementResultWithError(err)
+		sendStatementResult(ctx, statementResultCh, *newStatementResultWithError(query, err))
 		return false
 	}
 
 	return true
 }
 
-func readQueryResultSet(queryRows *sql.Rows, statementResultCh chan StatementResult) (shouldContinue bool) {
+func readQueryResultSet(ctx context.Context, queryRows *sql.Rows, query string, statementResultCh chan StatementResult) (shouldContinue bool) {
 	columnNames, err := getColumnNames(queryRows)
 	if err != nil {
-		statementResultCh <- *newStatementResultWithError(err)
-		return false
-	}
-
-	columnTypes, err := getColumnTypes(queryRows)
-	if err != nil {
-		statementResultCh <- *newStatementResultWithError(err)
+		sendStatementResult(ctx, statementResultCh, *newStatementResultWithError(query, err))
 		return false
 	}
 
-	columnNamesLen := len(columnNames)
-	columnPointers := make([]interface{}, columnNamesLen)
-	for i, t := range columnTypes {
-		if t.Kind() == reflect.Struct {
-			columnPointers[i] = reflect.New(t).Interface()
-		} else {
-			columnPointers[i] = new(interface{})
-		}
+	// The values are read as the driver returns them rather than converted to the declared types of their columns,
+	// which SQLite doesn't enforce, so that they're read the same from every driver and none is rounded or rejected
+	columnPointers := make([]interface{}, len(columnNames))
+	for i := range columnPointers {
+		columnPointers[i] = new(interface{})
 	}
 
 	rowCh := make(chan rowResult)
 	defer close(rowCh)
 
-	statementResultCh <- *newStatementResult(columnNames, rowCh)
+	if !sendStatementResult(ctx, statementResultCh, *newStatementResult(query, columnNames, rowCh)) {
+		return false
+	}
 
 	for queryRows.Next() {
 		err = queryRows.Scan(columnPointers...)
 		if err != nil {
-			rowCh <- *newRowResultWithError(err)
+			sendRowResult(ctx, rowCh, *newRowResultWithError(err))
 			return false
 		}
 
-		rowData := make([]interface{}, len(columnTypes))
+		rowData := make([]interface{}, len(columnPointers))
 		for i, ptr := range columnPointers {
-			val := reflect.ValueOf(ptr).Elem()
-			rowData[i] = val.Interface()
+			rowData[i] = normalizeValue(*ptr.(*interface{}))
+		}
+		if !sendRowResult(ctx, rowCh, *newRowResult(rowData)) {
+			return false
 		}
-		rowCh <- *newRowResult(rowData)
 	}
 
 	if err := queryRows.Err(); err != nil {
-		rowCh <- *newRowResultWithError(err)
+		sendRowResult(ctx, rowCh, *newRowResultWithError(err))
 		return false
 	}
 
 	return true
 }
 
+// CancelQuery cancels all queries that are currently running
 func (db *Db) CancelQuery() {
-	if db.cancelRunningQuery != nil {
-		db.cancelRunningQuery()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, cancel := range db.runningQueryCancels {
+		cancel()
 	}
 }
 
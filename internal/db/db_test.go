@@ -0,0 +1,94 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+func TestExecuteStatementsContext_GivenCancelWhileReadingRows_ExpectResultsClosedAndLaterStatementsNotRun(t *testing.T) {
+	c := qt.New(t)
+
+	shellDb, err := db.NewDb(t.TempDir()+"/test.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer shellDb.Close()
+	c.Assert(shellDb.ExecuteAndPrintStatements("CREATE TABLE t (a);", new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := shellDb.ExecuteStatementsContext(ctx, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n; INSERT INTO t VALUES (1);")
+	c.Assert(err, qt.IsNil)
+	statementResult := <-result.StatementResultCh
+	<-statementResult.RowCh
+	cancel()
+
+	// The goroutine feeding the rows of the endless query closes both channels once it stops
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range statementResult.RowCh {
+		}
+		for range result.StatementResultCh {
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the results weren't closed once canceled")
+	}
+
+	outF := new(bytes.Buffer)
+	c.Assert(shellDb.ExecuteAndPrintStatements("SELECT count(*) FROM t;", outF, db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+	c.Assert(outF.String(), qt.Equals, "count(*)\n0\n")
+}
+
+func TestOpen_GivenStatementsRunningConcurrently_ExpectReplayedSessionStatementsAndNoDeadlock(t *testing.T) {
+	c := qt.New(t)
+
+	shellDb, err := db.NewDb(t.TempDir()+"/test.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer shellDb.Close()
+	c.Assert(shellDb.ExecuteAndPrintStatements("PRAGMA cache_size=-4000;", new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				// A statement may run on the connection being replaced, which fails as closed
+				_ = shellDb.ExecuteAndPrintStatements("PRAGMA cache_size; SELECT 1;", new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE})
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			replayResults, err := shellDb.Open("", "")
+			c.Check(err, qt.IsNil)
+			c.Check(replayResults, qt.DeepEquals, []db.SessionReplayResult{{Statement: "PRAGMA cache_size=-4000"}})
+			shellDb.Disconnect()
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		c.Fatal("the statements and the reconnections didn't finish")
+	}
+
+	outF := new(bytes.Buffer)
+	c.Assert(shellDb.ExecuteAndPrintStatements("SELECT 1 AS a;", outF, db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+	c.Assert(outF.String(), qt.Equals, "a\n1\n")
+}
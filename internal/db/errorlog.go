@@ -0,0 +1,115 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DEFAULT_ERROR_LOG_RETAINED is how many of the first and of the last errors an ErrorLog retains for its summary
+const DEFAULT_ERROR_LOG_RETAINED = 10
+
+const maxRetainedStatementLength = 200
+
+// StatementError is a statement that failed while running with bail off
+type StatementError struct {
+	Statement string `json:"statement"`
+	Error     string `json:"error"`
+}
+
+// ErrorLog reports the statements that fail while running with bail off. Errors are written to errF as they happen,
+// either as text or as JSON lines. The count is always accurate, but only the first and the last errors are retained
+// for the summary, and none when writing JSON, so long runs with many failures use bounded memory
+type ErrorLog struct {
+	mu         sync.Mutex
+	errF       io.Writer
+	jsonErrors bool
+	retained   int
+	count      int64
+	first      []StatementError
+	// last is a ring buffer of the errors after the first ones, starting at nextLast once it's full
+	last     []StatementError
+	nextLast int
+}
+
+func NewErrorLog(errF io.Writer, jsonErrors bool, retained int) *ErrorLog {
+	return &ErrorLog{errF: errF, jsonErrors: jsonErrors, retained: retained}
+}
+
+func (l *ErrorLog) Record(statement string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.count++
+	if l.jsonErrors {
+		_ = json.NewEncoder(l.errF).Encode(StatementError{Statement: statement, Error: err.Error()})
+		return
+	}
+
+	PrintError(err, l.errF)
+
+	statementError := StatementError{Statement: truncateStatement(statement), Error: err.Error()}
+	switch {
+	case len(l.first) < l.retained:
+		l.first = append(l.first, statementError)
+	case len(l.last) < l.retained:
+		l.last = append(l.last, statementError)
+	case l.retained > 0:
+		l.last[l.nextLast] = statementError
+		l.nextLast = (l.nextLast + 1) % l.retained
+	}
+}
+
+func (l *ErrorLog) Count() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+// Retained returns the first and the last errors retained, and how many were omitted between them
+func (l *ErrorLog) Retained() (first []StatementError, last []StatementError, omitted int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	first = append(first, l.first...)
+	last = append(last, l.last[l.nextLast:]...)
+	last = append(last, l.last[:l.nextLast]...)
+	omitted = l.count - int64(len(first)+len(last))
+	return first, last, omitted
+}
+
+// PrintSummary writes how many statements failed, followed by the retained errors when not writing JSON
+func (l *ErrorLog) PrintSummary() {
+	count := l.Count()
+	if count == 0 {
+		return
+	}
+
+	if l.jsonErrors {
+		_ = json.NewEncoder(l.errF).Encode(map[string]int64{"failed_statements": count})
+		return
+	}
+
+	first, last, omitted := l.Retained()
+	fmt.Fprintf(l.errF, "%d statement(s) failed:\n", count)
+	printStatementErrors(l.errF, first)
+	if omitted > 0 {
+		fmt.Fprintf(l.errF, "  ... %d errors omitted ...\n", omitted)
+	}
+	printStatementErrors(l.errF, last)
+}
+
+func printStatementErrors(errF io.Writer, statementErrors []StatementError) {
+	for _, statementError := range statementErrors {
+		fmt.Fprintf(errF, "  %s: %s\n", statementError.Statement, statementError.Error)
+	}
+}
+
+func truncateStatement(statement string) string {
+	runes := []rune(statement)
+	if len(runes) <= maxRetainedStatementLength {
+		return statement
+	}
+	return string(runes[:maxRetainedStatementLength]) + "..."
+}
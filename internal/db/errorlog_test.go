@@ -0,0 +1,52 @@
+package db_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestErrorLog_GivenMoreErrorsThanRetained_ExpectFirstAndLastKeptWithOmittedCount(t *testing.T) {
+	c := qt.New(t)
+
+	errF := new(bytes.Buffer)
+	errorLog := db.NewErrorLog(errF, false, 2)
+	for i := 1; i <= 7; i++ {
+		errorLog.Record(fmt.Sprintf("SELECT %d;", i), errors.New("failed"))
+	}
+
+	first, last, omitted := errorLog.Retained()
+	c.Assert(first, qt.DeepEquals, []db.StatementError{{Statement: "SELECT 1;", Error: "failed"}, {Statement: "SELECT 2;", Error: "failed"}})
+	c.Assert(last, qt.DeepEquals, []db.StatementError{{Statement: "SELECT 6;", Error: "failed"}, {Statement: "SELECT 7;", Error: "failed"}})
+	c.Assert(omitted, qt.Equals, int64(3))
+	c.Assert(errorLog.Count(), qt.Equals, int64(7))
+
+	errF.Reset()
+	errorLog.PrintSummary()
+	c.Assert(errF.String(), qt.Equals, `7 statement(s) failed:
+  SELECT 1;: failed
+  SELECT 2;: failed
+  ... 3 errors omitted ...
+  SELECT 6;: failed
+  SELECT 7;: failed
+`)
+}
+
+func TestErrorLog_GivenJSONErrors_ExpectErrorsStreamedAndNoneRetained(t *testing.T) {
+	c := qt.New(t)
+
+	errF := new(bytes.Buffer)
+	errorLog := db.NewErrorLog(errF, true, 2)
+	errorLog.Record("SELECT \"x\";", errors.New("no such column: x"))
+	errorLog.PrintSummary()
+
+	c.Assert(errF.String(), qt.Equals, "{\"statement\":\"SELECT \\\"x\\\";\",\"error\":\"no such column: x\"}\n{\"failed_statements\":1}\n")
+	first, last, omitted := errorLog.Retained()
+	c.Assert(first, qt.HasLen, 0)
+	c.Assert(last, qt.HasLen, 0)
+	c.Assert(omitted, qt.Equals, int64(1))
+}
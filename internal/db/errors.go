@@ -1,5 +1,7 @@
 package db
 
+import "fmt"
+
 type InvalidStatementsResult struct{}
 
 func (e *InvalidStatementsResult) Error() string {
@@ -17,3 +19,15 @@ func (e *UnableToPrintStatementResult) Error() string {
 func (e *UnableToPrintStatementResult) internalError() string {
 	return "unable to print statement result. You should check if its an error before printing it"
 }
+
+type UnterminatedTextError struct {
+	Kind string
+	Line int
+}
+
+func (e *UnterminatedTextError) Error() string {
+	return e.userError()
+}
+func (e *UnterminatedTextError) userError() string {
+	return fmt.Sprintf("unterminated %s starting at line %d", e.Kind, e.Line)
+}
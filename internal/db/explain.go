@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EXPLAIN_COLUMN_NAMES are the columns of the bytecode listed by EXPLAIN, in their order
+var EXPLAIN_COLUMN_NAMES = []string{"addr", "opcode", "p1", "p2", "p3", "p4", "p5", "comment"}
+
+// explainJumpOpcodes are the opcodes whose p2 is the address they may jump to
+var explainJumpOpcodes = map[string]bool{
+	"Init": true, "Goto": true, "Gosub": true, "InitCoroutine": true, "Yield": true, "Once": true,
+	"If": true, "IfNot": true, "IfPos": true, "IfNotZero": true, "IfNullRow": true, "IsNull": true, "NotNull": true,
+	"Eq": true, "Ne": true, "Lt": true, "Le": true, "Gt": true, "Ge": true, "ElseEq": true, "DecrJumpZero": true,
+	"Rewind": true, "Last": true, "Next": true, "Prev": true, "SorterSort": true, "Sort": true, "SorterNext": true,
+	"VFilter": true, "VNext": true, "SeekLT": true, "SeekLE": true, "SeekGE": true, "SeekGT": true,
+	"IdxLT": true, "IdxLE": true, "IdxGT": true, "IdxGE": true, "NotFound": true, "Found": true,
+	"NoConflict": true, "NotExists": true, "RowSetRead": true, "RowSetTest": true, "FkIfZero": true,
+	"IfNoHope": true, "IfSmaller": true, "SequenceTest": true,
+}
+
+// explainLoopOpcodes are the opcodes that jump back to the start of a loop body. Goto is left out, as it jumps back to
+// the start of the program after the transaction is opened
+var explainLoopOpcodes = map[string]bool{"Next": true, "Prev": true, "SorterNext": true, "VNext": true}
+
+// IsExplainResult reports whether the columns are exactly the ones of EXPLAIN, so that it's never true for other
+// results
+func IsExplainResult(columnNames []string) bool {
+	return equalColumnNames(columnNames, EXPLAIN_COLUMN_NAMES)
+}
+
+// ExplainPrinter writes EXPLAIN bytecode in aligned columns, with the opcodes of loop bodies indented and the jump
+// targets of p2 marked with "->"
+type ExplainPrinter struct {
+	stats *FormatStats
+}
+
+type explainInstruction struct {
+	values []string
+	addr   int
+	opcode string
+	p2     int
+}
+
+func (e ExplainPrinter) print(statementResult StatementResult, outF io.Writer) error {
+	instructions := make([]explainInstruction, 0)
+	for row := range statementResult.RowCh {
+		if row.Err != nil {
+			return row.Err
+		}
+		values, err := FormatDataWithStats(row.Row, TABLE, e.stats)
+		if err != nil {
+			return err
+		}
+		for i, value := range row.Row {
+			if value == nil {
+				values[i] = ""
+			}
+		}
+		addr, _ := strconv.Atoi(values[0])
+		p2, _ := strconv.Atoi(values[3])
+		instructions = append(instructions, explainInstruction{values: values, addr: addr, opcode: values[1], p2: p2})
+	}
+
+	indents := getExplainIndents(instructions)
+	lines := make([][]string, 0, len(instructions)+1)
+	lines = append(lines, EXPLAIN_COLUMN_NAMES)
+	for i, instruction := range instructions {
+		values := append([]string{}, instruction.values...)
+		values[1] = strings.Repeat("  ", indents[i]) + values[1]
+		if explainJumpOpcodes[instruction.opcode] {
+			values[3] = "-> " + values[3]
+		}
+		lines = append(lines, values)
+	}
+
+	widths := make([]int, len(EXPLAIN_COLUMN_NAMES))
+	for _, line := range lines {
+		for i, value := range line {
+			if width := utf8.RuneCountInString(value); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+	for _, line := range lines {
+		var formattedLine strings.Builder
+		for i, value := range line {
+			if i == len(line)-1 {
+				formattedLine.WriteString(value)
+				break
+			}
+			formattedLine.WriteString(value + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value)+2))
+		}
+		fmt.Fprintln(outF, strings.TrimRight(formattedLine.String(), " "))
+	}
+	return nil
+}
+
+// getExplainIndents returns how many levels each instruction is indented by: one for each loop it's in, found from the
+// instructions jumping back to an earlier address
+func getExplainIndents(instructions []explainInstruction) []int {
+	positions := make(map[int]int, len(instructions))
+	for i, instruction := range instructions {
+		positions[instruction.addr] = i
+	}
+
+	indents := make([]int, len(instructions))
+	for i, instruction := range instructions {
+		if !explainLoopOpcodes[instruction.opcode] || instruction.p2 >= instruction.addr {
+			continue
+		}
+		start, ok := positions[instruction.p2]
+		if !ok {
+			continue
+		}
+		for j := start; j < i; j++ {
+			indents[j]++
+		}
+	}
+	return indents
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileLock is the lock file a shell keeps next to the local database file it has open, holding its process ID. It's
+// advisory: it only lets other shells opening the same file warn that they may contend for it
+type FileLock struct {
+	path string
+}
+
+// GetLockFilePath returns the lock file of a local database file, and an empty path for remote and in-memory databases
+func GetLockFilePath(dbUri string) string {
+	if dbUri == "" || dbUri == ":memory:" || IsUrl(dbUri) {
+		return ""
+	}
+	return dbUri + "-shell.lock"
+}
+
+// AcquireFileLock creates the lock file of a local database file. When another running process holds it, no lock is
+// returned but the ID of that process. Lock files left by processes that are no longer running are replaced
+func AcquireFileLock(dbUri string) (lock *FileLock, otherPid int, err error) {
+	path := GetLockFilePath(dbUri)
+	if path == "" {
+		return nil, 0, nil
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, 0, err
+			}
+			return &FileLock{path: path}, 0, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, 0, err
+		}
+
+		pid, err := readLockFilePid(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pid > 0 && isProcessRunning(pid) {
+			return nil, pid, nil
+		}
+		// The lock file is stale, so it's removed and created again, unless another process does it first
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, err
+		}
+	}
+	return nil, 0, fmt.Errorf("failed to create the lock file %s", path)
+}
+
+// readLockFilePid returns the process ID held by a lock file, 0 when it doesn't hold a valid one
+func readLockFilePid(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, nil
+	}
+	return pid, nil
+}
+
+// Release removes the lock file, unless another process replaced it, thinking it stale
+func (l *FileLock) Release() {
+	if l == nil {
+		return
+	}
+	if pid, err := readLockFilePid(l.path); err == nil && pid == os.Getpid() {
+		os.Remove(l.path)
+	}
+}
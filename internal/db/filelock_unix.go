@@ -0,0 +1,14 @@
+//go:build !windows
+
+package db
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isProcessRunning sends the null signal, which checks that the process exists without affecting it
+func isProcessRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package db
+
+import (
+	"os"
+)
+
+// isProcessRunning opens the process, which fails when it doesn't exist
+func isProcessRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -1,16 +1,25 @@
 package db
 
 import (
+	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 type Formatter interface {
 	formatBytes(value []byte) string
+	// bytesLiteral returns how formatBytes writes non-empty blobs
+	bytesLiteral() hexLiteral
 	formatString(value string) string
 	formatDateTime(value time.Time) string
 	formatNull() string
@@ -27,6 +36,9 @@ const (
 	SQLITE
 	CSV
 	JSON
+	POSTGRES
+	MYSQL
+	SQLITE3
 )
 
 type CommonFormatter struct{}
@@ -51,12 +63,65 @@ func (c CommonFormatter) formatFloat(value float64) string {
 	return strconv.FormatFloat(value, 'f', -1, 64)
 }
 
+// hexLiteral is a blob literal made of the hex digits of the blob between a prefix and a suffix
+type hexLiteral struct {
+	prefix    string
+	suffix    string
+	lowerCase bool
+}
+
+// hexLiteralChunkSize is how many bytes write encodes at a time
+const hexLiteralChunkSize = 32 * 1024
+
+func (l hexLiteral) format(value []byte) string {
+	var literal strings.Builder
+	literal.Grow(len(l.prefix) + 2*len(value) + len(l.suffix))
+	_ = l.write(&literal, value)
+	return literal.String()
+}
+
+// write writes the literal of value to out a chunk at a time, never holding it whole in memory
+func (l hexLiteral) write(out io.Writer, value []byte) error {
+	digits := "0123456789ABCDEF"
+	if l.lowerCase {
+		digits = "0123456789abcdef"
+	}
+
+	if _, err := io.WriteString(out, l.prefix); err != nil {
+		return err
+	}
+	chunkSize := hexLiteralChunkSize
+	if len(value) < chunkSize {
+		chunkSize = len(value)
+	}
+	chunk := make([]byte, 2*chunkSize)
+	for start := 0; start < len(value); start += chunkSize {
+		end := start + chunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		for i, b := range value[start:end] {
+			chunk[2*i] = digits[b>>4]
+			chunk[2*i+1] = digits[b&0x0F]
+		}
+		if _, err := out.Write(chunk[:2*(end-start)]); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(out, l.suffix)
+	return err
+}
+
 type TableFormatter struct {
 	*CommonFormatter
 }
 
 func (t TableFormatter) formatBytes(value []byte) string {
-	return fmt.Sprintf("0x%X", value)
+	return t.bytesLiteral().format(value)
+}
+
+func (t TableFormatter) bytesLiteral() hexLiteral {
+	return hexLiteral{prefix: "0x"}
 }
 
 func (t TableFormatter) formatDateTime(value time.Time) string {
@@ -72,7 +137,11 @@ type SQLiteFormatter struct {
 }
 
 func (s SQLiteFormatter) formatBytes(value []byte) string {
-	return fmt.Sprintf("X'%X'", value)
+	return s.bytesLiteral().format(value)
+}
+
+func (s SQLiteFormatter) bytesLiteral() hexLiteral {
+	return hexLiteral{prefix: "X'", suffix: "'"}
 }
 
 func (s SQLiteFormatter) formatDateTime(value time.Time) string {
@@ -80,22 +149,188 @@ func (s SQLiteFormatter) formatDateTime(value time.Time) string {
 }
 
 func (s SQLiteFormatter) formatString(value string) string {
+	if IsBinaryText(value) {
+		return fmt.Sprintf("CAST(X'%X' AS TEXT)", value)
+	}
 	formattedValue := EscapeSingleQuotes(value)
 	return fmt.Sprintf("'%v'", formattedValue)
 }
 
+// IsBinaryText reports whether the text contains NUL bytes or invalid UTF-8, which can't be reproduced byte by byte
+// with a string literal
+func IsBinaryText(value string) bool {
+	return strings.IndexByte(value, 0) != -1 || !utf8.ValidString(value)
+}
+
+// IsBinaryTextValue reports whether a value read from the database is a text for which IsBinaryText holds
+func IsBinaryTextValue(value interface{}) bool {
+	switch text := value.(type) {
+	case string:
+		return IsBinaryText(text)
+	case sql.NullString:
+		return text.Valid && IsBinaryText(text.String)
+	default:
+		return false
+	}
+}
+
+// SQLite3Formatter formats values as the .dump of the sqlite3 CLI does: blobs in lower case hex, texts holding control
+// characters with unistr, and reals like its %!.20g, with the shortest digits reading back as the same value
+type SQLite3Formatter struct {
+	*SQLiteFormatter
+}
+
+func (s SQLite3Formatter) formatBytes(value []byte) string {
+	return s.bytesLiteral().format(value)
+}
+
+func (s SQLite3Formatter) bytesLiteral() hexLiteral {
+	return hexLiteral{prefix: "X'", suffix: "'", lowerCase: true}
+}
+
+func (s SQLite3Formatter) formatString(value string) string {
+	if IsBinaryText(value) || strings.IndexFunc(value, isControlCharacter) == -1 {
+		return s.SQLiteFormatter.formatString(value)
+	}
+	var escaped strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '\'':
+			escaped.WriteString("''")
+		case r == '\\':
+			escaped.WriteString(`\\`)
+		case isControlCharacter(r):
+			fmt.Fprintf(&escaped, `\u%04x`, r)
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return "unistr('" + escaped.String() + "')"
+}
+
+func isControlCharacter(r rune) bool {
+	return r < 0x20
+}
+
+func (s SQLite3Formatter) formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "9.0e+999"
+	case math.IsInf(value, -1):
+		return "-9.0e+999"
+	case value == 0:
+		return "0.0"
+	}
+	mantissa, exponentText, _ := strings.Cut(strconv.FormatFloat(value, 'e', -1, 64), "e")
+	exponent, _ := strconv.Atoi(exponentText)
+	if exponent < -4 || exponent >= 20 {
+		if !strings.Contains(mantissa, ".") {
+			mantissa += ".0"
+		}
+		return mantissa + "e" + exponentText[:1] + fmt.Sprintf("%02d", absInt(exponent))
+	}
+	fixed := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(fixed, ".") {
+		fixed += ".0"
+	}
+	return fixed
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// PostgresFormatter formats values as Postgres literals, using the bytea hex format for blobs
+type PostgresFormatter struct {
+	*SQLiteFormatter
+}
+
+func (p PostgresFormatter) formatBytes(value []byte) string {
+	return p.bytesLiteral().format(value)
+}
+
+func (p PostgresFormatter) bytesLiteral() hexLiteral {
+	return hexLiteral{prefix: "'\\x", suffix: "'", lowerCase: true}
+}
+
+func (p PostgresFormatter) formatString(value string) string {
+	if IsBinaryText(value) {
+		return p.formatBytes([]byte(value))
+	}
+	return p.SQLiteFormatter.formatString(value)
+}
+
+// MySQLFormatter formats values as MySQL literals. Backslashes are escaped too, as MySQL treats them as escape
+// characters inside strings by default
+type MySQLFormatter struct {
+	*SQLiteFormatter
+}
+
+func (m MySQLFormatter) formatBytes(value []byte) string {
+	if len(value) == 0 {
+		return "X''"
+	}
+	return m.bytesLiteral().format(value)
+}
+
+func (m MySQLFormatter) bytesLiteral() hexLiteral {
+	return hexLiteral{prefix: "0x"}
+}
+
+func (m MySQLFormatter) formatString(value string) string {
+	if IsBinaryText(value) {
+		return m.formatBytes([]byte(value))
+	}
+	formattedValue := EscapeSingleQuotes(strings.ReplaceAll(value, "\\", "\\\\"))
+	return fmt.Sprintf("'%v'", formattedValue)
+}
+
 type CSVFormatter struct {
 	*TableFormatter
 }
 
+// formatNull leaves NULL fields empty, as programs reading CSV expect
+func (c CSVFormatter) formatNull() string {
+	return ""
+}
+
 func (c CSVFormatter) formatDateTime(value time.Time) string {
 	return fmt.Sprintf("'%s'", value.Format("2006-01-02 15:04:05"))
 }
 
+// JSONFormatter formats values as JSON literals: numbers, true and false, null, strings, and blobs as base64 strings
 type JSONFormatter struct {
 	*TableFormatter
 }
 
+func (j JSONFormatter) formatNull() string {
+	return "null"
+}
+
+// formatFloat writes infinities, which JSON numbers can't hold, as strings
+func (j JSONFormatter) formatFloat(value float64) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return j.formatString(strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return j.TableFormatter.formatFloat(value)
+}
+
+func (j JSONFormatter) formatString(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func (j JSONFormatter) formatBytes(value []byte) string {
+	return j.formatString(base64.StdEncoding.EncodeToString(value))
+}
+
+func (j JSONFormatter) formatDateTime(value time.Time) string {
+	return j.formatString(j.TableFormatter.formatDateTime(value))
+}
+
 func GetFormatter(format FormatType) Formatter {
 	common := &CommonFormatter{}
 	switch format {
@@ -111,16 +346,52 @@ func GetFormatter(format FormatType) Formatter {
 		return JSONFormatter{
 			&TableFormatter{common},
 		}
+	case POSTGRES:
+		return PostgresFormatter{
+			&SQLiteFormatter{common},
+		}
+	case MYSQL:
+		return MySQLFormatter{
+			&SQLiteFormatter{common},
+		}
+	case SQLITE3:
+		return SQLite3Formatter{
+			&SQLiteFormatter{common},
+		}
 	default:
 		return nil
 	}
 }
 
+// FormatStats counts what happened while formatting values, for the caller to report it. It's safe to share between
+// goroutines
+type FormatStats struct {
+	unrecognizedMaps   atomic.Int64
+	sparklineFallbacks atomic.Int64
+}
+
+// UnrecognizedMaps returns how many map values have been rendered as JSON because they didn't match any known blob
+// representation
+func (s *FormatStats) UnrecognizedMaps() int64 {
+	return s.unrecognizedMaps.Load()
+}
+
+// SparklineFallbacks returns how many results have been printed as tables in sparkline mode, because they didn't have a
+// label and a numeric column
+func (s *FormatStats) SparklineFallbacks() int64 {
+	return s.sparklineFallbacks.Load()
+}
+
 func FormatData(row []interface{}, format FormatType) ([]string, error) {
+	return FormatDataWithStats(row, format, nil)
+}
+
+// FormatDataWithStats is like FormatData, counting in stats, when it isn't nil, what happened while formatting
+func FormatDataWithStats(row []interface{}, format FormatType, stats *FormatStats) ([]string, error) {
 	formattedRow := make([]string, len(row))
 	formatter := GetFormatter(format)
 	for j, val := range row {
-		result, err := formatValue(val, formatter)
+		result, err := formatValue(val, formatter, stats)
 		if err != nil {
 			return nil, err
 		}
@@ -129,7 +400,23 @@ func FormatData(row []interface{}, format FormatType) ([]string, error) {
 	return formattedRow, nil
 }
 
-func formatValue(val interface{}, formatter Formatter) (string, error) {
+// WriteValue writes a value to out formatted like FormatData does. Blobs are written as they're encoded, so memory
+// isn't taken by their whole literal
+func WriteValue(out io.Writer, value interface{}, format FormatType) error {
+	formatter := GetFormatter(format)
+	if blob, ok := value.([]byte); ok && len(blob) > 0 {
+		return formatter.bytesLiteral().write(out, blob)
+	}
+
+	formattedValue, err := formatValue(value, formatter, nil)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, formattedValue)
+	return err
+}
+
+func formatValue(val interface{}, formatter Formatter, stats *FormatStats) (string, error) {
 	if val == nil {
 		return formatter.formatNull(), nil
 	} else {
@@ -140,7 +427,7 @@ func formatValue(val interface{}, formatter Formatter) (string, error) {
 		case reflect.Slice:
 			return formatSlice(rv, formatter)
 		case reflect.Map:
-			return formatMap(rv, formatter)
+			return formatMap(rv, formatter, stats)
 		default:
 			formattedRawType, err := formatRawType(rv, formatter)
 			if err != nil {
@@ -164,11 +451,11 @@ func formatStruct(value reflect.Value, formatter Formatter) (string, error) {
 	case "NullBool":
 		return formatter.formatBool(value.FieldByName("Bool").Bool()), nil
 	case "NullByte":
-		return formatter.formatInt(value.FieldByName("Byte").Int()), nil
+		return formatter.formatUint(value.FieldByName("Byte").Uint()), nil
 	case "NullInt16":
 		return formatter.formatInt(value.FieldByName("Int16").Int()), nil
 	case "NullInt32":
-		return formatter.formatInt(value.FieldByName("Int16").Int()), nil
+		return formatter.formatInt(value.FieldByName("Int32").Int()), nil
 	case "NullInt64":
 		return formatter.formatInt(value.FieldByName("Int64").Int()), nil
 	case "NullFloat64":
@@ -190,20 +477,13 @@ func formatSlice(value reflect.Value, formatter Formatter) (string, error) {
 	return "", fmt.Errorf("unsupported slice: %s", value.Type().Name())
 }
 
-func formatMap(value reflect.Value, formatter Formatter) (string, error) {
-	base64Value := value.MapIndex(reflect.ValueOf("base64"))
-	if base64Value.IsZero() {
-		return "", fmt.Errorf("unsupported map: no \"base64\" field")
+func formatMap(value reflect.Value, formatter Formatter, stats *FormatStats) (string, error) {
+	base64ValueString, found, err := findBase64Field(value)
+	if err != nil {
+		return "", err
 	}
-
-	var base64ValueString string
-	switch {
-	case base64Value.Kind() == reflect.Interface && base64Value.Elem().Kind() == reflect.String:
-		base64ValueString = base64Value.Elem().String()
-	case base64Value.Kind() == reflect.String:
-		base64ValueString = base64Value.String()
-	default:
-		return "", fmt.Errorf("unsupported map. unsupported \"base64\" field kind")
+	if !found {
+		return formatUnrecognizedMap(value, stats)
 	}
 
 	sliceOfBytesValue, err := decodeBase64(base64ValueString)
@@ -214,8 +494,102 @@ func formatMap(value reflect.Value, formatter Formatter) (string, error) {
 	return formatter.formatBytes(sliceOfBytesValue), nil
 }
 
+// findBase64Field looks for a "base64" entry in maps keyed by strings or interfaces. Remote protocols may also wrap the
+// blob in another map (e.g. {"type": "blob", "value": {"base64": "..."}}), so "value" and "data" entries are inspected
+// recursively as well
+func findBase64Field(value reflect.Value) (base64String string, found bool, err error) {
+	var nestedValues []reflect.Value
+
+	iter := value.MapRange()
+	for iter.Next() {
+		key, ok := stringFromValue(iter.Key())
+		if !ok {
+			continue
+		}
+
+		entry := iter.Value()
+		if entry.Kind() == reflect.Interface {
+			entry = entry.Elem()
+		}
+
+		switch key {
+		case "base64":
+			if entry.Kind() != reflect.String {
+				return "", false, fmt.Errorf("unsupported map. unsupported \"base64\" field kind")
+			}
+			return entry.String(), true, nil
+		case "value", "data":
+			if entry.Kind() == reflect.Map {
+				nestedValues = append(nestedValues, entry)
+			}
+		}
+	}
+
+	for _, nestedValue := range nestedValues {
+		if base64String, found, err = findBase64Field(nestedValue); found || err != nil {
+			return base64String, found, err
+		}
+	}
+
+	return "", false, nil
+}
+
+func stringFromValue(value reflect.Value) (string, bool) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.String {
+		return "", false
+	}
+	return value.String(), true
+}
+
+func formatUnrecognizedMap(value reflect.Value, stats *FormatStats) (string, error) {
+	jsonValue, err := json.Marshal(toJSONCompatible(value))
+	if err != nil {
+		return "", fmt.Errorf("unsupported map: %w", err)
+	}
+
+	if stats != nil {
+		stats.unrecognizedMaps.Add(1)
+	}
+	return string(jsonValue), nil
+}
+
+// toJSONCompatible converts maps with non-string keys, which encoding/json refuses to marshal, into maps keyed by the
+// keys' string representation
+func toJSONCompatible(value reflect.Value) interface{} {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Map:
+		result := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = toJSONCompatible(iter.Value())
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		result := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			result[i] = toJSONCompatible(value.Index(i))
+		}
+		return result
+	default:
+		return value.Interface()
+	}
+}
+
 func decodeBase64(base64String string) ([]byte, error) {
 	encodingWithNoPadding := base64.StdEncoding.WithPadding(base64.NoPadding)
+	base64String = strings.TrimRight(base64String, "=")
 	decodedBase64 := make([]byte, encodingWithNoPadding.DecodedLen(len(base64String)))
 	_, err := encodingWithNoPadding.Decode(decodedBase64, []byte(base64String))
 	if err != nil {
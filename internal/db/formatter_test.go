@@ -0,0 +1,153 @@
+package db_test
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestFormatData_GivenMapWithStringKeys_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[string]interface{}{"base64": "AQID"}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"0x010203"})
+}
+
+func TestFormatData_GivenMapOfStrings_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[string]string{"base64": "AQID"}}
+	result, err := db.FormatData(row, db.SQLITE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"X'010203'"})
+}
+
+func TestFormatData_GivenMapWithInterfaceKeys_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[interface{}]interface{}{"base64": "AQID"}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"0x010203"})
+}
+
+func TestFormatData_GivenPaddedBase64_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[string]interface{}{"base64": "AQI="}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"0x0102"})
+}
+
+func TestFormatData_GivenBlobNestedUnderValueKey_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[string]interface{}{"type": "blob", "value": map[string]interface{}{"base64": "AQID"}}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"0x010203"})
+}
+
+func TestFormatData_GivenBlobNestedUnderDataKeyWithInterfaceKeys_ExpectBlobFormatted(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[interface{}]interface{}{"type": "blob", "data": map[interface{}]interface{}{"base64": "AQID"}}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"0x010203"})
+}
+
+func TestFormatData_GivenBase64FieldWithUnsupportedKind_ExpectError(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[string]interface{}{"base64": 10}}
+	_, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.ErrorMatches, `unsupported map. unsupported "base64" field kind`)
+}
+
+func TestFormatData_GivenNullStructsOfEveryIntegerSize_ExpectTheirValues(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{sql.NullByte{Byte: 200, Valid: true}, sql.NullInt16{Int16: -300, Valid: true}, sql.NullInt32{Int32: 70000, Valid: true}, sql.NullInt64{Int64: 9007199254740993, Valid: true}, sql.NullInt32{}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{"200", "-300", "70000", "9007199254740993", "NULL"})
+}
+
+func TestFormatData_GivenUnrecognizedMap_ExpectCompactJSONAndCounterIncremented(t *testing.T) {
+	c := qt.New(t)
+
+	stats := &db.FormatStats{}
+	row := []interface{}{map[string]interface{}{"type": "point", "x": 1, "y": 2}}
+	result, err := db.FormatDataWithStats(row, db.TABLE, stats)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{`{"type":"point","x":1,"y":2}`})
+	c.Assert(stats.UnrecognizedMaps(), qt.Equals, int64(1))
+}
+
+func TestFormatData_GivenUnrecognizedMapWithNonStringKeys_ExpectCompactJSON(t *testing.T) {
+	c := qt.New(t)
+
+	row := []interface{}{map[interface{}]interface{}{1: "one", "nested": map[int]string{2: "two"}}}
+	result, err := db.FormatData(row, db.TABLE)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(result, qt.DeepEquals, []string{`{"1":"one","nested":{"2":"two"}}`})
+}
+
+func TestFormatDataWithStats_GivenConcurrentCallsWithTheirOwnStats_ExpectCountsKeptApart(t *testing.T) {
+	c := qt.New(t)
+
+	statsA, statsB := &db.FormatStats{}, &db.FormatStats{}
+	row := []interface{}{map[string]interface{}{"type": "point"}}
+	twoMapsRow := []interface{}{row[0], row[0]}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = db.FormatDataWithStats(row, db.TABLE, statsA)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = db.FormatDataWithStats(twoMapsRow, db.TABLE, statsB)
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(statsA.UnrecognizedMaps(), qt.Equals, int64(10))
+	c.Assert(statsB.UnrecognizedMaps(), qt.Equals, int64(20))
+}
+
+func TestGroupDigits(t *testing.T) {
+	c := qt.New(t)
+
+	en, err := db.GetNumberLocale("en_US.UTF-8")
+	c.Assert(err, qt.IsNil)
+	eu, err := db.GetNumberLocale("eu")
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(db.GroupDigits("1234567", en), qt.Equals, "1,234,567")
+	c.Assert(db.GroupDigits("-123456.789", en), qt.Equals, "-123,456.789")
+	c.Assert(db.GroupDigits("999", en), qt.Equals, "999")
+	c.Assert(db.GroupDigits("1234567.5", eu), qt.Equals, "1.234.567,5")
+	c.Assert(db.GroupDigits("+Inf", en), qt.Equals, "+Inf")
+	c.Assert(db.GroupDigits("NaN", en), qt.Equals, "NaN")
+}
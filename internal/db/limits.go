@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sqlite3driver "github.com/mattn/go-sqlite3"
+)
+
+// StatementLimits are the limits of the database on the statements it takes, named after the SQLITE_LIMIT ones. The
+// statements the shell generates, like the multi-row INSERTs of .dump, the transactions of .import and the UNION ALL
+// chains of SQL mode, are kept under them
+type StatementLimits struct {
+	// SQLLength is the length in bytes of the longest statement
+	SQLLength int64
+	// CompoundSelect is the number of SELECTs a compound SELECT, like a chain of UNION ALL, joins at most
+	CompoundSelect int64
+}
+
+// defaultStatementLimits are the limits of remote databases, which can't be queried, and of local ones whose limits
+// can't be read. They're the ones of SQLite, but for the length of statements, which servers may limit to far less
+// than its billion bytes through the size of the requests they take
+var defaultStatementLimits = StatementLimits{SQLLength: 1000000, CompoundSelect: 500}
+
+// StatementLimitNames are the names of the limits, as given to .limit
+var StatementLimitNames = []string{"compound_select", "sql_length"}
+
+// Get returns the limit named name, reporting whether there's one
+func (l StatementLimits) Get(name string) (int64, bool) {
+	switch strings.ToLower(name) {
+	case "compound_select":
+		return l.CompoundSelect, true
+	case "sql_length":
+		return l.SQLLength, true
+	}
+	return 0, false
+}
+
+// StatementLimits returns the limits of the database, read once per connection, with the ones set by
+// SetStatementLimit in their place
+func (db *Db) StatementLimits() StatementLimits {
+	sqlDb, err := db.getSqlDb()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.limits == nil {
+		limits := defaultStatementLimits
+		if err == nil && db.driver == sqlite3 {
+			limits = readSqliteStatementLimits(sqlDb)
+		}
+		db.limits = &limits
+	}
+
+	limits := *db.limits
+	if db.limitOverrides.SQLLength > 0 {
+		limits.SQLLength = db.limitOverrides.SQLLength
+	}
+	if db.limitOverrides.CompoundSelect > 0 {
+		limits.CompoundSelect = db.limitOverrides.CompoundSelect
+	}
+	return limits
+}
+
+// SetStatementLimit sets the limit named name the generated statements are kept under in place of the one of the
+// database, which is left unchanged, or goes back to the one of the database when value is 0. A lower limit makes them
+// fit another database, like the one a dump is restored to, or a server accepting shorter statements than assumed
+func (db *Db) SetStatementLimit(name string, value int64) error {
+	if value < 0 {
+		return fmt.Errorf("invalid value %d for limit %s. It must be a positive number, or 0 for the limit of the database", value, name)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch strings.ToLower(name) {
+	case "compound_select":
+		db.limitOverrides.CompoundSelect = value
+	case "sql_length":
+		db.limitOverrides.SQLLength = value
+	default:
+		return fmt.Errorf("unknown limit %s. Valid limits are %s", name, strings.Join(StatementLimitNames, " and "))
+	}
+	return nil
+}
+
+// readSqliteStatementLimits returns the limits of a connection of the local database, which all its connections share
+func readSqliteStatementLimits(sqlDb *sql.DB) StatementLimits {
+	limits := defaultStatementLimits
+	conn, err := sqlDb.Conn(context.Background())
+	if err != nil {
+		return limits
+	}
+	defer conn.Close()
+
+	_ = conn.Raw(func(driverConn interface{}) error {
+		if sqliteConn, ok := driverConn.(*sqlite3driver.SQLiteConn); ok {
+			limits.SQLLength = int64(sqliteConn.GetLimit(sqlite3driver.SQLITE_LIMIT_SQL_LENGTH))
+			limits.CompoundSelect = int64(sqliteConn.GetLimit(sqlite3driver.SQLITE_LIMIT_COMPOUND_SELECT))
+		}
+		return nil
+	})
+	return limits
+}
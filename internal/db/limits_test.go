@@ -0,0 +1,30 @@
+package db_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestStatementLimits_GivenLocalDatabase_ExpectTheLimitsOfSQLiteUnlessSet(t *testing.T) {
+	c := qt.New(t)
+
+	shellDb, err := db.NewDb(t.TempDir()+"/test.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer shellDb.Close()
+
+	c.Assert(shellDb.StatementLimits(), qt.Equals, db.StatementLimits{SQLLength: 1000000000, CompoundSelect: 500})
+
+	c.Assert(shellDb.SetStatementLimit("SQL_LENGTH", 1000), qt.IsNil)
+	c.Assert(shellDb.StatementLimits(), qt.Equals, db.StatementLimits{SQLLength: 1000, CompoundSelect: 500})
+	// The limits set are kept when the database is connected to again
+	shellDb.Disconnect()
+	c.Assert(shellDb.StatementLimits(), qt.Equals, db.StatementLimits{SQLLength: 1000, CompoundSelect: 500})
+	c.Assert(shellDb.SetStatementLimit("sql_length", 0), qt.IsNil)
+	c.Assert(shellDb.StatementLimits(), qt.Equals, db.StatementLimits{SQLLength: 1000000000, CompoundSelect: 500})
+
+	c.Assert(shellDb.SetStatementLimit("sql_length", -1), qt.ErrorMatches, "invalid value -1 for limit sql_length. It must be a positive number, or 0 for the limit of the database")
+	c.Assert(shellDb.SetStatementLimit("column", 10), qt.ErrorMatches, "unknown limit column. Valid limits are compound_select and sql_length")
+}
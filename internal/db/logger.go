@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+var noteFmt = color.New(color.FgYellow).SprintFunc()
+
+// logLevelOrder ranks the levels, the ones below the minimum level of a Logger being left out
+var logLevelOrder = map[enums.LogLevel]int{enums.LOG_INFO: 0, enums.LOG_NOTE: 1, enums.LOG_WARNING: 2}
+
+// IsLogLevel reports whether level is one of the levels a Logger writes
+func IsLogLevel(level enums.LogLevel) bool {
+	_, ok := logLevelOrder[level]
+	return ok
+}
+
+// Logger writes the diagnostics of the shell, like progress, reconnection notices and warnings, to errF as they've
+// always been written, to a file with the time and the level of each line, or to both. Errors aren't diagnostics: their
+// callers write them to errF whatever the destination of the diagnostics, as they make the shell fail
+type Logger struct {
+	mu       sync.Mutex
+	errF     io.Writer
+	file     io.Writer
+	minLevel int
+}
+
+// NewLogger returns a Logger writing to errF and to file, when they're not nil, the lines of minLevel and above. An
+// empty minLevel is LOG_INFO
+func NewLogger(errF io.Writer, file io.Writer, minLevel enums.LogLevel) *Logger {
+	return &Logger{errF: errF, file: file, minLevel: logLevelOrder[minLevel]}
+}
+
+// Infof writes a line of progress or of what the shell did, like a reconnection
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(enums.LOG_INFO, fmt.Sprintf(format, args...))
+}
+
+// Notef writes a line pointing out something worth knowing that needs no action, like a slow statement
+func (l *Logger) Notef(format string, args ...interface{}) {
+	l.log(enums.LOG_NOTE, fmt.Sprintf(format, args...))
+}
+
+// Warnf writes a line about something that may need action, like data left out of a result
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(enums.LOG_WARNING, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) log(level enums.LogLevel, message string) {
+	if logLevelOrder[level] < l.minLevel {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.errF != nil {
+		switch level {
+		case enums.LOG_NOTE:
+			fmt.Fprintln(l.errF, noteFmt("Note: "+message))
+		case enums.LOG_WARNING:
+			fmt.Fprintln(l.errF, "Warning: "+message)
+		default:
+			fmt.Fprintln(l.errF, message)
+		}
+	}
+	if l.file != nil {
+		// A line per message, so the lines of multiline messages, like the ones quoting statements, are joined
+		message = strings.Join(strings.Split(message, "\n"), " ")
+		fmt.Fprintf(l.file, "%s %s %s\n", time.Now().Format(time.RFC3339), strings.ToUpper(string(level)), message)
+	}
+}
@@ -0,0 +1,47 @@
+package db_test
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+func TestLogger_GivenErrFOnly_ExpectLinesPrefixedByLevelAsBefore(t *testing.T) {
+	c := qt.New(t)
+
+	errF := new(bytes.Buffer)
+	log := db.NewLogger(errF, nil, "")
+	log.Infof("Replayed: %s", "PRAGMA foreign_keys=ON")
+	log.Notef("statement took %s", "2s")
+	log.Warnf("%d value(s) were rendered as JSON", 3)
+
+	c.Assert(errF.String(), qt.Equals, "Replayed: PRAGMA foreign_keys=ON\nNote: statement took 2s\nWarning: 3 value(s) were rendered as JSON\n")
+}
+
+func TestLogger_GivenFileAndMinimumLevel_ExpectLinesOfTheLevelAndAboveWithTimeAndLevel(t *testing.T) {
+	c := qt.New(t)
+
+	file := new(bytes.Buffer)
+	log := db.NewLogger(nil, file, enums.LOG_NOTE)
+	log.Infof("Dumping table t")
+	log.Notef("statement took 2s")
+	log.Warnf("line 3: unsupported command .bail\nwas skipped")
+
+	c.Assert(file.String(), qt.Matches, `\S+ NOTE statement took 2s
+\S+ WARNING line 3: unsupported command .bail was skipped
+`)
+}
+
+func TestLogger_GivenErrFAndFile_ExpectEachLineWrittenToBoth(t *testing.T) {
+	c := qt.New(t)
+
+	errF, file := new(bytes.Buffer), new(bytes.Buffer)
+	log := db.NewLogger(errF, file, enums.LOG_INFO)
+	log.Infof("Dumped 2 tables")
+
+	c.Assert(errF.String(), qt.Equals, "Dumped 2 tables\n")
+	c.Assert(file.String(), qt.Matches, `\d{4}-\d\d-\d\dT\S+ INFO Dumped 2 tables\n`)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"path"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// MASKED_VALUE is shown in place of the values of masked columns
+const MASKED_VALUE = "****"
+
+// ValidateMaskPattern returns an error when the pattern isn't a valid glob pattern
+func ValidateMaskPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// isMaskedMode reports whether the columns are masked in the mode. Only the modes meant to be read on screen are, so
+// that the output of the modes read by programs is never altered
+func isMaskedMode(mode enums.PrintMode) bool {
+	return mode == enums.TABLE_MODE || mode == enums.SPARKLINE_MODE
+}
+
+// getMaskedColumns returns which columns have a name matching one of the glob patterns, ignoring case and Unicode normal
+// form
+func getMaskedColumns(columnNames []string, patterns []string) ([]bool, bool) {
+	masked := make([]bool, len(columnNames))
+	anyMasked := false
+	for i, name := range columnNames {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(NormalizeName(strings.ToLower(pattern)), NormalizeName(strings.ToLower(name))); matched {
+				masked[i] = true
+				anyMasked = true
+				break
+			}
+		}
+	}
+	return masked, anyMasked
+}
+
+// maskColumns replaces the values of the columns matching the patterns with MASKED_VALUE
+func maskColumns(statementResult StatementResult, patterns []string) StatementResult {
+	masked, anyMasked := getMaskedColumns(statementResult.ColumnNames, patterns)
+	if !anyMasked {
+		return statementResult
+	}
+
+	rowCh := statementResult.RowCh
+	maskedRowCh := make(chan rowResult)
+	go func() {
+		defer close(maskedRowCh)
+		for row := range rowCh {
+			if row.Err == nil {
+				// The row is copied, as it may be retained by the result cache
+				maskedRow := make([]interface{}, len(row.Row))
+				for i, value := range row.Row {
+					if i < len(masked) && masked[i] {
+						value = MASKED_VALUE
+					}
+					maskedRow[i] = value
+				}
+				row.Row = maskedRow
+			}
+			maskedRowCh <- row
+		}
+	}()
+
+	statementResult.RowCh = maskedRowCh
+	return statementResult
+}
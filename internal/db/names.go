@@ -0,0 +1,84 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName returns the NFC form of an object name. Names typed by users and names read from the catalog are
+// compared in it, so that é matches whether it's written as one code point or as e and a combining accent. Only
+// comparisons use it: the SQL generated from a name keeps its original bytes
+func NormalizeName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// EqualNames reports whether two object names refer to the same object once normalized, ignoring the case of ASCII
+// letters like SQLite does
+func EqualNames(a string, b string) bool {
+	return equalFoldASCII(NormalizeName(a), NormalizeName(b))
+}
+
+func equalFoldASCII(a string, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if toLowerASCII(a[i]) != toLowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// LikeAnyNormalForm returns an SQL condition matching the names in column against the LIKE pattern written in NFC or
+// in NFD, as SQL compares the bytes of names, which the catalog may hold in either form. escape is appended to each
+// LIKE, like ESCAPE '\', when not empty
+func LikeAnyNormalForm(column string, pattern string, escape string) string {
+	forms := []string{norm.NFC.String(pattern)}
+	if nfd := norm.NFD.String(pattern); nfd != forms[0] {
+		forms = append(forms, nfd)
+	}
+
+	conditions := make([]string, 0, len(forms))
+	for _, form := range forms {
+		condition := fmt.Sprintf("%s LIKE '%s'", column, EscapeSingleQuotes(form))
+		if escape != "" {
+			condition += " " + escape
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// SplitQualifiedName splits a name written schema.object into its schema and object names, removing the quotes of
+// either part written "name", `name` or [name]. The schema is empty for a name without a dot outside of quotes
+func SplitQualifiedName(name string) (schema string, object string) {
+	var quote byte
+	for i := 0; i < len(name); i++ {
+		switch {
+		case quote != 0:
+			if name[i] == quote {
+				quote = 0
+			}
+		case name[i] == '"' || name[i] == '`':
+			quote = name[i]
+		case name[i] == '[':
+			quote = ']'
+		case name[i] == '.':
+			return unquoteIdentifier(name[:i]), unquoteIdentifier(name[i+1:])
+		}
+	}
+	return "", unquoteIdentifier(name)
+}
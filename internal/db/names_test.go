@@ -0,0 +1,26 @@
+package db_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestSplitQualifiedName_GivenNamesWithAndWithoutSchema_ExpectUnquotedParts(t *testing.T) {
+	c := qt.New(t)
+
+	for name, expected := range map[string][2]string{
+		"users":                 {"", "users"},
+		"aux.users":             {"aux", "users"},
+		`"my table"`:            {"", "my table"},
+		`aux."my.table"`:        {"aux", "my.table"},
+		"[my db].`a``b`":        {"my db", "a`b"},
+		`"a""b".c`:              {`a"b`, "c"},
+		`"main.users"`:          {"", "main.users"},
+		"temp.[users.archived]": {"temp", "users.archived"},
+	} {
+		schema, object := db.SplitQualifiedName(name)
+		c.Assert([2]string{schema, object}, qt.Equals, expected, qt.Commentf("name %s", name))
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"reflect"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// isNullValueMode reports whether the NULL values of the mode are replaced with the placeholder set by .nullvalue.
+// Only the text modes have one: JSON and SQL always write actual NULL literals
+func isNullValueMode(mode enums.PrintMode) bool {
+	return mode == enums.TABLE_MODE || mode == enums.CSV_MODE || mode == enums.SPARKLINE_MODE
+}
+
+// isNullValue reports whether a value read from the database is NULL, either nil or an invalid sql.Null* value
+func isNullValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Struct {
+		return false
+	}
+	valid := rv.FieldByName("Valid")
+	return valid.IsValid() && valid.Kind() == reflect.Bool && !valid.Bool()
+}
+
+// replaceNulls replaces the NULL values of the rows with nullValue, which is then shown like any text value
+func replaceNulls(statementResult StatementResult, nullValue string) StatementResult {
+	rowCh := statementResult.RowCh
+	replacedRowCh := make(chan rowResult)
+	go func() {
+		defer close(replacedRowCh)
+		for row := range rowCh {
+			if row.Err == nil {
+				// The row is copied, as it may be retained by the result cache
+				replacedRow := make([]interface{}, len(row.Row))
+				for i, value := range row.Row {
+					if isNullValue(value) {
+						value = nullValue
+					}
+					replacedRow[i] = value
+				}
+				row.Row = replacedRow
+			}
+			replacedRowCh <- row
+		}
+	}()
+
+	statementResult.RowCh = replacedRowCh
+	return statementResult
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// NumberLocale holds the separators numbers are shown with by .numfmt. The zero value shows them as they are
+type NumberLocale struct {
+	Name             string
+	GroupSeparator   string
+	DecimalSeparator string
+}
+
+var numberLocales = []NumberLocale{
+	{Name: "en", GroupSeparator: ",", DecimalSeparator: "."},
+	{Name: "eu", GroupSeparator: ".", DecimalSeparator: ","},
+}
+
+// euLanguages are the languages grouping digits with dots and separating decimals with commas, like German
+var euLanguages = []string{"da", "de", "el", "es", "id", "it", "nl", "pt", "ro", "sl", "sr", "tr"}
+
+// GetNumberLocale returns the separators of a locale: en, eu, or a locale name like en_US.UTF-8 or de-DE, taken by
+// its language
+func GetNumberLocale(name string) (NumberLocale, error) {
+	language := strings.ToLower(name)
+	if end := strings.IndexAny(language, "_-.@"); end >= 0 {
+		language = language[:end]
+	}
+	for _, euLanguage := range euLanguages {
+		if language == euLanguage {
+			language = "eu"
+		}
+	}
+	for _, locale := range numberLocales {
+		if locale.Name == language {
+			return locale, nil
+		}
+	}
+	return NumberLocale{}, fmt.Errorf("unknown locale \"%s\". Use en for 1,234.5 or eu for 1.234,5", name)
+}
+
+func (l NumberLocale) enabled() bool {
+	return l.Name != ""
+}
+
+// isNumberFormattedMode reports whether numbers are formatted in the mode. Like masks, only table mode, read on screen,
+// is, so that csv, json and sql modes and .dump are never altered
+func isNumberFormattedMode(mode enums.PrintMode) bool {
+	return mode == enums.TABLE_MODE
+}
+
+// formatNumbers replaces the integers and floats of the rows with their text, their integer part grouped by thousands
+func formatNumbers(statementResult StatementResult, locale NumberLocale) StatementResult {
+	rowCh := statementResult.RowCh
+	formattedRowCh := make(chan rowResult)
+	go func() {
+		defer close(formattedRowCh)
+		for row := range rowCh {
+			if row.Err == nil {
+				// The row is copied, as it may be retained by the result cache
+				formattedRow := make([]interface{}, len(row.Row))
+				for i, value := range row.Row {
+					formattedRow[i] = formatNumber(value, locale)
+				}
+				row.Row = formattedRow
+			}
+			formattedRowCh <- row
+		}
+	}()
+
+	statementResult.RowCh = formattedRowCh
+	return statementResult
+}
+
+func formatNumber(value interface{}, locale NumberLocale) interface{} {
+	if !isNumberValue(value) {
+		return value
+	}
+	text, err := formatValue(value, GetFormatter(TABLE), nil)
+	if err != nil {
+		return value
+	}
+	return GroupDigits(text, locale)
+}
+
+// isNumberValue reports whether the value is an integer or a float, including the valid ones of the sql.Null types the
+// values of typed columns are read as
+func isNumberValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Struct:
+		switch rv.Type().Name() {
+		case "NullByte", "NullInt16", "NullInt32", "NullInt64", "NullFloat64":
+			valid := rv.FieldByName("Valid")
+			return valid.IsValid() && valid.Bool()
+		}
+	}
+	return false
+}
+
+// GroupDigits separates the thousands of the integer part of a number written like 1234567.5 or -1234, and replaces its
+// decimal point, as done by the locale. Text that isn't such a number, like +Inf, is returned unchanged
+func GroupDigits(number string, locale NumberLocale) string {
+	sign, digits := "", number
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	integerPart, decimalPart, hasDecimals := strings.Cut(digits, ".")
+	if integerPart == "" || !isDigits(integerPart) || (hasDecimals && !isDigits(decimalPart)) {
+		return number
+	}
+
+	var grouped strings.Builder
+	grouped.WriteString(sign)
+	for i, digit := range integerPart {
+		if i > 0 && (len(integerPart)-i)%3 == 0 {
+			grouped.WriteString(locale.GroupSeparator)
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasDecimals {
+		grouped.WriteString(locale.DecimalSeparator)
+		grouped.WriteString(decimalPart)
+	}
+	return grouped.String()
+}
+
+func isDigits(text string) bool {
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
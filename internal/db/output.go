@@ -1,100 +1,520 @@
 package db
 
 import (
+	"bufio"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 	"github.com/olekukonko/tablewriter"
 )
 
+// PrintOptions holds the settings used to print results. It's passed by value on every call, so concurrent calls with
+// different options don't share any state
+type PrintOptions struct {
+	WithoutHeader bool
+	Mode          enums.PrintMode
+	// ResultCache, when set, retains the raw values of the printed results
+	ResultCache *ResultCache
+	// ErrorLog, when set, records the statements that fail and lets the following ones run instead of stopping
+	ErrorLog *ErrorLog
+	// Summary, when set, is filled with the counts of the printed statements
+	Summary *ExecutionSummary
+	// SessionStats, when set, adds up the counts and the timings of the printed statements
+	SessionStats *SessionStats
+	// QuoteStyle quotes the identifiers of generated SQL. Empty means double quotes
+	QuoteStyle enums.IdentifierQuoteStyle
+	// FormatStats, when set, counts what happened while formatting the printed values
+	FormatStats *FormatStats
+	// CSV adapts the output of CSV mode
+	CSV CSVOptions
+	// JSON adapts the output of JSON mode
+	JSON JSONOptions
+	// Table adapts the output of table mode
+	Table TableOptions
+	// FormatExplain prints the results of EXPLAIN with ExplainPrinter, whatever the mode
+	FormatExplain bool
+	// PrettyPragmas prints the results of well known PRAGMAs rewritten for reading in table mode, as done by
+	// prettifyPragmaResult
+	PrettyPragmas bool
+	// HeaderInterval re-prints the header of tables every HeaderInterval rows, when not 0
+	HeaderInterval int
+	// PreserveHeaderCase shows the column names in the header of tables as they are, rather than upper cased. The other
+	// modes always keep them as they are
+	PreserveHeaderCase bool
+	// NullValue, when set, is shown in place of the NULL values of table, CSV and sparkline modes, rather than NULL in
+	// tables and an empty field in CSV. JSON and SQL modes always write actual NULL literals
+	NullValue *string
+	// MaskPatterns are glob patterns of the column names whose values are replaced with MASKED_VALUE, in the modes
+	// read on screen only
+	MaskPatterns []string
+	// NumberLocale, when set, groups the thousands of the numbers of table mode with its separators
+	NumberLocale NumberLocale
+	// ColumnTransform, when set, replaces the values of the printed rows in every mode, before they're prettified,
+	// masked and formatted
+	ColumnTransform ColumnTransform
+	// FailOnEmpty fails the statements returning columns but no rows with an EmptyResultError, after their header is
+	// printed
+	FailOnEmpty bool
+	// Timer writes how long each statement took to run and print its result on a line after it, like the sqlite3 CLI
+	Timer bool
+	// Changes writes the number of rows changed by each INSERT, UPDATE, DELETE and REPLACE on a line after it, with
+	// the last insert rowid of INSERT and REPLACE, and the number of rows returned after each table of table mode
+	Changes bool
+	// OnSlowStatement, when set, is called after each statement that took longer than SlowThreshold to run and print
+	SlowThreshold   time.Duration
+	OnSlowStatement func(timing StatementTiming)
+	// limits are the statement limits of the database SQL mode keeps its statements under, set by
+	// ExecuteAndPrintStatements
+	limits StatementLimits
+}
+
+// StatementTiming is how long a statement took to run and print its result
+type StatementTiming struct {
+	Statement    string
+	Duration     time.Duration
+	RowsReturned int64
+}
+
+// CSVOptions adapts CSV output to the programs reading it, like Excel, which needs both to read UTF-8 files right
+type CSVOptions struct {
+	// BOM writes a UTF-8 byte order mark before each result
+	BOM bool
+	// CRLF ends the rows with \r\n rather than \n
+	CRLF bool
+}
+
+// JSONOptions adapts JSON output
+type JSONOptions struct {
+	// Pretty writes each row and value on a line of its own, indented
+	Pretty bool
+}
+
+// DEFAULT_TABLE_WIDTH_ROWS is the number of first rows of a result the widths of the columns of tables are computed
+// from, unless TableOptions sets another one
+const DEFAULT_TABLE_WIDTH_ROWS = 500
+
+// tableStreamBatchRows is the number of rows shown at once once a table streams its rows
+const tableStreamBatchRows = 100
+
+// TableOptions adapts table output
+type TableOptions struct {
+	// Buffered reads whole results before showing them, so that every row is aligned, rather than showing the rows
+	// following the first WidthRows ones as they're read
+	Buffered bool
+	// WidthRows is the number of first rows the widths of the columns are computed from, DEFAULT_TABLE_WIDTH_ROWS when
+	// 0. The rows after them are shown in batches with the same widths, holding the memory of a batch only, and a value
+	// wider than its column shifts the columns after it in its row
+	WidthRows int
+}
+
 type Printer interface {
 	print(statementResult StatementResult, outF io.Writer) error
 }
 
 type TablePrinter struct {
-	withoutHeader bool
+	withoutHeader      bool
+	headerInterval     int
+	preserveHeaderCase bool
+	options            TableOptions
+	stats              *FormatStats
 }
 
+// print renders the first rows of the result, up to the number the widths of the columns are computed from, and then
+// the following ones in batches with the same widths, so that the first ones show up before the result is read whole
 func (t TablePrinter) print(statementResult StatementResult, outF io.Writer) error {
-	data := [][]string{}
-	table := createTable(outF)
-	if !t.withoutHeader {
-		table.SetHeader(statementResult.ColumnNames)
+	widthRows := t.options.WidthRows
+	if widthRows == 0 {
+		widthRows = DEFAULT_TABLE_WIDTH_ROWS
+	}
+	if t.options.Buffered {
+		widthRows = -1
 	}
 
-	tableData, err := appendData(statementResult, data, TABLE)
+	tableData, done, err := readTableRows(statementResult, widthRows, t.stats)
 	if err != nil {
 		return err
 	}
-
-	table.AppendBulk(tableData)
+	table := t.createTable(outF)
+	if !t.withoutHeader {
+		table.SetHeader(statementResult.ColumnNames)
+	}
+	table.AppendBulk(t.repeatHeader(tableData, statementResult.ColumnNames, 0))
 	table.Render()
+
+	widths := getTableColumnWidths(statementResult.ColumnNames, tableData, !t.withoutHeader)
+	out := bufio.NewWriter(outF)
+	for shownRows := len(tableData); !done; shownRows += len(tableData) {
+		tableData, done, err = readTableRows(statementResult, tableStreamBatchRows, t.stats)
+		for _, row := range t.repeatHeader(tableData, statementResult.ColumnNames, shownRows) {
+			writeTableRow(out, row, widths)
+		}
+		if flushErr := out.Flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (t TablePrinter) createTable(outF io.Writer) *tablewriter.Table {
+	table := createTable(outF)
+	table.SetAutoFormatHeaders(!t.preserveHeaderCase)
+	return table
+}
+
+// readTableRows reads up to limit rows of the result formatted for tables, or all of them when limit is negative. done
+// is set once there's no row left to read
+func readTableRows(statementResult StatementResult, limit int, stats *FormatStats) (rows [][]string, done bool, err error) {
+	rows = [][]string{}
+	for limit < 0 || len(rows) < limit {
+		row, ok := <-statementResult.RowCh
+		if !ok {
+			return rows, true, nil
+		}
+		if row.Err != nil {
+			return [][]string{}, true, row.Err
+		}
+		formattedRow, err := FormatDataWithStats(row.Row, TABLE, stats)
+		if err != nil {
+			return [][]string{}, true, err
+		}
+		rows = append(rows, formattedRow)
+	}
+	return rows, false, nil
+}
+
+// writeTableRow writes a row the way tablewriter renders the rows of the tables of createTable, each line of its values
+// padded to the width of its column. A value wider than its column shifts the columns after it in its row only
+func writeTableRow(out *bufio.Writer, row []string, widths []int) {
+	lines := make([][]string, len(row))
+	height := 1
+	for i, value := range row {
+		lines[i] = strings.Split(value, "\n")
+		if len(lines[i]) > height {
+			height = len(lines[i])
+		}
+	}
+	for x := 0; x < height; x++ {
+		for i := range row {
+			// Like tablewriter, the values with fewer lines are completed with lines of two spaces
+			line := "  "
+			if x < len(lines[i]) {
+				line = lines[i][x]
+			}
+			width := 0
+			if i < len(widths) {
+				width = widths[i]
+			}
+			out.WriteString(tablewriter.PadRight(line, " ", width))
+			out.WriteString(tablePadding)
+		}
+		out.WriteString("\n")
+	}
+}
+
+// getTableColumnWidths returns the widths tablewriter gives the columns of a table with the rows, and the header when
+// withHeader is set
+func getTableColumnWidths(columnNames []string, rows [][]string, withHeader bool) []int {
+	widths := make([]int, len(columnNames))
+	measure := func(row []string) {
+		for i, value := range row {
+			for _, line := range strings.Split(value, "\n") {
+				if i < len(widths) && tablewriter.DisplayWidth(line) > widths[i] {
+					widths[i] = tablewriter.DisplayWidth(line)
+				}
+			}
+		}
+	}
+	if withHeader {
+		measure(columnNames)
+	}
+	for _, row := range rows {
+		measure(row)
+	}
+	return widths
+}
+
+// repeatHeader inserts the header before every headerInterval rows of the table, the first of the rows given being
+// the row firstRow of the table. The copies are formatted like tablewriter formats the header, unless its case is
+// preserved, so they look the same
+func (t TablePrinter) repeatHeader(data [][]string, columnNames []string, firstRow int) [][]string {
+	if t.withoutHeader || t.headerInterval <= 0 {
+		return data
+	}
+	header := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		header[i] = name
+		if !t.preserveHeaderCase {
+			header[i] = tablewriter.Title(name)
+		}
+	}
+
+	repeated := make([][]string, 0, len(data)+len(data)/t.headerInterval+1)
+	for i, row := range data {
+		if tableRow := firstRow + i; tableRow > 0 && tableRow%t.headerInterval == 0 {
+			repeated = append(repeated, header)
+		}
+		repeated = append(repeated, row)
+	}
+	return repeated
+}
+
+// CSVPrinter writes the result as CSV, with the values formatted by CSVFormatter. Each row is written as it's read, so
+// results of any size take the memory of a row, and an output closed by its reader stops the query at the next rows.
+// When reading a row fails, the rows before the failure are kept in the output and the error is returned to be reported
 type CSVPrinter struct {
 	withoutHeader bool
+	stats         *FormatStats
+	options       CSVOptions
 }
 
+const utf8ByteOrderMark = "\ufeff"
+
 func (c CSVPrinter) print(statementResult StatementResult, outF io.Writer) error {
-	data := [][]string{}
-	if !c.withoutHeader {
-		data = append(data, statementResult.ColumnNames)
+	csvWriter := csv.NewWriter(outF)
+	csvWriter.UseCRLF = c.options.CRLF
+	err := c.writeRows(csvWriter, statementResult, outF)
+	csvWriter.Flush()
+	if flushErr := csvWriter.Error(); err == nil {
+		err = flushErr
 	}
+	return err
+}
 
-	csvData, err := appendData(statementResult, data, CSV)
-	if err != nil {
-		return err
+func (c CSVPrinter) writeRows(csvWriter *csv.Writer, statementResult StatementResult, outF io.Writer) error {
+	// The byte order mark and the header are written with the first row, so a query that fails or is interrupted
+	// before any leaves no output
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		if c.options.BOM {
+			if _, err := io.WriteString(outF, utf8ByteOrderMark); err != nil {
+				return err
+			}
+		}
+		if c.withoutHeader {
+			return nil
+		}
+		return csvWriter.Write(statementResult.ColumnNames)
 	}
 
-	csvWriter := csv.NewWriter(outF)
-	err = csvWriter.WriteAll(csvData)
-	if err != nil {
-		return err
+	for row := range statementResult.RowCh {
+		if row.Err != nil {
+			return row.Err
+		}
+		formattedRow, err := FormatDataWithStats(row.Row, CSV, c.stats)
+		if err != nil {
+			return err
+		}
+		if err := start(); err != nil {
+			return err
+		}
+		if err := csvWriter.Write(formattedRow); err != nil {
+			return err
+		}
 	}
-
-	return nil
+	return start()
 }
 
-type JSONPrinter struct{}
+// JSONPrinter writes the result as an array of objects, keyed by column name in alphabetical order, with the values
+// formatted by JSONFormatter. Each row is written as it's read, so results of any size take the memory of a row. When
+// reading a row fails, the array is still closed, so the output is valid JSON holding the rows before the failure, and
+// the error is returned to be reported. Queries without rows write [], and statements without columns nothing
+type JSONPrinter struct {
+	stats   *FormatStats
+	options JSONOptions
+}
 
 func (c JSONPrinter) print(statementResult StatementResult, outF io.Writer) error {
-	var data []map[string]interface{}
+	keys, columns := getJSONKeys(statementResult.ColumnNames)
+	out := bufio.NewWriter(outF)
+	rows := 0
+	err := c.writeRows(out, statementResult, keys, columns, &rows)
+	if rows == 0 && len(keys) > 0 && err == nil {
+		out.WriteString("[]\n")
+	} else if rows > 0 {
+		if c.options.Pretty {
+			out.WriteString("\n")
+		}
+		out.WriteString("]\n")
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func (c JSONPrinter) writeRows(out *bufio.Writer, statementResult StatementResult, keys []string, columns []int, rows *int) error {
+	separator, keySeparator, rowIndent, keyIndent := ",", ":", "", ""
+	if c.options.Pretty {
+		separator, keySeparator, rowIndent, keyIndent = ",\n", ": ", "  ", "\n    "
+	}
 
 	for row := range statementResult.RowCh {
 		if row.Err != nil {
 			return row.Err
 		}
-		rowData := make(map[string]interface{})
-		formattedRow, err := FormatData(row.Row, JSON)
+		formattedRow, err := FormatDataWithStats(row.Row, JSON, c.stats)
 		if err != nil {
 			return err
 		}
-		for i, v := range statementResult.ColumnNames {
-			rowData[v] = formattedRow[i]
+
+		if *rows == 0 {
+			out.WriteString("[" + strings.TrimPrefix(separator, ","))
+		} else {
+			out.WriteString(separator)
+		}
+		out.WriteString(rowIndent + "{")
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(keyIndent)
+			if err := writeJSONString(out, key); err != nil {
+				return err
+			}
+			out.WriteString(keySeparator)
+			out.WriteString(formattedRow[columns[i]])
+		}
+		if len(keys) > 0 {
+			out.WriteString(strings.TrimSuffix(keyIndent, "  "))
 		}
-		data = append(data, rowData)
+		out.WriteString("}")
+		*rows++
+	}
+	return nil
+}
+
+// getJSONKeys returns the keys of the row objects in alphabetical order, with the column each takes its value from.
+// Of the columns sharing a name, the last one is taken
+func getJSONKeys(columnNames []string) (keys []string, columns []int) {
+	lastColumns := make(map[string]int, len(columnNames))
+	for i, name := range columnNames {
+		lastColumns[name] = i
+	}
+	keys = make([]string, 0, len(lastColumns))
+	for name := range lastColumns {
+		keys = append(keys, name)
 	}
+	sort.Strings(keys)
+	columns = make([]int, len(keys))
+	for i, key := range keys {
+		columns[i] = lastColumns[key]
+	}
+	return keys, columns
+}
 
-	json, err := json.Marshal(data)
+func writeJSONString(out io.Writer, value string) error {
+	encoded, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
-	if string(json) != "null" {
-		fmt.Fprintln(outF, string(json))
+	_, err = out.Write(encoded)
+	return err
+}
+
+// SQL_MODE_MAX_ROWS is how many rows the SQL printer writes when the compound_select limit isn't known, as SQLite
+// accepts at most 500 SELECTs joined by UNION ALL
+const SQL_MODE_MAX_ROWS = 500
+
+// SQLPrinter writes the result as a SELECT statement that reproduces it, with its column names in a comment. Values
+// are written as the SQL literals of .dump. The rows that would take the statement over the limits of the database are
+// left out, with a warning
+type SQLPrinter struct {
+	quoteStyle enums.IdentifierQuoteStyle
+	stats      *FormatStats
+	limits     StatementLimits
+}
+
+func (s SQLPrinter) print(statementResult StatementResult, outF io.Writer) error {
+	if len(statementResult.ColumnNames) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(outF, "-- columns: %s\n", strings.Join(statementResult.ColumnNames, ", "))
+
+	maxRows := s.limits.CompoundSelect
+	if maxRows <= 0 {
+		maxRows = SQL_MODE_MAX_ROWS
+	}
+	var rows, omittedRows, length int64
+	omittedForLength := false
+	for row := range statementResult.RowCh {
+		if row.Err != nil {
+			return row.Err
+		}
+		if omittedRows > 0 || rows == maxRows {
+			omittedRows++
+			continue
+		}
+
+		literals, err := FormatDataWithStats(row.Row, SQLITE, s.stats)
+		if err != nil {
+			return err
+		}
+		var selectRow string
+		if rows == 0 {
+			selectRow = "SELECT " + strings.Join(aliasLiterals(literals, statementResult.ColumnNames, s.quoteStyle), ", ")
+		} else {
+			selectRow = "\nUNION ALL SELECT " + strings.Join(literals, ", ")
+		}
+		// The first row is written even when it's too long alone, as leaving it out leaves nothing to reproduce
+		if rows > 0 && s.limits.SQLLength > 0 && length+int64(len(selectRow))+1 > s.limits.SQLLength {
+			omittedRows++
+			omittedForLength = true
+			continue
+		}
+		fmt.Fprint(outF, selectRow)
+		length += int64(len(selectRow))
+		rows++
+	}
+
+	if rows == 0 {
+		nulls := make([]string, len(statementResult.ColumnNames))
+		for i := range nulls {
+			nulls[i] = "NULL"
+		}
+		fmt.Fprintf(outF, "SELECT %s WHERE 0", strings.Join(aliasLiterals(nulls, statementResult.ColumnNames, s.quoteStyle), ", "))
+	}
+	fmt.Fprintln(outF, ";")
+
+	switch {
+	case omittedForLength:
+		fmt.Fprintf(outF, "-- Warning: %d more rows were omitted, as only the first %d rows fit in a single SELECT under the sql_length limit of %d bytes\n", omittedRows, rows, s.limits.SQLLength)
+	case omittedRows > 0:
+		fmt.Fprintf(outF, "-- Warning: %d more rows were omitted, as only the first %d rows can be written as a single SELECT\n", omittedRows, maxRows)
 	}
 	return nil
 }
 
-func appendData(statementResult StatementResult, data [][]string, mode FormatType) ([][]string, error) {
+func aliasLiterals(literals []string, columnNames []string, quoteStyle enums.IdentifierQuoteStyle) []string {
+	aliased := make([]string, len(literals))
+	for i, literal := range literals {
+		aliased[i] = literal + " AS " + QuoteIdentifier(columnNames[i], quoteStyle)
+	}
+	return aliased
+}
+
+func appendData(statementResult StatementResult, data [][]string, mode FormatType, stats *FormatStats) ([][]string, error) {
 	for row := range statementResult.RowCh {
 		if row.Err != nil {
 			return [][]string{}, row.Err
 		}
-		formattedRow, err := FormatData(row.Row, mode)
+		formattedRow, err := FormatDataWithStats(row.Row, mode, stats)
 		if err != nil {
 			return [][]string{}, err
 		}
@@ -103,63 +523,223 @@ func appendData(statementResult StatementResult, data [][]string, mode FormatTyp
 	return data, nil
 }
 
-func getPrinter(mode enums.PrintMode, withoutHeader bool) (Printer, error) {
-	switch mode {
+func getPrinter(options PrintOptions) (Printer, error) {
+	switch options.Mode {
 	case enums.TABLE_MODE:
 		return &TablePrinter{
-			withoutHeader: withoutHeader,
+			withoutHeader:      options.WithoutHeader,
+			headerInterval:     options.HeaderInterval,
+			preserveHeaderCase: options.PreserveHeaderCase,
+			options:            options.Table,
+			stats:              options.FormatStats,
 		}, nil
 	case enums.CSV_MODE:
 		return &CSVPrinter{
-			withoutHeader: withoutHeader,
+			withoutHeader: options.WithoutHeader,
+			stats:         options.FormatStats,
+			options:       options.CSV,
 		}, nil
 	case enums.JSON_MODE:
-		return &JSONPrinter{}, nil
+		return &JSONPrinter{stats: options.FormatStats, options: options.JSON}, nil
+	case enums.SQL_MODE:
+		quoteStyle := options.QuoteStyle
+		if quoteStyle == "" {
+			quoteStyle = enums.DOUBLE_QUOTE_STYLE
+		}
+		return &SQLPrinter{quoteStyle: quoteStyle, stats: options.FormatStats, limits: options.limits}, nil
+	case enums.SPARKLINE_MODE:
+		return &SparklinePrinter{stats: options.FormatStats}, nil
 	default:
-		return nil, fmt.Errorf("unsupported printer: %s", mode)
+		return nil, fmt.Errorf("unsupported printer: %s", options.Mode)
 	}
 }
 
-func PrintStatementsResult(statementsResult StatementsResult, outF io.Writer, withoutHeader bool, mode enums.PrintMode) error {
+func PrintStatementsResult(statementsResult StatementsResult, outF io.Writer, options PrintOptions) error {
 	if statementsResult.StatementResultCh == nil {
 		return &InvalidStatementsResult{}
 	}
 
+	summary := options.Summary
+	if summary == nil {
+		summary = &ExecutionSummary{}
+	}
+	startTime := time.Now()
+	defer summary.finish(statementsResult.StatementCount, statementsResult.EmptyStatements, startTime)
+
+	// Each statement is timed from the end of the previous one, as they run while the results before them are printed
+	statementStartTime := startTime
+	countsRows := options.Summary != nil || options.OnSlowStatement != nil || options.SessionStats != nil || options.FailOnEmpty || options.Changes
 	for statementResult := range statementsResult.StatementResultCh {
+		if IsOutputClosed(outF) {
+			return &shellerrors.OutputClosedError{}
+		}
 		if statementResult.Err != nil {
+			summary.Failed++
+			if options.SessionStats != nil {
+				options.SessionStats.recordFailure(0)
+			}
+			if options.ErrorLog != nil {
+				options.ErrorLog.Record(statementResult.Statement, statementResult.Err)
+				statementStartTime = time.Now()
+				continue
+			}
 			return statementResult.Err
 		}
 
-		err := PrintStatementResult(statementResult, outF, withoutHeader, mode)
+		var rowsReturned int64
+		if countsRows {
+			statementResult = countRows(statementResult, &rowsReturned)
+		}
+		err := PrintStatementResult(statementResult, outF, options)
+		timing := StatementTiming{Statement: statementResult.Statement, Duration: time.Since(statementStartTime), RowsReturned: atomic.LoadInt64(&rowsReturned)}
+		statementStartTime = time.Now()
+		summary.rowsReturned += timing.RowsReturned
+		if err == nil && options.Changes && !IsOutputClosed(outF) {
+			printChanges(statementResult, timing.RowsReturned, outF, options.Mode)
+		}
+		if options.Timer && !IsOutputClosed(outF) {
+			fmt.Fprintf(outF, "Run Time: real %.3fs\n", timing.Duration.Seconds())
+		}
+		if options.OnSlowStatement != nil && timing.Duration > options.SlowThreshold {
+			options.OnSlowStatement(timing)
+		}
+		if err == nil && options.FailOnEmpty && len(statementResult.ColumnNames) > 0 && timing.RowsReturned == 0 {
+			err = &shellerrors.EmptyResultError{}
+		}
 		if err != nil {
+			summary.Failed++
+			if options.SessionStats != nil {
+				options.SessionStats.recordFailure(timing.RowsReturned)
+			}
+			if options.ErrorLog != nil && !IsOutputClosed(outF) {
+				options.ErrorLog.Record(statementResult.Statement, err)
+				continue
+			}
 			return err
 		}
+		summary.Succeeded++
+		summary.RowsChanged += statementResult.RowsChanged
+		if options.SessionStats != nil {
+			options.SessionStats.recordSuccess(timing, statementResult.RowsChanged)
+		}
+	}
+	if IsOutputClosed(outF) {
+		return &shellerrors.OutputClosedError{}
 	}
 	return nil
 }
 
-func PrintStatementResult(statementResult StatementResult, outF io.Writer, withoutHeader bool, mode enums.PrintMode) error {
+func PrintStatementResult(statementResult StatementResult, outF io.Writer, options PrintOptions) error {
 	if statementResult.RowCh == nil {
 		return &UnableToPrintStatementResult{}
 	}
 
-	printer, err := getPrinter(mode, withoutHeader)
+	printer, err := getPrinter(options)
 	if err != nil {
 		return err
 	}
+	formatExplain := options.FormatExplain && IsExplainResult(statementResult.ColumnNames)
+	if formatExplain {
+		printer = &ExplainPrinter{stats: options.FormatStats}
+	}
+
+	if options.ResultCache != nil && len(statementResult.ColumnNames) > 0 {
+		statementResult = options.ResultCache.record(statementResult)
+	}
+
+	if options.ColumnTransform != nil && !formatExplain {
+		statementResult = transformColumns(statementResult, options.ColumnTransform)
+	}
+
+	if options.PrettyPragmas && options.Mode == enums.TABLE_MODE {
+		statementResult, _, err = prettifyPragmaResult(statementResult, options.FormatStats)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.NullValue != nil && isNullValueMode(options.Mode) && !formatExplain {
+		statementResult = replaceNulls(statementResult, *options.NullValue)
+	}
+
+	if len(options.MaskPatterns) > 0 && isMaskedMode(options.Mode) {
+		statementResult = maskColumns(statementResult, options.MaskPatterns)
+	}
+
+	if options.NumberLocale.enabled() && isNumberFormattedMode(options.Mode) && !formatExplain {
+		statementResult = formatNumbers(statementResult, options.NumberLocale)
+	}
 
 	err = printer.print(statementResult, outF)
 	if err != nil {
+		if options.ErrorLog != nil && !IsOutputClosed(outF) {
+			drainRows(statementResult)
+		} else {
+			// The rows are dropped once the statements are canceled, which ends the goroutines passing them on
+			go drainRows(statementResult)
+		}
 		return err
 	}
 
 	return nil
 }
 
+// printChanges writes the rows changed by a statement, as a JSON object in JSON mode and as a comment in SQL mode, or
+// the rows returned after a table of table mode
+func printChanges(statementResult StatementResult, rowsReturned int64, outF io.Writer, mode enums.PrintMode) {
+	if !statementResult.ChangesCounted {
+		if mode == enums.TABLE_MODE && len(statementResult.ColumnNames) > 0 {
+			fmt.Fprintf(outF, "(%s)\n", formatRowCount(rowsReturned))
+		}
+		return
+	}
+
+	isInsert := isInsertStatement(statementResult.Statement)
+	if mode == enums.JSON_MODE {
+		if isInsert {
+			fmt.Fprintf(outF, "{\"rows_affected\":%d,\"last_insert_rowid\":%d}\n", statementResult.RowsChanged, statementResult.LastInsertRowid)
+		} else {
+			fmt.Fprintf(outF, "{\"rows_affected\":%d}\n", statementResult.RowsChanged)
+		}
+		return
+	}
+
+	changes := formatRowCount(statementResult.RowsChanged) + " affected"
+	if isInsert {
+		changes += fmt.Sprintf(", last insert rowid %d", statementResult.LastInsertRowid)
+	}
+	if mode == enums.SQL_MODE {
+		changes = "-- " + changes
+	}
+	fmt.Fprintln(outF, changes)
+}
+
+func formatRowCount(rows int64) string {
+	if rows == 1 {
+		return "1 row"
+	}
+	return fmt.Sprintf("%d rows", rows)
+}
+
+// drainRows consumes the rows a printer left unread, so the statements that run after a failing one aren't blocked
+func drainRows(statementResult StatementResult) {
+	for range statementResult.RowCh {
+	}
+}
+
+// PrintError writes the error a command or statement failed with, or Interrupted for the queries canceled with Ctrl-C
 func PrintError(err error, errF io.Writer) {
+	var cancelErr *shellerrors.CancelQueryContextError
+	if errors.As(err, &cancelErr) {
+		fmt.Fprintln(errF, "Interrupted")
+		return
+	}
 	fmt.Fprintf(errF, "Error: %s\n", err.Error())
 }
 
+// tablePadding separates the columns of tables
+const tablePadding = "     "
+
 func createTable(outF io.Writer) *tablewriter.Table {
 	table := tablewriter.NewWriter(outF)
 
@@ -172,7 +752,7 @@ func createTable(outF io.Writer) *tablewriter.Table {
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetColumnSeparator("  ")
 	table.SetNoWhiteSpace(true)
-	table.SetTablePadding("     ")
+	table.SetTablePadding(tablePadding)
 
 	return table
 }
@@ -1,9 +1,22 @@
 package db_test
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 	"github.com/libsql/libsql-shell-go/test/utils"
 )
 
@@ -76,3 +89,378 @@ func TestGetTableOutput_GivenHeaderAndDataNil_ExpectTableHasJustHeader(t *testin
 
 	c.Assert(result, qt.Equals, "ID     VALUE")
 }
+
+func TestExecuteAndPrintStatements_GivenParallelCallsWithDifferentOptions_ExpectEachPrintedWithItsOwnOptions(t *testing.T) {
+	c := qt.New(t)
+
+	sharedDb, err := db.NewDb(t.TempDir()+"/parallel.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer sharedDb.Close()
+
+	const calls = 50
+	outputs := make([]*bytes.Buffer, calls)
+	errs := make([]error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		outputs[i] = new(bytes.Buffer)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			options := db.PrintOptions{Mode: enums.CSV_MODE, WithoutHeader: i%2 == 0}
+			errs[i] = sharedDb.ExecuteAndPrintStatements(fmt.Sprintf("SELECT %d AS value;", i), outputs[i], options)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < calls; i++ {
+		c.Assert(errs[i], qt.IsNil)
+		if i%2 == 0 {
+			c.Assert(outputs[i].String(), qt.Equals, fmt.Sprintf("%d\n", i))
+		} else {
+			c.Assert(outputs[i].String(), qt.Equals, fmt.Sprintf("value\n%d\n", i))
+		}
+	}
+}
+
+func TestExecuteAndPrintStatements_GivenHeaderInterval_ExpectHeaderRepeatedLikeTheFirstOne(t *testing.T) {
+	c := qt.New(t)
+
+	testDb, err := db.NewDb(t.TempDir()+"/header.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer testDb.Close()
+
+	out := new(bytes.Buffer)
+	options := db.PrintOptions{Mode: enums.TABLE_MODE, HeaderInterval: 2}
+	err = testDb.ExecuteAndPrintStatements("SELECT column1 AS row_number FROM (VALUES (1), (2), (3), (4));", out, options)
+	c.Assert(err, qt.IsNil)
+	lines := strings.Split(out.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	c.Assert(lines, qt.DeepEquals, []string{"ROW NUMBER", "1", "2", "ROW NUMBER", "3", "4", ""})
+
+	out.Reset()
+	options.Mode = enums.CSV_MODE
+	err = testDb.ExecuteAndPrintStatements("SELECT column1 AS row_number FROM (VALUES (1), (2), (3));", out, options)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "row_number\n1\n2\n3\n")
+}
+
+func TestExecuteAndPrintStatements_GivenPreserveHeaderCase_ExpectExactAliasesInEveryMode(t *testing.T) {
+	c := qt.New(t)
+
+	testDb, err := db.NewDb(t.TempDir()+"/headercase.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer testDb.Close()
+
+	query := `SELECT column1 AS "userId", 'é' AS "créé le", 'x' AS "first name", 0 AS row_number FROM (VALUES (1), (2), (3));`
+	printLines := func(options db.PrintOptions) []string {
+		out := new(bytes.Buffer)
+		c.Assert(testDb.ExecuteAndPrintStatements(query, out, options), qt.IsNil)
+		lines := strings.Split(out.String(), "\n")
+		for i := range lines {
+			lines[i] = strings.Join(strings.Fields(lines[i]), " ")
+		}
+		return lines
+	}
+
+	c.Assert(printLines(db.PrintOptions{Mode: enums.TABLE_MODE})[0], qt.Equals, "USERID CRÉÉ LE FIRST NAME ROW NUMBER")
+	c.Assert(printLines(db.PrintOptions{Mode: enums.TABLE_MODE, PreserveHeaderCase: true, HeaderInterval: 2}), qt.DeepEquals, []string{
+		"userId créé le first name row_number", "1 é x 0", "2 é x 0", "userId créé le first name row_number", "3 é x 0", "",
+	})
+
+	for _, options := range []db.PrintOptions{{Mode: enums.CSV_MODE}, {Mode: enums.CSV_MODE, PreserveHeaderCase: true}} {
+		out := new(bytes.Buffer)
+		c.Assert(testDb.ExecuteAndPrintStatements(query, out, options), qt.IsNil)
+		c.Assert(strings.SplitN(out.String(), "\n", 2)[0], qt.Equals, "userId,créé le,first name,row_number")
+	}
+	for _, options := range []db.PrintOptions{{Mode: enums.JSON_MODE}, {Mode: enums.JSON_MODE, PreserveHeaderCase: true}} {
+		out := new(bytes.Buffer)
+		c.Assert(testDb.ExecuteAndPrintStatements(query, out, options), qt.IsNil)
+		c.Assert(out.String(), qt.Contains, `{"créé le":"é","first name":"x","row_number":0,"userId":1}`)
+	}
+}
+
+func TestExecuteAndPrintStatements_GivenNullValue_ExpectItInTextModesOnly(t *testing.T) {
+	c := qt.New(t)
+
+	testDb, err := db.NewDb(t.TempDir()+"/nullvalue.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer testDb.Close()
+	c.Assert(testDb.ExecuteAndPrintStatements("CREATE TABLE t (a TEXT, b INTEGER); INSERT INTO t VALUES ('NULL', NULL), (NULL, 1);", new(bytes.Buffer), db.PrintOptions{Mode: enums.TABLE_MODE}), qt.IsNil)
+
+	printResult := func(options db.PrintOptions) string {
+		out := new(bytes.Buffer)
+		c.Assert(testDb.ExecuteAndPrintStatements("SELECT a, b FROM t;", out, options), qt.IsNil)
+		return out.String()
+	}
+	empty, placeholder := "", "<null>"
+
+	c.Assert(printResult(db.PrintOptions{Mode: enums.TABLE_MODE}), qt.Equals, "A        B    \nNULL     NULL     \nNULL     1        \n")
+	c.Assert(printResult(db.PrintOptions{Mode: enums.TABLE_MODE, NullValue: &placeholder}), qt.Equals, "A          B      \nNULL       <null>     \n<null>     1          \n")
+	c.Assert(printResult(db.PrintOptions{Mode: enums.CSV_MODE}), qt.Equals, "a,b\nNULL,\n,1\n")
+	c.Assert(printResult(db.PrintOptions{Mode: enums.CSV_MODE, NullValue: &placeholder}), qt.Equals, "a,b\nNULL,<null>\n<null>,1\n")
+	c.Assert(printResult(db.PrintOptions{Mode: enums.TABLE_MODE, NullValue: &empty}), qt.Equals, "A        B \nNULL           \n         1     \n")
+	for _, nullValue := range []*string{nil, &placeholder} {
+		c.Assert(printResult(db.PrintOptions{Mode: enums.JSON_MODE, NullValue: nullValue}), qt.Equals, `[{"a":"NULL","b":null},{"a":null,"b":1}]`+"\n")
+	}
+}
+
+func TestExecuteAndPrintStatements_GivenMoreRowsThanWidthRows_ExpectThemStreamedWithTheWidthsOfTheFirstOnes(t *testing.T) {
+	c := qt.New(t)
+
+	testDb, err := db.NewDb(t.TempDir()+"/stream.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer testDb.Close()
+
+	printTable := func(query string, options db.PrintOptions) string {
+		out := new(bytes.Buffer)
+		options.Mode = enums.TABLE_MODE
+		c.Assert(testDb.ExecuteAndPrintStatements(query, out, options), qt.IsNil)
+		return out.String()
+	}
+
+	// Rows no wider than the first ones are laid out like the buffered table with all of them
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1234) SELECT i % 1000 AS id, 'value ' || (i % 10) AS value FROM n;"
+	buffered := printTable(query, db.PrintOptions{Table: db.TableOptions{Buffered: true}})
+	c.Assert(strings.Count(buffered, "\n"), qt.Equals, 1235)
+	c.Assert(printTable(query, db.PrintOptions{}), qt.Equals, buffered)
+	c.Assert(printTable(query, db.PrintOptions{Table: db.TableOptions{WidthRows: 1000}}), qt.Equals, buffered)
+	c.Assert(printTable(query, db.PrintOptions{HeaderInterval: 150}), qt.Equals, printTable(query, db.PrintOptions{Table: db.TableOptions{Buffered: true}, HeaderInterval: 150}))
+
+	// A row wider than the first ones only shifts the columns after its wider value
+	query = "SELECT column1 AS id, column2 AS value FROM (VALUES (1, 'a'), (2, 'b'), (3, 'a longer value'), (4, 'd'));"
+	c.Assert(printTable(query, db.PrintOptions{Table: db.TableOptions{WidthRows: 2}}), qt.Equals, "ID     VALUE \n1      a         \n2      b         \n3      a longer value     \n4      d         \n")
+	c.Assert(printTable(query, db.PrintOptions{Table: db.TableOptions{Buffered: true}}), qt.Equals, "ID     VALUE          \n1      a                  \n2      b                  \n3      a longer value     \n4      d                  \n")
+}
+
+func TestExecuteAndPrintStatements_GivenEmptyStatements_ExpectThemSkippedAndCounted(t *testing.T) {
+	c := qt.New(t)
+
+	testDb, err := db.NewDb(t.TempDir()+"/empty.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer testDb.Close()
+	var queries []string
+	testDb.SetQueryTrace(func(query string) {
+		queries = append(queries, query)
+	})
+
+	out := new(bytes.Buffer)
+	summary := &db.ExecutionSummary{}
+	options := db.PrintOptions{Mode: enums.CSV_MODE, Summary: summary}
+	err = testDb.ExecuteAndPrintStatements("CREATE TABLE t (v);\nINSERT INTO t VALUES (1);\nSELECT v FROM t;", out, options)
+	c.Assert(err, qt.IsNil)
+	legitimateOutput := out.String()
+	legitimateQueries := queries
+	c.Assert(legitimateQueries, qt.DeepEquals, []string{"CREATE TABLE t (v)", "INSERT INTO t VALUES (1)", "SELECT v FROM t"})
+	c.Assert(summary.EmptyStatements, qt.Equals, 0)
+	c.Assert(summary.String(), qt.Not(qt.Contains), "empty")
+
+	c.Assert(testDb.ExecuteAndPrintStatements("DROP TABLE t;", new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+	out.Reset()
+	queries = nil
+	summary = &db.ExecutionSummary{}
+	options.Summary = summary
+	err = testDb.ExecuteAndPrintStatements("CREATE TABLE t (v);;\n;\nINSERT INTO t VALUES (1);\n-- only a comment\n;\nSELECT v FROM t; /* another */ ;;", out, options)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, legitimateOutput)
+	c.Assert(queries, qt.DeepEquals, legitimateQueries)
+	c.Assert(summary.Statements, qt.Equals, 3)
+	c.Assert(summary.EmptyStatements, qt.Equals, 5)
+	c.Assert(summary.String(), qt.Matches, `3 statements: 3 ok, 0 errors, 1 rows returned, 1 rows changed, \S+ total, skipped 5 empty statements`)
+}
+
+// Remote databases receiving the statements of an input in a single query get them without the empty ones
+func TestExecuteStatements_GivenEmptyStatementsToHttpServer_ExpectOnlyTheOthersSent(t *testing.T) {
+	c := qt.New(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	serverDb, err := db.NewDb(server.URL, "token")
+	c.Assert(err, qt.IsNil)
+	defer serverDb.Close()
+	var queries []string
+	serverDb.SetQueryTrace(func(query string) {
+		queries = append(queries, query)
+	})
+
+	result, err := serverDb.ExecuteStatements("SELECT 1;;\n;\nSELECT 2;")
+	c.Assert(err, qt.IsNil)
+	for range result.StatementResultCh {
+	}
+	c.Assert(result.EmptyStatements, qt.Equals, 2)
+	c.Assert(queries, qt.DeepEquals, []string{"SELECT 1;\nSELECT 2"})
+	c.Assert(received, qt.Equals, `{"statements":[{"q":"SELECT 1","params":[]},{"q":"SELECT 2","params":[]}]}`)
+}
+
+// newBasicHttpServer serves the database at dbPath over the HTTP protocol of the first sqld versions, as sqld encodes
+// values for it: integers as JSON numbers, whatever the declared types of their columns, and blobs as base64
+func newBasicHttpServer(c *qt.C, dbPath string) *httptest.Server {
+	serverDb, err := sql.Open("sqlite3", dbPath)
+	c.Assert(err, qt.IsNil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Statements []struct {
+				Q string `json:"q"`
+			} `json:"statements"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results := make([]interface{}, 0, len(body.Statements))
+		for _, statement := range body.Statements {
+			rows, err := serverDb.Query(statement.Q)
+			if err != nil {
+				results = append(results, map[string]interface{}{"error": map[string]string{"message": err.Error()}})
+				continue
+			}
+			columns, _ := rows.Columns()
+			values := [][]interface{}{}
+			for rows.Next() {
+				row := make([]interface{}, len(columns))
+				pointers := make([]interface{}, len(columns))
+				for i := range row {
+					pointers[i] = &row[i]
+				}
+				c.Check(rows.Scan(pointers...), qt.IsNil)
+				for i, value := range row {
+					switch value := value.(type) {
+					case bool:
+						row[i] = 0
+						if value {
+							row[i] = 1
+						}
+					case []byte:
+						row[i] = map[string]string{"base64": base64.StdEncoding.EncodeToString(value)}
+					}
+				}
+				values = append(values, row)
+			}
+			rows.Close()
+			results = append(results, map[string]interface{}{"results": map[string]interface{}{"columns": columns, "rows": values}})
+		}
+		c.Check(json.NewEncoder(w).Encode(results), qt.IsNil)
+	}))
+	c.Cleanup(func() {
+		server.Close()
+		serverDb.Close()
+	})
+	return server
+}
+
+func TestExecuteAndPrintStatements_GivenTheSameTableLocallyAndRemotely_ExpectTheSameOutput(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := t.TempDir() + "/fixture.sqlite"
+	localDb, err := db.NewDb(dbPath, "")
+	c.Assert(err, qt.IsNil)
+	defer localDb.Close()
+	c.Assert(localDb.ExecuteAndPrintStatements(`CREATE TABLE fixture (i INTEGER, n NUMERIC, b BOOLEAN, r REAL, s TEXT, x BLOB);
+		INSERT INTO fixture VALUES (42, 12, 1, 1.5, 'a', x'0102'), ('abc', 1.25, 0, 2.0, NULL, NULL), (-7, 9007199254740, NULL, -0.5, '9', x'ff');
+		CREATE TABLE big (n NUMERIC); INSERT INTO big VALUES (9007199254740993);`, new(bytes.Buffer), db.PrintOptions{Mode: enums.TABLE_MODE}), qt.IsNil)
+
+	remoteDb, err := db.NewDb(newBasicHttpServer(c, dbPath).URL, "token")
+	c.Assert(err, qt.IsNil)
+	defer remoteDb.Close()
+
+	printResult := func(printDb *db.Db, mode enums.PrintMode) string {
+		out := new(bytes.Buffer)
+		c.Assert(printDb.ExecuteAndPrintStatements("SELECT * FROM fixture;", out, db.PrintOptions{Mode: mode}), qt.IsNil)
+		return out.String()
+	}
+	c.Assert(printResult(localDb, enums.JSON_MODE), qt.Equals, `[{"b":1,"i":42,"n":12,"r":1.5,"s":"a","x":"AQI="},{"b":0,"i":"abc","n":1.25,"r":2,"s":null,"x":null},{"b":null,"i":-7,"n":9007199254740,"r":-0.5,"s":"9","x":"/w=="}]`+"\n")
+	for _, mode := range []enums.PrintMode{enums.TABLE_MODE, enums.CSV_MODE, enums.JSON_MODE, enums.SQL_MODE} {
+		c.Assert(printResult(remoteDb, mode), qt.Equals, printResult(localDb, mode), qt.Commentf("mode %s", mode))
+	}
+
+	// Integers too large for float64, which the first HTTP protocol reads JSON numbers as, keep every digit locally
+	out := new(bytes.Buffer)
+	c.Assert(localDb.ExecuteAndPrintStatements("SELECT n, 9007199254740993 AS i FROM big;", out, db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "n,i\n9007199254740993,9007199254740993\n")
+}
+
+func TestExecuteAndPrintStatements_GivenEmptyResultLocallyAndRemotely_ExpectCSVHeaderUnlessWithoutHeader(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/empty.sqlite"
+	localDb, err := db.NewDb(dbPath, "")
+	c.Assert(err, qt.IsNil)
+	defer localDb.Close()
+	c.Assert(localDb.ExecuteAndPrintStatements("CREATE TABLE empty (id INTEGER, name TEXT);", new(bytes.Buffer), db.PrintOptions{Mode: enums.TABLE_MODE}), qt.IsNil)
+
+	remoteDb, err := db.NewDb(newBasicHttpServer(c, dbPath).URL, "token")
+	c.Assert(err, qt.IsNil)
+	defer remoteDb.Close()
+
+	for _, printDb := range []*db.Db{localDb, remoteDb} {
+		out := new(bytes.Buffer)
+		c.Assert(printDb.ExecuteAndPrintStatements("SELECT * FROM empty; SELECT 1 AS one WHERE 0;", out, db.PrintOptions{Mode: enums.CSV_MODE}), qt.IsNil)
+		c.Assert(out.String(), qt.Equals, "id,name\none\n", qt.Commentf("db %s", printDb.Uri))
+
+		out.Reset()
+		c.Assert(printDb.ExecuteAndPrintStatements("SELECT * FROM empty;", out, db.PrintOptions{Mode: enums.CSV_MODE, WithoutHeader: true}), qt.IsNil)
+		c.Assert(out.String(), qt.Equals, "", qt.Commentf("db %s", printDb.Uri))
+	}
+}
+
+func TestExecuteAndPrintStatements_GivenFailOnEmpty_ExpectQueriesWithoutRowsToFailAfterTheirHeader(t *testing.T) {
+	c := qt.New(t)
+
+	emptyDb, err := db.NewDb(c.TempDir()+"/empty.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer emptyDb.Close()
+
+	c.Assert(emptyDb.ExecuteAndPrintStatements("CREATE TABLE empty (id INTEGER);", new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE, FailOnEmpty: true}), qt.IsNil)
+
+	out := new(bytes.Buffer)
+	err = emptyDb.ExecuteAndPrintStatements("SELECT count(*) AS rows FROM empty; SELECT * FROM empty; SELECT 2;", out, db.PrintOptions{Mode: enums.CSV_MODE, FailOnEmpty: true})
+	c.Assert(err, qt.ErrorAs, new(*shellerrors.EmptyResultError))
+	c.Assert(out.String(), qt.Equals, "rows\n0\nid\n")
+
+	out.Reset()
+	errF := new(bytes.Buffer)
+	summary := &db.ExecutionSummary{}
+	options := db.PrintOptions{Mode: enums.CSV_MODE, FailOnEmpty: true, Summary: summary, ErrorLog: db.NewErrorLog(errF, false, db.DEFAULT_ERROR_LOG_RETAINED)}
+	c.Assert(emptyDb.ExecuteAndPrintStatements("SELECT * FROM empty; SELECT 2 AS two;", out, options), qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "id\ntwo\n2\n")
+	c.Assert(summary.Failed, qt.Equals, 1)
+	c.Assert(options.ErrorLog.Count(), qt.Equals, int64(1))
+}
+
+func TestExecuteAndPrintStatements_GivenChanges_ExpectRowsAffectedAfterChangesAndRowCountAfterTables(t *testing.T) {
+	c := qt.New(t)
+
+	changesDb, err := db.NewDb(c.TempDir()+"/changes.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer changesDb.Close()
+
+	out := new(bytes.Buffer)
+	err = changesDb.ExecuteAndPrintStatements("CREATE TABLE t (a INTEGER); INSERT INTO t VALUES (1), (2), (3); UPDATE t SET a = a * 10 WHERE a > 1; DELETE FROM t WHERE a = 42; SELECT a AS v FROM t WHERE a > 1;", out, db.PrintOptions{Mode: enums.TABLE_MODE, Changes: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "3 rows affected, last insert rowid 3\n2 rows affected\n0 rows affected\nV  \n20     \n30     \n(2 rows)\n")
+
+	for _, tt := range []struct {
+		mode     enums.PrintMode
+		expected string
+	}{
+		{enums.CSV_MODE, "\n1 row affected, last insert rowid 4\n\n1 row affected\nv\n2\n"},
+		{enums.JSON_MODE, "{\"rows_affected\":1,\"last_insert_rowid\":4}\n{\"rows_affected\":1}\n[{\"v\":2}]\n"},
+		{enums.SQL_MODE, "-- 1 row affected, last insert rowid 4\n-- 1 row affected\n-- columns: v\nSELECT 2 AS \"v\";\n"},
+	} {
+		out.Reset()
+		err = changesDb.ExecuteAndPrintStatements("INSERT INTO t VALUES (0); DELETE FROM t WHERE a = 0; SELECT 2 AS v;", out, db.PrintOptions{Mode: tt.mode, Changes: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(out.String(), qt.Equals, tt.expected, qt.Commentf("mode %s", tt.mode))
+	}
+}
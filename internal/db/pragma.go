@@ -0,0 +1,179 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prettyPragma rewrites the result of a well known PRAGMA, recognized by its exact columns, into easier to read rows
+type prettyPragma struct {
+	columnNames []string
+	header      []string
+	rewrite     func(rows [][]string) [][]string
+}
+
+var prettyPragmas = []prettyPragma{
+	{
+		columnNames: []string{"cid", "name", "type", "notnull", "dflt_value", "pk"},
+		header:      []string{"column", "type", "nullable", "default", "primary key"},
+		rewrite:     rewriteTableInfo,
+	},
+	{
+		columnNames: []string{"seq", "name", "unique", "origin", "partial"},
+		header:      []string{"index", "unique", "created by", "partial"},
+		rewrite:     rewriteIndexList,
+	},
+	{
+		columnNames: []string{"id", "seq", "table", "from", "to", "on_update", "on_delete", "match"},
+		header:      []string{"columns", "references", "on update", "on delete"},
+		rewrite:     rewriteForeignKeyList,
+	},
+	{
+		columnNames: []string{"seq", "name", "file"},
+		header:      []string{"database", "file"},
+		rewrite:     rewriteDatabaseList,
+	},
+}
+
+// prettifyPragmaResult returns the result of PRAGMA table_info, index_list, foreign_key_list or database_list
+// rewritten for reading, and false for any other result, whose rows are left unread
+func prettifyPragmaResult(statementResult StatementResult, stats *FormatStats) (StatementResult, bool, error) {
+	pragma, ok := getPrettyPragma(statementResult.ColumnNames)
+	if !ok {
+		return statementResult, false, nil
+	}
+
+	rows := make([][]string, 0)
+	for row := range statementResult.RowCh {
+		if row.Err != nil {
+			return statementResult, false, row.Err
+		}
+		formattedRow, err := FormatDataWithStats(row.Row, TABLE, stats)
+		if err != nil {
+			return statementResult, false, err
+		}
+		for i, value := range row.Row {
+			if value == nil {
+				formattedRow[i] = ""
+			}
+		}
+		rows = append(rows, formattedRow)
+	}
+
+	prettyRows := make([][]interface{}, 0, len(rows))
+	for _, row := range pragma.rewrite(rows) {
+		prettyRow := make([]interface{}, len(row))
+		for i, value := range row {
+			prettyRow[i] = value
+		}
+		prettyRows = append(prettyRows, prettyRow)
+	}
+	statementResult.ColumnNames = pragma.header
+	return replayRows(statementResult, prettyRows), true, nil
+}
+
+func getPrettyPragma(columnNames []string) (prettyPragma, bool) {
+	for _, pragma := range prettyPragmas {
+		if equalColumnNames(columnNames, pragma.columnNames) {
+			return pragma, true
+		}
+	}
+	return prettyPragma{}, false
+}
+
+func equalColumnNames(columnNames []string, expected []string) bool {
+	if len(columnNames) != len(expected) {
+		return false
+	}
+	for i, name := range expected {
+		if columnNames[i] != name {
+			return false
+		}
+	}
+	return true
+}
+
+func yesNo(flag string) string {
+	if flag == "0" {
+		return "NO"
+	}
+	return "YES"
+}
+
+// rewriteTableInfo marks the primary key columns, numbering them when the key has several, and shows the defaults as
+// the SQL they're written in, so text defaults keep their quotes
+func rewriteTableInfo(rows [][]string) [][]string {
+	compositeKey := false
+	for _, row := range rows {
+		if row[5] != "0" && row[5] != "1" {
+			compositeKey = true
+		}
+	}
+
+	rewritten := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		primaryKey := ""
+		if row[5] != "0" {
+			primaryKey = "✓"
+			if compositeKey {
+				primaryKey += " " + row[5]
+			}
+		}
+		nullable := "YES"
+		if row[3] != "0" {
+			nullable = "NO"
+		}
+		rewritten = append(rewritten, []string{row[1], row[2], nullable, row[4], primaryKey})
+	}
+	return rewritten
+}
+
+var indexOrigins = map[string]string{"c": "CREATE INDEX", "u": "UNIQUE constraint", "pk": "PRIMARY KEY"}
+
+func rewriteIndexList(rows [][]string) [][]string {
+	rewritten := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		origin, ok := indexOrigins[row[3]]
+		if !ok {
+			origin = row[3]
+		}
+		rewritten = append(rewritten, []string{row[1], yesNo(row[2]), origin, yesNo(row[4])})
+	}
+	return rewritten
+}
+
+// rewriteForeignKeyList joins the rows of each foreign key, one per column, into a single row
+func rewriteForeignKeyList(rows [][]string) [][]string {
+	rewritten := make([][]string, 0, len(rows))
+	var fromColumns, toColumns []string
+	for i, row := range rows {
+		fromColumns = append(fromColumns, row[3])
+		// The referenced columns are empty when the foreign key references the primary key
+		if row[4] != "" {
+			toColumns = append(toColumns, row[4])
+		}
+		if i+1 < len(rows) && rows[i+1][0] == row[0] {
+			continue
+		}
+
+		references := row[2]
+		if len(toColumns) > 0 {
+			references = fmt.Sprintf("%s(%s)", row[2], strings.Join(toColumns, ", "))
+		}
+		rewritten = append(rewritten, []string{strings.Join(fromColumns, ", "), references, row[5], row[6]})
+		fromColumns, toColumns = nil, nil
+	}
+	return rewritten
+}
+
+func rewriteDatabaseList(rows [][]string) [][]string {
+	rewritten := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		file := row[2]
+		if file == "" {
+			file = "(in memory)"
+		}
+		rewritten = append(rewritten, []string{row[1], file})
+	}
+	return rewritten
+}
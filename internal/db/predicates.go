@@ -0,0 +1,422 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr/v4"
+	"github.com/libsql/sqlite-antlr4-parser/sqliteparser"
+)
+
+// QueryTable is a table of the FROM clause of a query, along with the alias it's referred to by, empty when none
+type QueryTable struct {
+	Name  string
+	Alias string
+}
+
+// ColumnPredicate is a column compared by a condition of the WHERE clause or of a join, to a value or to a column of
+// another table
+type ColumnPredicate struct {
+	// Table is the table or alias the column is qualified with, empty when it isn't
+	Table  string
+	Column string
+	// Range is set for the comparisons with <, <=, >, >= and BETWEEN, and unset for =, ==, IS and IN
+	Range bool
+}
+
+// QueryPredicates are the tables of a query and the columns its conditions compare
+type QueryPredicates struct {
+	Tables     []QueryTable
+	Predicates []ColumnPredicate
+}
+
+// GetQueryPredicates finds the tables and the column comparisons of a simple SELECT statement, with a light parse of its
+// tokens. Only joins and conditions made of comparisons, BETWEEN and IN with values, joined by AND, are understood.
+// Anything else, like OR, subqueries or functions applied to columns, fails with the reason
+func GetQueryPredicates(statement string) (QueryPredicates, error) {
+	tokens := getStatementTokens(statement)
+	if len(tokens) > 0 && tokens[len(tokens)-1].GetTokenType() == sqliteparser.SQLiteLexerSCOL {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 || tokens[0].GetTokenType() != sqliteparser.SQLiteLexerSELECT_ {
+		return QueryPredicates{}, fmt.Errorf("only SELECT statements can be analyzed")
+	}
+
+	fromStart, whereStart, clausesEnd := -1, -1, len(tokens)
+	depth := 0
+	for i, token := range tokens {
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerOPEN_PAR:
+			depth++
+		case sqliteparser.SQLiteLexerCLOSE_PAR:
+			depth--
+		case sqliteparser.SQLiteLexerSELECT_:
+			if i > 0 {
+				return QueryPredicates{}, fmt.Errorf("subqueries aren't supported")
+			}
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerUNION_, sqliteparser.SQLiteLexerINTERSECT_, sqliteparser.SQLiteLexerEXCEPT_:
+			return QueryPredicates{}, fmt.Errorf("compound queries aren't supported")
+		case sqliteparser.SQLiteLexerFROM_:
+			if fromStart == -1 {
+				fromStart = i + 1
+			}
+		case sqliteparser.SQLiteLexerWHERE_:
+			whereStart = i + 1
+		case sqliteparser.SQLiteLexerGROUP_, sqliteparser.SQLiteLexerORDER_, sqliteparser.SQLiteLexerLIMIT_, sqliteparser.SQLiteLexerHAVING_, sqliteparser.SQLiteLexerWINDOW_:
+			if clausesEnd == len(tokens) {
+				clausesEnd = i
+			}
+		}
+	}
+
+	var predicates QueryPredicates
+	if fromStart == -1 {
+		return predicates, nil
+	}
+	fromEnd := clausesEnd
+	if whereStart != -1 {
+		fromEnd = whereStart - 1
+	}
+
+	conditions, err := parseFromClause(statement, tokens[fromStart:fromEnd], &predicates)
+	if err != nil {
+		return QueryPredicates{}, err
+	}
+	if whereStart != -1 {
+		conditions = append(conditions, tokens[whereStart:clausesEnd])
+	}
+	for _, condition := range conditions {
+		if err := parseCondition(statement, condition, &predicates); err != nil {
+			return QueryPredicates{}, err
+		}
+	}
+	return predicates, nil
+}
+
+// parseFromClause adds the tables of the FROM clause to the predicates, with the columns of USING, and returns the
+// conditions of ON
+func parseFromClause(statement string, tokens []antlr.Token, predicates *QueryPredicates) ([][]antlr.Token, error) {
+	conditions := make([][]antlr.Token, 0)
+	for i := 0; i < len(tokens); {
+		name, next, ok := parseQualifiedName(tokens, i)
+		if !ok {
+			return nil, fmt.Errorf("only tables are supported in FROM, not %s", getTokensText(statement, tokens[i:]))
+		}
+		i = next
+		table := QueryTable{Name: name[len(name)-1]}
+		if i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerAS_ {
+			i++
+		}
+		if i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerIDENTIFIER {
+			table.Alias = unquoteIdentifier(tokens[i].GetText())
+			i++
+		}
+		predicates.Tables = append(predicates.Tables, table)
+
+		if i+2 < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerINDEXED_ {
+			i += 3
+		} else if i+1 < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerNOT_ && tokens[i+1].GetTokenType() == sqliteparser.SQLiteLexerINDEXED_ {
+			i += 2
+		}
+
+		switch {
+		case i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerON_:
+			end := findJoinEnd(tokens, i+1)
+			conditions = append(conditions, tokens[i+1:end])
+			i = end
+		case i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerUSING_:
+			end := findJoinEnd(tokens, i+1)
+			if err := addUsingColumns(statement, tokens[i+1:end], predicates); err != nil {
+				return nil, err
+			}
+			i = end
+		}
+
+		if i == len(tokens) {
+			break
+		}
+		if tokens[i].GetTokenType() == sqliteparser.SQLiteLexerCOMMA {
+			i++
+			continue
+		}
+		if tokens[i].GetTokenType() == sqliteparser.SQLiteLexerNATURAL_ {
+			return nil, fmt.Errorf("NATURAL joins aren't supported")
+		}
+		joinStart := i
+		for i < len(tokens) && isJoinModifier(tokens[i]) {
+			i++
+		}
+		if i == len(tokens) || tokens[i].GetTokenType() != sqliteparser.SQLiteLexerJOIN_ {
+			return nil, fmt.Errorf("unexpected %s in FROM", getTokensText(statement, tokens[joinStart:]))
+		}
+		i++
+	}
+	return conditions, nil
+}
+
+func isJoinModifier(token antlr.Token) bool {
+	switch token.GetTokenType() {
+	case sqliteparser.SQLiteLexerLEFT_, sqliteparser.SQLiteLexerRIGHT_, sqliteparser.SQLiteLexerFULL_, sqliteparser.SQLiteLexerOUTER_, sqliteparser.SQLiteLexerINNER_, sqliteparser.SQLiteLexerCROSS_:
+		return true
+	}
+	return false
+}
+
+// findJoinEnd returns the position of the comma or join starting the next table of the FROM clause, or the end
+func findJoinEnd(tokens []antlr.Token, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i].GetTokenType() {
+		case sqliteparser.SQLiteLexerOPEN_PAR:
+			depth++
+		case sqliteparser.SQLiteLexerCLOSE_PAR:
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+		if tokens[i].GetTokenType() == sqliteparser.SQLiteLexerCOMMA || tokens[i].GetTokenType() == sqliteparser.SQLiteLexerJOIN_ || isJoinModifier(tokens[i]) || tokens[i].GetTokenType() == sqliteparser.SQLiteLexerNATURAL_ {
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// addUsingColumns adds the columns of USING (a, b), which compare the table joined with the one before it
+func addUsingColumns(statement string, tokens []antlr.Token, predicates *QueryPredicates) error {
+	if len(tokens) < 3 || tokens[0].GetTokenType() != sqliteparser.SQLiteLexerOPEN_PAR || tokens[len(tokens)-1].GetTokenType() != sqliteparser.SQLiteLexerCLOSE_PAR || len(predicates.Tables) < 2 {
+		return fmt.Errorf("unexpected USING %s", getTokensText(statement, tokens))
+	}
+	joined := predicates.Tables[len(predicates.Tables)-2 : len(predicates.Tables)]
+	for i := 1; i < len(tokens)-1; i += 2 {
+		if tokens[i].GetTokenType() != sqliteparser.SQLiteLexerIDENTIFIER {
+			return fmt.Errorf("unexpected USING %s", getTokensText(statement, tokens))
+		}
+		column := unquoteIdentifier(tokens[i].GetText())
+		for _, table := range joined {
+			predicates.Predicates = append(predicates.Predicates, ColumnPredicate{Table: table.ReferenceName(), Column: column})
+		}
+	}
+	return nil
+}
+
+// ReferenceName is how the columns of the table are qualified in the query
+func (t QueryTable) ReferenceName() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+// parseCondition adds the columns compared by the terms of a condition joined by AND
+func parseCondition(statement string, tokens []antlr.Token, predicates *QueryPredicates) error {
+	depth := 0
+	termStart := 0
+	inBetween := false
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) {
+			switch tokens[i].GetTokenType() {
+			case sqliteparser.SQLiteLexerOPEN_PAR:
+				depth++
+			case sqliteparser.SQLiteLexerCLOSE_PAR:
+				depth--
+			}
+			if depth > 0 {
+				continue
+			}
+			switch tokens[i].GetTokenType() {
+			case sqliteparser.SQLiteLexerOR_:
+				return fmt.Errorf("OR conditions aren't supported")
+			case sqliteparser.SQLiteLexerBETWEEN_:
+				inBetween = true
+				continue
+			case sqliteparser.SQLiteLexerAND_:
+				if inBetween {
+					inBetween = false
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		term := tokens[termStart:i]
+		termStart = i + 1
+		if len(term) == 0 {
+			return fmt.Errorf("unexpected condition %s", getTokensText(statement, tokens))
+		}
+		termPredicates, ok := parseTerm(term)
+		if !ok {
+			return fmt.Errorf("the condition %s isn't a simple comparison of a column", getTokensText(statement, term))
+		}
+		predicates.Predicates = append(predicates.Predicates, termPredicates...)
+	}
+	return nil
+}
+
+// parseTerm returns the columns compared by a term like a = 1, t.a > ?, a BETWEEN 1 AND 2, a IN (1, 2), a IS NULL or
+// t1.a = t2.b
+func parseTerm(tokens []antlr.Token) ([]ColumnPredicate, bool) {
+	left, next, ok := parseColumnReference(tokens, 0)
+	if !ok {
+		// A value compared to a column, like 1 = a
+		for i := range tokens {
+			if isComparisonOperator(tokens[i]) && isValue(tokens[:i]) {
+				if column, next, ok := parseColumnReference(tokens, i+1); ok && next == len(tokens) {
+					column.Range = isRangeOperator(tokens[i])
+					return []ColumnPredicate{column}, true
+				}
+			}
+		}
+		return nil, false
+	}
+	if next == len(tokens) {
+		return nil, false
+	}
+
+	operator := tokens[next]
+	rest := tokens[next+1:]
+	switch {
+	case isComparisonOperator(operator):
+		left.Range = isRangeOperator(operator)
+		if isValue(rest) {
+			return []ColumnPredicate{left}, true
+		}
+		if right, end, ok := parseColumnReference(rest, 0); ok && end == len(rest) {
+			right.Range = left.Range
+			return []ColumnPredicate{left, right}, true
+		}
+	case operator.GetTokenType() == sqliteparser.SQLiteLexerIS_:
+		if len(rest) == 1 && rest[0].GetTokenType() == sqliteparser.SQLiteLexerNULL_ || isValue(rest) {
+			return []ColumnPredicate{left}, true
+		}
+	case operator.GetTokenType() == sqliteparser.SQLiteLexerBETWEEN_:
+		for i := range rest {
+			if rest[i].GetTokenType() == sqliteparser.SQLiteLexerAND_ && isValue(rest[:i]) && isValue(rest[i+1:]) {
+				left.Range = true
+				return []ColumnPredicate{left}, true
+			}
+		}
+	case operator.GetTokenType() == sqliteparser.SQLiteLexerIN_:
+		if len(rest) >= 3 && rest[0].GetTokenType() == sqliteparser.SQLiteLexerOPEN_PAR && rest[len(rest)-1].GetTokenType() == sqliteparser.SQLiteLexerCLOSE_PAR && isValueList(rest[1:len(rest)-1]) {
+			return []ColumnPredicate{left}, true
+		}
+	}
+	return nil, false
+}
+
+// parseColumnReference parses a column, optionally qualified with its table, returning the position after it
+func parseColumnReference(tokens []antlr.Token, start int) (ColumnPredicate, int, bool) {
+	name, next, ok := parseQualifiedName(tokens, start)
+	switch {
+	case !ok || len(name) > 2:
+		return ColumnPredicate{}, start, false
+	case len(name) == 2:
+		return ColumnPredicate{Table: name[0], Column: name[1]}, next, true
+	}
+	return ColumnPredicate{Column: name[0]}, next, true
+}
+
+// parseQualifiedName parses identifiers separated by dots, like schema.table or table.column
+func parseQualifiedName(tokens []antlr.Token, start int) ([]string, int, bool) {
+	name := make([]string, 0, 2)
+	i := start
+	for i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerIDENTIFIER {
+		name = append(name, unquoteIdentifier(tokens[i].GetText()))
+		i++
+		if i+1 < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerDOT {
+			i++
+			continue
+		}
+		break
+	}
+	// A function call, like lower(a), isn't a name
+	if len(name) == 0 || i < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerOPEN_PAR {
+		return nil, start, false
+	}
+	return name, i, true
+}
+
+func isComparisonOperator(token antlr.Token) bool {
+	switch token.GetTokenType() {
+	case sqliteparser.SQLiteLexerASSIGN, sqliteparser.SQLiteLexerEQ:
+		return true
+	}
+	return isRangeOperator(token)
+}
+
+func isRangeOperator(token antlr.Token) bool {
+	switch token.GetTokenType() {
+	case sqliteparser.SQLiteLexerLT, sqliteparser.SQLiteLexerLT_EQ, sqliteparser.SQLiteLexerGT, sqliteparser.SQLiteLexerGT_EQ:
+		return true
+	}
+	return false
+}
+
+// isValue reports whether the tokens are a literal or a parameter, possibly signed, which doesn't refer to a column
+func isValue(tokens []antlr.Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	for i, token := range tokens {
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerPLUS, sqliteparser.SQLiteLexerMINUS:
+			if i == len(tokens)-1 {
+				return false
+			}
+		case sqliteparser.SQLiteLexerNUMERIC_LITERAL, sqliteparser.SQLiteLexerSTRING_LITERAL, sqliteparser.SQLiteLexerBLOB_LITERAL, sqliteparser.SQLiteLexerBIND_PARAMETER, sqliteparser.SQLiteLexerNULL_, sqliteparser.SQLiteLexerTRUE_, sqliteparser.SQLiteLexerFALSE_:
+			if i != len(tokens)-1 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isValueList reports whether the tokens are values separated by commas
+func isValueList(tokens []antlr.Token) bool {
+	valueStart := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].GetTokenType() != sqliteparser.SQLiteLexerCOMMA {
+			continue
+		}
+		if !isValue(tokens[valueStart:i]) {
+			return false
+		}
+		valueStart = i + 1
+	}
+	return true
+}
+
+// unquoteIdentifier removes the quotes of an identifier written "name", `name` or [name]
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) < 2 {
+		return identifier
+	}
+	switch identifier[0] {
+	case '"':
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	case '`':
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], "``", "`")
+	case '[':
+		return identifier[1 : len(identifier)-1]
+	}
+	return identifier
+}
+
+// getTokensText returns the part of the statement the tokens were read from
+func getTokensText(statement string, tokens []antlr.Token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	runes := []rune(statement)
+	return string(runes[tokens[0].GetStart() : tokens[len(tokens)-1].GetStop()+1])
+}
@@ -0,0 +1,31 @@
+package db
+
+import "time"
+
+type ProgressEventKind int
+
+const (
+	TABLE_STARTED ProgressEventKind = iota
+	ROWS_PROCESSED
+	TABLE_FINISHED
+	OPERATION_FINISHED
+)
+
+// PROGRESS_ROWS_INTERVAL is how many rows are processed between ROWS_PROCESSED events
+const PROGRESS_ROWS_INTERVAL = 1000
+
+// ProgressEvent reports the progress of a long operation. Table is empty for OPERATION_FINISHED, whose counts are the
+// totals of the operation
+type ProgressEvent struct {
+	Kind  ProgressEventKind
+	Table string
+	// Rows is the number of rows processed so far in the table, or in every table for OPERATION_FINISHED
+	Rows   int64
+	Tables int
+	// BinaryTextValues is the number of TEXT values with NUL bytes or invalid UTF-8, written as hex literals
+	BinaryTextValues int64
+	Duration         time.Duration
+}
+
+// ProgressFunc is called synchronously from the operation, so it should return quickly
+type ProgressFunc func(event ProgressEvent)
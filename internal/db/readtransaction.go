@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrReadTransactionUnsupported is returned by BeginReadTransaction for the remote databases, whose statements may
+// each be served by another connection
+var ErrReadTransactionUnsupported = errors.New("read transactions are only supported by local databases")
+
+// sqlQueryer runs the queries of executeQuery, on the pool of connections or on the one of a read transaction
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// BeginReadTransaction starts a deferred transaction on a connection of its own, which all the statements run on until
+// the returned function ends it, so that they read a single snapshot of the database however other connections change
+// it. It fails with ErrReadTransactionUnsupported for remote databases
+func (db *Db) BeginReadTransaction() (end func() error, err error) {
+	db.mu.Lock()
+	isLocal := db.driver == sqlite3
+	db.mu.Unlock()
+	if !isLocal {
+		return nil, ErrReadTransactionUnsupported
+	}
+
+	sqlDb, err := db.getSqlDb()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDb.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	db.traceQuery("BEGIN DEFERRED")
+	if _, err := conn.ExecContext(context.Background(), "BEGIN DEFERRED"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to begin the read transaction: %w", err)
+	}
+
+	db.mu.Lock()
+	db.transactionConn = conn
+	db.mu.Unlock()
+
+	return func() error {
+		db.mu.Lock()
+		db.transactionConn = nil
+		db.mu.Unlock()
+
+		db.traceQuery("COMMIT")
+		_, err := conn.ExecContext(context.Background(), "COMMIT")
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// getQueryer returns the connection of the read transaction in progress, or else the pool of connections
+func (db *Db) getQueryer() (sqlQueryer, error) {
+	db.mu.Lock()
+	conn := db.transactionConn
+	db.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	return db.getSqlDb()
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr/v4"
+	"github.com/libsql/sqlite-antlr4-parser/sqliteparser"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// RenameTableInStatement returns the CREATE TABLE, INDEX or TRIGGER statement of a table rewritten for a copy of the
+// table named newTableName. The name of the table is replaced wherever the statement names it as a table: as the name
+// of the table created, after ON, REFERENCES, FROM, INTO, UPDATE and JOIN, and before a dot qualifying a column. The
+// names of constraints embedding the name of the table as a word, like pk_users, have it replaced too. The name of the
+// index or trigger created is replaced with newObjectName, unless it's empty. The names written are quoted with
+// quoteStyle when they need to be
+func RenameTableInStatement(statement string, tableName string, newTableName string, newObjectName string, quoteStyle enums.IdentifierQuoteStyle) string {
+	tokens := getStatementTokens(statement)
+	replacements := make(map[int]string)
+	nameIndex, isTable := getCreatedNameIndex(tokens)
+	if nameIndex >= 0 {
+		if isTable {
+			replacements[nameIndex] = quoteNameIfNeeded(newTableName, quoteStyle)
+		} else if newObjectName != "" {
+			replacements[nameIndex] = quoteNameIfNeeded(newObjectName, quoteStyle)
+		}
+	}
+
+	for i, token := range tokens {
+		if i == nameIndex || !isNameToken(token) || i == 0 {
+			continue
+		}
+		name := unquoteIdentifier(token.GetText())
+		previous := tokens[i-1].GetTokenType()
+		if previous == sqliteparser.SQLiteLexerCONSTRAINT_ {
+			if renamed, ok := replaceNameWord(name, tableName, newTableName); ok {
+				replacements[i] = quoteNameIfNeeded(renamed, quoteStyle)
+			}
+			continue
+		}
+		if !EqualNames(name, tableName) {
+			continue
+		}
+		switch previous {
+		case sqliteparser.SQLiteLexerON_, sqliteparser.SQLiteLexerREFERENCES_, sqliteparser.SQLiteLexerFROM_,
+			sqliteparser.SQLiteLexerINTO_, sqliteparser.SQLiteLexerUPDATE_, sqliteparser.SQLiteLexerJOIN_:
+			replacements[i] = quoteNameIfNeeded(newTableName, quoteStyle)
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].GetTokenType() == sqliteparser.SQLiteLexerDOT {
+			replacements[i] = quoteNameIfNeeded(newTableName, quoteStyle)
+		}
+	}
+
+	if len(replacements) == 0 {
+		return statement
+	}
+	runes := []rune(statement)
+	var result strings.Builder
+	nextRune := 0
+	for i, token := range tokens {
+		replacement, ok := replacements[i]
+		if !ok {
+			continue
+		}
+		result.WriteString(string(runes[nextRune:token.GetStart()]))
+		result.WriteString(replacement)
+		nextRune = token.GetStop() + 1
+	}
+	result.WriteString(string(runes[nextRune:]))
+	return result.String()
+}
+
+// getCreatedNameIndex returns the index of the token naming the table, index, trigger or view a CREATE statement
+// creates, past its schema, reporting whether it creates a table. It's -1 for other statements
+func getCreatedNameIndex(tokens []antlr.Token) (int, bool) {
+	if len(tokens) == 0 || tokens[0].GetTokenType() != sqliteparser.SQLiteLexerCREATE_ {
+		return -1, false
+	}
+	i := 1
+	for i < len(tokens) && isKeywordToken(tokens[i]) {
+		kind := tokens[i].GetTokenType()
+		i++
+		if kind == sqliteparser.SQLiteLexerTABLE_ || kind == sqliteparser.SQLiteLexerINDEX_ ||
+			kind == sqliteparser.SQLiteLexerTRIGGER_ || kind == sqliteparser.SQLiteLexerVIEW_ {
+			if i+2 < len(tokens) && tokens[i].GetTokenType() == sqliteparser.SQLiteLexerIF_ {
+				i += 3
+			}
+			if i+2 < len(tokens) && tokens[i+1].GetTokenType() == sqliteparser.SQLiteLexerDOT {
+				i += 2
+			}
+			if i >= len(tokens) {
+				return -1, false
+			}
+			return i, kind == sqliteparser.SQLiteLexerTABLE_
+		}
+	}
+	return -1, false
+}
+
+// isNameToken reports whether the token may name a table or constraint: an identifier, quoted or not, or one of the
+// keywords SQLite also takes as names
+func isNameToken(token antlr.Token) bool {
+	return token.GetTokenType() == sqliteparser.SQLiteLexerIDENTIFIER || isKeywordToken(token)
+}
+
+// replaceNameWord replaces the occurrences of word in name that are whole words, between characters that aren't letters
+// or digits, compared like EqualNames, reporting whether there was any
+func replaceNameWord(name string, word string, replacement string) (string, bool) {
+	runes := []rune(name)
+	wordLength := len([]rune(word))
+	var result strings.Builder
+	replaced := false
+	for i := 0; i < len(runes); {
+		if i+wordLength <= len(runes) && EqualNames(string(runes[i:i+wordLength]), word) &&
+			(i == 0 || !isWordRune(runes[i-1])) && (i+wordLength == len(runes) || !isWordRune(runes[i+wordLength])) {
+			result.WriteString(replacement)
+			i += wordLength
+			replaced = true
+			continue
+		}
+		result.WriteRune(runes[i])
+		i++
+	}
+	return result.String(), replaced
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func quoteNameIfNeeded(name string, quoteStyle enums.IdentifierQuoteStyle) string {
+	if NeedsEscaping(name) {
+		return QuoteIdentifier(name, quoteStyle)
+	}
+	return name
+}
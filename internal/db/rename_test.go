@@ -0,0 +1,36 @@
+package db_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+func TestRenameTableInStatement_GivenCreateTable_ExpectTableAndConstraintNamesReplaced(t *testing.T) {
+	c := qt.New(t)
+
+	result := db.RenameTableInStatement(
+		`CREATE TABLE IF NOT EXISTS main.Users (id INTEGER CONSTRAINT pk_users PRIMARY KEY, users TEXT, parent INTEGER CONSTRAINT "users parent" REFERENCES users(id), CONSTRAINT superusers CHECK (Users.id > 0));`,
+		"users", "users copy", "", enums.DOUBLE_QUOTE_STYLE,
+	)
+	c.Assert(result, qt.Equals, `CREATE TABLE IF NOT EXISTS main."users copy" (id INTEGER CONSTRAINT "pk_users copy" PRIMARY KEY, users TEXT, parent INTEGER CONSTRAINT "users copy parent" REFERENCES "users copy"(id), CONSTRAINT superusers CHECK ("users copy".id > 0));`)
+}
+
+func TestRenameTableInStatement_GivenIndexAndTrigger_ExpectTheirTableAndNameReplaced(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(
+		db.RenameTableInStatement("CREATE UNIQUE INDEX idx_t_v ON [t] (v) WHERE v <> 't';", "t", "t2", "idx_t_v_t2", enums.DOUBLE_QUOTE_STYLE),
+		qt.Equals, "CREATE UNIQUE INDEX idx_t_v_t2 ON t2 (v) WHERE v <> 't';",
+	)
+	c.Assert(
+		db.RenameTableInStatement("CREATE TRIGGER tr AFTER UPDATE OF v ON t BEGIN UPDATE t SET n = n + 1 WHERE id = new.id; INSERT INTO log SELECT v FROM t; END;", "t", "t2", "tr_t2", enums.BACKTICK_STYLE),
+		qt.Equals, "CREATE TRIGGER tr_t2 AFTER UPDATE OF v ON t2 BEGIN UPDATE t2 SET n = n + 1 WHERE id = new.id; INSERT INTO log SELECT v FROM t2; END;",
+	)
+	c.Assert(
+		db.RenameTableInStatement("CREATE INDEX i ON t (v);", "t", "order", "", enums.BACKTICK_STYLE),
+		qt.Equals, "CREATE INDEX i ON `order` (v);",
+	)
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+const DEFAULT_RESULT_CACHE_SIZE = 10 * 1024 * 1024
+
+// ResultCache keeps the raw values of the last printed result, up to a maximum size in bytes, so its cells can be
+// shown again without running the query one more time
+type ResultCache struct {
+	mu          sync.Mutex
+	maxSize     int
+	size        int
+	columnNames []string
+	rows        [][]interface{}
+	incomplete  bool
+}
+
+func NewResultCache(maxSize int) *ResultCache {
+	return &ResultCache{maxSize: maxSize}
+}
+
+func (c *ResultCache) MaxSize() int {
+	return c.maxSize
+}
+
+// record makes the cache start over with the given statement result, whose rows are retained as they're read from
+// the returned one
+func (c *ResultCache) record(statementResult StatementResult) StatementResult {
+	c.mu.Lock()
+	c.size = 0
+	c.columnNames = statementResult.ColumnNames
+	c.rows = nil
+	c.incomplete = false
+	c.mu.Unlock()
+
+	recordedRowCh := make(chan rowResult)
+	go func() {
+		defer close(recordedRowCh)
+		for row := range statementResult.RowCh {
+			if row.Err == nil {
+				c.add(row.Row)
+			}
+			recordedRowCh <- row
+		}
+	}()
+
+	return StatementResult{Statement: statementResult.Statement, ColumnNames: statementResult.ColumnNames, RowCh: recordedRowCh, Err: statementResult.Err}
+}
+
+func (c *ResultCache) add(row []interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.incomplete {
+		return
+	}
+
+	rowSize := 0
+	for _, value := range row {
+		rowSize += getValueSize(value)
+	}
+	if c.size+rowSize > c.maxSize {
+		c.incomplete = true
+		return
+	}
+
+	c.size += rowSize
+	c.rows = append(c.rows, row)
+}
+
+// Cell returns the value at the given zero-based row and column. ok is false when the cache doesn't hold that cell
+func (c *ResultCache) Cell(row int, column int) (columnName string, value interface{}, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if row < 0 || row >= len(c.rows) || column < 0 || column >= len(c.columnNames) || column >= len(c.rows[row]) {
+		return "", nil, false
+	}
+	return c.columnNames[column], c.rows[row][column], true
+}
+
+// ColumnIndex returns the zero-based index of the column with the given name, or -1 if there's none
+func (c *ResultCache) ColumnIndex(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, columnName := range c.columnNames {
+		if columnName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Len returns the number of retained rows and whether rows were left out because the cache was full
+func (c *ResultCache) Len() (rows int, incomplete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.rows), c.incomplete
+}
+
+func getValueSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case sql.NullString:
+		return len(v.String)
+	}
+
+	// Remote blobs are maps holding their base64 encoding
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Map {
+		if base64String, found, err := findBase64Field(rv); err == nil && found {
+			return len(base64String)
+		}
+	}
+	return 8
+}
+
+// GetCellBytes returns the content of a value as bytes: the data of blobs, the text of strings and the table
+// representation of anything else
+func GetCellBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case sql.NullString:
+		if v.Valid {
+			return []byte(v.String), nil
+		}
+	}
+
+	if rv := reflect.ValueOf(value); value != nil && rv.Kind() == reflect.Map {
+		base64String, found, err := findBase64Field(rv)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return decodeBase64(base64String)
+		}
+	}
+
+	formatted, err := FormatData([]interface{}{value}, TABLE)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(formatted[0]), nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionStats adds up what happened to the statements executed during a session. It's cheap enough to be always
+// collected: each statement is counted once its result was printed
+type SessionStats struct {
+	mu           sync.Mutex
+	statements   int64
+	failed       int64
+	rowsReturned int64
+	rowsChanged  int64
+	slowest      StatementTiming
+}
+
+// SessionStatsSummary is a snapshot of the session statistics, along with the ones kept by the output and the
+// connection
+type SessionStatsSummary struct {
+	Statements    int64
+	Failed        int64
+	RowsReturned  int64
+	RowsChanged   int64
+	BytesWritten  int64
+	ConnectedTime time.Duration
+	// Slowest is the statement that took the longest to run and print its result, with an empty Statement when none
+	// succeeded
+	Slowest StatementTiming
+}
+
+func NewSessionStats() *SessionStats {
+	return &SessionStats{}
+}
+
+func (s *SessionStats) recordSuccess(timing StatementTiming, rowsChanged int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statements++
+	s.rowsReturned += timing.RowsReturned
+	s.rowsChanged += rowsChanged
+	if s.slowest.Statement == "" || timing.Duration > s.slowest.Duration {
+		s.slowest = timing
+	}
+}
+
+func (s *SessionStats) recordFailure(rowsReturned int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statements++
+	s.failed++
+	s.rowsReturned += rowsReturned
+}
+
+// Summary returns the statistics so far, with the bytes written to the output and the time connected to the database
+func (s *SessionStats) Summary(bytesWritten int64, connectedTime time.Duration) SessionStatsSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SessionStatsSummary{
+		Statements:    s.statements,
+		Failed:        s.failed,
+		RowsReturned:  s.rowsReturned,
+		RowsChanged:   s.rowsChanged,
+		BytesWritten:  bytesWritten,
+		ConnectedTime: connectedTime,
+		Slowest:       s.slowest,
+	}
+}
+
+// String renders the summary like "session: 20 statements, 2 errors, 1,204 rows returned, 350 rows changed, 52,113
+// bytes written, 4m2s connected", followed by the slowest statement on a line of its own
+func (s SessionStatsSummary) String() string {
+	summary := fmt.Sprintf("session: %s statements, %s errors, %s rows returned, %s rows changed, %s bytes written, %s connected",
+		FormatCount(s.Statements), FormatCount(s.Failed), FormatCount(s.RowsReturned), FormatCount(s.RowsChanged), FormatCount(s.BytesWritten), FormatDuration(s.ConnectedTime))
+	if s.Slowest.Statement != "" {
+		summary += fmt.Sprintf("\nslowest statement: %s (%s)", truncateStatement(s.Slowest.Statement), FormatDuration(s.Slowest.Duration))
+	}
+	return summary
+}
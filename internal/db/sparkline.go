@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// SPARKLINE_WIDTH is how many characters the bar of the largest value takes
+const SPARKLINE_WIDTH = 40
+
+// sparklineEighths are the blocks filling one to seven eighths of a character, left to right
+var sparklineEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+const sparklineFullBlock = "█"
+
+// SparklinePrinter writes a bar per row for results with a label and a numeric column, scaled to the largest value.
+// Negative and NULL values get no bar. Other results are written as tables, counted in stats
+type SparklinePrinter struct {
+	stats *FormatStats
+}
+
+func (s SparklinePrinter) print(statementResult StatementResult, outF io.Writer) error {
+	rows := make([][]interface{}, 0)
+	for row := range statementResult.RowCh {
+		if row.Err != nil {
+			return row.Err
+		}
+		rows = append(rows, row.Row)
+	}
+
+	values, ok := getSparklineValues(statementResult.ColumnNames, rows)
+	if !ok {
+		if s.stats != nil {
+			s.stats.sparklineFallbacks.Add(1)
+		}
+		return TablePrinter{stats: s.stats}.print(replayRows(statementResult, rows), outF)
+	}
+
+	labels := make([]string, len(rows))
+	numbers := make([]string, len(rows))
+	labelWidth := 0
+	maxValue := 0.0
+	for i, row := range rows {
+		formattedRow, err := FormatDataWithStats(row, TABLE, s.stats)
+		if err != nil {
+			return err
+		}
+		labels[i], numbers[i] = formattedRow[0], formattedRow[1]
+		if width := utf8.RuneCountInString(labels[i]); width > labelWidth {
+			labelWidth = width
+		}
+		if values[i] != nil && *values[i] > maxValue {
+			maxValue = *values[i]
+		}
+	}
+
+	for i := range rows {
+		line := labels[i] + strings.Repeat(" ", labelWidth-utf8.RuneCountInString(labels[i]))
+		if bar := renderSparklineBar(values[i], maxValue); bar != "" {
+			line += " " + bar
+		}
+		fmt.Fprintln(outF, line+" "+numbers[i])
+	}
+	return nil
+}
+
+// getSparklineValues returns the values of the second column, nil for NULL, when the result has exactly two columns
+// and the second one holds only numbers and NULLs
+func getSparklineValues(columnNames []string, rows [][]interface{}) ([]*float64, bool) {
+	if len(columnNames) != 2 {
+		return nil, false
+	}
+
+	values := make([]*float64, len(rows))
+	for i, row := range rows {
+		var value float64
+		switch v := row[1].(type) {
+		case nil:
+			continue
+		case int64:
+			value = float64(v)
+		case float64:
+			value = v
+		default:
+			return nil, false
+		}
+		values[i] = &value
+	}
+	return values, true
+}
+
+// renderSparklineBar returns the bar of a value, in eighths of a character so close values can be told apart. Any
+// positive value gets at least an eighth
+func renderSparklineBar(value *float64, maxValue float64) string {
+	if value == nil || *value <= 0 || maxValue <= 0 {
+		return ""
+	}
+
+	eighths := int(*value/maxValue*SPARKLINE_WIDTH*8 + 0.5)
+	if eighths == 0 {
+		eighths = 1
+	}
+	return strings.Repeat(sparklineFullBlock, eighths/8) + sparklineEighths[eighths%8]
+}
+
+// replayRows returns a copy of statementResult reading the given rows, already read from its own
+func replayRows(statementResult StatementResult, rows [][]interface{}) StatementResult {
+	rowCh := make(chan rowResult, len(rows))
+	for _, row := range rows {
+		rowCh <- rowResult{Row: row}
+	}
+	close(rowCh)
+	statementResult.RowCh = rowCh
+	return statementResult
+}
@@ -0,0 +1,573 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr/v4"
+	"github.com/libsql/sqlite-antlr4-parser/sqliteparser"
+)
+
+// Statement is a statement split from a script by SplitStatements, without the semicolon that ends it
+type Statement struct {
+	Text string
+	// Line is the line the statement starts on, counting from 1
+	Line int
+	// Offset is the byte offset the statement starts at in the script
+	Offset int
+}
+
+type splitStatementsInfo struct {
+	incompleteCreateTrigger    bool
+	incompleteMultilineComment bool
+	unterminatedQuote          bool
+	incompleteTextLine         int
+	lastTokenType              int
+	// emptyStatements counts the semicolons ending no statement, like the ones of ;; or lines holding only ; or a
+	// comment, which are left out
+	emptyStatements int
+}
+
+// SplitStatements splits a script into the statements its semicolons separate. Semicolons inside strings, quoted
+// identifiers, comments and the BEGIN...END body of a CREATE TRIGGER don't separate statements, so the text left between
+// the statements holds only whitespace, comments and semicolons. A script ending inside a string, quoted identifier or
+// multiline comment is an error
+func SplitStatements(script string) ([]Statement, error) {
+	statements, info := splitStatements(script)
+	switch {
+	case info.unterminatedQuote:
+		return nil, &UnterminatedTextError{Kind: "string or quoted identifier", Line: info.incompleteTextLine}
+	case info.incompleteMultilineComment:
+		return nil, &UnterminatedTextError{Kind: "multiline comment", Line: info.incompleteTextLine}
+	}
+	return statements, nil
+}
+
+// IsStatementFinished reports whether the text ends with a semicolon that isn't inside a string, comment or CREATE
+// TRIGGER body
+func IsStatementFinished(statement string) bool {
+	_, info := splitStatements(statement)
+	return !info.incompleteCreateTrigger &&
+		!info.incompleteMultilineComment &&
+		!info.unterminatedQuote &&
+		info.lastTokenType == sqliteparser.SQLiteLexerSCOL
+}
+
+// IsInsideStatement reports whether the text ends in the middle of a statement, string or multiline comment, so the
+// next line continues it. Text holding only whitespace and comments isn't inside a statement
+func IsInsideStatement(text string) bool {
+	statements, info := splitStatements(text)
+	if info.incompleteCreateTrigger || info.incompleteMultilineComment || info.unterminatedQuote {
+		return true
+	}
+	return len(statements) > 0 && info.lastTokenType != sqliteparser.SQLiteLexerSCOL
+}
+
+// NormalizeStatements returns the script in a canonical form, to tell apart scripts that only differ in their layout:
+// keywords are upper cased, comments are removed, the whitespace and comments between tokens become a single space and
+// the semicolons ending the script are left out. Identifiers and literals are kept exactly as written. A script ending
+// inside a string, quoted identifier or multiline comment is only trimmed, as its tokens can't be told apart
+func NormalizeStatements(script string) string {
+	_, info := splitStatements(script)
+	if info.unterminatedQuote || info.incompleteMultilineComment {
+		return strings.TrimSpace(script)
+	}
+
+	tokens := getSplitTokens(script)
+	for len(tokens) > 0 && tokens[len(tokens)-1].GetTokenType() == sqliteparser.SQLiteLexerSCOL {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	byteOffsets := getRuneByteOffsets(script)
+	var normalized strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.GetStart() > tokens[i-1].GetStop()+1 {
+			normalized.WriteByte(' ')
+		}
+		text := script[byteOffsets[token.GetStart()]:byteOffsets[token.GetStop()+1]]
+		if isKeywordToken(token) {
+			text = strings.ToUpper(text)
+		}
+		normalized.WriteString(text)
+	}
+	return normalized.String()
+}
+
+// JoinStatementLines returns a script written over several lines on a single line, like a statement typed over several
+// lines at the prompt, so it can be saved as a single history entry: the whitespace and comments between tokens holding
+// a line break become a single space, and the comments after the last token are left out. Strings and quoted
+// identifiers are kept exactly as written, even when they hold line breaks. A script ending inside a string, quoted
+// identifier or multiline comment only has its line breaks replaced by spaces
+func JoinStatementLines(script string) string {
+	_, info := splitStatements(script)
+	if info.unterminatedQuote || info.incompleteMultilineComment {
+		return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(strings.TrimSpace(script))
+	}
+
+	tokens := getSplitTokens(script)
+	byteOffsets := getRuneByteOffsets(script)
+	var joined strings.Builder
+	for i, token := range tokens {
+		start := byteOffsets[token.GetStart()]
+		if i > 0 {
+			separator := script[byteOffsets[tokens[i-1].GetStop()+1]:start]
+			if strings.ContainsAny(separator, "\r\n") {
+				separator = " "
+			}
+			joined.WriteString(separator)
+		}
+		joined.WriteString(script[start:byteOffsets[token.GetStop()+1]])
+	}
+	return joined.String()
+}
+
+// isKeyword reports whether the name is an SQL keyword, like order, which must be quoted to be used as an identifier
+func isKeyword(name string) bool {
+	tokens := getSplitTokens(name)
+	return len(tokens) == 1 && isKeywordToken(tokens[0]) && tokens[0].GetText() == name
+}
+
+// GetIdentifiers returns the identifiers of the script unquoted, in the order they appear, like the names of the
+// tables and columns its statements mention. The keywords SQLite also takes as names, like key, aren't among them
+func GetIdentifiers(script string) []string {
+	identifiers := make([]string, 0)
+	for _, token := range getSplitTokens(script) {
+		if token.GetTokenType() == sqliteparser.SQLiteLexerIDENTIFIER {
+			identifiers = append(identifiers, unquoteIdentifier(token.GetText()))
+		}
+	}
+	return identifiers
+}
+
+func isKeywordToken(token antlr.Token) bool {
+	return token.GetTokenType() >= sqliteparser.SQLiteLexerABORT_ && token.GetTokenType() <= sqliteparser.SQLiteLexerNOTHING_
+}
+
+// splitStatements splits the script like SplitStatements, also reporting how it ends. A script ending inside a string
+// or quoted identifier keeps the rest of the script in its last statement, while an unterminated multiline comment isn't
+// part of any statement
+func splitStatements(script string) ([]Statement, splitStatementsInfo) {
+	tokens := getSplitTokens(script)
+	byteOffsets := getRuneByteOffsets(script)
+	statements := make([]Statement, 0)
+	info := splitStatementsInfo{lastTokenType: antlr.TokenInvalidType}
+
+	appendStatement := func(first antlr.Token, stop int) {
+		start := byteOffsets[first.GetStart()]
+		statements = append(statements, Statement{Text: script[start:stop], Line: first.GetLine(), Offset: start})
+	}
+
+	var first, previous antlr.Token
+	caseDepth := 0
+	for i, token := range tokens {
+		if isUnterminatedQuote(token) {
+			info.unterminatedQuote = true
+			info.incompleteTextLine = token.GetLine()
+			if first == nil {
+				first = token
+			}
+			appendStatement(first, len(script))
+			return statements, info
+		}
+		if atIncompleteMultilineCommentStart(tokens[i:]) {
+			info.incompleteMultilineComment = true
+			info.incompleteTextLine = token.GetLine()
+			break
+		}
+		info.lastTokenType = token.GetTokenType()
+
+		if first == nil {
+			if token.GetTokenType() == sqliteparser.SQLiteLexerSCOL {
+				info.emptyStatements++
+				continue
+			}
+			first = token
+			info.incompleteCreateTrigger = atCreateTriggerStart(tokens[i:])
+			caseDepth = 0
+		}
+
+		if info.incompleteCreateTrigger {
+			// CASE expressions inside the trigger end with END too
+			switch token.GetTokenType() {
+			case sqliteparser.SQLiteLexerCASE_:
+				caseDepth++
+			case sqliteparser.SQLiteLexerEND_:
+				if caseDepth > 0 {
+					caseDepth--
+				} else {
+					info.incompleteCreateTrigger = false
+				}
+			}
+		} else if token.GetTokenType() == sqliteparser.SQLiteLexerSCOL {
+			appendStatement(first, byteOffsets[previous.GetStop()+1])
+			first = nil
+		}
+		previous = token
+	}
+
+	if first != nil {
+		appendStatement(first, byteOffsets[previous.GetStop()+1])
+	}
+	return statements, info
+}
+
+// getSplitTokens returns the tokens that may be part of a statement. Characters the lexer doesn't recognize are kept,
+// so they reach the database instead of being dropped, except for form feeds, which SQLite takes as whitespace too.
+// Carriage returns become spaces, as the lexer doesn't let a -- comment end at a carriage return that isn't followed by
+// a newline, while SQLite keeps the comment going until the next newline
+func getSplitTokens(script string) []antlr.Token {
+	lexer := sqliteparser.NewSQLiteLexer(antlr.NewInputStream(strings.ReplaceAll(script, "\r", " ")))
+	lexer.RemoveErrorListeners()
+
+	tokens := make([]antlr.Token, 0)
+	for token := lexer.NextToken(); token.GetTokenType() != antlr.TokenEOF; token = lexer.NextToken() {
+		if token.GetChannel() != antlr.TokenDefaultChannel {
+			continue
+		}
+		if token.GetTokenType() == sqliteparser.SQLiteLexerUNEXPECTED_CHAR && token.GetText() == "\f" {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// getRuneByteOffsets maps the rune positions of the lexer tokens to byte offsets in the script, including the position
+// right after its end. Invalid UTF-8 bytes are one rune each, as they are for the lexer
+func getRuneByteOffsets(script string) []int {
+	byteOffsets := make([]int, 0, len(script)+1)
+	for offset := range script {
+		byteOffsets = append(byteOffsets, offset)
+	}
+	return append(byteOffsets, len(script))
+}
+
+// isUnterminatedQuote reports whether the token is a quote the lexer couldn't match with its closing quote
+func isUnterminatedQuote(token antlr.Token) bool {
+	if token.GetTokenType() != sqliteparser.SQLiteLexerUNEXPECTED_CHAR {
+		return false
+	}
+	switch token.GetText() {
+	case "'", "\"", "`", "[":
+		return true
+	}
+	return false
+}
+
+// atIncompleteMultilineCommentStart reports whether the tokens start with /*. Complete multiline comments are hidden by
+// the lexer, so this only happens for comments that never end
+func atIncompleteMultilineCommentStart(tokens []antlr.Token) bool {
+	return len(tokens) >= 2 &&
+		tokens[0].GetTokenType() == sqliteparser.SQLiteLexerDIV &&
+		tokens[1].GetTokenType() == sqliteparser.SQLiteLexerSTAR &&
+		tokens[1].GetStart() == tokens[0].GetStop()+1
+}
+
+func atCreateTriggerStart(tokens []antlr.Token) bool {
+	if len(tokens) < 2 || tokens[0].GetTokenType() != sqliteparser.SQLiteLexerCREATE_ {
+		return false
+	}
+
+	switch tokens[1].GetTokenType() {
+	case sqliteparser.SQLiteLexerTRIGGER_:
+		return true
+	case sqliteparser.SQLiteLexerTEMP_, sqliteparser.SQLiteLexerTEMPORARY_:
+		return len(tokens) >= 3 && tokens[2].GetTokenType() == sqliteparser.SQLiteLexerTRIGGER_
+	}
+	return false
+}
+
+// statementTexts returns the text of each statement
+func statementTexts(statements []Statement) []string {
+	texts := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		texts = append(texts, statement.Text)
+	}
+	return texts
+}
+
+func getStatementTokens(statement string) []antlr.Token {
+	lexer := sqliteparser.NewSQLiteLexer(antlr.NewInputStream(statement))
+	lexer.RemoveErrorListeners()
+
+	tokens := make([]antlr.Token, 0)
+	for token := lexer.NextToken(); token.GetTokenType() != antlr.TokenEOF; token = lexer.NextToken() {
+		if token.GetChannel() == antlr.TokenDefaultChannel {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func mayContainSessionStatement(query string) bool {
+	upperCasedQuery := strings.ToUpper(query)
+	return strings.Contains(upperCasedQuery, "PRAGMA") ||
+		strings.Contains(upperCasedQuery, "ATTACH") ||
+		strings.Contains(upperCasedQuery, "DETACH")
+}
+
+// isSessionStatement reports whether the statement changes state that only lives as long as the connection, like
+// attached databases and PRAGMA assignments
+func isSessionStatement(statement string) bool {
+	tokens := getStatementTokens(statement)
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch tokens[0].GetTokenType() {
+	case sqliteparser.SQLiteLexerATTACH_, sqliteparser.SQLiteLexerDETACH_:
+		return true
+	case sqliteparser.SQLiteLexerPRAGMA_:
+		for i, token := range tokens[1:] {
+			if token.GetTokenType() == sqliteparser.SQLiteLexerASSIGN {
+				// defer_foreign_keys is reset at the end of every transaction
+				return !strings.EqualFold(tokens[i].GetText(), "defer_foreign_keys")
+			}
+		}
+	}
+	return false
+}
+
+// IsTransactionStatement reports whether the statement begins or ends a transaction. Savepoints, and rolling back to
+// them, don't
+func IsTransactionStatement(statement string) bool {
+	tokens := getStatementTokens(statement)
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch tokens[0].GetTokenType() {
+	case sqliteparser.SQLiteLexerBEGIN_, sqliteparser.SQLiteLexerCOMMIT_, sqliteparser.SQLiteLexerEND_:
+		return true
+	case sqliteparser.SQLiteLexerROLLBACK_:
+		for _, token := range tokens[1:] {
+			if token.GetTokenType() == sqliteparser.SQLiteLexerTO_ {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ChangesSchema reports whether any statement of the script may change the tables or their columns, like CREATE,
+// ALTER, DROP and ATTACH do, or ROLLBACK does when it undoes them
+func ChangesSchema(script string) bool {
+	statements, _ := splitStatements(script)
+	for _, statement := range statements {
+		tokens := getStatementTokens(statement.Text)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch tokens[0].GetTokenType() {
+		case sqliteparser.SQLiteLexerCREATE_, sqliteparser.SQLiteLexerALTER_, sqliteparser.SQLiteLexerDROP_,
+			sqliteparser.SQLiteLexerATTACH_, sqliteparser.SQLiteLexerDETACH_, sqliteparser.SQLiteLexerROLLBACK_:
+			return true
+		}
+	}
+	return false
+}
+
+type runeRange struct {
+	start int
+	stop  int
+}
+
+// RemoveSQLiteOnlyClauses removes the AUTOINCREMENT and WITHOUT ROWID clauses, which other engines don't support, from
+// a CREATE TABLE statement. The removed clauses are returned in the order they were found
+func RemoveSQLiteOnlyClauses(createTable string) (string, []string) {
+	tokens := getStatementTokens(createTable)
+	removedRanges := make([]runeRange, 0)
+	removedClauses := make([]string, 0)
+	for i := 1; i < len(tokens); i++ {
+		switch {
+		case tokens[i].GetTokenType() == sqliteparser.SQLiteLexerAUTOINCREMENT_:
+			removedRanges = append(removedRanges, runeRange{tokens[i-1].GetStop() + 1, tokens[i].GetStop()})
+			removedClauses = append(removedClauses, "AUTOINCREMENT")
+		case tokens[i].GetTokenType() == sqliteparser.SQLiteLexerWITHOUT_ &&
+			i+1 < len(tokens) && strings.EqualFold(tokens[i+1].GetText(), "ROWID"):
+			removed := runeRange{tokens[i-1].GetStop() + 1, tokens[i+1].GetStop()}
+			if i+2 < len(tokens) && tokens[i+2].GetTokenType() == sqliteparser.SQLiteLexerCOMMA {
+				removed.stop = tokens[i+2].GetStop()
+			} else if i >= 2 && tokens[i-1].GetTokenType() == sqliteparser.SQLiteLexerCOMMA {
+				removed.start = tokens[i-2].GetStop() + 1
+			}
+			removedRanges = append(removedRanges, removed)
+			removedClauses = append(removedClauses, "WITHOUT ROWID")
+		}
+	}
+
+	if len(removedRanges) == 0 {
+		return createTable, removedClauses
+	}
+
+	runes := []rune(createTable)
+	var result strings.Builder
+	nextRune := 0
+	for _, removed := range removedRanges {
+		result.WriteString(string(runes[nextRune:removed.start]))
+		nextRune = removed.stop + 1
+	}
+	result.WriteString(string(runes[nextRune:]))
+	return result.String(), removedClauses
+}
+
+// isChangeStatement reports whether the query is a single INSERT, UPDATE, DELETE or REPLACE without a RETURNING clause,
+// which returns no rows and can be executed to get the number of rows it changed
+func isChangeStatement(query string) bool {
+	tokens := getStatementTokens(query)
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch tokens[0].GetTokenType() {
+	case sqliteparser.SQLiteLexerINSERT_, sqliteparser.SQLiteLexerUPDATE_, sqliteparser.SQLiteLexerDELETE_, sqliteparser.SQLiteLexerREPLACE_:
+	default:
+		return false
+	}
+
+	for i, token := range tokens {
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerRETURNING_:
+			return false
+		case sqliteparser.SQLiteLexerSCOL:
+			if i != len(tokens)-1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetWrittenTable returns the table an INSERT, REPLACE, UPDATE or DELETE statement writes to, unquoted: the first name
+// after its INTO, UPDATE or FROM, with the schema qualifying it, if any. It reports false for the other statements, like
+// the ones starting with WITH, and for the UPDATE statements with a FROM clause, which read other tables too
+func GetWrittenTable(statement string) (schema string, table string, ok bool) {
+	tokens := getStatementTokens(statement)
+	if len(tokens) == 0 {
+		return "", "", false
+	}
+
+	nameIndex := -1
+	switch tokens[0].GetTokenType() {
+	case sqliteparser.SQLiteLexerINSERT_, sqliteparser.SQLiteLexerREPLACE_:
+		nameIndex = findTokenIndex(tokens, sqliteparser.SQLiteLexerINTO_) + 1
+	case sqliteparser.SQLiteLexerDELETE_:
+		nameIndex = findTokenIndex(tokens, sqliteparser.SQLiteLexerFROM_) + 1
+	case sqliteparser.SQLiteLexerUPDATE_:
+		nameIndex = 1
+		if len(tokens) > 2 && tokens[1].GetTokenType() == sqliteparser.SQLiteLexerOR_ {
+			nameIndex = 3
+		}
+	}
+	if nameIndex <= 0 || nameIndex >= len(tokens) || !isNameToken(tokens[nameIndex]) {
+		return "", "", false
+	}
+	table = unquoteIdentifier(tokens[nameIndex].GetText())
+	if nameIndex+2 < len(tokens) && tokens[nameIndex+1].GetTokenType() == sqliteparser.SQLiteLexerDOT {
+		if !isNameToken(tokens[nameIndex+2]) {
+			return "", "", false
+		}
+		schema, table = table, unquoteIdentifier(tokens[nameIndex+2].GetText())
+		nameIndex += 2
+	}
+
+	if tokens[0].GetTokenType() == sqliteparser.SQLiteLexerUPDATE_ {
+		depth := 0
+		for _, token := range tokens[nameIndex+1:] {
+			switch token.GetTokenType() {
+			case sqliteparser.SQLiteLexerOPEN_PAR:
+				depth++
+			case sqliteparser.SQLiteLexerCLOSE_PAR:
+				depth--
+			case sqliteparser.SQLiteLexerFROM_:
+				if depth == 0 {
+					return "", "", false
+				}
+			}
+		}
+	}
+	return schema, table, true
+}
+
+// findTokenIndex returns the index of the first token of the type, or -1 when there's none
+func findTokenIndex(tokens []antlr.Token, tokenType int) int {
+	for i, token := range tokens {
+		if token.GetTokenType() == tokenType {
+			return i
+		}
+	}
+	return -1
+}
+
+// isInsertStatement reports whether the statement is an INSERT or a REPLACE, which sets the last insert rowid
+func isInsertStatement(statement string) bool {
+	tokens := getStatementTokens(statement)
+	return len(tokens) > 0 &&
+		(tokens[0].GetTokenType() == sqliteparser.SQLiteLexerINSERT_ || tokens[0].GetTokenType() == sqliteparser.SQLiteLexerREPLACE_)
+}
+
+// QueryClauses describes the clauses of a statement that matter when paging through its result
+type QueryClauses struct {
+	// IsQuery is set for SELECT and VALUES statements, including the ones starting with a WITH clause
+	IsQuery bool
+	// HasLimit and HasOrderBy are set for the clauses applying to the whole result, rather than to a subquery or a
+	// window
+	HasLimit   bool
+	HasOrderBy bool
+}
+
+// GetQueryClauses returns the QueryClauses of a single statement
+func GetQueryClauses(statement string) QueryClauses {
+	tokens := getStatementTokens(statement)
+	if len(tokens) == 0 {
+		return QueryClauses{}
+	}
+	switch tokens[0].GetTokenType() {
+	case sqliteparser.SQLiteLexerSELECT_, sqliteparser.SQLiteLexerVALUES_, sqliteparser.SQLiteLexerWITH_:
+	default:
+		return QueryClauses{}
+	}
+
+	var clauses QueryClauses
+	depth := 0
+	statementKindFound := false
+	for _, token := range tokens {
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerOPEN_PAR:
+			depth++
+		case sqliteparser.SQLiteLexerCLOSE_PAR:
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch token.GetTokenType() {
+		case sqliteparser.SQLiteLexerSELECT_, sqliteparser.SQLiteLexerVALUES_:
+			if !statementKindFound {
+				clauses.IsQuery = true
+				statementKindFound = true
+			}
+		case sqliteparser.SQLiteLexerINSERT_, sqliteparser.SQLiteLexerUPDATE_, sqliteparser.SQLiteLexerDELETE_, sqliteparser.SQLiteLexerREPLACE_:
+			// The statement a WITH clause is given to
+			statementKindFound = true
+		case sqliteparser.SQLiteLexerLIMIT_:
+			clauses.HasLimit = true
+		case sqliteparser.SQLiteLexerORDER_:
+			clauses.HasOrderBy = true
+		}
+	}
+	if !clauses.IsQuery {
+		return QueryClauses{}
+	}
+	return clauses
+}
+
+// IsReadOnlyStatement reports whether the statement only reads the database, as queries and EXPLAIN do
+func IsReadOnlyStatement(statement string) bool {
+	tokens := getStatementTokens(statement)
+	if len(tokens) > 0 && tokens[0].GetTokenType() == sqliteparser.SQLiteLexerEXPLAIN_ {
+		return true
+	}
+	return GetQueryClauses(statement).IsQuery
+}
@@ -0,0 +1,500 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestRemoveSQLiteOnlyClauses_GivenTableOptionsList_ExpectClausesAndSeparatorsRemoved(t *testing.T) {
+	c := qt.New(t)
+
+	result, removed := db.RemoveSQLiteOnlyClauses("CREATE TABLE t (id INTEGER PRIMARY KEY autoincrement, v TEXT) STRICT, WITHOUT ROWID;")
+	c.Assert(result, qt.Equals, "CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT) STRICT;")
+	c.Assert(removed, qt.DeepEquals, []string{"AUTOINCREMENT", "WITHOUT ROWID"})
+
+	result, removed = db.RemoveSQLiteOnlyClauses("CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT) WITHOUT ROWID, STRICT;")
+	c.Assert(result, qt.Equals, "CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT) STRICT;")
+	c.Assert(removed, qt.DeepEquals, []string{"WITHOUT ROWID"})
+}
+
+func TestRemoveSQLiteOnlyClauses_GivenPortableTable_ExpectUnchanged(t *testing.T) {
+	c := qt.New(t)
+
+	result, removed := db.RemoveSQLiteOnlyClauses("CREATE TABLE \"without rowid\" (id INTEGER PRIMARY KEY);")
+	c.Assert(result, qt.Equals, "CREATE TABLE \"without rowid\" (id INTEGER PRIMARY KEY);")
+	c.Assert(removed, qt.HasLen, 0)
+}
+
+func TestIsInsideStatement(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.IsInsideStatement(""), qt.IsFalse)
+	c.Assert(db.IsInsideStatement("-- only a comment"), qt.IsFalse)
+	c.Assert(db.IsInsideStatement("SELECT 1;\n-- trailing comment"), qt.IsFalse)
+	c.Assert(db.IsInsideStatement("INSERT INTO t VALUES ('a'),"), qt.IsTrue)
+	c.Assert(db.IsInsideStatement("SELECT 1; SELECT"), qt.IsTrue)
+	c.Assert(db.IsInsideStatement("/* open comment"), qt.IsTrue)
+	c.Assert(db.IsInsideStatement("CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1;"), qt.IsTrue)
+}
+
+func TestIsTransactionStatement(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.IsTransactionStatement("BEGIN TRANSACTION"), qt.IsTrue)
+	c.Assert(db.IsTransactionStatement("begin immediate"), qt.IsTrue)
+	c.Assert(db.IsTransactionStatement("COMMIT"), qt.IsTrue)
+	c.Assert(db.IsTransactionStatement("END TRANSACTION"), qt.IsTrue)
+	c.Assert(db.IsTransactionStatement("ROLLBACK"), qt.IsTrue)
+	c.Assert(db.IsTransactionStatement("ROLLBACK TO SAVEPOINT sp"), qt.IsFalse)
+	c.Assert(db.IsTransactionStatement("SAVEPOINT sp"), qt.IsFalse)
+	c.Assert(db.IsTransactionStatement("SELECT 'BEGIN'"), qt.IsFalse)
+}
+
+func TestGetQueryClauses(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.GetQueryClauses("SELECT * FROM t"), qt.Equals, db.QueryClauses{IsQuery: true})
+	c.Assert(db.GetQueryClauses("VALUES (1), (2) ORDER BY 1"), qt.Equals, db.QueryClauses{IsQuery: true, HasOrderBy: true})
+	c.Assert(db.GetQueryClauses("SELECT * FROM t ORDER BY id LIMIT 10"), qt.Equals, db.QueryClauses{IsQuery: true, HasLimit: true, HasOrderBy: true})
+	c.Assert(db.GetQueryClauses("SELECT * FROM (SELECT * FROM t ORDER BY id LIMIT 10)"), qt.Equals, db.QueryClauses{IsQuery: true})
+	c.Assert(db.GetQueryClauses("SELECT row_number() OVER (ORDER BY id) FROM t"), qt.Equals, db.QueryClauses{IsQuery: true})
+	c.Assert(db.GetQueryClauses("WITH c AS (SELECT 1 LIMIT 1) SELECT * FROM c ORDER BY 1"), qt.Equals, db.QueryClauses{IsQuery: true, HasOrderBy: true})
+	c.Assert(db.GetQueryClauses("WITH c AS (SELECT 1) INSERT INTO t SELECT * FROM c"), qt.Equals, db.QueryClauses{})
+	c.Assert(db.GetQueryClauses("DELETE FROM t ORDER BY id LIMIT 1"), qt.Equals, db.QueryClauses{})
+}
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.IsReadOnlyStatement("SELECT count(*) FROM t"), qt.IsTrue)
+	c.Assert(db.IsReadOnlyStatement("EXPLAIN QUERY PLAN SELECT * FROM t"), qt.IsTrue)
+	c.Assert(db.IsReadOnlyStatement("WITH c AS (SELECT 1) SELECT * FROM c"), qt.IsTrue)
+	c.Assert(db.IsReadOnlyStatement("WITH c AS (SELECT 1) DELETE FROM t"), qt.IsFalse)
+	c.Assert(db.IsReadOnlyStatement("INSERT INTO t SELECT 1"), qt.IsFalse)
+	c.Assert(db.IsReadOnlyStatement("PRAGMA foreign_keys=ON"), qt.IsFalse)
+}
+
+func TestSplitStatements_GivenScript_ExpectStatementsWithTheirPositions(t *testing.T) {
+	c := qt.New(t)
+
+	statements, err := db.SplitStatements("SELECT 1;\n-- comment; here\nSELECT 'a;b', \"c;d\" /* ; */ ;; \n\n  SELECT 3")
+	c.Assert(err, qt.IsNil)
+	c.Assert(statements, qt.DeepEquals, []db.Statement{
+		{Text: "SELECT 1", Line: 1, Offset: 0},
+		{Text: "SELECT 'a;b', \"c;d\"", Line: 3, Offset: 27},
+		{Text: "SELECT 3", Line: 5, Offset: 62},
+	})
+}
+
+func TestSplitStatements_GivenSemicolonsInsideTextAndComments_ExpectThemNotSeparatingStatements(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		script     string
+		statements []string
+	}{
+		{"INSERT INTO t VALUES ('a;b');", []string{"INSERT INTO t VALUES ('a;b')"}},
+		{"SELECT 'it''s; here'; SELECT 2;", []string{"SELECT 'it''s; here'", "SELECT 2"}},
+		{"SELECT ''';'''; SELECT '';", []string{"SELECT ''';'''", "SELECT ''"}},
+		{`SELECT "a;b", "c"";" FROM t;`, []string{`SELECT "a;b", "c"";" FROM t`}},
+		{"SELECT `a;b`, [c;d] FROM t;", []string{"SELECT `a;b`, [c;d] FROM t"}},
+		{"SELECT 'a -- not a comment; b'; SELECT 2;", []string{"SELECT 'a -- not a comment; b'", "SELECT 2"}},
+		{"SELECT '/* not a comment; */';", []string{"SELECT '/* not a comment; */'"}},
+		{"SELECT 1; -- don't; split\nSELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT 1 -- it's; \"here\"\n, 2;", []string{"SELECT 1 -- it's; \"here\"\n, 2"}},
+		{"/* it's; */ SELECT 1 /* \"a; */;", []string{"SELECT 1"}},
+		{"SELECT 1 /* a;\n-- b; */, 2;", []string{"SELECT 1 /* a;\n-- b; */, 2"}},
+		{"SELECT 'a\nb;\nc';", []string{"SELECT 'a\nb;\nc'"}},
+	} {
+		statements, err := db.SplitStatements(test.script)
+		c.Assert(err, qt.IsNil, qt.Commentf("%q", test.script))
+		c.Assert(statementTexts(statements), qt.DeepEquals, test.statements, qt.Commentf("%q", test.script))
+		c.Assert(db.IsStatementFinished(test.script), qt.IsTrue, qt.Commentf("%q", test.script))
+	}
+}
+
+func TestSplitStatements_GivenCreateTrigger_ExpectBodyKeptWhole(t *testing.T) {
+	c := qt.New(t)
+
+	trigger := "CREATE TEMP TRIGGER tr AFTER INSERT ON t BEGIN SELECT CASE WHEN 1 THEN 2 END; SELECT 3; END"
+	statements, err := db.SplitStatements(trigger + "; CREATE TEMP TABLE u (a); SELECT 4;")
+	c.Assert(err, qt.IsNil)
+	c.Assert(statementTexts(statements), qt.DeepEquals, []string{trigger, "CREATE TEMP TABLE u (a)", "SELECT 4"})
+}
+
+func TestSplitStatements_GivenCarriageReturnInComment_ExpectCommentUntilNewline(t *testing.T) {
+	c := qt.New(t)
+
+	statements, err := db.SplitStatements("SELECT 1; -- a\r'b; c\nSELECT 2;")
+	c.Assert(err, qt.IsNil)
+	c.Assert(statementTexts(statements), qt.DeepEquals, []string{"SELECT 1", "SELECT 2"})
+}
+
+func TestSplitStatements_GivenUnrecognizedCharacter_ExpectItKept(t *testing.T) {
+	c := qt.New(t)
+
+	statements, err := db.SplitStatements("# SELECT 1;")
+	c.Assert(err, qt.IsNil)
+	c.Assert(statementTexts(statements), qt.DeepEquals, []string{"# SELECT 1"})
+}
+
+func TestSplitStatements_GivenUnterminatedText_ExpectError(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := db.SplitStatements("SELECT 1;\nSELECT 'a;")
+	c.Assert(err, qt.ErrorMatches, "unterminated string or quoted identifier starting at line 2")
+
+	_, err = db.SplitStatements("SELECT 1; /* a;")
+	c.Assert(err, qt.ErrorMatches, "unterminated multiline comment starting at line 1")
+}
+
+func TestIsStatementFinished_GivenSemicolonInsideUnterminatedString_ExpectFalse(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.IsStatementFinished("INSERT INTO t VALUES ('a;"), qt.IsFalse)
+	c.Assert(db.IsStatementFinished("CREATE TEMP TABLE t (a);"), qt.IsTrue)
+}
+
+func FuzzSplitStatements(f *testing.F) {
+	for _, script := range []string{
+		"SELECT 1; SELECT 2;",
+		"SELECT 'a;''b'; SELECT \"c;\"\"d\"; SELECT `e;f`, [g;h];",
+		"-- comment;\nSELECT 1 /* ; */; /* ; */",
+		"-- a\r; b\nSELECT 1;",
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN 1 THEN 2 END; END; SELECT 1;",
+		"CREATE TEMPORARY TRIGGER tr BEFORE DELETE ON t WHEN CASE 1 WHEN 1 THEN 1 END BEGIN SELECT 1; END;",
+		"CREATE TEMP TABLE t (a); select x'00;', $end, 1e5end, 0xend;",
+		"BEGIN; SELECT 1; END;",
+		"SELECT 'unterminated;",
+		"SELECT 1; /* unterminated;",
+		";;\f\v ;",
+	} {
+		f.Add(script)
+	}
+
+	f.Fuzz(func(t *testing.T, script string) {
+		reference := scanReference(script)
+		statements, err := db.SplitStatements(script)
+		if reference.unterminated {
+			if err == nil {
+				t.Fatalf("expected an error for %q", script)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", script, err)
+		}
+
+		// Concatenating the statements and the gaps between them must give back the script, with the gaps only
+		// holding whitespace, comments and the semicolons that separate statements
+		separatorsFound := 0
+		checkGap := func(start, stop int, mustSeparate bool) {
+			separators := 0
+			for i := start; i < stop; i++ {
+				switch {
+				case reference.classes[i] == referenceWhitespace, reference.classes[i] == referenceComment:
+				case reference.separators[i]:
+					separators++
+				default:
+					t.Fatalf("byte %d of %q is outside of the statements %#v", i, script, statements)
+				}
+			}
+			if mustSeparate && separators == 0 {
+				t.Fatalf("no separator between bytes %d and %d of %q", start, stop, script)
+			}
+			separatorsFound += separators
+		}
+
+		position := 0
+		for i, statement := range statements {
+			stop := statement.Offset + len(statement.Text)
+			if statement.Text == "" || statement.Offset < position || stop > len(script) || script[statement.Offset:stop] != statement.Text {
+				t.Fatalf("statement %#v doesn't match the script %q", statement, script)
+			}
+			if strings.Trim(statement.Text, referenceWhitespaceChars) != statement.Text {
+				t.Fatalf("statement %#v isn't trimmed", statement)
+			}
+			if line := 1 + strings.Count(script[:statement.Offset], "\n"); statement.Line != line {
+				t.Fatalf("statement %#v should start on line %d", statement, line)
+			}
+			if !reference.startsToken(statement.Offset) || !reference.endsToken(stop) {
+				t.Fatalf("statement %#v of %q splits a string, comment or token", statement, script)
+			}
+			checkGap(position, statement.Offset, i > 0)
+			position = stop
+		}
+		checkGap(position, len(script), false)
+
+		if separatorsFound != len(reference.separators) {
+			t.Fatalf("a semicolon separating statements ended up inside the statements %#v of %q", statements, script)
+		}
+	})
+}
+
+func statementTexts(statements []db.Statement) []string {
+	texts := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		texts = append(texts, statement.Text)
+	}
+	return texts
+}
+
+type referenceClass int
+
+const (
+	referenceCode referenceClass = iota
+	referenceWhitespace
+	referenceComment
+	referenceQuoted
+)
+
+const referenceWhitespaceChars = " \t\n\v\f\r"
+
+type referenceLexeme struct {
+	text   string
+	offset int
+}
+
+// referenceScan is a plain reading of the SQLite tokenizer rules, to check SplitStatements against. It classifies each
+// byte of the script and finds the semicolons that end statements or are left between them
+type referenceScan struct {
+	classes []referenceClass
+	// tokenStarts holds the offsets where code tokens start, as a statement can't start or end in the middle of one
+	tokenStarts  map[int]bool
+	separators   map[int]bool
+	unterminated bool
+}
+
+func scanReference(script string) referenceScan {
+	scan := referenceScan{
+		classes:     make([]referenceClass, len(script)),
+		tokenStarts: make(map[int]bool),
+		separators:  make(map[int]bool),
+	}
+	lexemes := make([]referenceLexeme, 0)
+
+	mark := func(start, stop int, class referenceClass) {
+		for i := start; i < stop; i++ {
+			scan.classes[i] = class
+		}
+	}
+	isWordStart := func(b byte) bool {
+		return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+	}
+	isDigit := func(i int) bool {
+		return i < len(script) && '0' <= script[i] && script[i] <= '9'
+	}
+	isWordPart := func(i int) bool {
+		return i < len(script) && (isWordStart(script[i]) || isDigit(i))
+	}
+	isHexDigit := func(i int) bool {
+		return isDigit(i) || i < len(script) && strings.ContainsRune("abcdefABCDEF", rune(script[i]))
+	}
+
+	for i := 0; i < len(script); {
+		start := i
+		switch {
+		case strings.IndexByte(referenceWhitespaceChars, script[i]) >= 0:
+			mark(i, i+1, referenceWhitespace)
+			i++
+			continue
+		case strings.HasPrefix(script[i:], "--"):
+			i += 2
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			mark(start, i, referenceComment)
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				scan.unterminated = true
+				return scan
+			}
+			i += 2 + end + 2
+			mark(start, i, referenceComment)
+			continue
+		case strings.IndexByte("'\"`[", script[i]) >= 0:
+			closing := script[i]
+			if closing == '[' {
+				closing = ']'
+			}
+			for i++; ; i++ {
+				if i >= len(script) {
+					scan.unterminated = true
+					return scan
+				}
+				if script[i] == closing {
+					// Quotes are escaped by doubling them, except for brackets
+					if closing != ']' && i+1 < len(script) && script[i+1] == closing {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			mark(start, i, referenceQuoted)
+		case isWordStart(script[i]):
+			for i++; isWordPart(i); i++ {
+			}
+			lexemes = append(lexemes, referenceLexeme{strings.ToUpper(script[start:i]), start})
+		case strings.IndexByte("$:@", script[i]) >= 0 && i+1 < len(script) && isWordStart(script[i+1]):
+			for i++; isWordPart(i); i++ {
+			}
+			lexemes = append(lexemes, referenceLexeme{script[start:i], start})
+		case strings.HasPrefix(strings.ToLower(script[i:]), "0x") && isHexDigit(i+2):
+			for i += 2; isHexDigit(i); i++ {
+			}
+			lexemes = append(lexemes, referenceLexeme{script[start:i], start})
+		case isDigit(i) || script[i] == '.' && isDigit(i+1):
+			for ; isDigit(i); i++ {
+			}
+			if i < len(script) && script[i] == '.' {
+				for i++; isDigit(i); i++ {
+				}
+			}
+			if exponent := i + 1; i < len(script) && (script[i] == 'e' || script[i] == 'E') {
+				if exponent < len(script) && (script[exponent] == '+' || script[exponent] == '-') {
+					exponent++
+				}
+				if isDigit(exponent) {
+					for i = exponent; isDigit(i); i++ {
+					}
+				}
+			}
+			lexemes = append(lexemes, referenceLexeme{script[start:i], start})
+		default:
+			_, size := utf8.DecodeRuneInString(script[i:])
+			i += size
+			lexemes = append(lexemes, referenceLexeme{script[start:i], start})
+		}
+		scan.tokenStarts[start] = true
+	}
+
+	keyword := func(i int) string {
+		if i < len(lexemes) {
+			return lexemes[i].text
+		}
+		return ""
+	}
+	insideStatement, insideCreateTrigger, caseDepth := false, false, 0
+	for i, lexeme := range lexemes {
+		if !insideStatement {
+			if lexeme.text == ";" {
+				scan.separators[lexeme.offset] = true
+				continue
+			}
+			insideStatement = true
+			insideCreateTrigger = keyword(i) == "CREATE" && (keyword(i+1) == "TRIGGER" ||
+				(keyword(i+1) == "TEMP" || keyword(i+1) == "TEMPORARY") && keyword(i+2) == "TRIGGER")
+			caseDepth = 0
+		}
+
+		switch {
+		case insideCreateTrigger && lexeme.text == "CASE":
+			caseDepth++
+		case insideCreateTrigger && lexeme.text == "END" && caseDepth > 0:
+			caseDepth--
+		case insideCreateTrigger && lexeme.text == "END":
+			insideCreateTrigger = false
+		case !insideCreateTrigger && lexeme.text == ";":
+			scan.separators[lexeme.offset] = true
+			insideStatement = false
+		}
+	}
+	return scan
+}
+
+func (s referenceScan) startsToken(offset int) bool {
+	return s.tokenStarts[offset] || offset < len(s.classes) && s.classes[offset] == referenceQuoted &&
+		(offset == 0 || s.classes[offset-1] != referenceQuoted)
+}
+
+// endsToken reports whether a token ends right before the offset, with the next byte starting another token, or not
+// being part of any
+func (s referenceScan) endsToken(offset int) bool {
+	return offset == len(s.classes) || s.startsToken(offset) || s.classes[offset] == referenceWhitespace ||
+		s.classes[offset] == referenceComment
+}
+
+func TestNormalizeStatements(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.NormalizeStatements("SELECT  1;"), qt.Equals, "SELECT 1")
+	c.Assert(db.NormalizeStatements("select 1"), qt.Equals, "SELECT 1")
+	c.Assert(db.NormalizeStatements("select\tName,\r\n  count(*) /* all */ from Users -- the users\n;;"), qt.Equals, "SELECT Name, count(*) FROM Users")
+	c.Assert(db.NormalizeStatements("select 'Select  -- x', \"From\" from t where a = X'ab' ;"), qt.Equals, "SELECT 'Select  -- x', \"From\" FROM t WHERE a = X'ab'")
+	c.Assert(db.NormalizeStatements("select 1;select 2;"), qt.Equals, "SELECT 1;SELECT 2")
+	c.Assert(db.NormalizeStatements("  select 'open  "), qt.Equals, "select 'open")
+}
+
+func TestGetQueryPredicates_GivenJoinAndWhereClause_ExpectTablesAndComparedColumns(t *testing.T) {
+	c := qt.New(t)
+
+	predicates, err := db.GetQueryPredicates("SELECT * FROM users u JOIN orgs USING (id) WHERE u.email = ? AND \"org\" BETWEEN 1 AND 3 AND 2 < u.age")
+	c.Assert(err, qt.IsNil)
+	c.Assert(predicates.Tables, qt.DeepEquals, []db.QueryTable{{Name: "users", Alias: "u"}, {Name: "orgs"}})
+	c.Assert(predicates.Predicates, qt.DeepEquals, []db.ColumnPredicate{
+		{Table: "u", Column: "id"},
+		{Table: "orgs", Column: "id"},
+		{Table: "u", Column: "email"},
+		{Column: "org", Range: true},
+		{Table: "u", Column: "age", Range: true},
+	})
+}
+
+func TestGetQueryPredicates_GivenUnsupportedQueries_ExpectErrors(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := db.GetQueryPredicates("DELETE FROM users")
+	c.Assert(err, qt.IsNotNil)
+	_, err = db.GetQueryPredicates("SELECT * FROM users WHERE id IN (SELECT id FROM orgs)")
+	c.Assert(err, qt.IsNotNil)
+	_, err = db.GetQueryPredicates("SELECT * FROM users WHERE lower(email) = 'a'")
+	c.Assert(err, qt.ErrorMatches, "the condition lower\\(email\\) = 'a' isn't a simple comparison of a column")
+}
+
+func TestJoinStatementLines(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.JoinStatementLines("SELECT a,\n  b -- the b\nFROM t\r\nWHERE a = 1;"), qt.Equals, "SELECT a, b FROM t WHERE a = 1;")
+	c.Assert(db.JoinStatementLines("SELECT  1 /* one */,\n'a\nb';\n-- trailing comment"), qt.Equals, "SELECT  1 /* one */, 'a\nb';")
+	c.Assert(db.JoinStatementLines("SELECT 'open\nstring"), qt.Equals, "SELECT 'open string")
+}
+
+func TestGetIdentifiers(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(db.GetIdentifiers(`CREATE TRIGGER tr AFTER INSERT ON t BEGIN INSERT INTO "audit log" (id) VALUES (new.id); END`), qt.DeepEquals, []string{"tr", "t", "audit log", "id", "new", "id"})
+	c.Assert(db.GetIdentifiers("SELECT 'users', [my table].v FROM `my table` -- users"), qt.DeepEquals, []string{"my table", "v", "my table"})
+	c.Assert(db.GetIdentifiers("SELECT 1"), qt.HasLen, 0)
+}
+
+func TestGetWrittenTable(t *testing.T) {
+	c := qt.New(t)
+
+	for _, tt := range []struct {
+		statement string
+		schema    string
+		table     string
+		ok        bool
+	}{
+		{"INSERT INTO users (id) SELECT id FROM other", "", "users", true},
+		{"INSERT OR REPLACE INTO main.\"my users\" VALUES (1)", "main", "my users", true},
+		{"REPLACE INTO [t] VALUES (1)", "", "t", true},
+		{"UPDATE OR IGNORE t SET v = (SELECT max(v) FROM other)", "", "t", true},
+		{"DELETE FROM `t` WHERE id IN (SELECT id FROM other) RETURNING id", "", "t", true},
+		{"UPDATE t SET v = o.v FROM other AS o WHERE o.id = t.id", "", "", false},
+		{"WITH x AS (SELECT 1) DELETE FROM t", "", "", false},
+		{"SELECT * FROM t", "", "", false},
+		{"DELETE FROM", "", "", false},
+	} {
+		schema, table, ok := db.GetWrittenTable(tt.statement)
+		c.Assert([]interface{}{schema, table, ok}, qt.DeepEquals, []interface{}{tt.schema, tt.table, tt.ok}, qt.Commentf(tt.statement))
+	}
+}
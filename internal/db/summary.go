@@ -0,0 +1,100 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ExecutionSummary counts what happened to the statements of an input while their results were printed
+type ExecutionSummary struct {
+	Statements   int
+	Succeeded    int
+	Failed       int
+	NotExecuted  int
+	RowsReturned int64
+	RowsChanged  int64
+	Duration     time.Duration
+	// EmptyStatements is how many empty statements, like the ones of ;;, were skipped
+	EmptyStatements int
+
+	// rowsReturned adds up the rows read from each statement result, as a printer may stop before reading them all
+	rowsReturned int64
+}
+
+// String renders the summary like "20 statements: 18 ok, 2 errors, 1,204 rows returned, 350 rows changed, 1.2s total",
+// followed by the empty statements skipped, if any
+func (s ExecutionSummary) String() string {
+	summary := fmt.Sprintf("%s statements: %s ok, %s errors", FormatCount(int64(s.Statements)), FormatCount(int64(s.Succeeded)), FormatCount(int64(s.Failed)))
+	if s.NotExecuted > 0 {
+		summary += fmt.Sprintf(", %s not executed", FormatCount(int64(s.NotExecuted)))
+	}
+	summary += fmt.Sprintf(", %s rows returned, %s rows changed, %s total", FormatCount(s.RowsReturned), FormatCount(s.RowsChanged), FormatDuration(s.Duration))
+	if s.EmptyStatements > 0 {
+		summary += fmt.Sprintf(", skipped %s empty statements", FormatCount(int64(s.EmptyStatements)))
+	}
+	return summary
+}
+
+func (s *ExecutionSummary) finish(statementCount int, emptyStatements int, startTime time.Time) {
+	s.Statements = statementCount
+	s.EmptyStatements = emptyStatements
+	if s.Succeeded+s.Failed > s.Statements {
+		s.Statements = s.Succeeded + s.Failed
+	}
+	s.NotExecuted = s.Statements - s.Succeeded - s.Failed
+	s.RowsReturned = s.rowsReturned
+	s.Duration = time.Since(startTime)
+}
+
+// countRows adds to count, atomically, each row read from the returned statement result
+func countRows(statementResult StatementResult, count *int64) StatementResult {
+	rowCh := statementResult.RowCh
+	countedRowCh := make(chan rowResult)
+	go func() {
+		defer close(countedRowCh)
+		for row := range rowCh {
+			if row.Err == nil {
+				atomic.AddInt64(count, 1)
+			}
+			countedRowCh <- row
+		}
+	}()
+
+	statementResult.RowCh = countedRowCh
+	return statementResult
+}
+
+// FormatCount writes a count with its thousands separated by commas, like 10,432
+func FormatCount(count int64) string {
+	digits := strconv.FormatInt(count, 10)
+	start := 0
+	if count < 0 {
+		start = 1
+	}
+
+	formatted := digits[:start]
+	for i := start; i < len(digits); i++ {
+		if i > start && (len(digits)-i)%3 == 0 {
+			formatted += ","
+		}
+		formatted += string(digits[i])
+	}
+	return formatted
+}
+
+// FormatDuration rounds a duration to microseconds below a millisecond, to milliseconds below a second, and to tenths
+// of a second above, like 8.2s. Instant runs keep their nanoseconds, so they don't look like they took no time
+func FormatDuration(duration time.Duration) string {
+	if duration < time.Microsecond {
+		return duration.String()
+	}
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	if duration < time.Second {
+		return duration.Round(time.Millisecond).String()
+	}
+	return duration.Round(100 * time.Millisecond).String()
+}
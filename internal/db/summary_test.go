@@ -0,0 +1,25 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+func TestExecutionSummary_String(t *testing.T) {
+	c := qt.New(t)
+
+	summary := db.ExecutionSummary{Statements: 20, Succeeded: 18, Failed: 2, RowsReturned: 1204, RowsChanged: 350, Duration: 1234 * time.Millisecond}
+	c.Assert(summary.String(), qt.Equals, "20 statements: 18 ok, 2 errors, 1,204 rows returned, 350 rows changed, 1.2s total")
+
+	summary = db.ExecutionSummary{Statements: 3, Succeeded: 1, Failed: 1, NotExecuted: 1, RowsChanged: 1234567, Duration: 3456 * time.Microsecond}
+	c.Assert(summary.String(), qt.Equals, "3 statements: 1 ok, 1 errors, 1 not executed, 0 rows returned, 1,234,567 rows changed, 3ms total")
+
+	summary = db.ExecutionSummary{Statements: 2, Succeeded: 2, Duration: 456789 * time.Nanosecond}
+	c.Assert(summary.String(), qt.Equals, "2 statements: 2 ok, 0 errors, 0 rows returned, 0 rows changed, 457µs total")
+
+	summary = db.ExecutionSummary{Statements: 2, Succeeded: 2, Duration: 800 * time.Nanosecond}
+	c.Assert(summary.String(), qt.Equals, "2 statements: 2 ok, 0 errors, 0 rows returned, 0 rows changed, 800ns total")
+}
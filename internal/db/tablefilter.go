@@ -0,0 +1,42 @@
+package db
+
+import (
+	"strings"
+)
+
+// INTERNAL_TABLE_PATTERNS are the LIKE patterns of the tables kept by SQLite, Litestream and libSQL for themselves
+var INTERNAL_TABLE_PATTERNS = []string{`sqlite\_%`, `\_litestream\_seq`, `\_litestream\_lock`, `libsql\_wasm\_func\_table`}
+
+// TableFilter selects the tables the dot commands list and dump
+type TableFilter struct {
+	// Include holds LIKE patterns of the tables to keep, escaped like Exclude. All the tables are kept when it's empty
+	Include []string
+	// Exclude holds LIKE patterns of the tables to leave out, where \ escapes the % and _ wildcards
+	Exclude []string
+	// IncludeInternal keeps the tables matching INTERNAL_TABLE_PATTERNS, which are left out otherwise
+	IncludeInternal bool
+}
+
+// Condition returns an SQL condition that holds for the names in column of the tables the filter selects
+func (f TableFilter) Condition(column string) string {
+	patterns := f.Exclude
+	if !f.IncludeInternal {
+		patterns = append(append([]string{}, INTERNAL_TABLE_PATTERNS...), patterns...)
+	}
+
+	conditions := make([]string, 0, len(patterns)+1)
+	if len(f.Include) > 0 {
+		includeConditions := make([]string, 0, len(f.Include))
+		for _, pattern := range f.Include {
+			includeConditions = append(includeConditions, LikeAnyNormalForm(column, pattern, `ESCAPE '\'`))
+		}
+		conditions = append(conditions, "("+strings.Join(includeConditions, " OR ")+")")
+	}
+	for _, pattern := range patterns {
+		conditions = append(conditions, "NOT "+LikeAnyNormalForm(column, pattern, `ESCAPE '\'`))
+	}
+	if len(conditions) == 0 {
+		return "1"
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,44 @@
+package db_test
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+func TestTableFilter_GivenTrickyNames_ExpectOnlyMatchingTablesLeftOut(t *testing.T) {
+	c := qt.New(t)
+
+	filterDb, err := db.NewDb(t.TempDir()+"/filter.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	defer filterDb.Close()
+
+	err = filterDb.ExecuteAndPrintStatements(`CREATE TABLE sqlitefoo (a);
+		CREATE TABLE "_litestream_seq" (a);
+		CREATE TABLE xlitestream_seq (a);
+		CREATE TABLE schema_migrations (a);
+		CREATE TABLE schemaXmigrations (a);
+		CREATE TABLE "it's" (a);
+		CREATE TABLE "100%" (a);
+		CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT);`, new(bytes.Buffer), db.PrintOptions{Mode: enums.CSV_MODE})
+	c.Assert(err, qt.IsNil)
+
+	listTables := func(filter db.TableFilter) string {
+		out := new(bytes.Buffer)
+		err := filterDb.ExecuteAndPrintStatements("SELECT name FROM sqlite_master WHERE type = 'table' AND "+filter.Condition("name")+" ORDER BY name;", out, db.PrintOptions{Mode: enums.CSV_MODE, WithoutHeader: true})
+		c.Assert(err, qt.IsNil)
+		return out.String()
+	}
+
+	c.Assert(listTables(db.TableFilter{}), qt.Equals, "100%\nit's\nschemaXmigrations\nschema_migrations\nsqlitefoo\nt\nxlitestream_seq\n")
+	c.Assert(listTables(db.TableFilter{Exclude: []string{`schema\_migrations`, `it's`, `100\%`}}), qt.Equals, "schemaXmigrations\nsqlitefoo\nt\nxlitestream_seq\n")
+	c.Assert(listTables(db.TableFilter{Exclude: []string{"SCHEMA_%"}}), qt.Equals, "100%\nit's\nsqlitefoo\nt\nxlitestream_seq\n")
+	c.Assert(listTables(db.TableFilter{IncludeInternal: true, Exclude: []string{"%seq%"}}), qt.Equals, "100%\nit's\nschemaXmigrations\nschema_migrations\nsqlitefoo\nt\n")
+	c.Assert(listTables(db.TableFilter{IncludeInternal: true}), qt.Contains, "_litestream_seq\n")
+	c.Assert(listTables(db.TableFilter{Include: []string{"T", `schema\_%`}, Exclude: []string{"%migrations"}}), qt.Equals, "t\n")
+	c.Assert(listTables(db.TableFilter{Include: []string{"sqlite%"}}), qt.Equals, "sqlitefoo\n")
+}
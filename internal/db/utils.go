@@ -1,9 +1,14 @@
 package db
 
 import (
+	"fmt"
 	"net/url"
+	"os"
 	"strings"
 	"unicode"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 )
 
 func IsUrl(uri string) bool {
@@ -14,12 +19,57 @@ func IsUrl(uri string) bool {
 	return url.Scheme != ""
 }
 
-func IsValidSqldUrl(uri string) (bool, string) {
-	url, err := url.ParseRequestURI(uri)
+// NormalizeSqldUrl returns the URL the libsql driver is opened with for a sqld URL. libsql:// URLs, the ones Turso
+// shows, are rewritten to wss:// so they use the websocket (hrana) transport, or to ws:// with ?tls=0, which needs an
+// explicit port. wss://, ws://, https:// and http:// URLs are kept as they are
+func NormalizeSqldUrl(uri string) (string, error) {
+	sqldUrl, err := url.Parse(uri)
 	if err != nil {
-		return false, ""
+		return "", err
+	}
+	// A host with a port and no protocol, like mydb-org.turso.io:443, is parsed as the scheme mydb-org.turso.io
+	if strings.Contains(sqldUrl.Scheme, ".") {
+		return "", &shellerrors.MissingProtocolError{Host: sqldUrl.Scheme + ":" + strings.TrimSuffix(sqldUrl.Opaque, "/")}
+	}
+
+	switch sqldUrl.Scheme {
+	case "libsql":
+		query := sqldUrl.Query()
+		tls := query.Get("tls")
+		query.Del("tls")
+		switch tls {
+		case "", "1":
+			sqldUrl.Scheme = "wss"
+		case "0":
+			if sqldUrl.Port() == "" {
+				return "", fmt.Errorf("libsql:// URLs with ?tls=0 must have a port, like libsql://localhost:8080?tls=0")
+			}
+			sqldUrl.Scheme = "ws"
+		default:
+			return "", fmt.Errorf("invalid value \"%s\" of the tls query parameter. Valid values are 0 and 1", tls)
+		}
+		sqldUrl.RawQuery = query.Encode()
+	case "wss", "ws", "https", "http":
+	default:
+		return "", &shellerrors.ProtocolError{Scheme: sqldUrl.Scheme}
+	}
+	if sqldUrl.Host == "" {
+		return "", &shellerrors.UrlDoesNotContainHostError{}
 	}
-	return url.Scheme == "libsql" || url.Scheme == "wss" || url.Scheme == "ws" || url.Scheme == "http" || url.Scheme == "https", url.Scheme
+	return sqldUrl.String(), nil
+}
+
+// looksLikeSqldHost reports whether a database path without protocol is the host of a Turso database, which would
+// otherwise be created as a local file named after it
+func looksLikeSqldHost(path string) bool {
+	host := strings.TrimSuffix(path, "/")
+	if strings.ContainsAny(host, `/\`) {
+		return false
+	}
+	if _, err := os.Stat(path); err == nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(host), ".turso.io")
 }
 
 func EscapeSingleQuotes(value string) string {
@@ -38,6 +88,9 @@ func NeedsEscaping(name string) bool {
 	if startsWithNumber(name) {
 		return true
 	}
+	if isKeyword(name) {
+		return true
+	}
 	for _, char := range name {
 		if !unicode.IsLetter(char) && !unicode.IsNumber(char) && char != rune('_') {
 			return true
@@ -45,3 +98,17 @@ func NeedsEscaping(name string) bool {
 	}
 	return false
 }
+
+// QuoteIdentifier quotes a table or column name for generated SQL in the given style. Quote characters inside the name
+// are doubled, except for brackets, which can't be escaped, so names containing "]" are double quoted instead
+func QuoteIdentifier(name string, style enums.IdentifierQuoteStyle) string {
+	switch style {
+	case enums.BACKTICK_STYLE:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case enums.BRACKET_STYLE:
+		if !strings.Contains(name, "]") {
+			return "[" + name + "]"
+		}
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+var variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var variableReferenceRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+func IsValidVariableName(name string) bool {
+	return variableNameRegexp.MatchString(name)
+}
+
+// ExpandVariables replaces every {{name}} reference with the value of the variable. The substitution is purely textual,
+// so it also applies inside string literals and comments
+func ExpandVariables(statements string, variables map[string]string) (string, error) {
+	matches := variableReferenceRegexp.FindAllStringSubmatchIndex(statements, -1)
+	if len(matches) == 0 {
+		return statements, nil
+	}
+
+	var expanded strings.Builder
+	lastEnd := 0
+	for _, match := range matches {
+		name := statements[match[2]:match[3]]
+		value, ok := variables[name]
+		if !ok {
+			line, column := getLineAndColumn(statements, match[0])
+			return "", &shellerrors.UndefinedVariableError{Name: name, Line: line, Column: column}
+		}
+
+		expanded.WriteString(statements[lastEnd:match[0]])
+		expanded.WriteString(value)
+		lastEnd = match[1]
+	}
+	expanded.WriteString(statements[lastEnd:])
+
+	return expanded.String(), nil
+}
+
+func getLineAndColumn(text string, offset int) (line int, column int) {
+	precedingText := text[:offset]
+	line = strings.Count(precedingText, "\n") + 1
+	column = len([]rune(precedingText[strings.LastIndex(precedingText, "\n")+1:])) + 1
+	return line, column
+}
@@ -0,0 +1,221 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/internal/suggester"
+)
+
+// completion completes the input at the prompt: dot command names at the start of a line, and otherwise SQL keywords,
+// table names, and the column names of the tables the statement mentions. The table and column names are cached, so
+// completing doesn't query the database, which may be remote, on every key. The cache is invalidated by the statements
+// that may change the schema and by dot commands, and filled again by the next completion
+type completion struct {
+	db           *db.Db
+	commandNames []string
+
+	// mu guards the fields below, as completions run in the goroutine of readline, while the shell may be executing
+	// statements
+	mu     sync.Mutex
+	loaded bool
+	tables []string
+	// columns holds the column names of the tables by their lower cased name, loaded the first time they're completed
+	columns map[string][]string
+	// pendingStatement holds the lines typed before the current one of a statement typed over several lines
+	pendingStatement string
+}
+
+func newCompletion(shellDb *db.Db, commandNames []string) *completion {
+	return &completion{db: shellDb, commandNames: commandNames}
+}
+
+func (c *completion) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+	c.tables = nil
+	c.columns = nil
+}
+
+func (c *completion) setPendingStatement(statement string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pendingStatement = statement
+}
+
+// suggest returns the text completing the last word of the input for each name or keyword it may be
+func (c *completion) suggest(input string) []string {
+	if strings.TrimSpace(input) == "" || unicode.IsSpace(rune(input[len(input)-1])) {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pendingStatement == "" && isCommand(strings.TrimLeft(input, " \t")) {
+		command := strings.TrimLeft(input, " \t")
+		if strings.ContainsAny(command, " \t") {
+			return nil
+		}
+		return completePrefix(c.commandNames, command, false)
+	}
+
+	qualifier, word := getCompletedWord(input)
+	suggestions := make([]string, 0)
+	if qualifier == "" {
+		suggestions = append(suggestions, suggester.SuggestCompletion(input)...)
+	}
+	if word == "" {
+		return suggestions
+	}
+
+	c.load()
+	if qualifier == "" {
+		suggestions = append(suggestions, completePrefix(c.tables, word, true)...)
+	}
+	for _, table := range c.getMentionedTables(c.pendingStatement+"\n"+input, qualifier) {
+		suggestions = append(suggestions, completePrefix(c.getColumns(table), word, true)...)
+	}
+	return uniqueSuggestions(suggestions)
+}
+
+// load reads the table names, unless they were read since the cache was invalidated. A failure leaves the cache empty,
+// to be read again by the next completion
+func (c *completion) load() {
+	if c.loaded {
+		return
+	}
+	tables, err := readNames(c.db, "SELECT name FROM sqlite_schema WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return
+	}
+	c.tables = tables
+	c.columns = make(map[string][]string)
+	c.loaded = true
+}
+
+func (c *completion) getColumns(table string) []string {
+	key := strings.ToLower(table)
+	if columns, ok := c.columns[key]; ok {
+		return columns
+	}
+	columns, err := readNames(c.db, fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", db.EscapeSingleQuotes(table)))
+	if err != nil {
+		return nil
+	}
+	c.columns[key] = columns
+	return columns
+}
+
+// getMentionedTables returns the tables whose name is a word of the statement. With a qualifier, only the table it
+// names is, or when it's an alias, all of them
+func (c *completion) getMentionedTables(statement string, qualifier string) []string {
+	mentioned := make([]string, 0)
+	words := strings.FieldsFunc(statement, func(r rune) bool { return !isWordRune(r) })
+	for _, table := range c.tables {
+		if qualifier != "" && strings.EqualFold(table, qualifier) {
+			return []string{table}
+		}
+		for _, word := range words {
+			if strings.EqualFold(table, word) {
+				mentioned = append(mentioned, table)
+				break
+			}
+		}
+	}
+	return mentioned
+}
+
+// getCompletedWord returns the word the input ends with, and the one before it when they're separated by a dot, like
+// users and em in "SELECT users.em"
+func getCompletedWord(input string) (qualifier string, word string) {
+	start := len(input)
+	for start > 0 && isWordRune(rune(input[start-1])) {
+		start--
+	}
+	word = input[start:]
+	if start == 0 || input[start-1] != '.' {
+		return "", word
+	}
+	qualifierStart := start - 1
+	for qualifierStart > 0 && isWordRune(rune(input[qualifierStart-1])) {
+		qualifierStart--
+	}
+	return strings.Trim(input[qualifierStart:start-1], "\"`[]"), word
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// completePrefix returns the rest of the names starting with prefix, ignoring case when asked
+func completePrefix(names []string, prefix string, ignoreCase bool) []string {
+	suggestions := make([]string, 0)
+	for _, name := range names {
+		if len(name) <= len(prefix) {
+			continue
+		}
+		if name[:len(prefix)] == prefix || (ignoreCase && strings.EqualFold(name[:len(prefix)], prefix)) {
+			suggestions = append(suggestions, name[len(prefix):])
+		}
+	}
+	return suggestions
+}
+
+func uniqueSuggestions(suggestions []string) []string {
+	seen := make(map[string]bool, len(suggestions))
+	unique := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if !seen[suggestion] {
+			seen[suggestion] = true
+			unique = append(unique, suggestion)
+		}
+	}
+	return unique
+}
+
+func readNames(shellDb *db.Db, query string) ([]string, error) {
+	result, err := shellDb.ExecuteStatements(query)
+	if err != nil {
+		return nil, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+	names := make([]string, 0)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return nil, rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, formattedRow[0])
+	}
+	return names, nil
+}
+
+// SuggestCompletion returns the text completing the last word of the input, as suggested by TAB at the interactive
+// prompt
+func (sh *Shell) SuggestCompletion(input string) []string {
+	return sh.completion.suggest(input)
+}
+
+// getCommandNames returns the names of the dot commands listed by .help, sorted
+func (sh *Shell) getCommandNames() []string {
+	names := make([]string, 0)
+	for _, command := range sh.databaseCmd.Commands() {
+		if !command.Hidden && command.Name() != "completion" {
+			names = append(names, command.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
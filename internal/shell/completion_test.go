@@ -0,0 +1,28 @@
+package shell_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/shell"
+)
+
+func TestSuggestCompletion_GivenTables_ExpectCommandTableAndColumnNamesCachedUntilSchemaChanges(t *testing.T) {
+	c := qt.New(t)
+
+	sh, shellDb := newTestShell(t, shell.ShellConfig{})
+	c.Assert(sh.ExecuteCommandOrStatements("CREATE TABLE users (id INTEGER, email TEXT); CREATE TABLE user_orders (id INTEGER, total REAL);"), qt.IsNil)
+
+	c.Assert(sh.SuggestCompletion(".he"), qt.DeepEquals, []string{"ader-interval", "adercase", "lp"})
+	c.Assert(sh.SuggestCompletion("SELECT * FROM use"), qt.DeepEquals, []string{"r_orders", "rs"})
+	c.Assert(sh.SuggestCompletion("SELECT * FROM users WHERE em"), qt.DeepEquals, []string{"ail"})
+	c.Assert(sh.SuggestCompletion("SELECT user_orders.t"), qt.DeepEquals, []string{"otal"})
+	c.Assert(sh.SuggestCompletion("SELECT to"), qt.DeepEquals, []string{})
+
+	queryCount := shellDb.QueryCount()
+	c.Assert(sh.SuggestCompletion("SELECT * FROM users WHERE em"), qt.DeepEquals, []string{"ail"})
+	c.Assert(shellDb.QueryCount(), qt.Equals, queryCount)
+
+	c.Assert(sh.ExecuteCommandOrStatements("ALTER TABLE users ADD COLUMN emoji TEXT;"), qt.IsNil)
+	c.Assert(sh.SuggestCompletion("SELECT * FROM users WHERE em"), qt.DeepEquals, []string{"ail", "oji"})
+}
@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// printStatementsWithDeltas executes and prints the statements like printStatements, but for the ones writing to a
+// single table, each executed on its own between two counts of the rows of the table, shown after it. The size of
+// local databases is shown too when it changed. The statements the table of can't be told are executed together
+func (sh *Shell) printStatementsWithDeltas(statements string) error {
+	splitStatements, err := db.SplitStatements(statements)
+	if err != nil {
+		_, err = sh.printStatements(statements, sh.config.ExecutionSummary)
+		return err
+	}
+
+	pending := make([]string, 0)
+	printPending := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		_, err := sh.printStatements(strings.Join(pending, ";\n")+";", sh.config.ExecutionSummary)
+		pending = pending[:0]
+		return err
+	}
+	for _, statement := range splitStatements {
+		schema, table, ok := db.GetWrittenTable(statement.Text)
+		if !ok {
+			pending = append(pending, statement.Text)
+			continue
+		}
+		if err := printPending(); err != nil {
+			return err
+		}
+
+		// The statements writing to a table that can't be counted, like one they fail for, have no delta
+		qualifiedTable := db.QuoteIdentifier(table, enums.DOUBLE_QUOTE_STYLE)
+		if schema != "" {
+			qualifiedTable = db.QuoteIdentifier(schema, enums.DOUBLE_QUOTE_STYLE) + "." + qualifiedTable
+		}
+		rowsBefore, countErr := sh.readDeltaCount("SELECT count(*) FROM " + qualifiedTable)
+		sizeBefore, sizeErr := sh.readDatabaseSize()
+		summary, err := sh.printStatements(statement.Text+";", true)
+		if err != nil {
+			return err
+		}
+		if summary.Succeeded == 0 || countErr != nil {
+			continue
+		}
+		rowsAfter, err := sh.readDeltaCount("SELECT count(*) FROM " + qualifiedTable)
+		if err != nil {
+			continue
+		}
+		name := table
+		if schema != "" {
+			name = schema + "." + table
+		}
+		fmt.Fprintf(sh.config.OutF, "%s: %s\n", name, formatDelta(rowsBefore, rowsAfter, ""))
+		if sizeErr != nil {
+			continue
+		}
+		if sizeAfter, err := sh.readDatabaseSize(); err == nil && sizeAfter != sizeBefore {
+			fmt.Fprintf(sh.config.OutF, "database size: %s\n", formatDelta(sizeBefore, sizeAfter, " bytes"))
+		}
+	}
+	return printPending()
+}
+
+// readDatabaseSize returns the size in bytes of the main database of local databases, whose pages are counted without
+// reading them
+func (sh *Shell) readDatabaseSize() (int64, error) {
+	if sh.db.IsRemote() {
+		return 0, fmt.Errorf("the size of remote databases isn't known")
+	}
+	return sh.readDeltaCount("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()")
+}
+
+func (sh *Shell) readDeltaCount(query string) (int64, error) {
+	values, err := readNames(sh.db, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 1 {
+		return 0, fmt.Errorf("expected a single count, got %d", len(values))
+	}
+	return strconv.ParseInt(values[0], 10, 64)
+}
+
+// formatDelta writes a count before and after a statement with their difference, like "10,432 → 9,876 (-556)"
+func formatDelta(before int64, after int64, unit string) string {
+	difference := db.FormatCount(after - before)
+	if after > before {
+		difference = "+" + difference
+	}
+	return fmt.Sprintf("%s → %s%s (%s)", db.FormatCount(before), db.FormatCount(after), unit, difference)
+}
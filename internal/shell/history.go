@@ -1,16 +1,21 @@
 package shell
 
 import (
+	"bufio"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 )
 
+// DEFAULT_HISTORY_LIMIT is the number of history entries kept when ShellConfig.HistoryLimit is 0, readline's default
+const DEFAULT_HISTORY_LIMIT = 500
+
 func GetHistoryFileBasedOnMode(dbPath string, mode enums.HistoryMode, historyName string) string {
 	sharedHistoryFileName := getHistoryFileName(historyName)
 
@@ -65,3 +70,45 @@ func getFileNameWithoutExtension(path string) string {
 	}
 	return filenameWithoutExtension
 }
+
+func (sh *Shell) getHistoryFile() string {
+	if sh.config.HistoryFile != "" {
+		return sh.config.HistoryFile
+	}
+	return GetHistoryFileBasedOnMode(sh.db.Uri, sh.config.HistoryMode, sh.config.HistoryName)
+}
+
+func (sh *Shell) getHistoryLimit() int {
+	if sh.config.HistoryLimit > 0 {
+		return sh.config.HistoryLimit
+	}
+	return DEFAULT_HISTORY_LIMIT
+}
+
+// readHistoryFile returns the last entries of the history file, one per non empty line like readline loads them. A file
+// that can't be read, like one not created yet, holds none
+func readHistoryFile(path string, limit int) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if entry := strings.TrimSpace(scanner.Text()); entry != "" {
+			entries = appendHistoryEntry(entries, entry, limit)
+		}
+	}
+	return entries
+}
+
+func appendHistoryEntry(entries []string, entry string, limit int) []string {
+	entries = append(entries, entry)
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries
+}
@@ -1,8 +1,10 @@
 package shell_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
@@ -127,3 +129,41 @@ func TestGetHistoryFileBasedOnMode_GivenPerDatabaseHistory_WhenPathIsWsUrl_Expec
 
 	c.Assert(result, qt.Equals, expectedPath)
 }
+
+func TestRun_GivenLinesOnlyDifferingInLayout_ExpectSavedToHistoryOnce(t *testing.T) {
+	c := qt.New(t)
+
+	input := "SELECT  1;\nselect 1; -- again\n.mode  csv\n.mode csv\nSELECT 'a  b';\nSELECT 'a b';\nSELECT 1;\n"
+	sh, _ := newTestShell(t, shell.ShellConfig{InF: strings.NewReader(input), HistoryMode: enums.SingleHistory})
+	c.Assert(sh.Run(), qt.IsNil)
+
+	history, err := os.ReadFile(getExpectedHistoryFullPath(historyName))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(history), qt.Equals, "SELECT  1;\n.mode  csv\nSELECT 'a  b';\nSELECT 'a b';\nSELECT 1;\n")
+}
+
+func TestRun_GivenHistoryFileAndLimit_ExpectStatementsOverSeveralLinesSavedAsOneEntryAndHistoryTrimmed(t *testing.T) {
+	c := qt.New(t)
+
+	historyFile := t.TempDir() + "/history"
+	c.Assert(os.WriteFile(historyFile, []byte("SELECT 'old 1';\nSELECT 'old 2';\n"), 0644), qt.IsNil)
+
+	input := ".mode csv\nSELECT 1,\n  2 -- two\n  ;\n.history\nSELECT 3;\n.history 2\n"
+	outF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{InF: strings.NewReader(input), OutF: outF, HistoryFile: historyFile, HistoryLimit: 4})
+	c.Assert(sh.Run(), qt.IsNil)
+
+	c.Assert(outF.String(), qt.Equals, "1,2\n1,2\n"+
+		"    1  SELECT 'old 1';\n    2  SELECT 'old 2';\n    3  .mode csv\n    4  SELECT 1, 2 ;\n"+
+		"3\n3\n"+
+		"    3  .history\n    4  SELECT 3;\n")
+	history, err := os.ReadFile(historyFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(history), qt.Equals, "SELECT 'old 1';\nSELECT 'old 2';\n.mode csv\nSELECT 1, 2 ;\n.history\nSELECT 3;\n.history 2\n")
+
+	// The history is trimmed to its limit when it's loaded again
+	newTestShell(t, shell.ShellConfig{HistoryFile: historyFile, HistoryLimit: 4})
+	history, err = os.ReadFile(historyFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(history), qt.Equals, "SELECT 1, 2 ;\n.history\nSELECT 3;\n.history 2\n")
+}
@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"fmt"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+type pagedQuery struct {
+	statement string
+	// pageSize is the page size set when the statement was executed, kept for all its pages
+	pageSize int
+	// page is the page shown last, counting from 1
+	page int
+	// lastPageShown is set once a page with fewer rows than pageSize was shown
+	lastPageShown bool
+}
+
+// getPageableQuery returns the statement to show in pages when statements is a single query. Queries with a LIMIT
+// clause are left alone, warning about it
+func (sh *Shell) getPageableQuery(statements string) (string, bool) {
+	splitStatements, err := db.SplitStatements(statements)
+	if err != nil || len(splitStatements) != 1 {
+		return "", false
+	}
+
+	query := splitStatements[0].Text
+	clauses := db.GetQueryClauses(query)
+	if !clauses.IsQuery {
+		return "", false
+	}
+	if clauses.HasLimit {
+		sh.config.Log.Warnf("the statement isn't shown in pages, as it has a LIMIT clause")
+		return "", false
+	}
+	if !clauses.HasOrderBy {
+		sh.config.Log.Warnf("the statement has no ORDER BY clause, so its rows may change pages between executions")
+	}
+	return query, true
+}
+
+// turnPage executes the paged query again to show the page the given number of pages away from the one shown last
+func (sh *Shell) turnPage(pages int) error {
+	query := &sh.state.pagedQuery
+	if query.statement == "" {
+		return fmt.Errorf("no result to page through. Set a page size with .page and execute a SELECT statement first")
+	}
+
+	page := query.page + pages
+	if page < 1 {
+		return fmt.Errorf("already at the first page")
+	}
+	if pages > 0 && query.lastPageShown {
+		return fmt.Errorf("already at the last page")
+	}
+
+	offset := (page - 1) * query.pageSize
+	summary, err := sh.printStatements(fmt.Sprintf("%s\nLIMIT %d OFFSET %d", query.statement, query.pageSize, offset), true)
+	if err != nil {
+		return err
+	}
+
+	query.page = page
+	query.lastPageShown = summary.RowsReturned < int64(query.pageSize)
+	if summary.RowsReturned == 0 {
+		sh.config.Log.Infof("page %d (no rows)", page)
+	} else {
+		sh.config.Log.Infof("page %d (rows %d–%d)", page, offset+1, offset+int(summary.RowsReturned))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/internal/shellcmd"
+)
+
+// outputRedirect is the writer the results are printed to: the file set by .once, or else the one set by .output, or
+// else the standard output
+type outputRedirect struct {
+	stdout   io.Writer
+	file     *os.File
+	onceFile *os.File
+	// onceLevel is the command nesting level .once was given at, and onceArmed is set once .once itself finished, so
+	// the next command or statements at that level end the redirection
+	onceLevel int
+	onceArmed bool
+}
+
+func newOutputRedirect(stdout io.Writer) *outputRedirect {
+	return &outputRedirect{stdout: stdout}
+}
+
+func (r *outputRedirect) Write(p []byte) (int, error) {
+	if r.onceFile != nil {
+		return r.onceFile.Write(p)
+	}
+	if r.file != nil {
+		return r.file.Write(p)
+	}
+	return r.stdout.Write(p)
+}
+
+// Closed reports whether the reader of the standard output went away, which only matters while it's written to
+func (r *outputRedirect) Closed() bool {
+	return !r.redirected() && db.IsOutputClosed(r.stdout)
+}
+
+func (r *outputRedirect) redirected() bool {
+	return r.onceFile != nil || r.file != nil
+}
+
+// setOutputFile sends the results to the file, replacing it, or back to the standard output when file is "". With
+// once, only the next command or statements are sent to it. The destination is left unchanged when the file can't be
+// created
+func (sh *Shell) setOutputFile(file string, once bool) error {
+	var outputFile *os.File
+	if file != "" {
+		path, err := shellcmd.ResolveFilePath(sh.config.FileRoot, file)
+		if err != nil {
+			return err
+		}
+		outputFile, err = os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to open the output file: %w", err)
+		}
+	}
+
+	if once {
+		err := closeOutputFile(sh.redirect.onceFile)
+		sh.redirect.onceFile = outputFile
+		// Called by .once, so from inside the command it's given at
+		sh.redirect.onceLevel = sh.commandLevel - 1
+		sh.redirect.onceArmed = false
+		return err
+	}
+	err := closeOutputFile(sh.redirect.file)
+	sh.redirect.file = outputFile
+	return err
+}
+
+// endOnceOutput sends the results back to where they were sent before .once, once the command or the statements
+// following it at the same nesting level ran
+func (sh *Shell) endOnceOutput(level int) {
+	if sh.redirect.onceFile == nil || sh.redirect.onceLevel != level {
+		return
+	}
+	if !sh.redirect.onceArmed {
+		sh.redirect.onceArmed = true
+		return
+	}
+	err := closeOutputFile(sh.redirect.onceFile)
+	sh.redirect.onceFile = nil
+	if err != nil {
+		db.PrintError(err, sh.config.ErrF)
+	}
+}
+
+func closeOutputFile(outputFile *os.File) error {
+	if outputFile == nil {
+		return nil
+	}
+	if err := outputFile.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile.Name(), err)
+	}
+	return nil
+}
+
+// Close closes the files the results are redirected to by .output and .once, reporting to ErrF when their content
+// couldn't be written
+func (sh *Shell) Close() {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	for _, outputFile := range []*os.File{sh.redirect.onceFile, sh.redirect.file} {
+		if err := closeOutputFile(outputFile); err != nil {
+			db.PrintError(err, sh.config.ErrF)
+		}
+	}
+	sh.redirect.onceFile = nil
+	sh.redirect.file = nil
+}
@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// databaseSettingsCommands are the dot commands a settings file may hold
+var databaseSettingsCommands = []string{".mode", ".quote"}
+
+// GetDatabaseSettingsFile returns the file the settings of a database are saved to, next to its per database history.
+// It's named after the database file name or URL host, like the history, and keyed by a hash of the absolute path of
+// the file or of the host and path of the URL, so databases sharing a file name don't share their settings. It's
+// empty when the database has neither
+func GetDatabaseSettingsFile(dbPath string, historyName string) string {
+	host, err := getHostFromDbUri(dbPath)
+	if err != nil || host == "" {
+		return ""
+	}
+	key, err := getDatabaseSettingsKey(dbPath)
+	if err != nil {
+		return ""
+	}
+	return getHistoryFileFullPath(historyName, getDatabaseSettingsFileName(host+"_"+key))
+}
+
+func getDatabaseSettingsFileName(name string) string {
+	return fmt.Sprintf(".%s_shell_settings", name)
+}
+
+// getDatabaseSettingsKey hashes what tells databases apart: the absolute path of a file, or the host and path of a URL,
+// which leave out the scheme and the query parameters, like the auth token, that may change between connections
+func getDatabaseSettingsKey(dbPath string) (string, error) {
+	location := ""
+	if db.IsUrl(dbPath) {
+		url, err := url.Parse(dbPath)
+		if err != nil {
+			return "", err
+		}
+		location = url.Host + url.Path
+	} else {
+		absPath, err := filepath.Abs(dbPath)
+		if err != nil {
+			return "", err
+		}
+		location = absPath
+	}
+	hash := sha256.Sum256([]byte(location))
+	return hex.EncodeToString(hash[:4]), nil
+}
+
+// DatabaseSettings returns the settings saved per database, written as the dot commands that restore them
+func (sh *Shell) DatabaseSettings() string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.databaseSettings()
+}
+
+func (sh *Shell) databaseSettings() string {
+	mode := string(sh.state.printMode)
+	if sh.state.csvOptions.BOM {
+		mode += " --bom"
+	}
+	if sh.state.csvOptions.CRLF {
+		mode += " --crlf"
+	}
+	if sh.state.jsonOptions.Pretty {
+		mode += " --pretty"
+	}
+	if sh.state.tableOptions.Buffered {
+		mode += " --buffered"
+	} else if sh.state.tableOptions.WidthRows > 0 {
+		mode += fmt.Sprintf(" --width-rows %d", sh.state.tableOptions.WidthRows)
+	}
+	return fmt.Sprintf(".mode %s\n.quote %s\n", mode, sh.state.identifierQuoteStyle)
+}
+
+// LoadDatabaseSettings applies the settings saved for the database, noting it on ErrF. It does nothing when none were
+// saved
+func (sh *Shell) LoadDatabaseSettings() error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	settingsFile := GetDatabaseSettingsFile(sh.db.Uri, sh.config.HistoryName)
+	if settingsFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(settingsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the settings of this database: %w", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !isDatabaseSettingsCommand(line) {
+			return fmt.Errorf("invalid setting \"%s\" in %s", line, settingsFile)
+		}
+		if err := sh.executeCommand(line); err != nil {
+			return fmt.Errorf("invalid setting \"%s\" in %s: %w", line, settingsFile, err)
+		}
+	}
+
+	sh.config.Log.Infof("Loaded the settings saved for this database from %s", settingsFile)
+	return nil
+}
+
+// SaveDatabaseSettings saves the current settings, to be loaded by LoadDatabaseSettings the next time the shell
+// connects to the database
+func (sh *Shell) SaveDatabaseSettings() error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.saveDatabaseSettings()
+}
+
+func (sh *Shell) saveDatabaseSettings() error {
+	settingsFile := GetDatabaseSettingsFile(sh.db.Uri, sh.config.HistoryName)
+	if settingsFile == "" {
+		return fmt.Errorf("unable to save the settings of this database, as it has no file name or URL host to save them for")
+	}
+	if err := os.WriteFile(settingsFile, []byte(sh.databaseSettings()), 0600); err != nil {
+		return fmt.Errorf("failed to save the settings of this database: %w", err)
+	}
+	return nil
+}
+
+func isDatabaseSettingsCommand(line string) bool {
+	name, _, _ := strings.Cut(line, " ")
+	for _, command := range databaseSettingsCommands {
+		if name == command {
+			return true
+		}
+	}
+	return false
+}
@@ -3,17 +3,18 @@ package shell
 import (
 	"fmt"
 	"io"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/fatih/color"
 	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/internal/shellcmd"
-	"github.com/libsql/libsql-shell-go/internal/suggester"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
-	"github.com/libsql/sqlite-antlr4-parser/sqliteparser"
-	"github.com/libsql/sqlite-antlr4-parser/sqliteparserutils"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
 	"github.com/spf13/cobra"
 )
 
@@ -32,17 +33,72 @@ type ShellConfig struct {
 	QuietMode             bool
 	WelcomeMessage        *string
 	DisableAutoCompletion bool
+	ResultCacheSize       int
+	Progress              db.ProgressFunc
+	// ContinueOnError turns bail off: the statements after a failing one still run, and the failures are summarized by
+	// PrintErrorSummary. JSONErrors writes them as JSON lines instead of text
+	ContinueOnError bool
+	JSONErrors      bool
+	// ExecutionSummary prints to ErrF a summary of each input holding more than one statement
+	ExecutionSummary bool
+	// FailOnEmpty fails the queries returning no rows, as db.PrintOptions.FailOnEmpty does
+	FailOnEmpty bool
+	// IdleTimeout is how long the prompt waits for input before IdleAction is taken. Zero disables it
+	IdleTimeout time.Duration
+	IdleAction  enums.IdleAction
+	// FileRoot, when set, confines the files dot commands read and write to this directory
+	FileRoot string
+	// ColumnTransform, when set, replaces the values of the printed results, and of .dump with ColumnTransformInDumps
+	ColumnTransform        db.ColumnTransform
+	ColumnTransformInDumps bool
+	// SessionStats prints the statistics of the session when Run returns, even when the input isn't a terminal
+	SessionStats bool
+	// HistoryFile, when set, is the file the history is saved to instead of the one chosen by HistoryMode, and
+	// HistoryLimit is the number of entries kept, DEFAULT_HISTORY_LIMIT when 0
+	HistoryFile  string
+	HistoryLimit int
+	// CancelableInput makes the input closable by Stop, which otherwise only stops Run once the next line is read
+	CancelableInput bool
+	// Log writes the diagnostics of the shell, like warnings and reconnection notices, to ErrF's Logger when nil.
+	// Errors are always written to ErrF
+	Log *db.Logger
 }
 
 type Shell struct {
 	config ShellConfig
 
-	db        *db.Db
+	db           *db.Db
+	resultCache  *db.ResultCache
+	errorLog     *db.ErrorLog
+	sessionStats *db.SessionStats
+	// output is the writer the standard output is wrapped in, counting the bytes written, which the prompt is written
+	// to. redirect is the one config.OutF is set to, writing to output unless .output or .once redirect the results to
+	// a file
+	output    *db.BrokenPipeWriter
+	redirect  *outputRedirect
 	promptFmt func(p ...interface{}) string
 
+	// mu serializes the execution of commands and statements, which share the state and the command tree. It isn't
+	// taken by the callbacks given to the commands, as they always run while it's held
+	mu    sync.Mutex
 	state shellState
 
 	databaseCmd *cobra.Command
+	// completion completes the input at the interactive prompt
+	completion *completion
+
+	// outIsTerminal is set when the output is written to a terminal, not redirected
+	outIsTerminal bool
+
+	// interrupts receives a value when CancelQuery is called, stopping the commands that run until interrupted
+	interrupts chan struct{}
+
+	cancelableStdin *readline.CancelableStdin
+	// idleTimerGeneration is guarded by mu and changes every time the idle timer is stopped
+	idleTimerGeneration uint64
+	// commandLevel is how many dot commands are running, one inside the other like the commands of a script run by
+	// .read
+	commandLevel int
 }
 
 type shellState struct {
@@ -51,24 +107,175 @@ type shellState struct {
 	insideMultilineStatement   bool
 	interruptReadEvalPrintLoop bool
 	printMode                  enums.PrintMode
+	csvOptions                 db.CSVOptions
+	jsonOptions                db.JSONOptions
+	tableOptions               db.TableOptions
+	variables                  map[string]string
+	identifierQuoteStyle       enums.IdentifierQuoteStyle
+	excludedTables             []string
+	// timer prints how long each statement took after it, as set by .timer
+	timer bool
+	// changes prints the rows each statement changed or returned after it, as set by .changes
+	changes bool
+	// delta prints the rows of the table each statement writes to before and after it, as set by .delta
+	delta bool
+	// explainFormat prints the results of EXPLAIN aligned and indented, as set by .explain-fmt
+	explainFormat bool
+	// nullValue is shown in place of NULL values in the text modes, as set by .nullvalue, when not nil
+	nullValue *string
+	// maskPatterns are the glob patterns of the columns whose values are hidden on screen, as set by .mask
+	maskPatterns []string
+	// numberLocale, when set, groups the thousands of the numbers shown in table mode, as set by .numfmt
+	numberLocale db.NumberLocale
+	// prettyPragmas rewrites the results of well known PRAGMAs for reading, as set by .pragma-pretty
+	prettyPragmas bool
+	// slowThreshold is the duration above which statements are flagged, when not 0, and slowLogFile the file they're
+	// appended to, when not empty
+	slowThreshold time.Duration
+	slowLogFile   string
+	// headerInterval is how many rows of a table are shown before its header is shown again, when not 0
+	headerInterval int
+	// preserveHeaderCase shows the column names of tables as they are rather than upper cased, as set by .headercase
+	preserveHeaderCase bool
+	// pageSize is the number of rows of the pages SELECT statements are shown in, when not 0
+	pageSize int
+	// pagedQuery is the last statement shown in pages, browsed with .next and .prev
+	pagedQuery pagedQuery
+	// forceBail stops at the first error even with bail off
+	forceBail bool
+	// prompting is set while Run reads the input at an interactive prompt, and readingInput while Run reads the input,
+	// whether it's at a prompt or piped
+	prompting    bool
+	readingInput bool
+	// lastHistoryKey is the historyKey of the last line saved to the history
+	lastHistoryKey string
+	// history holds the entries of the history, the ones loaded from its file followed by the ones saved since, and
+	// historyParts the lines of the statement being typed, saved as a single entry once it's complete
+	history      []string
+	historyParts []string
 }
 
-func NewShell(config ShellConfig, db *db.Db) (*Shell, error) {
+func NewShell(config ShellConfig, shellDb *db.Db) (*Shell, error) {
 	promptFmt := color.New(color.FgBlue, color.Bold).SprintFunc()
+	outF := config.OutF
+	output := newOutputWriter(config.OutF, shellDb)
+	redirect := newOutputRedirect(output)
+	config.OutF = redirect
+	if config.Log == nil {
+		config.Log = db.NewLogger(config.ErrF, nil, "")
+	}
 
-	newShell := Shell{config: config, db: db, promptFmt: promptFmt}
+	newShell := Shell{config: config, db: shellDb, resultCache: newResultCache(config.ResultCacheSize), errorLog: newErrorLog(config), sessionStats: db.NewSessionStats(), output: output, redirect: redirect, promptFmt: promptFmt, outIsTerminal: isTerminal(outF), interrupts: make(chan struct{}, 1)}
 
 	dbCmdConfig := &shellcmd.DbCmdConfig{
-		Db:                db,
+		Db:                shellDb,
+		ResultCache:       newShell.resultCache,
+		GetErrorLog:       newShell.activeErrorLog,
+		SessionStats:      newShell.sessionStats,
+		GetSessionStats:   newShell.getSessionStats,
+		GetPrintOptions:   newShell.getPrintOptions,
+		Progress:          config.Progress,
 		OutF:              config.OutF,
 		ErrF:              config.ErrF,
+		Log:               config.Log,
 		SetInterruptShell: func() { newShell.state.interruptReadEvalPrintLoop = true },
 		SetMode:           func(mode enums.PrintMode) { newShell.state.printMode = mode },
 		GetMode: func() enums.PrintMode {
 			return newShell.state.printMode
 		},
+		SetCSVOptions: func(options db.CSVOptions) { newShell.state.csvOptions = options },
+		GetCSVOptions: func() db.CSVOptions {
+			return newShell.state.csvOptions
+		},
+		SetJSONOptions: func(options db.JSONOptions) { newShell.state.jsonOptions = options },
+		GetJSONOptions: func() db.JSONOptions {
+			return newShell.state.jsonOptions
+		},
+		SetTableOptions: func(options db.TableOptions) { newShell.state.tableOptions = options },
+		GetTableOptions: func() db.TableOptions {
+			return newShell.state.tableOptions
+		},
+		SetVariable: func(name string, value string) { newShell.state.variables[name] = value },
+		GetVariables: func() map[string]string {
+			return newShell.state.variables
+		},
+		SetExcludedTables: func(patterns []string) { newShell.state.excludedTables = patterns },
+		GetExcludedTables: func() []string {
+			return newShell.state.excludedTables
+		},
+		SetIdentifierQuoteStyle: func(style enums.IdentifierQuoteStyle) { newShell.state.identifierQuoteStyle = style },
+		GetIdentifierQuoteStyle: func() enums.IdentifierQuoteStyle {
+			return newShell.state.identifierQuoteStyle
+		},
+		SetDelta: func(enabled bool) { newShell.state.delta = enabled },
+		GetDelta: func() bool {
+			return newShell.state.delta
+		},
+		SetChanges: func(enabled bool) { newShell.state.changes = enabled },
+		GetChanges: func() bool {
+			return newShell.state.changes
+		},
+		SetTimer: func(enabled bool) { newShell.state.timer = enabled },
+		GetTimer: func() bool {
+			return newShell.state.timer
+		},
+		SetExplainFormat: func(enabled bool) { newShell.state.explainFormat = enabled },
+		GetExplainFormat: func() bool {
+			return newShell.state.explainFormat
+		},
+		SetNullValue: func(value string) { newShell.state.nullValue = &value },
+		GetNullValue: func() *string {
+			return newShell.state.nullValue
+		},
+		SetMaskPatterns: func(patterns []string) { newShell.state.maskPatterns = patterns },
+		GetMaskPatterns: func() []string {
+			return newShell.state.maskPatterns
+		},
+		SetNumberLocale: func(locale db.NumberLocale) { newShell.state.numberLocale = locale },
+		GetNumberLocale: func() db.NumberLocale {
+			return newShell.state.numberLocale
+		},
+		SetPrettyPragmas: func(enabled bool) { newShell.state.prettyPragmas = enabled },
+		GetPrettyPragmas: func() bool {
+			return newShell.state.prettyPragmas
+		},
+		SetSlowThreshold: func(threshold time.Duration) { newShell.state.slowThreshold = threshold },
+		GetSlowThreshold: func() time.Duration {
+			return newShell.state.slowThreshold
+		},
+		SetSlowLogFile: func(file string) error { return newShell.setSlowLogFile(file) },
+		GetSlowLogFile: func() string {
+			return newShell.state.slowLogFile
+		},
+		SetHeaderInterval: func(interval int) { newShell.state.headerInterval = interval },
+		GetHeaderInterval: func() int {
+			return newShell.state.headerInterval
+		},
+		SetPreserveHeaderCase: func(preserve bool) { newShell.state.preserveHeaderCase = preserve },
+		GetPreserveHeaderCase: func() bool {
+			return newShell.state.preserveHeaderCase
+		},
+		SetPageSize: func(size int) { newShell.state.pageSize = size },
+		GetPageSize: func() int {
+			return newShell.state.pageSize
+		},
+		TurnPage:               func(pages int) error { return newShell.turnPage(pages) },
+		SetOutputFile:          func(file string, once bool) error { return newShell.setOutputFile(file, once) },
+		EndOnceOutput:          func() { newShell.endOnceOutput(newShell.commandLevel) },
+		FileRoot:               config.FileRoot,
+		ColumnTransform:        config.ColumnTransform,
+		ColumnTransformInDumps: config.ColumnTransformInDumps,
+		IsOutTerminal:          func() bool { return newShell.isOutTerminal() },
+		Interrupts:             newShell.interrupts,
+		Confirm:                func(question string) bool { return newShell.confirm(question) },
+		ReadSecret:             func(prompt string) (string, error) { return newShell.readSecret(prompt) },
+		ReadStdin:              func() ([]byte, error) { return newShell.readStdin() },
+		GetHistory:             func() []string { return newShell.state.history },
+		ExecuteCommand:         func(command string) error { return newShell.executeCommand(command) },
+		SaveDatabaseSettings:   func() error { return newShell.saveDatabaseSettings() },
 	}
 	newShell.databaseCmd = shellcmd.CreateNewDatabaseRootCmd(dbCmdConfig)
+	newShell.completion = newCompletion(shellDb, newShell.getCommandNames())
 
 	err := newShell.resetState()
 	if err != nil {
@@ -78,15 +285,71 @@ func NewShell(config ShellConfig, db *db.Db) (*Shell, error) {
 	return &newShell, nil
 }
 
+// newOutputWriter makes the shell stop quietly, canceling the running query, when the reader of its output goes away
+func newOutputWriter(outF io.Writer, shellDb *db.Db) *db.BrokenPipeWriter {
+	return db.NewBrokenPipeWriter(outF, shellDb.CancelQuery)
+}
+
+func newResultCache(size int) *db.ResultCache {
+	if size <= 0 {
+		size = db.DEFAULT_RESULT_CACHE_SIZE
+	}
+	return db.NewResultCache(size)
+}
+
+func newErrorLog(config ShellConfig) *db.ErrorLog {
+	if !config.ContinueOnError {
+		return nil
+	}
+	return db.NewErrorLog(config.ErrF, config.JSONErrors, db.DEFAULT_ERROR_LOG_RETAINED)
+}
+
+// PrintErrorSummary reports the statements that failed with bail off, returning an error when there was any
+func (sh *Shell) PrintErrorSummary() error {
+	if sh.errorLog == nil || sh.errorLog.Count() == 0 {
+		return nil
+	}
+	sh.errorLog.PrintSummary()
+	return &shellerrors.StatementsFailedError{Count: sh.errorLog.Count()}
+}
+
+// PrintSessionStats writes to ErrF the statistics of the statements executed so far
+func (sh *Shell) PrintSessionStats() {
+	fmt.Fprintln(sh.config.ErrF, sh.getSessionStats())
+}
+
+func (sh *Shell) getSessionStats() db.SessionStatsSummary {
+	return sh.sessionStats.Summary(sh.output.Written(), sh.db.ConnectedTime())
+}
+
+// Run reads and executes the input until it ends or .quit is entered. The session statistics are printed when it
+// returns, if the input is a terminal or SessionStats is set
 func (sh *Shell) Run() error {
 	defer sh.state.readline.Close()
+	defer func() {
+		if sh.config.SessionStats || isTerminal(sh.config.InF) {
+			sh.PrintSessionStats()
+		}
+	}()
 
 	if !sh.config.QuietMode {
-		fmt.Print(sh.getWelcomeMessage())
+		fmt.Fprint(sh.output, sh.getWelcomeMessage())
 	}
 
-	for !sh.state.interruptReadEvalPrintLoop {
+	sh.mu.Lock()
+	sh.state.prompting = isTerminal(sh.config.InF)
+	sh.state.readingInput = true
+	sh.mu.Unlock()
+	defer func() {
+		sh.mu.Lock()
+		sh.state.readingInput = false
+		sh.mu.Unlock()
+	}()
+
+	for !sh.isInterrupted() {
+		stopIdleTimer := sh.startIdleTimer()
 		line, err := sh.state.readline.Readline()
+		stopIdleTimer()
 
 		if err == readline.ErrInterrupt {
 			if len(line) == 0 {
@@ -100,24 +363,95 @@ func (sh *Shell) Run() error {
 
 		line = strings.TrimSpace(line)
 
-		switch {
-		case len(line) == 0:
+		// The idle action may have run just before the input arrived
+		if len(line) == 0 || sh.isInterrupted() {
+			sh.saveHistory(line)
 			continue
-		case sh.state.insideMultilineStatement:
-			sh.appendStatementPartAndExecuteIfFinished(line)
-		case isCommand(line):
-			err = sh.executeCommand(line)
-			if err != nil {
-				db.PrintError(err, sh.config.ErrF)
-			}
-		default:
-			sh.appendStatementPartAndExecuteIfFinished(line)
 		}
-
+		sh.processLine(line)
+		// Saved once processed, to know whether it's a part of a statement typed over several lines
+		sh.saveHistory(line)
 	}
 	return nil
 }
 
+func (sh *Shell) processLine(line string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.reconnectIfDisconnected()
+	switch {
+	case sh.state.insideMultilineStatement:
+		sh.appendStatementPartAndExecuteIfFinished(line)
+	case isCommand(line):
+		err := sh.ignoreErrorIfOutputClosed(sh.executeCommand(line))
+		if err != nil {
+			db.PrintError(err, sh.config.ErrF)
+		}
+	default:
+		sh.appendStatementPartAndExecuteIfFinished(line)
+	}
+	sh.completion.setPendingStatement(strings.Join(sh.state.statementParts, "\n"))
+}
+
+// startIdleTimer takes the idle action if no input arrives before the idle timeout. It's only armed while waiting for
+// input, so statements can run for as long as they need
+func (sh *Shell) startIdleTimer() (stop func()) {
+	if sh.config.IdleTimeout <= 0 {
+		return func() {}
+	}
+
+	sh.mu.Lock()
+	generation := sh.idleTimerGeneration
+	sh.mu.Unlock()
+
+	timer := time.AfterFunc(sh.config.IdleTimeout, func() { sh.onIdle(generation) })
+	return func() {
+		timer.Stop()
+		// An onIdle that already fired and waits for mu sees the generation changed and does nothing
+		sh.mu.Lock()
+		sh.idleTimerGeneration++
+		sh.mu.Unlock()
+	}
+}
+
+func (sh *Shell) onIdle(generation uint64) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if generation != sh.idleTimerGeneration {
+		return
+	}
+
+	if sh.config.IdleAction == enums.IDLE_DISCONNECT {
+		sh.config.Log.Infof("Disconnected after %s without input. The connection will be reopened by the next statement", sh.config.IdleTimeout)
+		sh.db.Disconnect()
+		return
+	}
+
+	sh.config.Log.Infof("Closing the connection and exiting after %s without input", sh.config.IdleTimeout)
+	sh.db.Close()
+	sh.state.interruptReadEvalPrintLoop = true
+	// Closing the input makes the pending read return io.EOF
+	sh.cancelableStdin.Close()
+}
+
+// reconnectIfDisconnected reopens the connection closed by the idle action, reporting the session statements replayed
+func (sh *Shell) reconnectIfDisconnected() {
+	replayResults, err := sh.db.Reconnect()
+	if err != nil {
+		db.PrintError(err, sh.config.ErrF)
+		return
+	}
+	shellcmd.PrintSessionReplayResults(sh.config.Log, replayResults)
+}
+
+func (sh *Shell) isInterrupted() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.state.interruptReadEvalPrintLoop
+}
+
 func (sh *Shell) resetState() error {
 	var err error
 	sh.state.readline, err = sh.newReadline()
@@ -127,10 +461,35 @@ func (sh *Shell) resetState() error {
 
 	sh.state.insideMultilineStatement = false
 	sh.state.statementParts = make([]string, 0)
+	sh.state.historyParts = nil
 
 	sh.state.interruptReadEvalPrintLoop = false
 
 	sh.state.printMode = enums.TABLE_MODE
+	sh.state.csvOptions = db.CSVOptions{}
+	sh.state.jsonOptions = db.JSONOptions{}
+	sh.state.tableOptions = db.TableOptions{}
+
+	sh.state.variables = make(map[string]string)
+
+	sh.state.identifierQuoteStyle = enums.DOUBLE_QUOTE_STYLE
+
+	sh.state.excludedTables = nil
+
+	sh.state.timer = false
+	sh.state.changes = false
+	sh.state.delta = false
+	sh.state.explainFormat = false
+	sh.state.prettyPragmas = false
+	sh.state.nullValue = nil
+	sh.state.maskPatterns = nil
+	sh.state.numberLocale = db.NumberLocale{}
+	sh.state.headerInterval = 0
+	sh.state.preserveHeaderCase = false
+	sh.state.slowThreshold = 0
+	sh.state.slowLogFile = ""
+	sh.state.pageSize = 0
+	sh.state.pagedQuery = pagedQuery{}
 
 	return nil
 }
@@ -155,35 +514,153 @@ func (sac *shellAutoCompleter) Do(line []rune, pos int) (newLine [][]rune, lengh
 }
 
 func (sh *Shell) newReadline() (*readline.Instance, error) {
-	historyFile := GetHistoryFileBasedOnMode(sh.db.Uri, sh.config.HistoryMode, sh.config.HistoryName)
+	historyFile := sh.getHistoryFile()
+	historyLimit := sh.getHistoryLimit()
+	sh.state.history = readHistoryFile(historyFile, historyLimit)
 
 	config := &readline.Config{
 		Prompt:          sh.promptFmt(promptNewStatement),
 		InterruptPrompt: "^C",
 		HistoryFile:     historyFile,
+		HistoryLimit:    historyLimit,
 		EOFPrompt:       QUIT_COMMAND,
-		Stdin:           io.NopCloser(sh.config.InF),
-		Stdout:          sh.config.OutF,
+		Stdin:           sh.newReadlineStdin(),
+		Stdout:          sh.output,
 		Stderr:          sh.config.ErrF,
+		// Lines are saved by saveHistory, which leaves out repeated ones
+		DisableAutoSaveHistory: true,
 	}
 
 	if !sh.config.DisableAutoCompletion {
-		autoCompleter := &shellAutoCompleter{suggestCompletion: suggester.SuggestCompletion}
+		autoCompleter := &shellAutoCompleter{suggestCompletion: sh.completion.suggest}
 		config.AutoComplete = autoCompleter
 	}
 
 	return readline.NewEx(config)
 }
 
+// newReadlineStdin makes the input cancelable when the shell may need to stop waiting for it on its own
+func (sh *Shell) newReadlineStdin() io.ReadCloser {
+	if sh.config.IdleTimeout > 0 || sh.config.CancelableInput {
+		sh.cancelableStdin = readline.NewCancelableStdin(sh.config.InF)
+		return sh.cancelableStdin
+	}
+	return io.NopCloser(sh.config.InF)
+}
+
+// IsInteractive reports whether Run reads the input at an interactive prompt, from a terminal
+func (sh *Shell) IsInteractive() bool {
+	return isTerminal(sh.config.InF)
+}
+
+// isOutTerminal reports whether the results are shown on a terminal, not redirected by the shell or by .output
+func (sh *Shell) isOutTerminal() bool {
+	return sh.outIsTerminal && !sh.redirect.redirected()
+}
+
+func isTerminal(f interface{}) bool {
+	file, ok := f.(*os.File)
+	return ok && readline.IsTerminal(int(file.Fd()))
+}
+
+// confirm asks a yes or no question at the interactive prompt. Without one, like when the input is piped, nothing is
+// confirmed
+func (sh *Shell) confirm(question string) bool {
+	if !sh.state.prompting {
+		return false
+	}
+
+	rl := sh.state.readline
+	rl.SetPrompt(question + " [y/N] ")
+	defer rl.SetPrompt(sh.promptFmt(promptNewStatement))
+
+	answer, err := rl.Readline()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// readSecret reads a line at the interactive prompt without echoing it. Lines read this way never reach saveHistory
+func (sh *Shell) readSecret(prompt string) (string, error) {
+	if !sh.state.prompting {
+		return "", fmt.Errorf("it can only be entered at an interactive prompt")
+	}
+
+	secret, err := sh.state.readline.ReadPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// readStdin reads the input of the shell to its end, for .read -. While Run reads the input, its lines are the
+// shell's own, some of them possibly already buffered, so it isn't read then
+func (sh *Shell) readStdin() ([]byte, error) {
+	if sh.state.readingInput {
+		return nil, fmt.Errorf("the standard input is read by the shell for its statements. Pass the file with .read FILENAME instead, or run the shell with -e \".read -\"")
+	}
+	return io.ReadAll(sh.config.InF)
+}
+
+// saveHistory adds the line to the history, unless it only differs from the previous one in its layout. The lines of a
+// statement typed over several lines are saved as a single entry once it's complete, so recalling it recalls all of it
+func (sh *Shell) saveHistory(line string) {
+	if line == "" {
+		return
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.state.insideMultilineStatement {
+		sh.state.historyParts = append(sh.state.historyParts, line)
+		return
+	}
+	if len(sh.state.historyParts) > 0 {
+		line = db.JoinStatementLines(strings.Join(append(sh.state.historyParts, line), "\n"))
+		sh.state.historyParts = nil
+	}
+
+	key := historyKey(line)
+	if key == sh.state.lastHistoryKey {
+		return
+	}
+	sh.state.lastHistoryKey = key
+	sh.state.history = appendHistoryEntry(sh.state.history, line, sh.getHistoryLimit())
+	// Like readline, the history is kept even when it can't be written to its file
+	_ = sh.state.readline.SaveHistory(line)
+}
+
+// historyKey returns what tells history lines apart: their normalized SQL, or for dot commands, whose arguments aren't
+// SQL, their words
+func historyKey(line string) string {
+	if isCommand(line) {
+		return strings.Join(strings.Fields(line), " ")
+	}
+	return db.NormalizeStatements(line)
+}
+
 func isCommand(line string) bool {
 	return line[0] == '.'
 }
 
 func (sh *Shell) executeCommand(command string) error {
-	parts := strings.Fields(command)
+	parts, err := shellcmd.SplitCommandArguments(command)
+	if err != nil {
+		return err
+	}
+	shellcmd.ResetFlags(sh.databaseCmd)
 	sh.databaseCmd.SetArgs(parts)
 
-	err := sh.databaseCmd.Execute()
+	level := sh.commandLevel
+	sh.commandLevel++
+	err = sh.databaseCmd.Execute()
+	sh.commandLevel--
+	sh.endOnceOutput(level)
+	// Commands like .read, .import and .open may change the tables completed
+	sh.completion.invalidate()
 
 	if err != nil && strings.HasPrefix(err.Error(), "unknown command") {
 		rx := regexp.MustCompile(`"[^"]*"`)
@@ -196,11 +673,11 @@ func (sh *Shell) executeCommand(command string) error {
 func (sh *Shell) appendStatementPartAndExecuteIfFinished(statementPart string) {
 	sh.state.statementParts = append(sh.state.statementParts, statementPart)
 	completeStatement := strings.Join(sh.state.statementParts, "\n")
-	if isStatementFinished(completeStatement) {
+	if db.IsStatementFinished(completeStatement) {
 		sh.state.statementParts = make([]string, 0)
 		sh.state.insideMultilineStatement = false
 		sh.state.readline.SetPrompt(sh.promptFmt(promptNewStatement))
-		err := sh.db.ExecuteAndPrintStatements(completeStatement, sh.config.OutF, false, sh.state.printMode)
+		err := sh.ignoreErrorIfOutputClosed(sh.executeAndPrintStatements(completeStatement))
 		if err != nil {
 			db.PrintError(err, sh.state.readline.Stderr())
 		}
@@ -210,12 +687,113 @@ func (sh *Shell) appendStatementPartAndExecuteIfFinished(statementPart string) {
 	}
 }
 
+// ExecuteCommandOrStatements is safe to call from multiple goroutines. Calls on the same Shell run one at a time
 func (sh *Shell) ExecuteCommandOrStatements(commandOrStatements string) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	return sh.executeCommandOrStatements(commandOrStatements)
+}
+
+// ExecuteCommandOrStatementsWithBail is like ExecuteCommandOrStatements, but stops at the first error and returns it
+// even with bail off. It's meant for setup steps, like init statements, whose failure must abort
+func (sh *Shell) ExecuteCommandOrStatementsWithBail(commandOrStatements string) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.state.forceBail = true
+	defer func() { sh.state.forceBail = false }()
+	return sh.executeCommandOrStatements(commandOrStatements)
+}
+
+// activeErrorLog returns the error log statements are executed with, which is nil when they must stop at their first
+// error
+func (sh *Shell) activeErrorLog() *db.ErrorLog {
+	if sh.state.forceBail {
+		return nil
+	}
+	return sh.errorLog
+}
+
+func (sh *Shell) executeCommandOrStatements(commandOrStatements string) error {
+	sh.reconnectIfDisconnected()
 	if isCommand(commandOrStatements) {
-		return sh.executeCommand(commandOrStatements)
+		return sh.ignoreErrorIfOutputClosed(sh.executeCommand(commandOrStatements))
 	}
 
-	return sh.db.ExecuteAndPrintStatements(commandOrStatements, sh.config.OutF, false, sh.state.printMode)
+	return sh.ignoreErrorIfOutputClosed(sh.executeAndPrintStatements(commandOrStatements))
+}
+
+// ignoreErrorIfOutputClosed stops the shell without reporting errors once the reader of the output went away, like
+// standard Unix tools do when piped to `head`
+func (sh *Shell) ignoreErrorIfOutputClosed(err error) error {
+	if db.IsOutputClosed(sh.config.OutF) {
+		sh.state.interruptReadEvalPrintLoop = true
+		return nil
+	}
+	return err
+}
+
+func (sh *Shell) executeAndPrintStatements(statements string) error {
+	defer sh.endOnceOutput(sh.commandLevel)
+
+	statements, err := db.ExpandVariables(statements, sh.state.variables)
+	if err != nil {
+		return err
+	}
+	if db.ChangesSchema(statements) {
+		defer sh.completion.invalidate()
+	}
+
+	if sh.state.pageSize > 0 {
+		if query, ok := sh.getPageableQuery(statements); ok {
+			sh.state.pagedQuery = pagedQuery{statement: query, pageSize: sh.state.pageSize}
+			return sh.turnPage(1)
+		}
+	}
+
+	if sh.state.delta {
+		return sh.printStatementsWithDeltas(statements)
+	}
+	_, err = sh.printStatements(statements, sh.config.ExecutionSummary)
+	return err
+}
+
+// getPrintOptions returns the options statements are printed with, as set by the commands of the shell. The commands
+// printing statements of their own, like .read and .watch, get them too
+func (sh *Shell) getPrintOptions() db.PrintOptions {
+	options := db.PrintOptions{Mode: sh.state.printMode, ResultCache: sh.resultCache, ErrorLog: sh.activeErrorLog(), SessionStats: sh.sessionStats, QuoteStyle: sh.state.identifierQuoteStyle, CSV: sh.state.csvOptions, JSON: sh.state.jsonOptions, Table: sh.state.tableOptions, Timer: sh.state.timer, Changes: sh.state.changes, FailOnEmpty: sh.config.FailOnEmpty, FormatExplain: sh.state.explainFormat, PrettyPragmas: sh.state.prettyPragmas, NullValue: sh.state.nullValue, MaskPatterns: sh.state.maskPatterns, NumberLocale: sh.state.numberLocale, PreserveHeaderCase: sh.state.preserveHeaderCase, ColumnTransform: sh.config.ColumnTransform}
+	if sh.state.slowThreshold > 0 {
+		options.SlowThreshold = sh.state.slowThreshold
+		options.OnSlowStatement = sh.onSlowStatement
+	}
+	// The header is repeated for the people reading the output, never for the programs it's redirected to
+	if sh.isOutTerminal() {
+		options.HeaderInterval = sh.state.headerInterval
+	}
+	return options
+}
+
+// printStatements executes and prints the statements, returning their summary when withSummary is set
+func (sh *Shell) printStatements(statements string, withSummary bool) (*db.ExecutionSummary, error) {
+	options := sh.getPrintOptions()
+	options.FormatStats = &db.FormatStats{}
+	if withSummary {
+		options.Summary = &db.ExecutionSummary{}
+	}
+	err := sh.db.ExecuteAndPrintStatements(statements, sh.config.OutF, options)
+	if sh.config.ExecutionSummary && options.Summary != nil && (options.Summary.Statements > 1 || options.Summary.EmptyStatements > 0) && !db.IsOutputClosed(sh.config.OutF) {
+		fmt.Fprintln(sh.config.ErrF, options.Summary)
+	}
+
+	if count := options.FormatStats.UnrecognizedMaps(); count > 0 {
+		sh.config.Log.Warnf("%d value(s) in an unrecognized map format were rendered as JSON", count)
+	}
+	if count := options.FormatStats.SparklineFallbacks(); count > 0 {
+		sh.config.Log.Warnf("%d result(s) were shown as tables, as sparkline mode needs a label and a numeric column", count)
+	}
+
+	return options.Summary, err
 }
 
 func (sh *Shell) getWelcomeMessage() string {
@@ -225,13 +803,23 @@ func (sh *Shell) getWelcomeMessage() string {
 	return *sh.config.WelcomeMessage
 }
 
-func (sh *Shell) CancelQuery() {
-	sh.db.CancelQuery()
+// Stop cancels the statement running, if any, and makes Run return as if the input ended, without waiting for the next
+// line when the input is cancelable
+func (sh *Shell) Stop() {
+	sh.CancelQuery()
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.state.interruptReadEvalPrintLoop = true
+	if sh.cancelableStdin != nil {
+		sh.cancelableStdin.Close()
+	}
 }
 
-func isStatementFinished(statement string) bool {
-	_, splitExtraInfos := sqliteparserutils.SplitStatement(statement)
-	return !splitExtraInfos.IncompleteCreateTriggerStatement &&
-		!splitExtraInfos.IncompleteMultilineComment &&
-		splitExtraInfos.LastTokenType == sqliteparser.SQLiteLexerSCOL
+func (sh *Shell) CancelQuery() {
+	sh.db.CancelQuery()
+	select {
+	case sh.interrupts <- struct{}{}:
+	default:
+	}
 }
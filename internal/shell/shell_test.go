@@ -0,0 +1,654 @@
+package shell_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/internal/shell"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// newTestShell creates a shell on a new SQLite database, closed when the test finishes. The input and outputs left
+// unset in config are empty buffers
+func newTestShell(t *testing.T, config shell.ShellConfig) (*shell.Shell, *db.Db) {
+	t.Helper()
+	c := qt.New(t)
+	t.Setenv("HOME", t.TempDir())
+
+	shellDb, err := db.NewDb(t.TempDir()+"/test.sqlite", "")
+	c.Assert(err, qt.IsNil)
+	t.Cleanup(shellDb.Close)
+
+	if config.InF == nil {
+		config.InF = strings.NewReader("")
+	}
+	if config.OutF == nil {
+		config.OutF = new(bytes.Buffer)
+	}
+	if config.ErrF == nil {
+		config.ErrF = new(bytes.Buffer)
+	}
+	config.HistoryName = historyName
+	config.QuietMode = true
+	config.DisableAutoCompletion = true
+
+	sh, err := shell.NewShell(config, shellDb)
+	c.Assert(err, qt.IsNil)
+	return sh, shellDb
+}
+
+func TestExecuteCommandOrStatements_GivenManyGoroutinesSharingAShell_ExpectEveryResultPrintedWhole(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(bytes.Buffer)
+	errF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ErrF: errF})
+	c.Assert(sh.ExecuteCommandOrStatements(".mode csv"), qt.IsNil)
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*2)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- sh.ExecuteCommandOrStatements(fmt.Sprintf(".set V%d %d", i, i))
+			errs <- sh.ExecuteCommandOrStatements(fmt.Sprintf("SELECT {{V%d}} AS value;", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(errF.String(), qt.Equals, "")
+
+	results := strings.Split(strings.TrimSuffix(outF.String(), "\n"), "\n")
+	c.Assert(results, qt.HasLen, goroutines*2)
+	values := make([]string, 0, goroutines)
+	for i := 0; i < len(results); i += 2 {
+		c.Assert(results[i], qt.Equals, "value")
+		values = append(values, results[i+1])
+	}
+	expectedValues := make([]string, 0, goroutines)
+	for i := 0; i < goroutines; i++ {
+		expectedValues = append(expectedValues, fmt.Sprint(i))
+	}
+	sort.Strings(values)
+	sort.Strings(expectedValues)
+	c.Assert(values, qt.DeepEquals, expectedValues)
+}
+
+func TestCancelQuery_GivenConcurrentExecutions_ExpectNoDataRace(t *testing.T) {
+	sh, _ := newTestShell(t, shell.ShellConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = sh.ExecuteCommandOrStatements("SELECT 1;")
+		}()
+		go func() {
+			defer wg.Done()
+			sh.CancelQuery()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDotCell_GivenResultLargerThanCache_ExpectNoticeForRowsNotRetained(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ResultCacheSize: 10})
+
+	c.Assert(sh.ExecuteCommandOrStatements("SELECT 'abcdef' AS v UNION ALL SELECT 'ghijkl';"), qt.IsNil)
+	outF.Reset()
+
+	c.Assert(sh.ExecuteCommandOrStatements(".cell 1 v"), qt.IsNil)
+	c.Assert(outF.String(), qt.Equals, "v = abcdef\n")
+
+	err := sh.ExecuteCommandOrStatements(".cell 2 v")
+	c.Assert(err, qt.ErrorMatches, "row 2 wasn't retained because the last result exceeded the cache size of 10 bytes. Only the first 1 rows are available")
+}
+
+func newShellWithClosingPipe(t *testing.T, input string) (sh *shell.Shell, errF *bytes.Buffer, closeWriter func()) {
+	t.Helper()
+	c := qt.New(t)
+
+	r, w, err := os.Pipe()
+	c.Assert(err, qt.IsNil)
+	go func() {
+		// Like `head`, read the beginning of the output and go away
+		_, _ = r.Read(make([]byte, 16))
+		r.Close()
+	}()
+
+	errF = new(bytes.Buffer)
+	sh, _ = newTestShell(t, shell.ShellConfig{InF: strings.NewReader(input), OutF: w, ErrF: errF})
+	return sh, errF, func() { w.Close() }
+}
+
+const manyRowsStatement = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 20000) SELECT i, 'some padding text' FROM n;"
+
+const endlessStatement = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i, 'some padding text' FROM n;"
+
+func TestExecuteCommandOrStatements_GivenReaderClosesOutputEarly_ExpectNoErrorReported(t *testing.T) {
+	c := qt.New(t)
+	sh, errF, closeWriter := newShellWithClosingPipe(t, "")
+	defer closeWriter()
+
+	c.Assert(sh.ExecuteCommandOrStatements(manyRowsStatement), qt.IsNil)
+	c.Assert(errF.String(), qt.Equals, "")
+}
+
+func TestExecuteCommandOrStatements_GivenReaderClosesCSVOutputEarly_ExpectQueryStopped(t *testing.T) {
+	c := qt.New(t)
+	sh, errF, closeWriter := newShellWithClosingPipe(t, "")
+	defer closeWriter()
+
+	// Without streaming, the rows of the query would all be read before the first one is written
+	c.Assert(sh.ExecuteCommandOrStatements(".mode csv"), qt.IsNil)
+	c.Assert(sh.ExecuteCommandOrStatements(endlessStatement), qt.IsNil)
+	c.Assert(errF.String(), qt.Equals, "")
+}
+
+func TestRun_GivenReaderClosesOutputEarly_ExpectShellStopsQuietly(t *testing.T) {
+	c := qt.New(t)
+	sh, errF, closeWriter := newShellWithClosingPipe(t, manyRowsStatement+"\nSELECT 1;\n.tables\n")
+	defer closeWriter()
+
+	c.Assert(sh.Run(), qt.IsNil)
+	c.Assert(errF.String(), qt.Equals, "")
+}
+
+func TestDotDump_GivenProgressCallback_ExpectEventsInsteadOfProgressOutput(t *testing.T) {
+	c := qt.New(t)
+
+	events := make([]db.ProgressEvent, 0)
+	errF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{ErrF: errF, Progress: func(event db.ProgressEvent) { events = append(events, event) }})
+
+	c.Assert(sh.ExecuteCommandOrStatements("CREATE TABLE t (v TEXT); WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2500) INSERT INTO t SELECT CAST(X'00' AS TEXT) FROM n;"), qt.IsNil)
+	c.Assert(sh.ExecuteCommandOrStatements(".dump --progress"), qt.IsNil)
+	c.Assert(errF.String(), qt.Equals, "")
+
+	c.Assert(events, qt.HasLen, 5)
+	c.Assert(events[0], qt.Equals, db.ProgressEvent{Kind: db.TABLE_STARTED, Table: "t"})
+	c.Assert(events[1], qt.Equals, db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: "t", Rows: 1000, BinaryTextValues: 1000})
+	c.Assert(events[2], qt.Equals, db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: "t", Rows: 2000, BinaryTextValues: 2000})
+	c.Assert(events[3].Kind, qt.Equals, db.TABLE_FINISHED)
+	c.Assert(events[3].Rows, qt.Equals, int64(2500))
+	c.Assert(events[4].Kind, qt.Equals, db.OPERATION_FINISHED)
+	c.Assert(events[4].Tables, qt.Equals, 1)
+	c.Assert(events[4].Rows, qt.Equals, int64(2500))
+	c.Assert(events[4].BinaryTextValues, qt.Equals, int64(2500))
+}
+
+// syncBuffer is a bytes.Buffer that can be read while the shell writes to it from another goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRun_GivenIdleTimeout_WhenNoInput_ExpectConnectionClosedAndShellExited(t *testing.T) {
+	c := qt.New(t)
+
+	r, w, err := os.Pipe()
+	c.Assert(err, qt.IsNil)
+	defer w.Close()
+
+	errF := new(syncBuffer)
+	sh, shellDb := newTestShell(t, shell.ShellConfig{InF: r, ErrF: errF, IdleTimeout: 50 * time.Millisecond, IdleAction: enums.IDLE_EXIT})
+
+	c.Assert(sh.Run(), qt.IsNil)
+	c.Assert(errF.String(), qt.Equals, "Closing the connection and exiting after 50ms without input\n")
+	c.Assert(shellDb.TestConnection(), qt.IsNotNil)
+}
+
+func TestRun_GivenIdleActionDisconnect_WhenInputArrivesAfterTimeout_ExpectReconnectedWithSessionState(t *testing.T) {
+	c := qt.New(t)
+
+	r, w, err := os.Pipe()
+	c.Assert(err, qt.IsNil)
+
+	outF := new(syncBuffer)
+	errF := new(syncBuffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{InF: r, OutF: outF, ErrF: errF, IdleTimeout: 50 * time.Millisecond, IdleAction: enums.IDLE_DISCONNECT})
+
+	runErr := make(chan error)
+	go func() { runErr <- sh.Run() }()
+
+	_, err = w.WriteString(".mode csv\nPRAGMA foreign_keys=ON;\n")
+	c.Assert(err, qt.IsNil)
+	notice := "Disconnected after 50ms without input. The connection will be reopened by the next statement\n"
+	for deadline := time.Now().Add(5 * time.Second); errF.String() != notice && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(errF.String(), qt.Equals, notice)
+
+	_, err = w.WriteString("PRAGMA foreign_keys;\n")
+	c.Assert(err, qt.IsNil)
+	for deadline := time.Now().Add(5 * time.Second); !strings.HasSuffix(outF.String(), "1\n") && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Close()
+
+	c.Assert(<-runErr, qt.IsNil)
+	c.Assert(strings.TrimSpace(outF.String()), qt.Equals, "foreign_keys\n1")
+	c.Assert(errF.String(), qt.Equals, notice+"Replayed: PRAGMA foreign_keys=ON\n")
+}
+
+func TestExecuteCommandOrStatements_GivenBailOffAndManyFailingStatements_ExpectBoundedMemoryAndAccurateCount(t *testing.T) {
+	c := qt.New(t)
+
+	const failingStatements = 100000
+	script := strings.Repeat("INSERT INTO missing VALUES (1);\n", failingStatements) + "SELECT 1 AS last;"
+
+	for _, jsonErrors := range []bool{false, true} {
+		outF := new(bytes.Buffer)
+		errF := new(countingWriter)
+		sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ErrF: errF, ContinueOnError: true, JSONErrors: jsonErrors})
+		c.Assert(sh.ExecuteCommandOrStatements(".mode csv"), qt.IsNil)
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		c.Assert(sh.ExecuteCommandOrStatements(script), qt.IsNil)
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		c.Assert(int64(after.HeapAlloc)-int64(before.HeapAlloc) < 4*1024*1024, qt.IsTrue, qt.Commentf("heap grew from %d to %d bytes", before.HeapAlloc, after.HeapAlloc))
+
+		c.Assert(errF.lines, qt.Equals, failingStatements)
+		c.Assert(outF.String(), qt.Equals, "last\n1\n")
+		c.Assert(sh.PrintErrorSummary(), qt.ErrorMatches, "100000 statement\\(s\\) failed")
+	}
+}
+
+// countingWriter counts the lines written without retaining them
+type countingWriter struct {
+	lines int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+func TestDotWatch_GivenCancelQuery_ExpectWatchStoppedAndShellKept(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF})
+
+	done := make(chan error)
+	go func() { done <- sh.ExecuteCommandOrStatements(".watch 10ms SELECT 1") }()
+	time.Sleep(50 * time.Millisecond)
+	sh.CancelQuery()
+
+	select {
+	case err := <-done:
+		c.Assert(err, qt.IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the watch wasn't stopped by CancelQuery")
+	}
+	c.Assert(strings.Contains(outF.String(), "iteration 1\n"), qt.IsTrue)
+
+	c.Assert(sh.ExecuteCommandOrStatements("SELECT 2;"), qt.IsNil)
+}
+
+func TestDotDump_GivenHugeBlobs_ExpectPeakMemoryProportionalToOneValue(t *testing.T) {
+	c := qt.New(t)
+
+	const blobSize = 32 * 1024 * 1024
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	errF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: io.Discard, ErrF: errF})
+	c.Assert(sh.ExecuteCommandOrStatements(fmt.Sprintf("CREATE TABLE t (data BLOB); INSERT INTO t VALUES (zeroblob(%d)), (zeroblob(%d)), (zeroblob(%d));", blobSize, blobSize, blobSize)), qt.IsNil)
+
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	baseline := memStats.HeapAlloc
+
+	var peak uint64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			var memStats runtime.MemStats
+			runtime.ReadMemStats(&memStats)
+			if memStats.HeapAlloc > peak {
+				peak = memStats.HeapAlloc
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	err := sh.ExecuteCommandOrStatements(fmt.Sprintf(".dump --warn-value-size %d", blobSize-1))
+	close(stop)
+	<-sampled
+
+	c.Assert(err, qt.IsNil)
+	// The blobs are read and hex encoded one at a time, so the peak stays a few values above the baseline, far from
+	// the size of the whole dump
+	c.Assert(peak-baseline < 6*blobSize, qt.IsTrue, qt.Commentf("peak of %d bytes over the baseline", peak-baseline))
+	c.Assert(strings.Count(errF.String(), fmt.Sprintf("has a value of %d bytes", blobSize)), qt.Equals, 3)
+}
+
+func TestDotDump_GivenThousandsOfTables_ExpectSchemaReadInASingleQuery(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(countingWriter)
+	sh, shellDb := newTestShell(t, shell.ShellConfig{OutF: outF})
+
+	const tables = 2000
+	var statements strings.Builder
+	for i := 0; i < tables; i++ {
+		fmt.Fprintf(&statements, "CREATE TABLE t%d (id INTEGER PRIMARY KEY); CREATE INDEX t%d_id ON t%d (id);\n", i, i, i)
+	}
+	c.Assert(sh.ExecuteCommandOrStatements(statements.String()), qt.IsNil)
+
+	queriesBefore := shellDb.QueryCount()
+	c.Assert(sh.ExecuteCommandOrStatements(".dump"), qt.IsNil)
+
+	// A query lists the virtual tables, another the tables, another reads all their schemas, each table has its records
+	// selected and a last query lists the views, between the BEGIN and COMMIT of the read transaction. Reading the
+	// schema of each table on its own would take as many queries again
+	c.Assert(shellDb.QueryCount()-queriesBefore, qt.Equals, uint64(tables+6))
+	c.Assert(outF.lines, qt.Equals, 3+2*tables)
+}
+
+func TestDotDump_GivenTableDroppedByAnotherClientWhileDumping_ExpectSnapshotDumped(t *testing.T) {
+	c := qt.New(t)
+
+	var otherDb *db.Db
+	var dropErr error
+	progress := func(event db.ProgressEvent) {
+		if event.Kind == db.TABLE_STARTED && event.Table == "a" {
+			dropErr = otherDb.ExecuteAndPrintStatements("DROP TABLE b;", io.Discard, db.PrintOptions{Mode: enums.CSV_MODE})
+		}
+	}
+	outF := new(bytes.Buffer)
+	sh, shellDb := newTestShell(t, shell.ShellConfig{OutF: outF, Progress: progress})
+	c.Assert(sh.ExecuteCommandOrStatements("PRAGMA journal_mode=WAL; CREATE TABLE a (id INTEGER); CREATE TABLE b (id INTEGER); INSERT INTO b VALUES (1);"), qt.IsNil)
+
+	otherDb, err := db.NewDb(shellDb.Uri, "")
+	c.Assert(err, qt.IsNil)
+	defer otherDb.Close()
+
+	// The dump reads the database as it was when it started, so the table dropped meanwhile is dumped whole
+	outF.Reset()
+	c.Assert(sh.ExecuteCommandOrStatements(".dump"), qt.IsNil)
+	c.Assert(dropErr, qt.IsNil)
+	c.Assert(outF.String(), qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE a (id INTEGER);\nCREATE TABLE b (id INTEGER);\nINSERT INTO b VALUES (1);\nCOMMIT;\n")
+
+	outF.Reset()
+	c.Assert(sh.ExecuteCommandOrStatements(".tables"), qt.IsNil)
+	c.Assert(strings.TrimSpace(outF.String()), qt.Equals, "a")
+}
+
+func TestFileRoot_GivenPathsInsideAndOutside_ExpectOnlyThePathsInsideAccessed(t *testing.T) {
+	c := qt.New(t)
+
+	root := t.TempDir()
+	outside := t.TempDir() + "/outside"
+	c.Assert(os.Mkdir(outside, 0755), qt.IsNil)
+	c.Assert(os.WriteFile(root+"/inside.sql", []byte("SELECT 'inside' AS v;"), 0644), qt.IsNil)
+	c.Assert(os.WriteFile(outside+"/outside.sql", []byte("SELECT 'outside' AS v;"), 0644), qt.IsNil)
+	c.Assert(os.Mkdir(root+"/dir", 0755), qt.IsNil)
+	c.Assert(os.Symlink(outside, root+"/link"), qt.IsNil)
+	c.Assert(os.Symlink(root+"/inside.sql", root+"/dir/inside-link.sql"), qt.IsNil)
+
+	outF := new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, FileRoot: root})
+	c.Assert(sh.ExecuteCommandOrStatements(".mode csv"), qt.IsNil)
+
+	for _, path := range []string{"inside.sql", root + "/inside.sql", "dir/../inside.sql", "dir/inside-link.sql"} {
+		c.Assert(sh.ExecuteCommandOrStatements(".read "+path), qt.IsNil, qt.Commentf(path))
+	}
+	c.Assert(outF.String(), qt.Equals, strings.Repeat("v\ninside\n", 4))
+
+	// link/.. is the parent of the outside directory once the symlink is resolved, not the root
+	for _, path := range []string{"../" + filepath.Base(filepath.Dir(outside)) + "/outside/outside.sql", outside + "/outside.sql", "link/outside.sql", "link/../outside/outside.sql"} {
+		err := sh.ExecuteCommandOrStatements(".read " + path)
+		c.Assert(err, qt.ErrorMatches, `access to .* is refused, as it's outside the directory file access is confined to`, qt.Commentf(path))
+	}
+	c.Assert(sh.ExecuteCommandOrStatements(".slow-log link/slow.log"), qt.ErrorMatches, `access to link/slow.log is refused.*`)
+	c.Assert(sh.ExecuteCommandOrStatements(".slow-log logs/../slow.log"), qt.IsNil)
+}
+
+func TestJSONMode_GivenAMillionRows_ExpectFlatMemory(t *testing.T) {
+	c := qt.New(t)
+
+	const rows = 1000000
+	outF := new(countingWriter)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ResultCacheSize: 1})
+	c.Assert(sh.ExecuteCommandOrStatements(".mode json --pretty"), qt.IsNil)
+
+	peak, err := executeMeasuringPeakMemory(sh, fmt.Sprintf("WITH RECURSIVE r(id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM r WHERE id < %d) SELECT id, 'row ' || id AS name FROM r;", rows))
+	c.Assert(err, qt.IsNil)
+	// Each of the rows takes 4 lines, with the [ and ] lines around them
+	c.Assert(outF.lines, qt.Equals, 4*rows+2)
+	// Holding the rows would take hundreds of megabytes
+	c.Assert(peak < 16*1024*1024, qt.IsTrue, qt.Commentf("peak of %d bytes over the baseline", peak))
+}
+
+func TestTableMode_GivenAMillionRows_ExpectFlatMemory(t *testing.T) {
+	c := qt.New(t)
+
+	const rows = 1000000
+	outF := new(countingWriter)
+	sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ResultCacheSize: 1})
+
+	peak, err := executeMeasuringPeakMemory(sh, fmt.Sprintf("WITH RECURSIVE r(id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM r WHERE id < %d) SELECT id, 'row ' || id AS name FROM r;", rows))
+	c.Assert(err, qt.IsNil)
+	// Each of the rows takes a line, after the header
+	c.Assert(outF.lines, qt.Equals, rows+1)
+	c.Assert(peak < 16*1024*1024, qt.IsTrue, qt.Commentf("peak of %d bytes over the baseline", peak))
+}
+
+// executeMeasuringPeakMemory executes the statements, returning the peak of the heap while they ran over the heap
+// before them
+func executeMeasuringPeakMemory(sh *shell.Shell, statements string) (uint64, error) {
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	baseline := memStats.HeapAlloc
+
+	var peak uint64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			var memStats runtime.MemStats
+			runtime.ReadMemStats(&memStats)
+			if memStats.HeapAlloc > peak {
+				peak = memStats.HeapAlloc
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	err := sh.ExecuteCommandOrStatements(statements)
+	close(stop)
+	<-sampled
+
+	if peak < baseline {
+		return 0, err
+	}
+	return peak - baseline, err
+}
+
+func TestColumnTransform_GivenTransformedColumns_ExpectRawValuesTransformedInEveryModeButNotInDumps(t *testing.T) {
+	c := qt.New(t)
+
+	statusNames := map[int64]string{1: "active", 2: "banned"}
+	var transformedTypes []string
+	transform := func(column string, value interface{}) (interface{}, bool) {
+		switch column {
+		case "status":
+			transformedTypes = append(transformedTypes, fmt.Sprintf("%T", value))
+			status, ok := value.(int64)
+			if !ok {
+				return nil, false
+			}
+			return statusNames[status], true
+		case "email":
+			return "***", true
+		}
+		return nil, false
+	}
+
+	dump := func(inDumps bool) string {
+		outF := new(bytes.Buffer)
+		sh, _ := newTestShell(t, shell.ShellConfig{OutF: outF, ColumnTransform: transform, ColumnTransformInDumps: inDumps})
+		c.Assert(sh.ExecuteCommandOrStatements("CREATE TABLE users (id INTEGER, email TEXT, status INTEGER); INSERT INTO users VALUES (1, 'a@b.c', 1), (2, 'd@e.f', 2);"), qt.IsNil)
+
+		c.Assert(sh.ExecuteCommandOrStatements(".mode csv"), qt.IsNil)
+		c.Assert(sh.ExecuteCommandOrStatements("SELECT * FROM users;"), qt.IsNil)
+		c.Assert(outF.String(), qt.Equals, "id,email,status\n1,***,active\n2,***,banned\n")
+		c.Assert(transformedTypes, qt.DeepEquals, []string{"int64", "int64"})
+
+		outF.Reset()
+		c.Assert(sh.ExecuteCommandOrStatements(".mode json"), qt.IsNil)
+		c.Assert(sh.ExecuteCommandOrStatements("SELECT id, status FROM users WHERE id = 2;"), qt.IsNil)
+		c.Assert(outF.String(), qt.Equals, `[{"id":2,"status":"banned"}]`+"\n")
+
+		outF.Reset()
+		c.Assert(sh.ExecuteCommandOrStatements(".dump users"), qt.IsNil)
+		transformedTypes = nil
+		return outF.String()
+	}
+
+	c.Assert(dump(false), qt.Contains, "INSERT INTO users VALUES (1, 'a@b.c', 1);")
+	c.Assert(dump(true), qt.Contains, "INSERT INTO users VALUES (1, '***', 'active');")
+}
+
+func TestDotDump_GivenChunkedSelect_ExpectRecordsSelectedAChunkAtATime(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(bytes.Buffer)
+	sh, shellDb := newTestShell(t, shell.ShellConfig{OutF: outF})
+	c.Assert(sh.ExecuteCommandOrStatements("CREATE TABLE t (id INTEGER PRIMARY KEY, v); WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10) INSERT INTO t SELECT i, i FROM n;"), qt.IsNil)
+
+	queriesBefore := shellDb.QueryCount()
+	c.Assert(sh.ExecuteCommandOrStatements(".dump --chunked-select 100"), qt.IsNil)
+	singleChunkQueries := shellDb.QueryCount() - queriesBefore
+	singleChunkDump := outF.String()
+
+	outF.Reset()
+	queriesBefore = shellDb.QueryCount()
+	c.Assert(sh.ExecuteCommandOrStatements(".dump --chunked-select 4"), qt.IsNil)
+
+	// The 10 records take chunks of 4, 4 and 2 records, the last one being short of a chunk
+	c.Assert(shellDb.QueryCount()-queriesBefore, qt.Equals, singleChunkQueries+2)
+	c.Assert(outF.String(), qt.Equals, singleChunkDump)
+}
+
+func TestRun_GivenTriggerTypedOverSeveralLines_ExpectItContinuedUntilItsEnd(t *testing.T) {
+	c := qt.New(t)
+
+	input := `.mode csv
+CREATE TABLE t (x, y, z);
+CREATE TRIGGER t_set AFTER INSERT ON t
+BEGIN
+  UPDATE t SET x = 1;
+  UPDATE t SET y = CASE WHEN new.y THEN 'end;' END;
+.not a command;
+END;
+INSERT INTO t VALUES (0, 1, 2);
+SELECT * FROM t;
+.history 3
+`
+	outF, errF := new(bytes.Buffer), new(bytes.Buffer)
+	sh, _ := newTestShell(t, shell.ShellConfig{InF: strings.NewReader(input), OutF: outF, ErrF: errF, HistoryFile: t.TempDir() + "/history"})
+	c.Assert(sh.Run(), qt.IsNil)
+
+	// Inside the trigger, lines starting with a dot are part of its body rather than commands
+	c.Assert(errF.String(), qt.Equals, `Error: near ".": syntax error`+"\n")
+
+	outF.Reset()
+	errF.Reset()
+	sh, _ = newTestShell(t, shell.ShellConfig{InF: strings.NewReader(strings.Replace(input, ".not a command;\n", "", 1)), OutF: outF, ErrF: errF, HistoryFile: t.TempDir() + "/history"})
+	c.Assert(sh.Run(), qt.IsNil)
+
+	c.Assert(errF.String(), qt.Equals, "")
+	// The lines of the trigger are saved as a single history entry once its END is reached, so they were all read as
+	// the continuation of the statement
+	c.Assert(outF.String(), qt.Equals, "\n\n\nx,y,z\n1,end;,2\n"+
+		"    3  CREATE TRIGGER t_set AFTER INSERT ON t BEGIN UPDATE t SET x = 1; UPDATE t SET y = CASE WHEN new.y THEN 'end;' END; END;\n"+
+		"    4  INSERT INTO t VALUES (0, 1, 2);\n    5  SELECT * FROM t;\n")
+}
+
+func TestRun_GivenCancelQueryWhileAQueryRuns_ExpectInterruptedAndTheShellKept(t *testing.T) {
+	c := qt.New(t)
+
+	inR, inW := io.Pipe()
+	outF, errF := new(syncBuffer), new(syncBuffer)
+	sh, shellDb := newTestShell(t, shell.ShellConfig{InF: inR, OutF: outF, ErrF: errF})
+	started := make(chan struct{})
+	shellDb.SetQueryTrace(func(query string) {
+		if strings.HasPrefix(query, "WITH") {
+			close(started)
+		}
+	})
+	done := make(chan error)
+	go func() { done <- sh.Run() }()
+
+	fmt.Fprintln(inW, ".mode csv")
+	fmt.Fprintln(inW, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n;")
+	// SQLite drops the interrupts given before a query starts stepping, which follows its trace closely
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	sh.CancelQuery()
+	go func() {
+		fmt.Fprintln(inW, "SELECT 2 AS two;")
+		inW.Close()
+	}()
+
+	select {
+	case err := <-done:
+		c.Assert(err, qt.IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the query wasn't interrupted by CancelQuery")
+	}
+	c.Assert(errF.String(), qt.Equals, "Interrupted\n")
+	c.Assert(outF.String(), qt.Equals, "two\n2\n")
+}
@@ -0,0 +1,51 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/internal/shellcmd"
+)
+
+// setSlowLogFile sets the file the slow statements are appended to, checking first that it can be written
+func (sh *Shell) setSlowLogFile(file string) error {
+	if file != "" {
+		var err error
+		file, err = shellcmd.ResolveFilePath(sh.config.FileRoot, file)
+		if err != nil {
+			return err
+		}
+		logFile, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open the slow log: %w", err)
+		}
+		logFile.Close()
+	}
+	sh.state.slowLogFile = file
+	return nil
+}
+
+// onSlowStatement notes a statement that took longer than the slow threshold, and appends it to the slow log when set.
+// The log is opened for each statement, so it's never left open
+func (sh *Shell) onSlowStatement(timing db.StatementTiming) {
+	sh.config.Log.Notef("statement took %s, exceeding slow threshold of %s", db.FormatDuration(timing.Duration), sh.state.slowThreshold)
+
+	if sh.state.slowLogFile == "" {
+		return
+	}
+	logFile, err := os.OpenFile(sh.state.slowLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		db.PrintError(fmt.Errorf("failed to write the slow log: %w", err), sh.config.ErrF)
+		return
+	}
+	defer logFile.Close()
+	// A line per statement, so the statement's own line breaks are turned into spaces
+	statement := strings.Join(strings.Split(timing.Statement, "\n"), " ")
+	_, err = fmt.Fprintf(logFile, "%s\t%s\t%d rows\t%s\n", time.Now().Format(time.RFC3339), db.FormatDuration(timing.Duration), timing.RowsReturned, statement)
+	if err != nil {
+		db.PrintError(fmt.Errorf("failed to write the slow log: %w", err), sh.config.ErrF)
+	}
+}
@@ -0,0 +1,260 @@
+package shellcmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+const adviseHeuristicsNote = "-- Heuristic suggestions from the query plan and the conditions of the query, not from SQLite's expert extension. Check them with EXPLAIN QUERY PLAN"
+
+// queryPlanScanPattern matches the full scans of tables in query plans, like "SCAN users" or "SCAN u USING INDEX i"
+var queryPlanScanPattern = regexp.MustCompile(`^SCAN (\S+)(?: |$)`)
+
+// queryPlanAutomaticIndexPattern matches the automatic indexes SQLite builds for a single statement, like
+// "SEARCH o USING AUTOMATIC COVERING INDEX (name=?)", whose columns a lasting index would have
+var queryPlanAutomaticIndexPattern = regexp.MustCompile(`^SEARCH (\S+) USING AUTOMATIC (?:COVERING |PARTIAL )*INDEX \((.+)\)`)
+
+var adviseCmd = &cobra.Command{
+	Use:   ".advise SQL...",
+	Short: "Suggest indexes for the full table scans of a query",
+	Long: `Suggest CREATE INDEX statements for a SELECT statement, from the tables its EXPLAIN QUERY PLAN scans in full and
+for which SQLite builds automatic indexes. The columns suggested are the ones the WHERE clause and the joins compare to
+values or to other tables, the ones compared for equality first and then a single one compared with a range. They're
+heuristics, which only understand comparisons, BETWEEN and IN joined by AND: the statements with anything else, like OR
+or subqueries, are reported as unable to be analyzed. The indexes aren't created.
+
+The quotes around arguments are removed, so quote SQL when it holds quotes of its own, like
+.advise "SELECT * FROM t WHERE name = 'x'".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		statement := strings.Join(args, " ")
+		splitStatements, err := db.SplitStatements(statement)
+		if err != nil {
+			return err
+		}
+		if len(splitStatements) != 1 {
+			return fmt.Errorf("expected a single statement to analyze, got %d", len(splitStatements))
+		}
+		statement = splitStatements[0].Text
+
+		predicates, err := db.GetQueryPredicates(statement)
+		if err != nil {
+			return fmt.Errorf("unable to analyze: %w", err)
+		}
+		steps, err := getQueryPlanSteps(config, statement)
+		if err != nil {
+			return err
+		}
+
+		suggestions, err := getIndexSuggestions(config, predicates, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(config.OutF, adviseHeuristicsNote)
+		for _, suggestion := range suggestions {
+			fmt.Fprintln(config.OutF, suggestion)
+		}
+		return nil
+	},
+}
+
+func init() {
+	adviseCmd.Flags().SetInterspersed(false)
+}
+
+// getIndexSuggestions returns a CREATE INDEX statement for each table scanned in full or searched with an automatic
+// index, or a comment telling why there's none
+func getIndexSuggestions(config *DbCmdConfig, predicates db.QueryPredicates, steps []queryPlanStep) ([]string, error) {
+	tableColumns, err := getPredicateColumnsByTable(config, predicates)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]string, 0)
+	suggested := make(map[string]bool)
+	for _, step := range steps {
+		var reference string
+		var columns []string
+		if match := queryPlanAutomaticIndexPattern.FindStringSubmatch(step.detail); match != nil {
+			reference = match[1]
+			columns = getAutomaticIndexColumns(match[2])
+		} else if match := queryPlanScanPattern.FindStringSubmatch(step.detail); match != nil {
+			reference = match[1]
+		} else {
+			continue
+		}
+
+		table, ok := findQueryTable(predicates.Tables, reference)
+		if !ok || strings.HasPrefix(strings.ToLower(table.Name), "sqlite_") || suggested[table.ReferenceName()] {
+			continue
+		}
+		suggested[table.ReferenceName()] = true
+		if compared, ok := tableColumns[table.ReferenceName()]; ok && columns == nil {
+			columns = compared.getIndexColumns()
+		}
+		if len(columns) == 0 {
+			suggestions = append(suggestions, fmt.Sprintf("-- No index suggested for the scan of %s, which no condition of the WHERE clause or of a join filters", table.ReferenceName()))
+			continue
+		}
+		suggestions = append(suggestions, getCreateIndexStatement(table.Name, columns, config.GetIdentifierQuoteStyle()))
+	}
+
+	if len(suggestions) == 0 {
+		suggestions = append(suggestions, "-- No index suggested, as the query plan scans no table in full")
+	}
+	return suggestions, nil
+}
+
+// predicateColumns are the columns of a table compared by the conditions of a query, in the order they appear
+type predicateColumns struct {
+	equality []string
+	ranges   []string
+}
+
+// getIndexColumns returns the columns compared for equality, followed by the first one compared with a range, as an
+// index can only be searched by a range on its last column used
+func (c predicateColumns) getIndexColumns() []string {
+	columns := append([]string{}, c.equality...)
+	for _, column := range c.ranges {
+		if !containsName(columns, column) {
+			return append(columns, column)
+		}
+	}
+	return columns
+}
+
+func containsName(names []string, name string) bool {
+	for _, existingName := range names {
+		if db.EqualNames(existingName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPredicateColumnsByTable returns the columns compared by the predicates by the reference name of their table. The
+// columns that aren't qualified are found in the only table of the query, or else in the one table having them
+func getPredicateColumnsByTable(config *DbCmdConfig, predicates db.QueryPredicates) (map[string]*predicateColumns, error) {
+	columnsByTable := make(map[string]*predicateColumns)
+	var tableColumnNames map[string][]string
+	for _, predicate := range predicates.Predicates {
+		var table db.QueryTable
+		var found bool
+		switch {
+		case predicate.Table != "":
+			table, found = findQueryTable(predicates.Tables, predicate.Table)
+		case len(predicates.Tables) == 1:
+			table, found = predicates.Tables[0], true
+		default:
+			if tableColumnNames == nil {
+				var err error
+				if tableColumnNames, err = getQueryTableColumnNames(config, predicates.Tables); err != nil {
+					return nil, err
+				}
+			}
+			table, found = findColumnTable(predicates.Tables, tableColumnNames, predicate.Column)
+		}
+		if !found {
+			continue
+		}
+
+		columns, ok := columnsByTable[table.ReferenceName()]
+		if !ok {
+			columns = &predicateColumns{}
+			columnsByTable[table.ReferenceName()] = columns
+		}
+		switch {
+		case predicate.Range:
+			columns.ranges = append(columns.ranges, predicate.Column)
+		case !containsName(columns.equality, predicate.Column):
+			columns.equality = append(columns.equality, predicate.Column)
+		}
+	}
+	return columnsByTable, nil
+}
+
+// findQueryTable finds the table of the query referred to by its alias, or by its name when it has none
+func findQueryTable(tables []db.QueryTable, reference string) (db.QueryTable, bool) {
+	for _, table := range tables {
+		if db.EqualNames(table.ReferenceName(), reference) {
+			return table, true
+		}
+	}
+	return db.QueryTable{}, false
+}
+
+// findColumnTable finds the one table of the query having the column, failing when there's none or several of them
+func findColumnTable(tables []db.QueryTable, tableColumnNames map[string][]string, column string) (db.QueryTable, bool) {
+	var columnTable db.QueryTable
+	found := false
+	for _, table := range tables {
+		if !containsName(tableColumnNames[table.Name], column) {
+			continue
+		}
+		if found {
+			return db.QueryTable{}, false
+		}
+		columnTable, found = table, true
+	}
+	return columnTable, found
+}
+
+func getQueryTableColumnNames(config *DbCmdConfig, tables []db.QueryTable) (map[string][]string, error) {
+	tableColumnNames := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		if _, ok := tableColumnNames[table.Name]; ok {
+			continue
+		}
+		result, err := config.Db.ExecuteStatements(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", db.EscapeSingleQuotes(table.Name)))
+		if err != nil {
+			return nil, err
+		}
+		statementResult := <-result.StatementResultCh
+		if statementResult.Err != nil {
+			return nil, statementResult.Err
+		}
+		if tableColumnNames[table.Name], err = readFirstColumn(statementResult); err != nil {
+			return nil, err
+		}
+	}
+	return tableColumnNames, nil
+}
+
+// getAutomaticIndexColumns returns the columns of the constraints of an automatic index, like "a=? AND b>?"
+func getAutomaticIndexColumns(constraints string) []string {
+	columns := make([]string, 0)
+	for _, constraint := range strings.Split(constraints, " AND ") {
+		column := strings.TrimSpace(constraint[:strings.IndexAny(constraint+"=", "=<>")])
+		if column != "" && !containsName(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func getCreateIndexStatement(tableName string, columns []string, quoteStyle enums.IdentifierQuoteStyle) string {
+	quotedColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quotedColumns = append(quotedColumns, quoteIdentifierIfNeeded(column, quoteStyle))
+	}
+	indexName := tableName + "_" + strings.Join(columns, "_")
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s);", quoteIdentifierIfNeeded(indexName, quoteStyle), quoteIdentifierIfNeeded(tableName, quoteStyle), strings.Join(quotedColumns, ", "))
+}
+
+func quoteIdentifierIfNeeded(name string, quoteStyle enums.IdentifierQuoteStyle) string {
+	if db.NeedsEscaping(name) {
+		return db.QuoteIdentifier(name, quoteStyle)
+	}
+	return name
+}
@@ -0,0 +1,105 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SplitArguments splits a dot command line into its arguments the way sqlite3 does. Arguments are separated by
+// whitespace, unless they start with a quote: 'single quoted' arguments are taken literally and "double quoted" ones
+// accept the \n, \t, \\ and \" escapes
+func SplitArguments(line string) ([]string, error) {
+	args, _, err := splitArguments([]rune(line), -1)
+	return args, err
+}
+
+// rawValueCommands are the commands whose last argument is SQL, with the number of arguments before it. That argument
+// is the rest of the line as written, so the quotes of its SQL strings are kept
+var rawValueCommands = map[string]int{
+	".set": 1,
+}
+
+// SplitCommandArguments splits a dot command line like SplitArguments, except for the commands of rawValueCommands,
+// whose last argument is kept as written
+func SplitCommandArguments(line string) ([]string, error) {
+	runes := []rune(line)
+	command, _, err := splitArguments(runes, 1)
+	if err != nil || len(command) == 0 {
+		return command, err
+	}
+	argsBeforeValue, ok := rawValueCommands[command[0]]
+	if !ok {
+		return SplitArguments(line)
+	}
+
+	args, value, err := splitArguments(runes, argsBeforeValue+1)
+	if err != nil {
+		return nil, err
+	}
+	if value != "" {
+		args = append(args, value)
+	}
+	return args, nil
+}
+
+// splitArguments splits up to maxArgs arguments from the beginning of the line, or all of them when maxArgs is
+// negative, and returns the rest of the line trimmed
+func splitArguments(runes []rune, maxArgs int) ([]string, string, error) {
+	args := make([]string, 0)
+
+	i := 0
+	for i < len(runes) && len(args) != maxArgs {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var arg strings.Builder
+		switch quote := runes[i]; quote {
+		case '\'', '"':
+			start := i
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if quote == '"' && runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					arg.WriteString(unescapeArgumentRune(runes[i]))
+					continue
+				}
+				arg.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, "", fmt.Errorf("unterminated quoted argument: %s", string(runes[start:]))
+			}
+			i++
+		default:
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				arg.WriteRune(runes[i])
+			}
+		}
+		args = append(args, arg.String())
+	}
+
+	return args, strings.TrimSpace(string(runes[i:])), nil
+}
+
+// QuoteArgument quotes an argument so that SplitArguments returns it unchanged
+func QuoteArgument(arg string) string {
+	if !strings.ContainsRune(arg, '\'') {
+		return "'" + arg + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+}
+
+func unescapeArgumentRune(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case '\\', '"':
+		return string(r)
+	default:
+		return "\\" + string(r)
+	}
+}
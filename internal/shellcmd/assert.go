@@ -0,0 +1,124 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var assertCmd = &cobra.Command{
+	Use:   ".assert ROWS N SQL... | .assert EQUALS VALUE SQL...",
+	Short: "Fail unless a statement returns the expected result",
+	Long: `Check the result of a statement, for scripts testing a database. ROWS N fails unless SQL returns exactly N rows,
+and EQUALS VALUE fails unless it returns a single row with a single column equal to VALUE, compared as it's shown in
+table mode, so NULL is written NULL. A failing assertion shows the expected and the actual result. It stops the script
+like any error does, and with --bail=false the script goes on and the shell exits with an error at the end.
+
+The quotes around arguments are removed, so quote VALUE and SQL when they hold spaces or quotes of their own, like
+.assert ROWS 1 "SELECT * FROM t WHERE name = 'x'".`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		checkRows, err := getAssertion(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		statement := strings.Join(args[2:], " ")
+
+		rows, err := queryAssertRows(config, statement)
+		if err == nil {
+			err = checkRows(rows)
+		}
+		if err == nil {
+			return nil
+		}
+
+		err = fmt.Errorf("assertion failed: %w", err)
+		// With bail off the failure is counted like the one of a statement, so the script goes on
+		if errorLog := config.GetErrorLog(); errorLog != nil {
+			errorLog.Record(".assert "+strings.Join(args, " "), err)
+			return nil
+		}
+		return err
+	},
+}
+
+func init() {
+	assertCmd.Flags().SetInterspersed(false)
+}
+
+// getAssertion returns the check of the rows for an assertion kind and its expected value
+func getAssertion(kind string, expected string) (func(rows [][]string) error, error) {
+	switch strings.ToUpper(kind) {
+	case "ROWS":
+		expectedCount, err := strconv.Atoi(expected)
+		if err != nil || expectedCount < 0 {
+			return nil, fmt.Errorf("invalid row count \"%s\". Give the number of rows expected", expected)
+		}
+		return func(rows [][]string) error {
+			if len(rows) != expectedCount {
+				return fmt.Errorf("expected %d row(s), got %d", expectedCount, len(rows))
+			}
+			return nil
+		}, nil
+	case "EQUALS":
+		return func(rows [][]string) error {
+			if len(rows) != 1 || len(rows[0]) != 1 {
+				return fmt.Errorf("expected a single value %s, got %d row(s) of %d column(s)", QuoteArgument(expected), len(rows), getColumnCount(rows))
+			}
+			if rows[0][0] != expected {
+				return fmt.Errorf("expected %s, got %s", QuoteArgument(expected), QuoteArgument(rows[0][0]))
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown assertion \"%s\". Use ROWS or EQUALS", kind)
+}
+
+func getColumnCount(rows [][]string) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	return len(rows[0])
+}
+
+// queryAssertRows executes a single statement, returning its rows formatted as in table mode
+func queryAssertRows(config *DbCmdConfig, statement string) ([][]string, error) {
+	splitStatements, err := db.SplitStatements(statement)
+	if err != nil {
+		return nil, err
+	}
+	if len(splitStatements) != 1 {
+		return nil, fmt.Errorf("expected a single statement to check, got %d", len(splitStatements))
+	}
+
+	result, err := config.Db.ExecuteStatements(statement)
+	if err != nil {
+		return nil, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+
+	rows := make([][]string, 0)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return nil, rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, formattedRow)
+	}
+	return rows, nil
+}
@@ -0,0 +1,215 @@
+package shellcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// defaultPlatformApiUrl is the Turso platform API the branches of databases are found with, unless TURSO_API_URL
+// names another one
+const defaultPlatformApiUrl = "https://api.turso.tech"
+
+const platformApiTimeout = 10 * time.Second
+
+var branchCmd = &cobra.Command{
+	Use:   ".branch list|switch NAME",
+	Short: "List the branches of this database or switch to one",
+	Long: `List the branches of this Turso database, created from it or from the database it was itself created from
+with "turso db create --from-db", or switch to the branch NAME. Switching reconnects like .open, with the auth token of
+the current connection unless --auth is given: session statements are replayed and the settings are kept.
+
+Branches are found with the Turso platform API, which needs an API token, created with "turso auth api-tokens mint",
+in TURSO_API_TOKEN and the organization of the database in TURSO_ORG.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("missing argument. Use list or switch NAME")
+		}
+		switch args[0] {
+		case "list":
+			return cobra.ExactArgs(1)(cmd, args)
+		case "switch":
+			if len(args) != 2 {
+				return fmt.Errorf("switch needs the NAME of a branch")
+			}
+			return nil
+		}
+		return fmt.Errorf("invalid argument \"%s\". Valid arguments are list and switch", args[0])
+	},
+	ValidArgs: []string{"list", "switch"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		dbUri := config.Db.Uri
+		if !db.IsUrl(dbUri) {
+			return fmt.Errorf("branches belong to Turso databases, and the current database is a local file")
+		}
+		dbUrl, err := url.Parse(dbUri)
+		if err != nil {
+			return err
+		}
+		databases, err := listPlatformDatabases()
+		if err != nil {
+			return err
+		}
+		branches, current := getBranches(databases, dbUrl.Host)
+		if current == "" {
+			return fmt.Errorf("no database of organization %s is served at %s. Check TURSO_ORG", os.Getenv("TURSO_ORG"), dbUrl.Host)
+		}
+
+		if args[0] == "list" {
+			data := make([][]string, 0, len(branches))
+			for _, branch := range branches {
+				name := branch.Name
+				if name == current {
+					name += " (current)"
+				}
+				data = append(data, []string{name, branch.getParentName(), "libsql://" + branch.Hostname})
+			}
+			db.PrintTable(config.OutF, []string{"name", "branched from", "url"}, data)
+			return nil
+		}
+
+		var branch *platformDatabase
+		for i := range branches {
+			if branches[i].Name == args[1] {
+				branch = &branches[i]
+			}
+		}
+		if branch == nil {
+			return fmt.Errorf("no branch %s of database %s. List them with .branch list", args[1], current)
+		}
+		authToken, err := cmd.Flags().GetString("auth")
+		if err != nil {
+			return err
+		}
+
+		// The branch is reached like the current database, with the same scheme and query, holding the auth token
+		dbUrl.Host = branch.Hostname
+		replayResults, err := config.Db.Open(dbUrl.String(), authToken)
+		if err != nil {
+			return err
+		}
+		PrintSessionReplayResults(config.Log, replayResults)
+		return nil
+	},
+}
+
+func init() {
+	branchCmd.Flags().String("auth", "", "Add a JWT Token for the branch.")
+}
+
+// platformDatabase is a database listed by the Turso platform API, with the database it was created from when it's a
+// branch
+type platformDatabase struct {
+	Name     string `json:"Name"`
+	Hostname string `json:"Hostname"`
+	Parent   *struct {
+		Name string `json:"name"`
+	} `json:"parent"`
+}
+
+func (d platformDatabase) getParentName() string {
+	if d.Parent == nil {
+		return ""
+	}
+	return d.Parent.Name
+}
+
+// listPlatformDatabases returns the databases of the organization TURSO_ORG, explaining which credentials are
+// missing when they aren't all set
+func listPlatformDatabases() ([]platformDatabase, error) {
+	token, organization := os.Getenv("TURSO_API_TOKEN"), os.Getenv("TURSO_ORG")
+	missing := make([]string, 0, 2)
+	if token == "" {
+		missing = append(missing, `TURSO_API_TOKEN to an API token, created with "turso auth api-tokens mint NAME"`)
+	}
+	if organization == "" {
+		missing = append(missing, "TURSO_ORG to the organization of the database")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("branches are found with the Turso platform API, which needs credentials. Set %s", strings.Join(missing, ", and "))
+	}
+
+	apiUrl := os.Getenv("TURSO_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultPlatformApiUrl
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), platformApiTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(apiUrl, "/")+"/v1/organizations/"+url.PathEscape(organization)+"/databases", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the Turso platform API: %w", err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("the Turso platform API refused TURSO_API_TOKEN (HTTP %d). Check that it's an API token of organization %s", response.StatusCode, organization)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("the Turso platform API has no organization %s. Check TURSO_ORG", organization)
+	default:
+		return nil, fmt.Errorf("the Turso platform API failed to list the databases (HTTP %d)", response.StatusCode)
+	}
+
+	var body struct {
+		Databases []platformDatabase `json:"databases"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to read the databases listed by the Turso platform API: %w", err)
+	}
+	return body.Databases, nil
+}
+
+// getBranches returns the database served at host with its branches, the databases created from it or from its
+// own branches, in the order they were listed. When that database is a branch, the other branches of the database
+// it was created from are returned with it. current is the name of the database served at host, or "" when none is
+func getBranches(databases []platformDatabase, host string) (branches []platformDatabase, current string) {
+	byName := make(map[string]platformDatabase, len(databases))
+	for _, database := range databases {
+		byName[database.Name] = database
+		if strings.EqualFold(database.Hostname, host) {
+			current = database.Name
+		}
+	}
+	if current == "" {
+		return nil, ""
+	}
+
+	// getRoot follows the databases the branches were created from, as long as they're listed, guarding against cycles
+	getRoot := func(name string) string {
+		for seen := map[string]bool{name: true}; ; {
+			parent, ok := byName[byName[name].getParentName()]
+			if !ok || seen[parent.Name] {
+				return name
+			}
+			name = parent.Name
+			seen[name] = true
+		}
+	}
+	root := getRoot(current)
+	for _, database := range databases {
+		if getRoot(database.Name) == root {
+			branches = append(branches, database)
+		}
+	}
+	return branches, current
+}
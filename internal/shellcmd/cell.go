@@ -0,0 +1,74 @@
+package shellcmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var cellCmd = &cobra.Command{
+	Use:   ".cell ROW COL",
+	Short: "Show the full value of a cell from the last result",
+	Long: `Show the full value of a cell from the last displayed result. ROW starts at 1 and COL is either a column
+number starting at 1 or a column name. Use --out to write the raw value, like a blob, to a file.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		row, err := strconv.Atoi(args[0])
+		if err != nil || row < 1 {
+			return fmt.Errorf("invalid row \"%s\". Rows are numbered starting at 1", args[0])
+		}
+
+		column := config.ResultCache.ColumnIndex(args[1])
+		if column == -1 {
+			column, err = strconv.Atoi(args[1])
+			if err != nil || column < 1 {
+				return fmt.Errorf("no column \"%s\" in the last result", args[1])
+			}
+			column--
+		}
+
+		columnName, value, ok := config.ResultCache.Cell(row-1, column)
+		if !ok {
+			retainedRows, incomplete := config.ResultCache.Len()
+			if incomplete && row > retainedRows {
+				return fmt.Errorf("row %d wasn't retained because the last result exceeded the cache size of %d bytes. Only the first %d rows are available", row, config.ResultCache.MaxSize(), retainedRows)
+			}
+			return fmt.Errorf("no cell at row %s, column %s in the last result", args[0], args[1])
+		}
+
+		outFile, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return err
+		}
+		if outFile != "" {
+			content, err := db.GetCellBytes(value)
+			if err != nil {
+				return err
+			}
+			outFile, err = ResolveFilePath(config.FileRoot, outFile)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(outFile, content, 0644)
+		}
+
+		formattedValue, err := db.FormatData([]interface{}{value}, db.TABLE)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(config.OutF, "%s = %s\n", columnName, formattedValue[0])
+		return nil
+	},
+}
+
+func init() {
+	cellCmd.Flags().String("out", "", "Write the raw value of the cell to this file")
+}
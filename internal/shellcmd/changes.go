@@ -0,0 +1,43 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var changesCmd = &cobra.Command{
+	Use:   ".changes ?on|off?",
+	Short: "Show how many rows each statement changes",
+	Long: `Show how many rows each INSERT, UPDATE, DELETE and REPLACE changes with a line like "3 rows affected" after
+it, with the rowid of the last row inserted by INSERT and REPLACE, like "1 row affected, last insert rowid 42". Tables
+are followed by the number of rows they have, like "(42 rows)". JSON mode writes the changes as an object like
+{"rows_affected":3}, and SQL mode as a comment. Without an argument, the current setting is shown.
+
+Statements with a RETURNING clause, or starting with WITH, show the rows they return rather than the ones they change.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetChanges() {
+				fmt.Fprintln(config.OutF, "changes: on")
+			} else {
+				fmt.Fprintln(config.OutF, "changes: off")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "on", "off":
+			config.SetChanges(args[0] == "on")
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on and off", args[0])
+		}
+		return nil
+	},
+}
@@ -0,0 +1,157 @@
+package shellcmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+var columnsCmd = &cobra.Command{
+	Use:   ".columns TABLE ?PATTERN?",
+	Short: "List the column names of a table or view",
+	Long: `List the column names of the table or view TABLE, one per line, or only the ones matching the glob PATTERN,
+like *_id, ignoring case. TABLE may be qualified with its schema, like aux.users, to list the columns of a table of an
+attached database. The names needing it are quoted with the style set with .quote, so they can be copied into a query.
+
+With --join, the names are written on a single line separated by commas, and with --select as a SELECT statement
+reading them from TABLE.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		join, err := cmd.Flags().GetBool("join")
+		if err != nil {
+			return err
+		}
+		selectStatement, err := cmd.Flags().GetBool("select")
+		if err != nil {
+			return err
+		}
+		if join && selectStatement {
+			return fmt.Errorf("--join and --select can't be used together")
+		}
+		pattern := "*"
+		if len(args) == 2 {
+			pattern = args[1]
+		}
+		if err := db.ValidateMaskPattern(pattern); err != nil {
+			return fmt.Errorf("invalid pattern \"%s\": %w", pattern, err)
+		}
+
+		schemaName, objectName, err := findColumnsObjectName(config, args[0])
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf("SELECT name FROM pragma_table_xinfo('%s') WHERE hidden <> 1 ORDER BY cid", db.EscapeSingleQuotes(objectName))
+		if schemaName != "" {
+			query = fmt.Sprintf("SELECT name FROM pragma_table_xinfo('%s', '%s') WHERE hidden <> 1 ORDER BY cid", db.EscapeSingleQuotes(objectName), db.EscapeSingleQuotes(schemaName))
+		}
+		rows, err := queryAssertRows(config, query)
+		if err != nil {
+			return err
+		}
+
+		quoteStyle := config.GetIdentifierQuoteStyle()
+		columnNames := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if matched, _ := path.Match(db.NormalizeName(strings.ToLower(pattern)), db.NormalizeName(strings.ToLower(row[0]))); matched {
+				columnNames = append(columnNames, quoteIdentifierIfNeeded(row[0], quoteStyle))
+			}
+		}
+		if len(columnNames) == 0 {
+			return fmt.Errorf("no column of %s matches %s", args[0], pattern)
+		}
+
+		switch {
+		case selectStatement:
+			from := quoteIdentifierIfNeeded(objectName, quoteStyle)
+			if schemaName != "" {
+				from = quoteIdentifierIfNeeded(schemaName, quoteStyle) + "." + from
+			}
+			fmt.Fprintf(config.OutF, "SELECT %s FROM %s;\n", strings.Join(columnNames, ", "), from)
+		case join:
+			fmt.Fprintln(config.OutF, strings.Join(columnNames, ", "))
+		default:
+			fmt.Fprintln(config.OutF, strings.Join(columnNames, "\n"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	columnsCmd.Flags().Bool("join", false, "Write the names on a single line, separated by commas")
+	columnsCmd.Flags().Bool("select", false, "Write a SELECT statement reading the columns from the table")
+}
+
+// findColumnsObjectName returns the schema, empty for the main database when none is given, and the name of the table
+// or view named name, matched as db.EqualNames does. A name of the main database containing a dot is taken whole
+// before being split into its schema and its name
+func findColumnsObjectName(config *DbCmdConfig, name string) (schemaName string, objectName string, err error) {
+	objectName, found, err := findSchemaObjectName(config, "", name)
+	if err != nil || found {
+		return "", objectName, err
+	}
+
+	schemaName, unqualifiedName := db.SplitQualifiedName(name)
+	if schemaName == "" {
+		objectName, found, err = findSchemaObjectName(config, "", unqualifiedName)
+	} else {
+		schemaName, err = findAttachedSchemaName(config, schemaName)
+		if err != nil {
+			return "", "", err
+		}
+		objectName, found, err = findSchemaObjectName(config, schemaName, unqualifiedName)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !found {
+		return "", "", fmt.Errorf("no such table or view: %s", name)
+	}
+	return schemaName, objectName, nil
+}
+
+func findAttachedSchemaName(config *DbCmdConfig, schemaName string) (string, error) {
+	rows, err := queryAssertRows(config, "SELECT name FROM pragma_database_list")
+	if err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		if db.EqualNames(row[0], schemaName) {
+			return row[0], nil
+		}
+	}
+	return "", fmt.Errorf("unknown database %s", schemaName)
+}
+
+// findSchemaObjectName returns the name of the table or view named name in the schema, or in the main database when
+// it's empty, the exact name winning over the ones differing only in case or normal form
+func findSchemaObjectName(config *DbCmdConfig, schemaName string, name string) (string, bool, error) {
+	master := "sqlite_master"
+	if schemaName != "" {
+		master = db.QuoteIdentifier(schemaName, enums.DOUBLE_QUOTE_STYLE) + ".sqlite_master"
+	}
+	rows, err := queryAssertRows(config, "SELECT name FROM "+master+" WHERE type IN ('table', 'view')")
+	if err != nil {
+		return "", false, err
+	}
+	for _, row := range rows {
+		if row[0] == name {
+			return name, true, nil
+		}
+	}
+	for _, row := range rows {
+		if db.EqualNames(row[0], name) {
+			return row[0], true, nil
+		}
+	}
+	return "", false, nil
+}
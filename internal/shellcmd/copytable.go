@@ -0,0 +1,247 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// copyBatchRows is the number of rows copied by each transaction of .copy-table
+const copyBatchRows = db.PROGRESS_ROWS_INTERVAL
+
+var copyTableCmd = &cobra.Command{
+	Use:   ".copy-table SRC DST",
+	Short: "Create a copy of a table, with its rows",
+	Long: `Create the table DST with the schema of the table SRC and copy the rows of SRC into it, only the ones matching
+--where when given, or none with --no-data. The CREATE TABLE statement of SRC is rewritten with the name DST, replacing
+it also in its foreign keys referencing SRC itself and in the names of its constraints embedding it, like pk_SRC.
+
+The rows are copied in transactions of 1000 rows, so a copy interrupted with Ctrl-C keeps the rows copied so far, with
+the progress shown once there's more than one transaction. Generated columns are computed again rather than copied.
+
+With --with-indexes, the indexes and triggers of SRC are created on DST too, once its rows are copied, named after
+theirs with _DST appended.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		condition, err := cmd.Flags().GetString("where")
+		if err != nil {
+			return err
+		}
+		noData, err := cmd.Flags().GetBool("no-data")
+		if err != nil {
+			return err
+		}
+		withIndexes, err := cmd.Flags().GetBool("with-indexes")
+		if err != nil {
+			return err
+		}
+		if noData && condition != "" {
+			return fmt.Errorf("--where and --no-data can't be used together")
+		}
+
+		srcName, found, err := findTableName(config, args[0])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no such table: %s", args[0])
+		}
+		dstName := args[1]
+		if err := checkSchemaNameFree(config, dstName); err != nil {
+			return err
+		}
+		if condition != "" {
+			if err := checkWhereClause(config, srcName, condition); err != nil {
+				return err
+			}
+		}
+
+		rows, err := queryAssertRows(config, fmt.Sprintf("SELECT type, name, sql FROM sqlite_master WHERE tbl_name='%s' COLLATE NOCASE AND sql IS NOT NULL ORDER BY type <> 'table', rowid", db.EscapeSingleQuotes(srcName)))
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 || rows[0][0] != "table" {
+			return fmt.Errorf("no such table: %s", args[0])
+		}
+		createTable := rows[0][2]
+		if strings.HasPrefix(strings.ToUpper(createTable), "CREATE VIRTUAL") {
+			return fmt.Errorf("table %s is a virtual table, which can't be copied", srcName)
+		}
+
+		quoteStyle := config.GetIdentifierQuoteStyle()
+		if err := executeStatementsSilently(config, db.RenameTableInStatement(createTable, srcName, dstName, "", quoteStyle)+";"); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", dstName, err)
+		}
+
+		if !noData {
+			copied, total, err := copyTableRows(config, srcName, dstName, condition)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(config.OutF, "Copied %d rows from %s to %s\n", copied, srcName, dstName)
+			if copied < total {
+				return fmt.Errorf("interrupted, so only %d of the %d rows were copied into %s", copied, total, dstName)
+			}
+		}
+
+		if !withIndexes {
+			return nil
+		}
+		for _, row := range rows[1:] {
+			if row[0] != "index" && row[0] != "trigger" {
+				continue
+			}
+			name, err := getCopyObjectName(config, row[1], dstName)
+			if err != nil {
+				return err
+			}
+			if err := executeStatementsSilently(config, db.RenameTableInStatement(row[2], srcName, dstName, name, quoteStyle)+";"); err != nil {
+				return fmt.Errorf("failed to create the %s %s of table %s: %w", row[0], name, dstName, err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	copyTableCmd.Flags().String("where", "", "Copy only the rows matching this SQL condition")
+	copyTableCmd.Flags().Bool("no-data", false, "Create the table without copying any rows")
+	copyTableCmd.Flags().Bool("with-indexes", false, "Create the indexes and triggers of the table on the copy too")
+}
+
+// getCopyObjectName returns the name of the copy of an index or trigger on the copy of its table: its name with _ and
+// the name of the table appended, and a number after that when it's taken already
+func getCopyObjectName(config *DbCmdConfig, name string, tableName string) (string, error) {
+	copyName := name + "_" + tableName
+	for number := 2; ; number++ {
+		err := checkSchemaNameFree(config, copyName)
+		if err == nil {
+			return copyName, nil
+		}
+		if !strings.HasPrefix(err.Error(), "there is already") {
+			return "", err
+		}
+		copyName = fmt.Sprintf("%s_%s_%d", name, tableName, number)
+	}
+}
+
+// copyTableRows copies the rows of the table srcName matching the condition, when it's not empty, into the table
+// dstName, in transactions of copyBatchRows rows. Each transaction selects the rows after the last one copied in the
+// order of the rowid, or of the primary key for tables without rowid. When interrupted, the current transaction is
+// rolled back, so fewer than total rows are copied
+func copyTableRows(config *DbCmdConfig, srcName string, dstName string, condition string) (copied int64, total int64, err error) {
+	// A Ctrl-C given before the copy started isn't meant for it
+	select {
+	case <-config.Interrupts:
+	default:
+	}
+
+	quoteStyle := config.GetIdentifierQuoteStyle()
+	src := quoteIdentifierIfNeeded(srcName, quoteStyle)
+	dst := quoteIdentifierIfNeeded(dstName, quoteStyle)
+	where := "1"
+	if condition != "" {
+		where = "(" + condition + ")"
+	}
+
+	counts, err := queryAssertRows(config, "SELECT count(*) FROM "+src+" WHERE "+where)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err = strconv.ParseInt(counts[0][0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Generated columns can't be inserted into, and are computed again in the copy
+	columnRows, err := queryAssertRows(config, fmt.Sprintf("SELECT name, pk FROM pragma_table_xinfo('%s') WHERE hidden = 0 ORDER BY cid", db.EscapeSingleQuotes(srcName)))
+	if err != nil {
+		return 0, 0, err
+	}
+	columnNames := make([]string, 0, len(columnRows))
+	primaryKey := make([]string, len(columnRows))
+	for _, row := range columnRows {
+		column := quoteIdentifierIfNeeded(row[0], quoteStyle)
+		columnNames = append(columnNames, column)
+		if position, err := strconv.Atoi(row[1]); err == nil && position > 0 && position <= len(primaryKey) {
+			primaryKey[position-1] = column
+		}
+	}
+	columns := strings.Join(columnNames, ", ")
+	rowidColumn, err := getRowidColumn(config, srcName)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	progress := config.Progress
+	if progress == nil {
+		progress = newCopyProgressPrinter(config.Log, total)
+	}
+	start := time.Now()
+	progress(db.ProgressEvent{Kind: db.TABLE_STARTED, Table: dstName})
+	lastKey := ""
+	for copied < total {
+		if isImportInterrupted(config) {
+			break
+		}
+
+		var query string
+		batchRows := int64(copyBatchRows)
+		if rowidColumn == "" {
+			orderBy := strings.Join(strings.Fields(strings.Join(primaryKey, " ")), ", ")
+			query = fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d OFFSET %d", columns, src, where, orderBy, copyBatchRows, copied)
+		} else {
+			batchWhere := where
+			if lastKey != "" {
+				batchWhere += " AND " + rowidColumn + " > " + lastKey
+			}
+			bounds, err := queryAssertRows(config, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT 1 OFFSET %d", rowidColumn, src, batchWhere, rowidColumn, copyBatchRows-1))
+			if err != nil {
+				return copied, total, err
+			}
+			if len(bounds) > 0 {
+				lastKey = bounds[0][0]
+				batchWhere += " AND " + rowidColumn + " <= " + lastKey
+			} else {
+				batchRows = total - copied
+			}
+			query = fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s", columns, src, batchWhere, rowidColumn)
+		}
+
+		if err := executeStatementsSilently(config, fmt.Sprintf("BEGIN;\nINSERT INTO %s (%s) %s;\nCOMMIT;", dst, columns, query)); err != nil {
+			_ = executeStatementsSilently(config, "ROLLBACK;")
+			if isImportInterrupted(config) {
+				break
+			}
+			return copied, total, fmt.Errorf("failed to copy the rows of table %s into %s: %w", srcName, dstName, err)
+		}
+		copied += batchRows
+		if copied > total {
+			copied = total
+		}
+		progress(db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: dstName, Rows: copied})
+	}
+	progress(db.ProgressEvent{Kind: db.TABLE_FINISHED, Table: dstName, Rows: copied})
+	progress(db.ProgressEvent{Kind: db.OPERATION_FINISHED, Tables: 1, Rows: copied, Duration: time.Since(start)})
+	return copied, total, nil
+}
+
+// newCopyProgressPrinter returns the progress callback of .copy-table in the CLI, which shows the rows copied after each
+// transaction when there's more than one
+func newCopyProgressPrinter(log *db.Logger, total int64) db.ProgressFunc {
+	return func(event db.ProgressEvent) {
+		if event.Kind == db.ROWS_PROCESSED && total > copyBatchRows {
+			log.Infof("  %d of %d rows", event.Rows, total)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package shellcmd
 import (
 	"context"
 	"io"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
@@ -14,12 +16,125 @@ import (
 type dbCtx struct{}
 
 type DbCmdConfig struct {
-	OutF              io.Writer
-	ErrF              io.Writer
-	Db                *db.Db
+	OutF io.Writer
+	ErrF io.Writer
+	// Log writes the diagnostics of the commands, like warnings and progress. Errors are returned instead
+	Log         *db.Logger
+	Db          *db.Db
+	ResultCache *db.ResultCache
+	GetErrorLog func() *db.ErrorLog
+	// SessionStats adds up the statements executed by the commands, and GetSessionStats returns the statistics of the
+	// session so far
+	SessionStats    *db.SessionStats
+	GetSessionStats func() db.SessionStatsSummary
+	// GetPrintOptions returns the options the shell prints statements with, as set by .mode, .mask, .numfmt and the
+	// other commands, for the commands printing statements of their own
+	GetPrintOptions   func() db.PrintOptions
+	Progress          db.ProgressFunc
 	SetInterruptShell func()
 	SetMode           func(mode enums.PrintMode)
 	GetMode           func() enums.PrintMode
+	// SetCSVOptions and GetCSVOptions hold the options given to .mode csv
+	SetCSVOptions func(options db.CSVOptions)
+	GetCSVOptions func() db.CSVOptions
+	// SetJSONOptions and GetJSONOptions hold the options given to .mode json
+	SetJSONOptions func(options db.JSONOptions)
+	GetJSONOptions func() db.JSONOptions
+	// SetTableOptions and GetTableOptions hold the options given to .mode table
+	SetTableOptions func(options db.TableOptions)
+	GetTableOptions func() db.TableOptions
+
+	SetVariable  func(name string, value string)
+	GetVariables func() map[string]string
+	// SetExcludedTables and GetExcludedTables hold the LIKE patterns of the tables hidden by .exclude
+	SetExcludedTables func(patterns []string)
+	GetExcludedTables func() []string
+
+	SetIdentifierQuoteStyle func(style enums.IdentifierQuoteStyle)
+	GetIdentifierQuoteStyle func() enums.IdentifierQuoteStyle
+
+	// SetTimer and GetTimer hold whether .timer is on
+	SetTimer func(enabled bool)
+	GetTimer func() bool
+	// SetChanges and GetChanges hold whether .changes is on
+	SetChanges func(enabled bool)
+	GetChanges func() bool
+	// SetDelta and GetDelta hold whether .delta is on
+	SetDelta func(enabled bool)
+	GetDelta func() bool
+	// SetExplainFormat and GetExplainFormat hold whether .explain-fmt is on
+	SetExplainFormat func(enabled bool)
+	GetExplainFormat func() bool
+
+	// SetNullValue and GetNullValue hold the placeholder of NULL values set by .nullvalue, nil until it's set
+	SetNullValue func(value string)
+	GetNullValue func() *string
+	// SetMaskPatterns and GetMaskPatterns hold the glob patterns of the columns masked by .mask
+	SetMaskPatterns func(patterns []string)
+	GetMaskPatterns func() []string
+
+	// SetNumberLocale and GetNumberLocale hold the locale numbers are grouped by in table mode, as set by .numfmt, the
+	// zero NumberLocale when off
+	SetNumberLocale func(locale db.NumberLocale)
+	GetNumberLocale func() db.NumberLocale
+
+	// SetPrettyPragmas and GetPrettyPragmas hold whether .pragma-pretty is on
+	SetPrettyPragmas func(enabled bool)
+	GetPrettyPragmas func() bool
+
+	// SetHeaderInterval and GetHeaderInterval hold the number of rows between the headers of tables set by
+	// .header-interval, 0 when off
+	SetHeaderInterval func(interval int)
+	GetHeaderInterval func() int
+	// SetPreserveHeaderCase and GetPreserveHeaderCase hold whether .headercase preserves the case of the column names
+	// of tables
+	SetPreserveHeaderCase func(preserve bool)
+	GetPreserveHeaderCase func() bool
+
+	// SetSlowThreshold and GetSlowThreshold hold the duration above which statements are flagged by .slow-threshold,
+	// 0 when off. SetSlowLogFile sets the file they're appended to, failing when it can't be written, "" when off
+	SetSlowThreshold func(threshold time.Duration)
+	GetSlowThreshold func() time.Duration
+	SetSlowLogFile   func(file string) error
+	GetSlowLogFile   func() string
+
+	// SetPageSize and GetPageSize hold the number of rows of the pages set by .page, 0 when paging is off
+	SetPageSize func(size int)
+	GetPageSize func() int
+	// TurnPage shows the page of the last paged statement the given number of pages away from the current one
+	TurnPage func(pages int) error
+
+	// SetOutputFile redirects OutF to a file, or back to the standard output when file is "", for the next command or
+	// statements only with once. EndOnceOutput is called by scripts once statements ran, ending the redirection given
+	// to .once before them
+	SetOutputFile func(file string, once bool) error
+	EndOnceOutput func()
+
+	// FileRoot, when set, is the directory the files dot commands read and write are confined to, as resolved by
+	// ResolveFilePath
+	FileRoot string
+	// ColumnTransform, when set, replaces the values of the results printed by the commands, and of the rows written by
+	// .dump when ColumnTransformInDumps is set too
+	ColumnTransform        db.ColumnTransform
+	ColumnTransformInDumps bool
+	// IsOutTerminal reports whether OutF writes to a terminal, which it doesn't while .output redirects it to a file
+	IsOutTerminal func() bool
+	// Interrupts receives a value when the user interrupts the shell with Ctrl-C
+	Interrupts <-chan struct{}
+	// Confirm asks a yes or no question, returning false when it can't be asked, like when the input isn't a terminal
+	Confirm func(question string) bool
+	// ReadSecret reads a line at the interactive prompt without echoing it nor saving it to the history, failing when
+	// the input isn't a terminal
+	ReadSecret func(prompt string) (string, error)
+	// ReadStdin reads the standard input of the shell to its end, failing while the shell reads its input from it
+	ReadStdin func() ([]byte, error)
+	// GetHistory returns the entries of the history, from the oldest one
+	GetHistory func() []string
+
+	// ExecuteCommand runs a dot command line as if it was typed in the shell
+	ExecuteCommand func(command string) error
+	// SaveDatabaseSettings saves the settings to load the next time the shell connects to the database
+	SaveDatabaseSettings func() error
 }
 
 const helpTemplate = `{{range .Commands}}{{if (and (not .Hidden) (or .IsAvailableCommand) (ne .Name "completion"))}}
@@ -39,13 +154,28 @@ func NewDatabaseRootCmd(config *DbCmdConfig) *cobra.Command {
 		},
 	}
 
-	rootCmd.AddCommand(tableCmd, schemaCmd, helpCmd, readCmd, indexesCmd, quitCmd, dumpCmd, modeCmd)
+	rootCmd.AddCommand(tableCmd, schemaCmd, helpCmd, readCmd, indexesCmd, quitCmd, dumpCmd, modeCmd, setCmd, openCmd, branchCmd, sessionCmd, foreignKeysCmd, fkcheckCmd, cellCmd, quoteCmd, printCmd, saveSettingsCmd, sleepCmd, excludeCmd, importCmd, pageCmd, nextCmd, prevCmd, watchCmd, explainFmtCmd, timerCmd, headerIntervalCmd, headerCaseCmd, selftestCmd, pragmaPrettyCmd, slowThresholdCmd, slowLogCmd, nullValueCmd, maskCmd, assertCmd, statsCmd, eqpCmd, outputCmd, onceCmd, keyCmd, adviseCmd, historyCmd, numfmtCmd, showCmd, describeCmd, sampleCmd, limitCmd, renameTableCmd, copyTableCmd, changesCmd, deltaCmd, columnsCmd)
 	rootCmd.SetOut(config.OutF)
 	rootCmd.SetErr(config.ErrF)
 	rootCmd.SetHelpTemplate(helpTemplate)
 	return rootCmd
 }
 
+// ResetFlags restores the flags of every dot command to their default values. The dot commands are shared between
+// executions, so without this the flags parsed in one execution would leak into the next one
+func ResetFlags(rootCmd *cobra.Command) {
+	for _, cmd := range rootCmd.Commands() {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+				_ = sliceValue.Replace([]string{})
+			} else {
+				_ = flag.Value.Set(flag.DefValue)
+			}
+			flag.Changed = false
+		})
+	}
+}
+
 func CreateNewDatabaseRootCmd(config *DbCmdConfig) *cobra.Command {
 	return NewDatabaseRootCmd(config)
 }
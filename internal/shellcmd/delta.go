@@ -0,0 +1,55 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var deltaCmd = &cobra.Command{
+	Use:   ".delta ?on|off|force?",
+	Short: "Show how each statement changes the rows of its table",
+	Long: `Count the rows of the table each INSERT, REPLACE, UPDATE and DELETE writes to before and after it, and show
+them with a line like "users: 10,432 → 9,876 (-556)". The size of local databases is shown too when it changes, like
+"database size: 8,192 → 12,288 bytes (+4,096)". Without an argument, the current setting is shown.
+
+The table is the first one after the INTO, UPDATE or FROM of the statement. The statements writing to several tables,
+like UPDATE ... FROM, the ones starting with WITH, and the ones that fail have no delta. The rows changed by triggers
+in other tables aren't counted.
+
+Counting the rows of a table reads all of it, which takes two extra requests per statement on remote databases, so it
+must be turned on with force for them.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off", "force"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetDelta() {
+				fmt.Fprintln(config.OutF, "delta: on")
+			} else {
+				fmt.Fprintln(config.OutF, "delta: off")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "on":
+			if config.Db.IsRemote() {
+				config.Log.Warnf("counting the rows of a table before and after each statement takes two extra requests to remote databases, and reads the whole table, so delta stays off. Use .delta force to turn it on anyway")
+				return nil
+			}
+			config.SetDelta(true)
+		case "force":
+			config.SetDelta(true)
+		case "off":
+			config.SetDelta(false)
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on, off and force", args[0])
+		}
+		return nil
+	},
+}
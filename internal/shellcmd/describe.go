@@ -0,0 +1,213 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/spf13/cobra"
+)
+
+// describeExactStatsRowsLimit is the number of rows above which --stats doesn't read the whole table, but needs
+// --sample or the statistics of ANALYZE
+const describeExactStatsRowsLimit = 100000
+
+const describeStat1Source = "sqlite_stat1, as of the last ANALYZE"
+
+// columnStats holds the number of distinct values of a column, without its NULLs, and the percentage of them that are
+// NULL, as SQL literals. Either is NULL when it isn't known
+type columnStats struct {
+	distinctValues string
+	nulls          string
+}
+
+var describeCmd = &cobra.Command{
+	Use:   ".describe TABLE",
+	Short: "Describe the columns of a table",
+	Long: `Describe the columns of a table: their name, type, whether they're NOT NULL, their default value and their
+position in the primary key.
+
+With --stats, the number of distinct values of each column, NULLs left out, and the percentage of NULLs are added,
+along with where they come from:
+  exact                                 The whole table was read, when it has at most 100000 rows
+  sample of the first N rows            Only the rows read with --sample N were
+  sqlite_stat1, as of the last ANALYZE  The table is larger and ANALYZE was run, so the counts are estimated from the
+                                        statistics of the indexes, which may be stale. Only the columns leading an
+                                        index have one, and the NULLs aren't known`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		withStats, err := cmd.Flags().GetBool("stats")
+		if err != nil {
+			return err
+		}
+		sample, err := cmd.Flags().GetInt("sample")
+		if err != nil {
+			return err
+		}
+		if sample < 0 {
+			return fmt.Errorf("invalid --sample %d. It must be a positive number of rows", sample)
+		}
+		if sample > 0 && !withStats {
+			return fmt.Errorf("--sample only applies with --stats")
+		}
+
+		tableName, found, err := findTableName(config, args[0])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no such table: %s", args[0])
+		}
+
+		columnsQuery := `SELECT p.name, p.type, p."notnull" AS not_null, p.dflt_value AS "default", p.pk`
+		fromColumns := fmt.Sprintf(" FROM pragma_table_info('%s') AS p", db.EscapeSingleQuotes(tableName))
+		if !withStats {
+			return config.Db.ExecuteAndPrintStatements(columnsQuery+fromColumns+" ORDER BY p.cid", config.OutF, db.PrintOptions{Mode: enums.TABLE_MODE})
+		}
+
+		columnNames, err := getDescribedColumnNames(config, tableName)
+		if err != nil {
+			return err
+		}
+		stats, source, err := getColumnStats(config, tableName, columnNames, sample)
+		if err != nil {
+			return err
+		}
+
+		values := make([]string, 0, len(columnNames))
+		for _, columnName := range columnNames {
+			values = append(values, fmt.Sprintf("('%s', %s, %s)", db.EscapeSingleQuotes(columnName), stats[columnName].distinctValues, stats[columnName].nulls))
+		}
+		statsQuery := "WITH stats (name, distinct_values, nulls) AS (VALUES " + strings.Join(values, ", ") + ") " +
+			columnsQuery + fmt.Sprintf(", s.distinct_values, s.nulls, '%s' AS source", db.EscapeSingleQuotes(source)) +
+			fromColumns + " LEFT JOIN stats AS s ON s.name = p.name ORDER BY p.cid"
+		return config.Db.ExecuteAndPrintStatements(statsQuery, config.OutF, db.PrintOptions{Mode: enums.TABLE_MODE})
+	},
+}
+
+func init() {
+	describeCmd.Flags().Bool("stats", false, "Add the number of distinct values and the percentage of NULLs of each column")
+	describeCmd.Flags().Int("sample", 0, fmt.Sprintf("With --stats, count the values of only the first N rows, needed by tables of more than %d rows without the statistics of ANALYZE", describeExactStatsRowsLimit))
+}
+
+func getDescribedColumnNames(config *DbCmdConfig, tableName string) ([]string, error) {
+	rows, err := queryAssertRows(config, fmt.Sprintf("SELECT name FROM pragma_table_info('%s') ORDER BY cid", db.EscapeSingleQuotes(tableName)))
+	if err != nil {
+		return nil, err
+	}
+	columnNames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		columnNames = append(columnNames, row[0])
+	}
+	return columnNames, nil
+}
+
+// getColumnStats counts the values of the columns in the first sample rows when given, or else in the whole table when
+// it's small enough, or else estimates them from sqlite_stat1. It returns where the counts come from
+func getColumnStats(config *DbCmdConfig, tableName string, columnNames []string, sample int) (map[string]columnStats, string, error) {
+	from := db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
+	if sample > 0 {
+		stats, err := countColumnValues(config, fmt.Sprintf("(SELECT * FROM %s LIMIT %d)", from, sample), columnNames)
+		return stats, fmt.Sprintf("sample of the first %d rows", sample), err
+	}
+
+	// The rows are only counted up to the limit, so a large table isn't read whole to find out it's too large
+	rows, err := queryAssertRows(config, fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM %s LIMIT %d)", from, describeExactStatsRowsLimit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if rows[0][0] != strconv.Itoa(describeExactStatsRowsLimit+1) {
+		stats, err := countColumnValues(config, from, columnNames)
+		return stats, "exact", err
+	}
+
+	stats, found, err := estimateColumnValues(config, tableName, columnNames)
+	if err != nil {
+		return nil, "", err
+	}
+	if !found {
+		return nil, "", fmt.Errorf("table %s has more than %d rows. Give --sample N to count the values of its first N rows, or run ANALYZE to estimate them from sqlite_stat1", tableName, describeExactStatsRowsLimit)
+	}
+	return stats, describeStat1Source, nil
+}
+
+// countColumnValues counts the distinct values and the NULLs of every column of the rows selected from source in a
+// single query
+func countColumnValues(config *DbCmdConfig, source string, columnNames []string) (map[string]columnStats, error) {
+	counts := []string{"count(*)"}
+	for _, columnName := range columnNames {
+		column := db.QuoteIdentifier(columnName, enums.DOUBLE_QUOTE_STYLE)
+		counts = append(counts, fmt.Sprintf("count(DISTINCT %s)", column), fmt.Sprintf("count(*) - count(%s)", column))
+	}
+	rows, err := queryAssertRows(config, "SELECT "+strings.Join(counts, ", ")+" FROM "+source)
+	if err != nil {
+		return nil, err
+	}
+
+	rowCount, err := strconv.ParseInt(rows[0][0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]columnStats, len(columnNames))
+	for i, columnName := range columnNames {
+		columnStat := columnStats{distinctValues: rows[0][1+2*i], nulls: "NULL"}
+		if rowCount > 0 {
+			nulls, err := strconv.ParseInt(rows[0][2+2*i], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			columnStat.nulls = fmt.Sprintf("'%.1f%%'", 100*float64(nulls)/float64(rowCount))
+		}
+		stats[columnName] = columnStat
+	}
+	return stats, nil
+}
+
+// estimateColumnValues estimates the distinct values of the columns leading an index from sqlite_stat1, whose stat of
+// an index starts with the number of rows of the table followed by the average number of rows per value of its first
+// column. It returns false when ANALYZE left no statistics of the table
+func estimateColumnValues(config *DbCmdConfig, tableName string, columnNames []string) (map[string]columnStats, bool, error) {
+	hasStat1, err := queryAssertRows(config, "SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1'")
+	if err != nil || len(hasStat1) == 0 {
+		return nil, false, err
+	}
+	rows, err := queryAssertRows(config, fmt.Sprintf(
+		"SELECT coalesce(i.name, ''), s.stat FROM sqlite_stat1 AS s LEFT JOIN pragma_index_info(s.idx) AS i ON i.seqno = 0 WHERE s.tbl = '%s' COLLATE NOCASE",
+		db.EscapeSingleQuotes(tableName),
+	))
+	if err != nil || len(rows) == 0 {
+		return nil, false, err
+	}
+
+	stats := make(map[string]columnStats, len(columnNames))
+	for _, columnName := range columnNames {
+		stats[columnName] = columnStats{distinctValues: "NULL", nulls: "NULL"}
+	}
+	for _, row := range rows {
+		stat := strings.Fields(row[1])
+		if row[0] == "" || len(stat) < 2 {
+			continue
+		}
+		rowCount, err := strconv.ParseInt(stat[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		rowsPerValue, err := strconv.ParseInt(stat[1], 10, 64)
+		if err != nil || rowsPerValue <= 0 {
+			continue
+		}
+		for _, columnName := range columnNames {
+			if strings.EqualFold(columnName, row[0]) {
+				stats[columnName] = columnStats{distinctValues: strconv.FormatInt(rowCount/rowsPerValue, 10), nulls: "NULL"}
+			}
+		}
+	}
+	return stats, true, nil
+}
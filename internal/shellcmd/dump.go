@@ -1,103 +1,935 @@
 package shellcmd
 
 import (
+	"bufio"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 	"github.com/spf13/cobra"
 )
 
+// dumpCompat holds how the dump is written for a target engine
+type dumpCompat struct {
+	formatType db.FormatType
+	preamble   string
+	// beginTransaction starts the transaction the statements of a dump are restored in, committed once all of them
+	// are written
+	beginTransaction string
+	// quoteStyle is the identifier quote style of the target engine. It's empty for SQLite, whose dumps accept any
+	// style, only quote the names that need it and omit column lists
+	quoteStyle        enums.IdentifierQuoteStyle
+	binaryTextLiteral string
+	// sqlite3Layout lays the dump out like the .dump of the sqlite3 CLI, as done by dumpsqlite3.go
+	sqlite3Layout bool
+}
+
+var dumpCompats = map[string]dumpCompat{
+	"sqlite": {
+		formatType:        db.SQLITE,
+		preamble:          "PRAGMA foreign_keys=OFF;",
+		beginTransaction:  "BEGIN TRANSACTION;",
+		binaryTextLiteral: "CAST(X'...' AS TEXT)",
+	},
+	"sqlite3": {
+		formatType:        db.SQLITE3,
+		preamble:          "PRAGMA foreign_keys=OFF;",
+		beginTransaction:  "BEGIN TRANSACTION;",
+		binaryTextLiteral: "CAST(X'...' AS TEXT)",
+		sqlite3Layout:     true,
+	},
+	"postgres": {
+		formatType:        db.POSTGRES,
+		beginTransaction:  "BEGIN;",
+		quoteStyle:        enums.DOUBLE_QUOTE_STYLE,
+		binaryTextLiteral: `'\x...'`,
+	},
+	"mysql": {
+		formatType:        db.MYSQL,
+		preamble:          "SET FOREIGN_KEY_CHECKS=0;",
+		beginTransaction:  "START TRANSACTION;",
+		quoteStyle:        enums.BACKTICK_STYLE,
+		binaryTextLiteral: "0x...",
+	},
+}
+
+// dumpOptions holds the settings of a single dump
+type dumpOptions struct {
+	compat      dumpCompat
+	quoteStyle  enums.IdentifierQuoteStyle
+	tableFilter db.TableFilter
+	progress    db.ProgressFunc
+	// largeValueSize is the size in bytes above which values are warned about on log, as dumping them takes memory
+	// proportional to their size. Zero disables the warning
+	largeValueSize int64
+	log            *db.Logger
+	// whereClauses holds the conditions given with --where, by table name, that select the records dumped of a table
+	whereClauses map[string]string
+	// schemaOnly leaves out the records of the tables, and dataOnly the statements creating them, their indexes,
+	// triggers and the views
+	schemaOnly bool
+	dataOnly   bool
+	// columnTransform, when set, replaces the values of the records of the tables before they're written
+	columnTransform db.ColumnTransform
+	// chunkSize, when set, is the number of records each query selecting the records of a table is limited to
+	chunkSize int
+	// rowsPerInsert, when more than 1, is the number of records coalesced into each INSERT statement, as long as the
+	// statement stays under the sql_length limit of limits
+	rowsPerInsert int
+	limits        db.StatementLimits
+
+	// schemas holds the schema of every table, read in a single scan of sqlite_master. When nil, the schema of each
+	// table is queried on its own
+	schemas map[string]*tableSchema
+	// virtualTables holds the virtual tables of the database along with their shadow tables, and firstVirtualTable the
+	// first one dumped with sqlite3Layout
+	virtualTables     virtualTables
+	firstVirtualTable string
+
+	// verify restores the dump once written and compares its tables with the ones of the database, unless they have
+	// more rows than verifyMaxRows, when it's set. outputCopy receives a copy of the dump written to the output, for it
+	// to be restored
+	verify        bool
+	verifyMaxRows int64
+	outputCopy    io.Writer
+}
+
+const defaultLargeValueSize = 64 * 1024 * 1024
+
 var dumpCmd = &cobra.Command{
-	Use:   ".dump",
+	Use:   ".dump ?TABLE...?",
 	Short: "Render database content as SQL",
-	Args:  cobra.NoArgs,
+	Long: `Render database content as SQL, or only the tables named, with their indexes and triggers. A name may be a LIKE
+pattern, like audit_%, where \ escapes the % and _ wildcards.
+
+The full-text and R*Tree virtual tables are dumped as CREATE VIRTUAL TABLE and the INSERT statements of their rows,
+which fill their shadow tables again. The other virtual tables, like contentless full-text ones, are restored from a
+copy of their shadow tables, which requires SQLITE_DBCONFIG_DEFENSIVE to be off. Virtual tables are skipped by the
+postgres and mysql dumps.
+
+With --verify, the dump is restored into a temporary database once written, and the number of rows of each table and
+a hash of their values are compared with the ones of the database, failing with the tables that differ. The virtual
+tables and the internal ones of SQLite aren't compared. Verifying reads the whole dump into memory, so it's skipped
+with a warning when the tables have more rows than --verify-max-rows.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
 		if !ok {
 			return fmt.Errorf("missing db connection")
 		}
 
-		fmt.Fprintln(config.OutF, "PRAGMA foreign_keys=OFF;")
+		compatName, err := cmd.Flags().GetString("compat")
+		if err != nil {
+			return err
+		}
+		compat, ok := dumpCompats[compatName]
+		if !ok {
+			return fmt.Errorf("unsupported compat target %q. Use postgres, mysql, sqlite or sqlite3", compatName)
+		}
 
-		getTableNamesStatementResult, err := getDbTableNames(config)
+		quoteStyle, err := getDumpQuoteStyle(cmd, config, compat)
 		if err != nil {
 			return err
 		}
 
-		err = dumpTables(getTableNamesStatementResult, config)
+		showProgress, err := cmd.Flags().GetBool("progress")
 		if err != nil {
 			return err
 		}
 
-		return nil
-	},
-}
+		tableFilter, err := getDumpTableFilter(cmd, config, args)
+		if err != nil {
+			return err
+		}
 
-func dumpTables(getTableStatementResult db.StatementResult, config *DbCmdConfig) error {
-	for tableNameRowResult := range getTableStatementResult.RowCh {
-		if tableNameRowResult.Err != nil {
-			return tableNameRowResult.Err
+		splitDir, err := cmd.Flags().GetString("split-dir")
+		if err != nil {
+			return err
+		}
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return err
+		}
+		if file != "" && splitDir != "" {
+			return fmt.Errorf("--file and --split-dir can't be used together")
+		}
+		if compat.sqlite3Layout && splitDir != "" {
+			return fmt.Errorf("--compat sqlite3 can't be used with --split-dir, as the sqlite3 CLI has no such dump")
 		}
-		formattedRow, err := db.FormatData(tableNameRowResult.Row, db.TABLE)
+		resumeManifest, err := cmd.Flags().GetString("resume-manifest")
+		if err != nil {
+			return err
+		}
+		if resumeManifest != "" && splitDir == "" {
+			return fmt.Errorf("--resume-manifest only applies with --split-dir")
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		largeValueSize, err := cmd.Flags().GetInt64("warn-value-size")
 		if err != nil {
 			return err
 		}
 
-		formattedTableName := formattedRow[0]
+		schemaOnly, err := cmd.Flags().GetBool("schema-only")
+		if err != nil {
+			return err
+		}
+		dataOnly, err := cmd.Flags().GetBool("data-only")
+		if err != nil {
+			return err
+		}
+		if schemaOnly && dataOnly {
+			return fmt.Errorf("--schema-only and --data-only can't be used together. Give neither to dump both")
+		}
+		if (schemaOnly || dataOnly) && splitDir != "" {
+			return fmt.Errorf("--schema-only and --data-only can't be used with --split-dir, whose schema file already holds the schema alone")
+		}
 
-		createTableStmt, otherStmts, err := getTableSchema(config, formattedTableName)
+		whereClauses, err := getDumpWhereClauses(cmd, config)
 		if err != nil {
 			return err
 		}
+		chunkSize, err := cmd.Flags().GetInt("chunked-select")
+		if err != nil {
+			return err
+		}
+		if chunkSize < 0 {
+			return fmt.Errorf("invalid --chunked-select %d. It must be a positive number of records", chunkSize)
+		}
 
-		fmt.Fprintln(config.OutF, createTableStmt)
+		rowsPerInsert, err := cmd.Flags().GetInt("multi-row-insert")
+		if err != nil {
+			return err
+		}
+		if rowsPerInsert < 0 {
+			return fmt.Errorf("invalid --multi-row-insert %d. It must be a positive number of records", rowsPerInsert)
+		}
+		if rowsPerInsert > 1 && compat.sqlite3Layout {
+			return fmt.Errorf("--multi-row-insert can't be used with --compat sqlite3, whose dump has an INSERT statement per record")
+		}
 
-		tableRecordsStatementResult, err := getTableRecords(config, formattedTableName)
+		verify, err := cmd.Flags().GetBool("verify")
+		if err != nil {
+			return err
+		}
+		verifyMaxRows, err := cmd.Flags().GetInt64("verify-max-rows")
 		if err != nil {
 			return err
 		}
+		if verifyMaxRows < 0 {
+			return fmt.Errorf("invalid --verify-max-rows %d. It must be a positive number of rows, or 0 for no limit", verifyMaxRows)
+		}
+		if verify && compat.formatType != db.SQLITE && compat.formatType != db.SQLITE3 {
+			return fmt.Errorf("--verify only applies to the dumps for sqlite and sqlite3, which it restores into SQLite")
+		}
+		if verify && (schemaOnly || dataOnly) {
+			return fmt.Errorf("--verify can't be used with --schema-only or --data-only, whose dumps don't restore the tables with their records")
+		}
 
-		err = dumpTableRecords(tableRecordsStatementResult, config, formattedTableName)
+		options := dumpOptions{compat: compat, quoteStyle: quoteStyle, tableFilter: tableFilter, progress: config.Progress, largeValueSize: largeValueSize, log: config.Log, whereClauses: whereClauses, schemaOnly: schemaOnly, dataOnly: dataOnly, chunkSize: chunkSize, rowsPerInsert: rowsPerInsert, limits: config.Db.StatementLimits(), verify: verify, verifyMaxRows: verifyMaxRows}
+		if config.ColumnTransformInDumps {
+			options.columnTransform = config.ColumnTransform
+		}
+		if verify && options.columnTransform != nil {
+			return fmt.Errorf("--verify can't be used while the values of dumps are masked, as they differ from the ones of the database")
+		}
+		if options.progress == nil {
+			options.progress = newDumpProgressPrinter(config.Log, compat, showProgress)
+		}
+
+		preSQL, err := cmd.Flags().GetStringArray("pre-sql")
+		if err != nil {
+			return err
+		}
+		postSQL, err := cmd.Flags().GetStringArray("post-sql")
 		if err != nil {
 			return err
 		}
 
-		for _, stmt := range otherStmts {
-			fmt.Fprintln(config.OutF, stmt)
+		// The hooks run on their own, before the first line of the dump is written and after the last one, and their
+		// results are discarded so they never end up in the dump
+		if err := executeDumpHooks(config, preSQL); err != nil {
+			return fmt.Errorf("--pre-sql failed, nothing was dumped: %w", err)
 		}
+		if err := dumpInReadTransaction(config, options, file, splitDir, resumeManifest, force); err != nil {
+			return err
+		}
+		if err := executeDumpHooks(config, postSQL); err != nil {
+			return fmt.Errorf("the dump was written, but --post-sql failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// dumpInReadTransaction dumps in a read transaction when the database supports one, so that the dump is a snapshot of
+// the database however other clients change it meanwhile. Otherwise, like when it's remote or a transaction is already
+// in progress, the tables dropped while dumping are skipped
+func dumpInReadTransaction(config *DbCmdConfig, options dumpOptions, file string, splitDir string, resumeManifest string, force bool) error {
+	endReadTransaction, err := config.Db.BeginReadTransaction()
+	if err != nil {
+		return dumpAndVerify(config, options, file, splitDir, resumeManifest, force)
+	}
+	err = dumpAndVerify(config, options, file, splitDir, resumeManifest, force)
+	if endErr := endReadTransaction(); err == nil {
+		err = endErr
 	}
+	return err
+}
 
+// dumpTo writes the dump into the split directory or the file when given, or else to the output
+func dumpTo(config *DbCmdConfig, options dumpOptions, file string, splitDir string, resumeManifest string, force bool) (err error) {
+	options.virtualTables, err = getVirtualTables(config)
+	if err != nil {
+		return err
+	}
+	if splitDir != "" {
+		splitDir, err = ResolveFilePath(config.FileRoot, splitDir)
+		if err != nil {
+			return err
+		}
+		if resumeManifest != "" {
+			resumeManifest, err = ResolveFilePath(config.FileRoot, resumeManifest)
+			if err != nil {
+				return err
+			}
+			return dumpSplitResumably(config, options, splitDir, force, resumeManifest)
+		}
+		return dumpSplit(config, options, splitDir, force)
+	}
+	if file != "" {
+		file, err = ResolveFilePath(config.FileRoot, file)
+		if err != nil {
+			return err
+		}
+		// The file is only replaced once the whole dump is written, so a failed dump leaves the previous one intact. A
+		// dump skipping dropped tables is complete otherwise, and replaces it before failing
+		var dropped *droppedTablesError
+		err = writeFileAtomically(file, func(out io.Writer) error {
+			if err := dump(out, config, options); !errors.As(err, &dropped) {
+				return err
+			}
+			return nil
+		})
+		if err == nil && dropped != nil {
+			return dropped
+		}
+		return err
+	}
+	if options.outputCopy != nil {
+		return dump(io.MultiWriter(config.OutF, options.outputCopy), config, options)
+	}
+	return dump(config.OutF, config, options)
+}
+
+func executeDumpHooks(config *DbCmdConfig, hooks []string) error {
+	for _, hook := range hooks {
+		if err := executeStatementsSilently(config, hook); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func dumpTableRecords(tableRecordsStatementResult db.StatementResult, config *DbCmdConfig, tableName string) error {
-	for tableRecordsRowResult := range tableRecordsStatementResult.RowCh {
-		if tableRecordsRowResult.Err != nil {
-			return tableRecordsRowResult.Err
+func init() {
+	dumpCmd.Flags().String("compat", "sqlite", "Target engine of the dump: postgres, mysql or sqlite. sqlite3 writes the dump of the sqlite3 CLI, statement by statement")
+	dumpCmd.Flags().String("quote", "", "Quote style for identifiers: double, backtick or bracket. Defaults to the target engine style or the one set with .quote")
+	dumpCmd.Flags().Bool("progress", false, "Report the progress of each table")
+	dumpCmd.Flags().StringArray("exclude", nil, "Leave out the tables whose name matches this LIKE pattern, where \\ escapes the % and _ wildcards. Can be repeated")
+	dumpCmd.Flags().String("split-dir", "", "Write the dump into this directory, as a schema file, a file per table and a manifest.json listing them")
+	dumpCmd.Flags().String("file", "", "Write the dump into this file instead of the output. The file is replaced only once the dump succeeded")
+	dumpCmd.Flags().String("resume-manifest", "", "With --split-dir, record the progress of the dump in this file, and resume the dump it records when it exists: the tables done are skipped and the one in progress is continued after its last record written")
+	dumpCmd.Flags().Bool("force", false, "With --split-dir, write into the directory even if it's not empty")
+	dumpCmd.Flags().Bool("include-internal", false, "Dump the tables SQLite, Litestream and libSQL keep for themselves too")
+	dumpCmd.Flags().StringArray("where", nil, "Dump only the records of a table matching a condition, given as TABLE:CONDITION. Can be repeated")
+	dumpCmd.Flags().Bool("schema-only", false, "Dump only the statements creating the tables, their indexes and triggers, and the views")
+	dumpCmd.Flags().Bool("data-only", false, "Dump only the records of the tables")
+	dumpCmd.Flags().StringArray("pre-sql", nil, "Execute these statements before dumping, which fails if they do. Can be repeated")
+	dumpCmd.Flags().StringArray("post-sql", nil, "Execute these statements once the dump is written. Can be repeated")
+	dumpCmd.Flags().Int("chunked-select", 0, "Select the records of each table in chunks of N records, following its rowid or single-column primary key, for servers buffering whole results")
+	dumpCmd.Flags().Int("multi-row-insert", 0, fmt.Sprintf("Coalesce up to N records into each INSERT statement, %d without a value, and fewer when they'd make it larger than %d bytes or the sql_length limit shown by .limit", defaultMultiRowInsertRows, multiRowInsertMaxSize))
+	dumpCmd.Flags().Lookup("multi-row-insert").NoOptDefVal = strconv.Itoa(defaultMultiRowInsertRows)
+	dumpCmd.Flags().Bool("verify", false, "Restore the dump into a temporary database once written, and fail if its tables differ from the ones of the database")
+	dumpCmd.Flags().Int64("verify-max-rows", defaultVerifyMaxRows, "With --verify, skip the verification of dumps whose tables have more rows than this. 0 for no limit")
+	dumpCmd.Flags().Int64("warn-value-size", defaultLargeValueSize, "Warn about values larger than this many bytes, as dumping one takes memory proportional to its size. 0 disables the warning")
+}
+
+// getDumpTableFilter returns the filter of the tables given as arguments, with --exclude and --include-internal,
+// checking that each argument matches a table. Unlike the listing commands, the dump doesn't leave out the tables
+// hidden with .exclude
+func getDumpTableFilter(cmd *cobra.Command, config *DbCmdConfig, tablePatterns []string) (db.TableFilter, error) {
+	exclude, err := cmd.Flags().GetStringArray("exclude")
+	if err != nil {
+		return db.TableFilter{}, err
+	}
+	includeInternal, err := cmd.Flags().GetBool("include-internal")
+	if err != nil {
+		return db.TableFilter{}, err
+	}
+	for _, tablePattern := range tablePatterns {
+		matchingTables, err := queryAssertRows(config, "SELECT 1 FROM sqlite_master WHERE type='table' AND "+db.LikeAnyNormalForm("name", tablePattern, `ESCAPE '\'`)+" LIMIT 1")
+		if err != nil {
+			return db.TableFilter{}, err
+		}
+		if len(matchingTables) == 0 {
+			return db.TableFilter{}, fmt.Errorf("no such table: %s", tablePattern)
 		}
+	}
+	return db.TableFilter{Include: tablePatterns, Exclude: exclude, IncludeInternal: includeInternal}, nil
+}
 
-		var formattedTableName = tableName
-		if db.NeedsEscaping(tableName) {
-			formattedTableName = "'" + db.EscapeSingleQuotes(tableName) + "'"
+// getDumpWhereClauses returns the conditions given with --where by table name, checking each one against its table so
+// that a wrong one fails before anything is written. As leaving out records may leave references to them dangling, it
+// warns when the database has foreign keys
+func getDumpWhereClauses(cmd *cobra.Command, config *DbCmdConfig) (map[string]string, error) {
+	clauses, err := cmd.Flags().GetStringArray("where")
+	if err != nil {
+		return nil, err
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	whereClauses := make(map[string]string, len(clauses))
+	for _, clause := range clauses {
+		tableName, condition, found := strings.Cut(clause, ":")
+		if !found || strings.TrimSpace(tableName) == "" || strings.TrimSpace(condition) == "" {
+			return nil, fmt.Errorf("invalid --where \"%s\". Give it as TABLE:CONDITION, like \"orders:created_at > '2024-01-01'\"", clause)
+		}
+		tableName, err := getExistingTableName(config, strings.TrimSpace(tableName))
+		if err != nil {
+			return nil, err
+		}
+		if _, repeated := whereClauses[tableName]; repeated {
+			return nil, fmt.Errorf("--where was given more than once for table %s", tableName)
+		}
+		if err := checkWhereClause(config, tableName, condition); err != nil {
+			return nil, err
+		}
+		whereClauses[tableName] = condition
+	}
+
+	hasForeignKeys, err := hasForeignKeys(config)
+	if err != nil {
+		return nil, err
+	}
+	if hasForeignKeys {
+		config.Log.Warnf("records left out with --where may still be referenced by foreign keys, which aren't checked by the dump")
+	}
+	return whereClauses, nil
+}
+
+// getExistingTableName returns the name of the table as stored in the schema, which may differ in case or in Unicode
+// normal form from the given one
+func getExistingTableName(config *DbCmdConfig, tableName string) (string, error) {
+	name, found, err := findTableName(config, tableName)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no such table: %s, given with --where", tableName)
+	}
+	return name, nil
+}
+
+// findTableName looks up the name of a table as stored in the schema, matching the given one as is or else as
+// db.EqualNames does. It returns false when there's no such table
+func findTableName(config *DbCmdConfig, tableName string) (string, bool, error) {
+	result, err := config.Db.ExecuteStatements("SELECT name FROM sqlite_master WHERE type='table'")
+	if err != nil {
+		return "", false, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return "", false, statementResult.Err
+	}
+	names, err := readFirstColumn(statementResult)
+	if err != nil {
+		return "", false, err
+	}
+	// Two tables may have names differing only in their normal form, so the exact one wins
+	for _, name := range names {
+		if name == tableName {
+			return name, true, nil
+		}
+	}
+	for _, name := range names {
+		if db.EqualNames(name, tableName) {
+			return name, true, nil
 		}
-		insertStatement := "INSERT INTO " + formattedTableName + " VALUES ("
+	}
+	return "", false, nil
+}
 
-		tableRecordsFormattedRow, err := db.FormatData(tableRecordsRowResult.Row, db.SQLITE)
+// checkWhereClause prepares the query of the records of the table with the condition, without reading any of them
+func checkWhereClause(config *DbCmdConfig, tableName string, condition string) error {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE (%s) LIMIT 0", db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE), condition)
+	statements, err := db.SplitStatements(query)
+	if err != nil || len(statements) != 1 {
+		return fmt.Errorf("invalid --where condition for table %s: it must be a single SQL expression", tableName)
+	}
+	if err := executeStatementsSilently(config, query); err != nil {
+		return fmt.Errorf("invalid --where condition for table %s: %w", tableName, err)
+	}
+	return nil
+}
+
+func hasForeignKeys(config *DbCmdConfig) (bool, error) {
+	result, err := config.Db.ExecuteStatements(
+		"SELECT 1 FROM sqlite_master AS m, pragma_foreign_key_list(m.name) WHERE m.type='table' LIMIT 1",
+	)
+	if err != nil {
+		return false, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return false, statementResult.Err
+	}
+	values, err := readFirstColumn(statementResult)
+	if err != nil {
+		return false, err
+	}
+	return len(values) > 0, nil
+}
+
+// newDumpProgressPrinter returns the progress callback of the CLI. It always reports the TEXT values written as hex
+// literals, and per table progress when showProgress is set
+func newDumpProgressPrinter(log *db.Logger, compat dumpCompat, showProgress bool) db.ProgressFunc {
+	return func(event db.ProgressEvent) {
+		switch event.Kind {
+		case db.TABLE_STARTED:
+			if showProgress {
+				log.Infof("Dumping table %s", event.Table)
+			}
+		case db.ROWS_PROCESSED:
+			if showProgress {
+				log.Infof("  %d rows", event.Rows)
+			}
+		case db.TABLE_FINISHED:
+			if showProgress {
+				log.Infof("Dumped table %s: %d rows", event.Table, event.Rows)
+			}
+		case db.OPERATION_FINISHED:
+			if showProgress {
+				log.Infof("Dumped %d tables, %d rows in %s", event.Tables, event.Rows, event.Duration.Round(time.Millisecond))
+			}
+			if event.BinaryTextValues > 0 {
+				log.Notef("%d TEXT value(s) containing NUL bytes or invalid UTF-8 were written as %s", event.BinaryTextValues, compat.binaryTextLiteral)
+			}
+		}
+	}
+}
+
+// getDumpQuoteStyle returns the style given with --quote, or else the one of the target engine, or else the one of the
+// shell
+func getDumpQuoteStyle(cmd *cobra.Command, config *DbCmdConfig, compat dumpCompat) (enums.IdentifierQuoteStyle, error) {
+	quote, err := cmd.Flags().GetString("quote")
+	if err != nil {
+		return "", err
+	}
+	if quote != "" {
+		return parseIdentifierQuoteStyle(quote)
+	}
+	if compat.quoteStyle != "" {
+		return compat.quoteStyle, nil
+	}
+	return config.GetIdentifierQuoteStyle(), nil
+}
+
+func dump(outF io.Writer, config *DbCmdConfig, options dumpOptions) error {
+	startTime := time.Now()
+
+	// The table names are read before the schemas, and both before the records, so the queries run one after the
+	// other rather than interleaved
+	tableNames, err := getDumpTableNames(config, options)
+	if err != nil {
+		return err
+	}
+	options.schemas, err = getDbTableSchemas(config)
+	if err != nil {
+		return err
+	}
+	tableNames = selectShadowTables(removeSequenceTable(tableNames, options), options)
+
+	out := bufio.NewWriter(outF)
+	if options.compat.sqlite3Layout {
+		options.firstVirtualTable = options.virtualTables.getFirst(tableNames)
+		writeSQLite3DumpStart(out, options)
+	} else {
+		writeDumpPreamble(out, options)
+		fmt.Fprintln(out, options.compat.beginTransaction)
+	}
+
+	totals, droppedTables, err := dumpTables(out, tableNames, config, options)
+	if err == nil {
+		err = dumpSequences(out, config, tableNames, options)
+	}
+	if err == nil {
+		err = dumpViewsAndTriggers(out, config, tableNames, options, true)
+	}
+	if err == nil {
+		err = dumpSQLite3SchemaObjects(out, config, options)
+	}
+	if err == nil && options.firstVirtualTable != "" && !options.dataOnly {
+		fmt.Fprintln(out, "PRAGMA writable_schema=OFF;")
+	}
+	// The transaction is only committed by a complete dump, so that restoring a partial one changes nothing
+	if err == nil && !(options.compat.sqlite3Layout && options.dataOnly) {
+		fmt.Fprintln(out, "COMMIT;")
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+
+	totals.Kind = db.OPERATION_FINISHED
+	totals.Duration = time.Since(startTime)
+	options.progress(totals)
+	if len(droppedTables) > 0 {
+		return &droppedTablesError{tables: droppedTables}
+	}
+	return nil
+}
+
+func dumpTables(out *bufio.Writer, tableNames []string, config *DbCmdConfig, options dumpOptions) (totals db.ProgressEvent, droppedTables []string, err error) {
+	for _, tableName := range tableNames {
+		tableProgress, err := dumpTable(out, config, tableName, options, true)
+		if errors.Is(err, errTableDropped) {
+			fmt.Fprintf(out, "-- Skipped table %s, dropped while dumping\n", db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE))
+			droppedTables = append(droppedTables, tableName)
+			continue
+		}
+		if err != nil {
+			return totals, droppedTables, err
+		}
+		// The output of each table is written before its progress is reported
+		if err := out.Flush(); err != nil {
+			return totals, droppedTables, err
+		}
+		addTableProgress(&totals, tableProgress)
+	}
+
+	return totals, droppedTables, nil
+}
+
+// errTableDropped is returned by dumpTable for a table that was dropped by another client since it was listed
+var errTableDropped = errors.New("table dropped while dumping")
+
+// droppedTablesError fails a dump that skipped tables dropped while dumping, once it's complete otherwise
+type droppedTablesError struct {
+	tables []string
+}
+
+func (e *droppedTablesError) Error() string {
+	return fmt.Sprintf("skipped tables dropped while dumping: %s", strings.Join(e.tables, ", "))
+}
+
+func isNoSuchTableError(err error, tableName string) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "no such table: "+strings.ToLower(tableName)) ||
+		strings.Contains(message, "no such table: main."+strings.ToLower(tableName))
+}
+
+// sequenceTable is the table SQLite keeps the largest rowid of each AUTOINCREMENT table in, so that rowids aren't reused
+const sequenceTable = "sqlite_sequence"
+
+// removeSequenceTable leaves sqlite_sequence out of the tables dumped to SQLite, given with --include-internal, as it
+// can't be created but only filled by dumpSequences
+func removeSequenceTable(tableNames []string, options dumpOptions) []string {
+	if options.compat.formatType != db.SQLITE {
+		return tableNames
+	}
+	kept := make([]string, 0, len(tableNames))
+	for _, tableName := range tableNames {
+		if !strings.EqualFold(tableName, sequenceTable) {
+			kept = append(kept, tableName)
+		}
+	}
+	return kept
+}
+
+// dumpSequences writes the rows of sqlite_sequence of the dumped tables after deleting the ones their inserts made, like
+// the sqlite3 CLI does, so the restored AUTOINCREMENT tables don't reuse the rowids of deleted records. Nothing is
+// written without such rows, as sqlite_sequence only exists in the restored database once one was inserted
+func dumpSequences(out io.Writer, config *DbCmdConfig, tableNames []string, options dumpOptions) error {
+	if options.compat.formatType != db.SQLITE || options.schemaOnly || options.schemas[sequenceTable] == nil {
+		return nil
+	}
+	dumped := make(map[string]bool, len(tableNames))
+	for _, tableName := range tableNames {
+		dumped[strings.ToLower(tableName)] = true
+	}
+
+	sequencesStatementResult, err := getTableRecords(config, sequenceTable, "")
+	if err != nil {
+		return err
+	}
+	insertInto := getInsertInto(sequenceTable, sequencesStatementResult.ColumnNames, options.compat, options.quoteStyle)
+	progress := db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: sequenceTable}
+	inserts := newRecordInserts(out, sequenceTable, insertInto, options)
+	for sequenceRowResult := range sequencesStatementResult.RowCh {
+		if sequenceRowResult.Err != nil {
+			return sequenceRowResult.Err
+		}
+		formattedRow, err := db.FormatData(sequenceRowResult.Row, db.TABLE)
 		if err != nil {
 			return err
 		}
+		if len(formattedRow) == 0 || !dumped[strings.ToLower(formattedRow[0])] {
+			continue
+		}
+		if progress.Rows == 0 {
+			fmt.Fprintf(out, "DELETE FROM %s;\n", sequenceTable)
+		}
+		if err := dumpRecord(inserts, sequenceRowResult.Row, sequenceTable, options, &progress); err != nil {
+			return err
+		}
+	}
+	return inserts.end()
+}
+
+// dumpViewsAndTriggers writes the views with their triggers once all the tables are dumped, along with the triggers of
+// the tables mentioning other tables or views when withTableTriggers is set. They come in the order they were created,
+// so that each one is created after what it mentions. Only SQLite dumps of all the tables have the views, as a view may
+// select from any table, and with any SQLite function
+func dumpViewsAndTriggers(out io.Writer, config *DbCmdConfig, tableNames []string, options dumpOptions, withTableTriggers bool) error {
+	if options.dataOnly || options.compat.sqlite3Layout {
+		return nil
+	}
+	viewCondition := "0"
+	if options.compat.formatType == db.SQLITE && len(options.tableFilter.Include) == 0 {
+		viewCondition = options.tableFilter.Condition("name")
+	}
+	rows, err := queryAssertRows(config, "SELECT type, tbl_name, sql || ';' FROM sqlite_master WHERE sql IS NOT NULL AND (type='trigger' OR (type='view' AND "+viewCondition+")) ORDER BY rowid")
+	if err != nil {
+		return err
+	}
+
+	dumped := make(map[string]bool, len(tableNames))
+	for _, tableName := range tableNames {
+		dumped[strings.ToLower(tableName)] = true
+	}
+	views := make(map[string]bool)
+	for _, row := range rows {
+		kind, tableName, stmt := row[0], strings.ToLower(row[1]), row[2]
+		switch {
+		case kind == "view":
+			views[tableName] = true
+		case views[tableName]:
+		case !withTableTriggers || !dumped[tableName] || options.schemas[tableName] == nil || !containsString(options.schemas[tableName].deferredTriggers, stmt):
+			continue
+		}
+		fmt.Fprintln(out, stmt)
+	}
+	return nil
+}
+
+func removeString(values []string, value string) []string {
+	kept := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func addTableProgress(totals *db.ProgressEvent, tableProgress db.ProgressEvent) {
+	totals.Tables++
+	totals.Rows += tableProgress.Rows
+	totals.BinaryTextValues += tableProgress.BinaryTextValues
+}
+
+// dumpTable writes the records of a table to out, followed by the statements creating its indexes and triggers, and
+// preceded by its CREATE TABLE statement when withCreateTable is set
+func dumpTable(out io.Writer, config *DbCmdConfig, tableName string, options dumpOptions, withCreateTable bool) (tableProgress db.ProgressEvent, err error) {
+	tableStartTime := time.Now()
+	options.progress(db.ProgressEvent{Kind: db.TABLE_STARTED, Table: tableName})
+
+	createTableStmt, otherStmts, err := getDumpTableSchema(config, tableName, options)
+	if err != nil {
+		return tableProgress, err
+	}
+
+	// The records are selected before anything is written, so nothing is left of a table dropped since it was listed
+	var tableRecordsStatementResult db.StatementResult
+	withRecords := !options.schemaOnly && dumpsVirtualTableRecords(tableName, options)
+	if withRecords {
+		tableRecordsStatementResult, err = getDumpTableRecords(config, tableName, options)
+		if err != nil {
+			if isNoSuchTableError(err, tableName) {
+				return tableProgress, errTableDropped
+			}
+			return tableProgress, err
+		}
+	}
+	if withCreateTable && !options.dataOnly {
+		writeDumpCreateTable(out, tableName, createTableStmt, options)
+	}
+
+	if withRecords {
+		tableProgress, err = dumpTableRecords(out, tableRecordsStatementResult, tableName, options)
+		if err != nil {
+			return tableProgress, err
+		}
+	}
+
+	// The sqlite3 CLI writes the indexes and triggers after all the tables, with dumpSQLite3SchemaObjects
+	if !options.dataOnly && !options.compat.sqlite3Layout {
+		for _, stmt := range otherStmts {
+			fmt.Fprintln(out, stmt)
+		}
+		// The schema file of a split dump creates all the tables beforehand, so the triggers mentioning other tables
+		// can come with their own table
+		if !withCreateTable {
+			for _, stmt := range getDeferredTriggers(tableName, options) {
+				fmt.Fprintln(out, stmt)
+			}
+		}
+	}
+
+	tableProgress.Kind = db.TABLE_FINISHED
+	tableProgress.Tables = 1
+	tableProgress.Duration = time.Since(tableStartTime)
+	options.progress(tableProgress)
+	return tableProgress, nil
+}
+
+// writeDumpCreateTable writes the statements creating a table, which differ for the virtual tables and the layout of
+// the sqlite3 CLI
+func writeDumpCreateTable(out io.Writer, tableName string, createTableStmt string, options dumpOptions) {
+	switch {
+	case options.virtualTables.get(tableName) != nil:
+		writeVirtualTableCreation(out, options.virtualTables.get(tableName), options)
+	case options.compat.sqlite3Layout:
+		writeSQLite3CreateTable(out, tableName, createTableStmt)
+	default:
+		writeCreateTable(out, createTableStmt, options)
+	}
+}
+
+func writeCreateTable(out io.Writer, createTableStmt string, options dumpOptions) {
+	if options.compat.quoteStyle != "" {
+		var removedClauses []string
+		createTableStmt, removedClauses = db.RemoveSQLiteOnlyClauses(createTableStmt)
+		if len(removedClauses) > 0 {
+			fmt.Fprintf(out, "-- Omitted SQLite-only clauses: %s\n", strings.Join(removedClauses, ", "))
+		}
+	}
+	fmt.Fprintln(out, createTableStmt)
+}
 
-		insertStatement += strings.Join(tableRecordsFormattedRow, ", ")
-		insertStatement += ");"
-		fmt.Fprintln(config.OutF, insertStatement)
+func dumpTableRecords(out io.Writer, tableRecordsStatementResult db.StatementResult, tableName string, options dumpOptions) (progress db.ProgressEvent, err error) {
+	insertInto := getVirtualTableInsertInto(tableName, tableRecordsStatementResult.ColumnNames, options)
+	if insertInto == "" {
+		insertInto = getInsertInto(tableName, tableRecordsStatementResult.ColumnNames, options.compat, options.quoteStyle)
 	}
+	progress = db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: tableName}
 
+	inserts := newRecordInserts(out, tableName, insertInto, options)
+	for tableRecordsRowResult := range tableRecordsStatementResult.RowCh {
+		if tableRecordsRowResult.Err != nil {
+			return progress, tableRecordsRowResult.Err
+		}
+		row := tableRecordsRowResult.Row
+		if options.columnTransform != nil {
+			row = db.TransformRow(options.columnTransform, tableRecordsStatementResult.ColumnNames, row)
+		}
+		if err := dumpRecord(inserts, row, tableName, options, &progress); err != nil {
+			return progress, err
+		}
+	}
+
+	return progress, inserts.end()
+}
+
+// dumpRecord writes a record with inserts, counting it in progress
+func dumpRecord(inserts *recordInserts, row []interface{}, tableName string, options dumpOptions, progress *db.ProgressEvent) error {
+	for _, value := range row {
+		if db.IsBinaryTextValue(value) {
+			progress.BinaryTextValues++
+		}
+		if size := getValueSize(value); options.largeValueSize > 0 && size > options.largeValueSize {
+			options.log.Warnf("row %d of table %s has a value of %d bytes, and dumping it takes memory proportional to its size", progress.Rows+1, tableName, size)
+		}
+	}
+
+	if err := inserts.write(row); err != nil {
+		return err
+	}
+
+	progress.Rows++
+	if progress.Rows%db.PROGRESS_ROWS_INTERVAL == 0 {
+		options.progress(*progress)
+	}
 	return nil
 }
 
-func getDbTableNames(config *DbCmdConfig) (db.StatementResult, error) {
-	listTablesResult, err := config.Db.ExecuteStatements("SELECT name FROM sqlite_master WHERE type='table' and name not like 'sqlite_%' and name != '_litestream_seq' and name != '_litestream_lock' and name != 'libsql_wasm_func_table'")
+// writeInsertStatement writes the INSERT statement of a row value by value, so that the statement is never held whole
+// in memory
+func writeInsertStatement(out io.Writer, insertInto string, row []interface{}, compat dumpCompat) error {
+	if _, err := io.WriteString(out, insertInto); err != nil {
+		return err
+	}
+	if err := writeInsertValues(out, row, compat); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, ");\n")
+	return err
+}
+
+func getValueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	case sql.NullString:
+		return int64(len(v.String))
+	default:
+		return 0
+	}
+}
+
+func getInsertInto(tableName string, columnNames []string, compat dumpCompat, quoteStyle enums.IdentifierQuoteStyle) string {
+	if compat.quoteStyle == "" {
+		var formattedTableName = tableName
+		if db.NeedsEscaping(tableName) {
+			formattedTableName = db.QuoteIdentifier(tableName, quoteStyle)
+		}
+		if compat.sqlite3Layout {
+			return "INSERT INTO " + formattedTableName + " VALUES("
+		}
+		return "INSERT INTO " + formattedTableName + " VALUES ("
+	}
+
+	quotedColumnNames := make([]string, 0, len(columnNames))
+	for _, columnName := range columnNames {
+		quotedColumnNames = append(quotedColumnNames, db.QuoteIdentifier(columnName, quoteStyle))
+	}
+	return "INSERT INTO " + db.QuoteIdentifier(tableName, quoteStyle) + " (" + strings.Join(quotedColumnNames, ", ") + ") VALUES ("
+}
+
+func getDbTableNames(config *DbCmdConfig, tableFilter db.TableFilter) (db.StatementResult, error) {
+	listTablesResult, err := config.Db.ExecuteStatements("SELECT name FROM sqlite_master WHERE type='table' AND " + tableFilter.Condition("name"))
 	if err != nil {
 		return db.StatementResult{}, err
 	}
@@ -110,51 +942,153 @@ func getDbTableNames(config *DbCmdConfig) (db.StatementResult, error) {
 	return statementResult, nil
 }
 
+// tableSchema holds the CREATE TABLE statement of a table and the statements creating its indexes and triggers
+type tableSchema struct {
+	createTable string
+	otherStmts  []string
+	// deferredTriggers are the triggers mentioning other tables or views, left out of otherStmts as they're created
+	// once all the tables and views are
+	deferredTriggers []string
+}
+
+// getDeferredTriggers returns the triggers of a table mentioning other tables or views, from the schemas read for the
+// dump
+func getDeferredTriggers(tableName string, options dumpOptions) []string {
+	if schema := options.schemas[strings.ToLower(tableName)]; schema != nil {
+		return schema.deferredTriggers
+	}
+	return nil
+}
+
+// getDumpTableSchema returns the schema of a table from the schemas read for the dump, or queries it when none were
+func getDumpTableSchema(config *DbCmdConfig, tableName string, options dumpOptions) (createTable string, otherStmts []string, err error) {
+	if options.schemas == nil {
+		return getTableSchema(config, tableName)
+	}
+	schema, ok := options.schemas[strings.ToLower(tableName)]
+	if !ok {
+		return "", nil, nil
+	}
+	return schema.createTable, schema.otherStmts, nil
+}
+
 func getTableSchema(config *DbCmdConfig, tableName string) (createTable string, otherStmts []string, err error) {
-	formattedTableName := db.EscapeSingleQuotes(tableName)
+	// tbl_name keeps the table name as written in each CREATE statement, and SQLite table names are case insensitive
+	// for ASCII letters, like NOCASE, so the indexes and triggers written with another case are matched too without
+	// matching any other table
+	schemas, err := queryTableSchemas(config, fmt.Sprintf("tbl_name='%s' COLLATE NOCASE", db.EscapeSingleQuotes(tableName)))
+	if err != nil {
+		return "", nil, err
+	}
+	schema, ok := schemas[strings.ToLower(tableName)]
+	if !ok {
+		return "", nil, nil
+	}
+	return schema.createTable, schema.otherStmts, nil
+}
+
+// getDbTableSchemas returns the schema of every table by its lower case name, read in a single query so that dumping
+// a database with many tables doesn't take a round trip per table
+func getDbTableSchemas(config *DbCmdConfig) (map[string]*tableSchema, error) {
+	return queryTableSchemas(config, "1")
+}
+
+func queryTableSchemas(config *DbCmdConfig, condition string) (map[string]*tableSchema, error) {
+	// The indexes SQLite creates for UNIQUE and PRIMARY KEY constraints have no sql, as the CREATE TABLE makes them
 	tableInfoResult, err := config.Db.ExecuteStatements(
-		fmt.Sprintf("SELECT type, sql || ';' FROM sqlite_master WHERE TBL_NAME='%s'", formattedTableName),
+		"SELECT tbl_name, type, sql || ';' FROM sqlite_master WHERE " + condition + " AND sql IS NOT NULL ORDER BY rowid",
 	)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	statementResult := <-tableInfoResult.StatementResultCh
 	if statementResult.Err != nil {
-		return "", nil, statementResult.Err
+		return nil, statementResult.Err
 	}
 
+	schemas := make(map[string]*tableSchema)
+	var triggers [][2]string
 	for statementRowResult := range statementResult.RowCh {
 		if statementRowResult.Err != nil {
-			return "", nil, statementResult.Err
+			return nil, statementRowResult.Err
 		}
 
 		formatted, err := db.FormatData(statementRowResult.Row, db.TABLE)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to format data: %w", err)
+			return nil, fmt.Errorf("failed to format data: %w", err)
 		}
-		if len(formatted) != 2 {
-			return "", nil, fmt.Errorf("expected 2 columns, got %d", len(formatted))
+		if len(formatted) != 3 {
+			return nil, fmt.Errorf("expected 3 columns, got %d", len(formatted))
 		}
 
-		kind := formatted[0]
-		sql := formatted[1]
+		tableName := strings.ToLower(formatted[0])
+		schema, ok := schemas[tableName]
+		if !ok {
+			schema = &tableSchema{}
+			schemas[tableName] = schema
+		}
+		kind := formatted[1]
+		sql := formatted[2]
 		if kind == "table" {
-			createTable = sql
+			schema.createTable = sql
 			continue
 		}
 
-		otherStmts = append(otherStmts, sql)
+		if kind == "trigger" {
+			triggers = append(triggers, [2]string{tableName, sql})
+		}
+		schema.otherStmts = append(schema.otherStmts, sql)
 	}
 
-	return
+	// The names of the tables and views are all known once every row is read
+	for _, trigger := range triggers {
+		schema := schemas[trigger[0]]
+		for _, identifier := range db.GetIdentifiers(trigger[1]) {
+			name := strings.ToLower(identifier)
+			if _, isTableOrView := schemas[name]; isTableOrView && name != trigger[0] {
+				schema.otherStmts = removeString(schema.otherStmts, trigger[1])
+				schema.deferredTriggers = append(schema.deferredTriggers, trigger[1])
+				break
+			}
+		}
+	}
+
+	return schemas, nil
 }
 
-func getTableRecords(config *DbCmdConfig, tableName string) (db.StatementResult, error) {
-	formattedTableName := db.EscapeSingleQuotes(tableName)
-	tableRecordsResult, err := config.Db.ExecuteStatements(
-		fmt.Sprintf("SELECT * FROM '%s'", formattedTableName),
-	)
+// getDumpTableRecords returns the records dumped of a table, in chunks of chunkSize records when it's set and the
+// table has a key to select them by
+func getDumpTableRecords(config *DbCmdConfig, tableName string, options dumpOptions) (db.StatementResult, error) {
+	columns, err := getDumpTableColumns(config, tableName, options)
+	if err != nil {
+		return db.StatementResult{}, err
+	}
+	condition := options.whereClauses[tableName]
+	if options.chunkSize > 0 {
+		keyColumn, err := getChunkKeyColumn(config, tableName)
+		if err != nil {
+			return db.StatementResult{}, err
+		}
+		if keyColumn != "" {
+			return selectDumpRecordsInChunks(config, tableName, keyColumn, columns, condition, nil, options.chunkSize)
+		}
+		options.log.Warnf("table %s has neither a rowid nor a single-column primary key to select its records in chunks by, so they're selected at once", tableName)
+	}
+	return selectDumpRecords(config, "SELECT "+columns+" FROM "+db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE), condition)
+}
+
+// getTableRecords returns the records of a table, only the ones matching the condition when it's not empty
+func getTableRecords(config *DbCmdConfig, tableName string, condition string) (db.StatementResult, error) {
+	return selectDumpRecords(config, "SELECT * FROM "+db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE), condition)
+}
+
+// selectDumpRecords returns the records selected by the query, only the ones matching the condition when it's not empty
+func selectDumpRecords(config *DbCmdConfig, query string, condition string) (db.StatementResult, error) {
+	if condition != "" {
+		query += " WHERE (" + condition + ")"
+	}
+	tableRecordsResult, err := config.Db.ExecuteStatements(query)
 	if err != nil {
 		return db.StatementResult{}, err
 	}
@@ -0,0 +1,50 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// getChunkKeyColumn returns the column the records of a table are selected in chunks by: its rowid, or else its
+// primary key when it's a single column. It's empty for the other tables, which are selected at once
+func getChunkKeyColumn(config *DbCmdConfig, tableName string) (string, error) {
+	rowidColumn, err := getRowidColumn(config, tableName)
+	if err != nil || rowidColumn != "" {
+		return rowidColumn, err
+	}
+	rows, err := queryAssertRows(config, fmt.Sprintf("SELECT name FROM pragma_table_info('%s') WHERE pk > 0", db.EscapeSingleQuotes(tableName)))
+	if err != nil || len(rows) != 1 {
+		return "", err
+	}
+	return db.QuoteIdentifier(rows[0][0], enums.DOUBLE_QUOTE_STYLE), nil
+}
+
+// selectDumpRecordsInChunks selects the columns of the records of a table matching the condition, when it's not
+// empty, in chunks of chunkSize records ordered by keyColumn. The records start after the key after when it's not nil
+func selectDumpRecordsInChunks(config *DbCmdConfig, tableName string, keyColumn string, columns string, condition string, after interface{}, chunkSize int) (db.StatementResult, error) {
+	return config.Db.SelectInChunks(func(lastKey interface{}) (string, error) {
+		if lastKey == nil {
+			lastKey = after
+		}
+		conditions := make([]string, 0, 2)
+		if condition != "" {
+			conditions = append(conditions, "("+condition+")")
+		}
+		if lastKey != nil {
+			var literal strings.Builder
+			if err := db.WriteValue(&literal, lastKey, db.SQLITE); err != nil {
+				return "", err
+			}
+			conditions = append(conditions, keyColumn+" > "+literal.String())
+		}
+
+		query := "SELECT " + keyColumn + ", " + columns + " FROM " + db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		return query + fmt.Sprintf(" ORDER BY %s LIMIT %d", keyColumn, chunkSize), nil
+	}, chunkSize)
+}
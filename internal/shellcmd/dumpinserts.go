@@ -0,0 +1,119 @@
+package shellcmd
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// defaultMultiRowInsertRows is the number of rows of each INSERT statement given to --multi-row-insert without a value
+const defaultMultiRowInsertRows = 100
+
+// multiRowInsertMaxSize is the size in bytes a multi-row INSERT statement stops taking rows at, so that wide rows
+// don't make statements too large for the engine restoring them, unless the sql_length limit of the database is lower.
+// A single row larger than it still gets its own statement
+const multiRowInsertMaxSize = 1024 * 1024
+
+// recordInserts writes the INSERT statements of the records of a table, coalescing up to rowsPerInsert records into
+// each one when it's more than 1. end must be called once the last record is written, to end the statement holding it
+type recordInserts struct {
+	out           io.Writer
+	tableName     string
+	insertInto    string
+	compat        dumpCompat
+	rowsPerInsert int
+	maxSize       int64
+	// sizeLimit is the sql_length limit when it's what sets maxSize, 0 otherwise
+	sizeLimit int64
+	log       *db.Logger
+	// rows and size are the number of records of the statement being written and its size, and values holds the values
+	// of the record being added to it, written before it's known whether they fit
+	rows   int
+	size   int64
+	values bytes.Buffer
+	// capped is set once the sql_length limit made a statement end before it held rowsPerInsert records
+	capped bool
+}
+
+func newRecordInserts(out io.Writer, tableName string, insertInto string, options dumpOptions) *recordInserts {
+	inserts := &recordInserts{out: out, tableName: tableName, insertInto: insertInto, compat: options.compat, rowsPerInsert: options.rowsPerInsert, maxSize: multiRowInsertMaxSize, log: options.log}
+	if limit := options.limits.SQLLength; limit > 0 && limit < multiRowInsertMaxSize {
+		inserts.maxSize = limit
+		inserts.sizeLimit = limit
+	}
+	return inserts
+}
+
+func (i *recordInserts) write(row []interface{}) error {
+	if i.rowsPerInsert <= 1 {
+		return writeInsertStatement(i.out, i.insertInto, row, i.compat)
+	}
+
+	i.values.Reset()
+	if err := writeInsertValues(&i.values, row, i.compat); err != nil {
+		return err
+	}
+	// The statement is already as long as its INSERT INTO and ending ");", and each record after the first one adds ),(
+	rowSize := int64(i.values.Len())
+	if i.rows > 0 {
+		rowSize += 3
+	}
+	if i.rows > 0 && i.size+rowSize > i.maxSize {
+		if i.sizeLimit > 0 && !i.capped {
+			i.capped = true
+			i.log.Notef("the INSERT statements of table %s hold fewer than %d records, to stay under the sql_length limit of %d bytes", i.tableName, i.rowsPerInsert, i.sizeLimit)
+		}
+		if err := i.end(); err != nil {
+			return err
+		}
+		rowSize -= 3
+	}
+	prefix := "),("
+	if i.rows == 0 {
+		prefix = i.insertInto
+		i.size = int64(len(i.insertInto)) + 2
+	}
+	if _, err := io.WriteString(i.out, prefix); err != nil {
+		return err
+	}
+	if _, err := i.values.WriteTo(i.out); err != nil {
+		return err
+	}
+	i.rows++
+	i.size += rowSize
+	if i.rows == i.rowsPerInsert {
+		return i.end()
+	}
+	return nil
+}
+
+// end ends the statement being written, if any
+func (i *recordInserts) end() error {
+	if i.rows == 0 {
+		return nil
+	}
+	i.rows = 0
+	i.size = 0
+	_, err := io.WriteString(i.out, ");\n")
+	return err
+}
+
+// writeInsertValues writes the values of a row separated by commas, as in the VALUES of an INSERT statement
+func writeInsertValues(out io.Writer, row []interface{}, compat dumpCompat) error {
+	separator := ", "
+	if compat.sqlite3Layout {
+		separator = ","
+	}
+	for i, value := range row {
+		if i > 0 {
+			if _, err := io.WriteString(out, separator); err != nil {
+				return err
+			}
+		}
+		if err := db.WriteValue(out, value, compat.formatType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package shellcmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// resumeCheckpointRows is how many records of a table a resumable dump writes between two checkpoints, the most it
+// writes again when resumed
+const resumeCheckpointRows = 10000
+
+// dumpResumeManifest records how far a resumable split dump went
+type dumpResumeManifest struct {
+	// Fingerprint identifies the schema of the database and the options of the dump, which must be the same to resume it
+	Fingerprint string            `json:"fingerprint"`
+	Tables      []dumpResumeTable `json:"tables"`
+}
+
+type dumpResumeTable struct {
+	Table    string `json:"table"`
+	File     string `json:"file"`
+	Started  bool   `json:"started"`
+	Complete bool   `json:"complete"`
+	Rows     int64  `json:"rows"`
+	// LastRowid is the rowid of the last record written, nil before the first one is or when the table has no rowid
+	LastRowid *int64 `json:"last_rowid,omitempty"`
+	// Size is the size of File once the records up to LastRowid were written. The file is truncated to it on resume,
+	// leaving out the records written after the last checkpoint
+	Size int64 `json:"size"`
+}
+
+// dumpSplitResumably writes a split dump like dumpSplit, recording its progress in the manifest at manifestPath so that
+// a run interrupted can be resumed: the tables complete are skipped, and the one in progress is continued after the
+// rowid of its last record written, appending to its file. The tables without rowid are dumped again from scratch
+func dumpSplitResumably(config *DbCmdConfig, options dumpOptions, dir string, force bool, manifestPath string) error {
+	startTime := time.Now()
+
+	tableNames, err := getDumpTableNamesInDependencyOrder(config, options.tableFilter)
+	if err != nil {
+		return err
+	}
+	tableNames = selectShadowTables(tableNames, options)
+	options.schemas, err = getDbTableSchemas(config)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := getDumpFingerprint(config, options, tableNames)
+	if err != nil {
+		return err
+	}
+	fileNames := getSplitDumpFileNames(tableNames)
+
+	manifest, found, err := readDumpResumeManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if found {
+		if manifest.Fingerprint != fingerprint {
+			return fmt.Errorf("the schema or the options of the dump changed since %s was written, so the dump can't be resumed. Remove it to dump from scratch", manifestPath)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create the dump directory: %w", err)
+		}
+	} else {
+		if err := prepareSplitDumpDir(dir, force); err != nil {
+			return err
+		}
+		manifest = dumpResumeManifest{Fingerprint: fingerprint, Tables: make([]dumpResumeTable, 0, len(tableNames))}
+		for i, tableName := range tableNames {
+			manifest.Tables = append(manifest.Tables, dumpResumeTable{Table: tableName, File: fileNames[i+1]})
+		}
+		if err := writeDumpResumeManifest(manifestPath, manifest); err != nil {
+			return err
+		}
+	}
+
+	if err := writeSplitDumpSchema(config, options, filepath.Join(dir, fileNames[0]), tableNames); err != nil {
+		return err
+	}
+
+	var totals db.ProgressEvent
+	splitManifest := splitDumpManifest{Schema: fileNames[0], Tables: make([]splitDumpManifestTable, 0, len(tableNames))}
+	for i := range manifest.Tables {
+		table := &manifest.Tables[i]
+		tableProgress := db.ProgressEvent{Rows: table.Rows}
+		if !table.Complete {
+			tableProgress, err = dumpTableResumably(config, options, dir, manifestPath, &manifest, table)
+			if err != nil {
+				return err
+			}
+		}
+
+		addTableProgress(&totals, tableProgress)
+		splitManifest.Tables = append(splitManifest.Tables, splitDumpManifestTable{Table: table.Table, File: table.File, Rows: table.Rows})
+	}
+
+	if err := writeSplitDumpManifest(dir, splitManifest); err != nil {
+		return err
+	}
+
+	totals.Kind = db.OPERATION_FINISHED
+	totals.Duration = time.Since(startTime)
+	options.progress(totals)
+	return nil
+}
+
+// dumpTableResumably writes the records, indexes and triggers of a table to its file, continuing after the last
+// record written when the table was already started. The manifest is written at each checkpoint, once the records
+// before it are synced to the file
+func dumpTableResumably(config *DbCmdConfig, options dumpOptions, dir string, manifestPath string, manifest *dumpResumeManifest, table *dumpResumeTable) (tableProgress db.ProgressEvent, err error) {
+	tableStartTime := time.Now()
+	options.progress(db.ProgressEvent{Kind: db.TABLE_STARTED, Table: table.Table})
+
+	_, otherStmts, err := getDumpTableSchema(config, table.Table, options)
+	if err != nil {
+		return tableProgress, err
+	}
+	rowidColumn, err := getRowidColumn(config, table.Table)
+	if err != nil {
+		return tableProgress, err
+	}
+	// The records of the virtual tables and their shadow tables are selected their own way, so they're always dumped
+	// from scratch
+	if options.virtualTables.get(table.Table) != nil || options.virtualTables.getOwner(table.Table) != nil {
+		rowidColumn = ""
+		table.Started = false
+	}
+	if table.Started && rowidColumn == "" {
+		options.log.Warnf("table %s has no rowid to continue its dump from, so it's dumped again from scratch", table.Table)
+		table.Started = false
+	}
+
+	file, err := openResumedTableFile(filepath.Join(dir, table.File), table, options)
+	if err != nil {
+		return tableProgress, err
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	checkpoint := func() error {
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			return err
+		}
+		size, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		table.Size = size
+		return writeDumpResumeManifest(manifestPath, *manifest)
+	}
+
+	if rowidColumn == "" {
+		tableProgress, err = dumpTableRecordsFromScratch(out, config, table, options)
+	} else {
+		tableProgress, err = dumpTableRecordsAfterRowid(out, config, table, rowidColumn, options, checkpoint)
+	}
+	if err != nil {
+		return tableProgress, err
+	}
+
+	for _, stmt := range append(otherStmts, getDeferredTriggers(table.Table, options)...) {
+		fmt.Fprintln(out, stmt)
+	}
+	table.Complete = true
+	if err := checkpoint(); err != nil {
+		return tableProgress, err
+	}
+
+	tableProgress.Kind = db.TABLE_FINISHED
+	tableProgress.Tables = 1
+	tableProgress.Duration = time.Since(tableStartTime)
+	options.progress(tableProgress)
+	return tableProgress, nil
+}
+
+// openResumedTableFile opens the file of a table positioned after its last record written, truncating what follows,
+// or creates it with the preamble of the dump when the table wasn't started
+func openResumedTableFile(path string, table *dumpResumeTable, options dumpOptions) (*os.File, error) {
+	if !table.Started {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		writeDumpPreamble(file, options)
+		size, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		*table = dumpResumeTable{Table: table.Table, File: table.File, Started: true, Size: size}
+		return file, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s to resume the dump of table %s: %w", path, table.Table, err)
+	}
+	if err := file.Truncate(table.Size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to resume the dump of table %s in %s: %w", table.Table, path, err)
+	}
+	if _, err := file.Seek(table.Size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to resume the dump of table %s in %s: %w", table.Table, path, err)
+	}
+	return file, nil
+}
+
+func dumpTableRecordsFromScratch(out io.Writer, config *DbCmdConfig, table *dumpResumeTable, options dumpOptions) (db.ProgressEvent, error) {
+	if !dumpsVirtualTableRecords(table.Table, options) {
+		return db.ProgressEvent{}, nil
+	}
+	tableRecordsStatementResult, err := getDumpTableRecords(config, table.Table, options)
+	if err != nil {
+		return db.ProgressEvent{}, err
+	}
+	tableProgress, err := dumpTableRecords(out, tableRecordsStatementResult, table.Table, options)
+	table.Rows = tableProgress.Rows
+	return tableProgress, err
+}
+
+// dumpTableRecordsAfterRowid writes the records of a table in rowid order, starting after the last one written, with a
+// checkpoint every resumeCheckpointRows records
+func dumpTableRecordsAfterRowid(out io.Writer, config *DbCmdConfig, table *dumpResumeTable, rowidColumn string, options dumpOptions, checkpoint func() error) (db.ProgressEvent, error) {
+	progress := db.ProgressEvent{Kind: db.ROWS_PROCESSED, Table: table.Table, Rows: table.Rows}
+
+	statementResult, err := selectResumedTableRecords(config, table, rowidColumn, options)
+	if err != nil {
+		return progress, err
+	}
+
+	insertInto := getInsertInto(table.Table, statementResult.ColumnNames[1:], options.compat, options.quoteStyle)
+	inserts := newRecordInserts(out, table.Table, insertInto, options)
+	sinceCheckpoint := 0
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return progress, rowResult.Err
+		}
+		rowid, err := getRowid(rowResult.Row[0])
+		if err != nil {
+			return progress, err
+		}
+		row := rowResult.Row[1:]
+		if options.columnTransform != nil {
+			row = db.TransformRow(options.columnTransform, statementResult.ColumnNames[1:], row)
+		}
+		if err := dumpRecord(inserts, row, table.Table, options, &progress); err != nil {
+			return progress, err
+		}
+
+		table.LastRowid = &rowid
+		table.Rows = progress.Rows
+		sinceCheckpoint++
+		if sinceCheckpoint == resumeCheckpointRows {
+			// The file is truncated to the checkpoint on resume, so it must not end in the middle of a statement
+			if err := inserts.end(); err != nil {
+				return progress, err
+			}
+			if err := checkpoint(); err != nil {
+				return progress, err
+			}
+			sinceCheckpoint = 0
+		}
+	}
+	return progress, inserts.end()
+}
+
+// selectResumedTableRecords selects the records of a table after the last one written along with their rowid, in
+// chunks with --chunked-select
+func selectResumedTableRecords(config *DbCmdConfig, table *dumpResumeTable, rowidColumn string, options dumpOptions) (db.StatementResult, error) {
+	condition := options.whereClauses[table.Table]
+	if options.chunkSize > 0 {
+		var after interface{}
+		if table.LastRowid != nil {
+			after = *table.LastRowid
+		}
+		return selectDumpRecordsInChunks(config, table.Table, rowidColumn, rowidColumn+", *", condition, after, options.chunkSize)
+	}
+
+	query := fmt.Sprintf("SELECT %s, * FROM %s", rowidColumn, db.QuoteIdentifier(table.Table, enums.DOUBLE_QUOTE_STYLE))
+	conditions := make([]string, 0, 2)
+	if condition != "" {
+		conditions = append(conditions, "("+condition+")")
+	}
+	if table.LastRowid != nil {
+		conditions = append(conditions, fmt.Sprintf("%s > %d", rowidColumn, *table.LastRowid))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + rowidColumn
+
+	result, err := config.Db.ExecuteStatements(query)
+	if err != nil {
+		return db.StatementResult{}, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return db.StatementResult{}, statementResult.Err
+	}
+	return statementResult, nil
+}
+
+func getRowid(value interface{}) (int64, error) {
+	if rowid, ok := value.(int64); ok {
+		return rowid, nil
+	}
+	formattedValue, err := db.FormatData([]interface{}{value}, db.TABLE)
+	if err != nil {
+		return 0, err
+	}
+	rowid, err := strconv.ParseInt(formattedValue[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rowid %s", formattedValue[0])
+	}
+	return rowid, nil
+}
+
+// getRowidColumn returns the name the rowid of a table is selected with: rowid, or _rowid_ or oid when the table has
+// columns of these names. It's empty for the tables without rowid
+func getRowidColumn(config *DbCmdConfig, tableName string) (string, error) {
+	result, err := config.Db.ExecuteStatements(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", db.EscapeSingleQuotes(tableName)))
+	if err != nil {
+		return "", err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return "", statementResult.Err
+	}
+	columnNames, err := readFirstColumn(statementResult)
+	if err != nil {
+		return "", err
+	}
+	columns := make(map[string]bool, len(columnNames))
+	for _, name := range columnNames {
+		columns[strings.ToLower(name)] = true
+	}
+
+	for _, alias := range []string{"rowid", "_rowid_", "oid"} {
+		if columns[alias] {
+			continue
+		}
+		// Selecting it fails for WITHOUT ROWID tables
+		if executeStatementsSilently(config, fmt.Sprintf("SELECT %s FROM %s LIMIT 0;", alias, db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE))) != nil {
+			return "", nil
+		}
+		return alias, nil
+	}
+	return "", nil
+}
+
+// getDumpFingerprint hashes the schema of the database along with the tables dumped and the options changing the
+// output of the dump
+func getDumpFingerprint(config *DbCmdConfig, options dumpOptions, tableNames []string) (string, error) {
+	result, err := config.Db.ExecuteStatements("SELECT type, name, tbl_name, sql FROM sqlite_master ORDER BY type, name")
+	if err != nil {
+		return "", err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return "", statementResult.Err
+	}
+
+	hash := sha256.New()
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return "", rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n", strings.Join(formattedRow, "\x00"))
+	}
+
+	fmt.Fprintf(hash, "%d\x00%s\x00%s\x00%s\n", options.compat.formatType, options.compat.preamble, options.quoteStyle, strings.Join(tableNames, "\x00"))
+	whereTables := make([]string, 0, len(options.whereClauses))
+	for tableName := range options.whereClauses {
+		whereTables = append(whereTables, tableName)
+	}
+	sort.Strings(whereTables)
+	for _, tableName := range whereTables {
+		fmt.Fprintf(hash, "%s\x00%s\n", tableName, options.whereClauses[tableName])
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func readDumpResumeManifest(path string) (manifest dumpResumeManifest, found bool, err error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, false, nil
+	}
+	if err != nil {
+		return manifest, false, fmt.Errorf("failed to read the resume manifest: %w", err)
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, false, fmt.Errorf("invalid resume manifest %s: %w", path, err)
+	}
+	return manifest, true, nil
+}
+
+func writeDumpResumeManifest(path string, manifest dumpResumeManifest) error {
+	return writeFileAtomically(path, func(out io.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	})
+}
@@ -0,0 +1,301 @@
+package shellcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+const splitDumpManifestFile = "manifest.json"
+
+const maxSplitDumpFileNameLength = 100
+
+// splitDumpManifest describes the files of a split dump. The schema file must be restored first, and then the table
+// files in any order, or in parallel
+type splitDumpManifest struct {
+	Schema string                   `json:"schema"`
+	Tables []splitDumpManifestTable `json:"tables"`
+}
+
+type splitDumpManifestTable struct {
+	Table string `json:"table"`
+	File  string `json:"file"`
+	Rows  int64  `json:"rows"`
+}
+
+// dumpSplit writes the dump into dir: the CREATE TABLE statements to a schema file, and the records, indexes and
+// triggers of each table to a file of its own, numbered so that referenced tables come first. Each file is written to
+// a temporary file renamed once complete, so no partial file is left behind
+func dumpSplit(config *DbCmdConfig, options dumpOptions, dir string, force bool) error {
+	startTime := time.Now()
+
+	if err := prepareSplitDumpDir(dir, force); err != nil {
+		return err
+	}
+
+	tableNames, err := getDumpTableNamesInDependencyOrder(config, options.tableFilter)
+	if err != nil {
+		return err
+	}
+	tableNames = selectShadowTables(tableNames, options)
+	options.schemas, err = getDbTableSchemas(config)
+	if err != nil {
+		return err
+	}
+
+	fileNames := getSplitDumpFileNames(tableNames)
+	manifest := splitDumpManifest{Schema: fileNames[0], Tables: make([]splitDumpManifestTable, 0, len(tableNames))}
+	if err := writeSplitDumpSchema(config, options, filepath.Join(dir, manifest.Schema), tableNames); err != nil {
+		return err
+	}
+
+	var totals db.ProgressEvent
+	var droppedTables []string
+	for i, tableName := range tableNames {
+		fileName := fileNames[i+1]
+		var tableProgress db.ProgressEvent
+		err := writeFileAtomically(filepath.Join(dir, fileName), func(out io.Writer) error {
+			writeDumpPreamble(out, options)
+			var err error
+			tableProgress, err = dumpTable(out, config, tableName, options, false)
+			return err
+		})
+		// The table is left out of the manifest, though the schema file still creates it
+		if errors.Is(err, errTableDropped) {
+			droppedTables = append(droppedTables, tableName)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		addTableProgress(&totals, tableProgress)
+		manifest.Tables = append(manifest.Tables, splitDumpManifestTable{Table: tableName, File: fileName, Rows: tableProgress.Rows})
+	}
+
+	if err := writeSplitDumpManifest(dir, manifest); err != nil {
+		return err
+	}
+
+	totals.Kind = db.OPERATION_FINISHED
+	totals.Duration = time.Since(startTime)
+	options.progress(totals)
+	if len(droppedTables) > 0 {
+		return &droppedTablesError{tables: droppedTables}
+	}
+	return nil
+}
+
+// getSplitDumpFileNames returns the name of the schema file followed by the names of the files of the tables, numbered
+// in their order
+func getSplitDumpFileNames(tableNames []string) []string {
+	numberWidth := len(strconv.Itoa(len(tableNames)))
+	if numberWidth < 3 {
+		numberWidth = 3
+	}
+	fileNames := make([]string, 0, len(tableNames)+1)
+	fileNames = append(fileNames, fmt.Sprintf("%0*d_schema.sql", numberWidth, 0))
+	for i, tableName := range tableNames {
+		fileNames = append(fileNames, fmt.Sprintf("%0*d_%s.sql", numberWidth, i+1, sanitizeFileName(tableName)))
+	}
+	return fileNames
+}
+
+// writeSplitDumpSchema writes the CREATE TABLE statements of the tables to the schema file of a split dump, followed by
+// the views
+func writeSplitDumpSchema(config *DbCmdConfig, options dumpOptions, path string, tableNames []string) error {
+	return writeFileAtomically(path, func(out io.Writer) error {
+		writeDumpPreamble(out, options)
+		for _, tableName := range tableNames {
+			createTableStmt, _, err := getDumpTableSchema(config, tableName, options)
+			if err != nil {
+				return err
+			}
+			writeDumpCreateTable(out, tableName, createTableStmt, options)
+		}
+		return dumpViewsAndTriggers(out, config, tableNames, options, false)
+	})
+}
+
+func writeSplitDumpManifest(dir string, manifest splitDumpManifest) error {
+	return writeFileAtomically(filepath.Join(dir, splitDumpManifestFile), func(out io.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	})
+}
+
+func writeDumpPreamble(out io.Writer, options dumpOptions) {
+	if options.compat.preamble != "" {
+		fmt.Fprintln(out, options.compat.preamble)
+	}
+}
+
+// prepareSplitDumpDir creates the directory of a split dump, refusing to write into a directory holding files unless
+// forced, as they could be mixed up with the ones of the dump
+func prepareSplitDumpDir(dir string, force bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create the dump directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the dump directory: %w", err)
+	}
+	if len(entries) > 0 && !force {
+		return fmt.Errorf("dump directory %s is not empty. Use --force to write into it anyway", dir)
+	}
+	return nil
+}
+
+// writeFileAtomically writes a file through a temporary file in the same directory, renamed to path once it's
+// complete and synced
+func writeFileAtomically(path string, write func(out io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	if err := write(out); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// sanitizeFileName turns a table name into a file name portable across filesystems, replacing the characters other
+// than letters, digits, '-', '_' and '.' with '_'. The names of different tables may end up the same, which the number
+// of their files keeps apart
+func sanitizeFileName(name string) string {
+	var sanitized strings.Builder
+	for _, r := range name {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '_', r == '.':
+			sanitized.WriteRune(r)
+		default:
+			sanitized.WriteRune('_')
+		}
+		if sanitized.Len() == maxSplitDumpFileNameLength {
+			break
+		}
+	}
+	return sanitized.String()
+}
+
+// getDumpTableNamesInDependencyOrder returns the names of the tables to dump, with the tables referenced by foreign
+// keys before the ones referencing them. Otherwise, and for tables referencing each other, the tables keep the order
+// of the schema
+func getDumpTableNamesInDependencyOrder(config *DbCmdConfig, tableFilter db.TableFilter) ([]string, error) {
+	getTableNamesStatementResult, err := getDbTableNames(config, tableFilter)
+	if err != nil {
+		return nil, err
+	}
+	tableNames, err := readFirstColumn(getTableNamesStatementResult)
+	if err != nil {
+		return nil, err
+	}
+
+	// Foreign keys may name the tables they reference in another case
+	dumpedTables := make(map[string]string, len(tableNames))
+	for _, tableName := range tableNames {
+		dumpedTables[strings.ToLower(tableName)] = tableName
+	}
+	references := make(map[string][]string, len(tableNames))
+	for _, tableName := range tableNames {
+		referencedTables, err := getReferencedTables(config, tableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, referencedTable := range referencedTables {
+			referencedTable, dumped := dumpedTables[strings.ToLower(referencedTable)]
+			if dumped && referencedTable != tableName {
+				references[tableName] = append(references[tableName], referencedTable)
+			}
+		}
+	}
+
+	ordered := make([]string, 0, len(tableNames))
+	placed := make(map[string]bool, len(tableNames))
+	for len(ordered) < len(tableNames) {
+		next := -1
+		for i, tableName := range tableNames {
+			if !placed[tableName] && allPlaced(references[tableName], placed) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			// The tables left reference each other
+			for i, tableName := range tableNames {
+				if !placed[tableName] {
+					next = i
+					break
+				}
+			}
+		}
+		ordered = append(ordered, tableNames[next])
+		placed[tableNames[next]] = true
+	}
+	return ordered, nil
+}
+
+func allPlaced(tableNames []string, placed map[string]bool) bool {
+	for _, tableName := range tableNames {
+		if !placed[tableName] {
+			return false
+		}
+	}
+	return true
+}
+
+func getReferencedTables(config *DbCmdConfig, tableName string) ([]string, error) {
+	foreignKeysResult, err := config.Db.ExecuteStatements(
+		fmt.Sprintf(`SELECT DISTINCT "table" FROM pragma_foreign_key_list('%s')`, db.EscapeSingleQuotes(tableName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	statementResult := <-foreignKeysResult.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+	return readFirstColumn(statementResult)
+}
+
+func readFirstColumn(statementResult db.StatementResult) ([]string, error) {
+	values := make([]string, 0)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return nil, rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, formattedRow[0])
+	}
+	return values, nil
+}
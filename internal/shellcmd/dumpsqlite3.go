@@ -0,0 +1,86 @@
+package shellcmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// The dumps of --compat sqlite3 follow the .dump of the sqlite3 CLI statement by statement, so that they can be diffed
+// with its dumps: the tables come in the order they were created, each CREATE TABLE followed by its records, with the
+// statistics of ANALYZE and sqlite_sequence among them. The views, triggers and indexes come last
+
+// writeSQLite3DumpStart writes the first lines of a dump but, like the sqlite3 CLI, not of a --data-only one, made to
+// be loaded into an existing database
+func writeSQLite3DumpStart(out io.Writer, options dumpOptions) {
+	if options.firstVirtualTable != "" {
+		fmt.Fprintln(out, "/* WARNING: Script requires that SQLITE_DBCONFIG_DEFENSIVE be disabled */")
+	}
+	if options.dataOnly {
+		return
+	}
+	writeDumpPreamble(out, options)
+	fmt.Fprintln(out, options.compat.beginTransaction)
+}
+
+// getDumpTableNames returns the names of the tables to dump, in the order of the sqlite3 CLI with sqlite3Layout
+func getDumpTableNames(config *DbCmdConfig, options dumpOptions) ([]string, error) {
+	if !options.compat.sqlite3Layout {
+		getTableNamesStatementResult, err := getDbTableNames(config, options.tableFilter)
+		if err != nil {
+			return nil, err
+		}
+		return readFirstColumn(getTableNamesStatementResult)
+	}
+
+	// The sqlite3 CLI dumps sqlite_sequence and the sqlite_stat tables, but none of the other internal tables of SQLite
+	tableFilter := options.tableFilter
+	tableFilter.IncludeInternal = true
+	rows, err := queryAssertRows(config, "SELECT name FROM sqlite_master WHERE type='table' AND sql IS NOT NULL AND "+tableFilter.Condition("name")+
+		` AND (name NOT LIKE 'sqlite\_%' ESCAPE '\' OR name = 'sqlite_sequence' OR name GLOB 'sqlite_stat?') ORDER BY tbl_name = 'sqlite_sequence', rowid`)
+	if err != nil {
+		return nil, err
+	}
+	tableNames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		tableNames = append(tableNames, row[0])
+	}
+	return tableNames, nil
+}
+
+// writeSQLite3CreateTable writes the CREATE TABLE statement of a table as the sqlite3 CLI does. sqlite_sequence has
+// none, as it's created by the first AUTOINCREMENT table, and the sqlite_stat tables are created by ANALYZE. A table
+// whose name is quoted is created IF NOT EXISTS, in case it's one SQLite creates by itself
+func writeSQLite3CreateTable(out io.Writer, tableName string, createTableStmt string) {
+	switch {
+	case tableName == sequenceTable:
+	case strings.HasPrefix(tableName, "sqlite_stat"):
+		fmt.Fprintln(out, "ANALYZE sqlite_schema;")
+	case strings.HasPrefix(createTableStmt, `CREATE TABLE "`) || strings.HasPrefix(createTableStmt, "CREATE TABLE '"):
+		fmt.Fprintln(out, "CREATE TABLE IF NOT EXISTS "+createTableStmt[len("CREATE TABLE "):])
+	default:
+		fmt.Fprintln(out, createTableStmt)
+	}
+}
+
+// dumpSQLite3SchemaObjects writes the statements creating the views, the triggers and the indexes once all the tables
+// are dumped, as the sqlite3 CLI does. Like it, the tables given as arguments select them by their own name, while the
+// ones left out with --exclude leave out their indexes and triggers too
+func dumpSQLite3SchemaObjects(out io.Writer, config *DbCmdConfig, options dumpOptions) error {
+	if !options.compat.sqlite3Layout || options.dataOnly {
+		return nil
+	}
+	included := db.TableFilter{Include: options.tableFilter.Include, IncludeInternal: true}
+	notExcluded := db.TableFilter{Exclude: options.tableFilter.Exclude, IncludeInternal: true}
+	rows, err := queryAssertRows(config, "SELECT sql || ';' FROM sqlite_master WHERE type IN ('index', 'trigger', 'view') AND sql IS NOT NULL AND "+
+		included.Condition("name")+" AND "+notExcluded.Condition("tbl_name")+" ORDER BY type COLLATE NOCASE DESC, rowid")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fmt.Fprintln(out, row[0])
+	}
+	return nil
+}
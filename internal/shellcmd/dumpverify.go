@@ -0,0 +1,252 @@
+package shellcmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+const defaultVerifyMaxRows = 1000000
+
+// tableDigest holds the number of rows of a table and the SHA-256 hash of their values, compared by .dump --verify
+type tableDigest struct {
+	rows int64
+	hash string
+}
+
+// dumpAndVerify dumps like dumpTo, and with options.verify restores the dump into a temporary database once written,
+// comparing the rows of each of its tables with the ones of the database. The comparison runs in the read transaction
+// of the dump when there's one, so it's against the snapshot dumped. It's skipped, with a warning, when the tables have
+// more rows than options.verifyMaxRows
+func dumpAndVerify(config *DbCmdConfig, options dumpOptions, file string, splitDir string, resumeManifest string, force bool) error {
+	if !options.verify {
+		return dumpTo(config, options, file, splitDir, resumeManifest, force)
+	}
+
+	tableNames, err := getVerifiedTableNames(config, options)
+	if err != nil {
+		return err
+	}
+	var totalRows int64
+	for _, tableName := range tableNames {
+		rows, err := queryAssertRows(config, "SELECT count(*) FROM "+getVerifiedTableSource(tableName, options.whereClauses[tableName]))
+		if err != nil {
+			return err
+		}
+		count, err := strconv.ParseInt(rows[0][0], 10, 64)
+		if err != nil {
+			return err
+		}
+		totalRows += count
+	}
+	if options.verifyMaxRows > 0 && totalRows > options.verifyMaxRows {
+		options.log.Warnf("the dump isn't verified, as its %s rows are more than the %s of --verify-max-rows", db.FormatCount(totalRows), db.FormatCount(options.verifyMaxRows))
+		return dumpTo(config, options, file, splitDir, resumeManifest, force)
+	}
+
+	var output bytes.Buffer
+	if file == "" && splitDir == "" {
+		options.outputCopy = &output
+	}
+	if err := dumpTo(config, options, file, splitDir, resumeManifest, force); err != nil {
+		return err
+	}
+	scripts, err := readDumpScripts(config, file, splitDir, output.String())
+	if err != nil {
+		return err
+	}
+	return verifyDump(config, options, tableNames, scripts)
+}
+
+// getVerifiedTableNames returns the tables dumped whose rows are compared with their restored copy. The internal tables
+// of SQLite, the virtual tables and their shadow tables are left out, as their content is rebuilt rather than copied
+func getVerifiedTableNames(config *DbCmdConfig, options dumpOptions) ([]string, error) {
+	getTableNamesStatementResult, err := getDbTableNames(config, options.tableFilter)
+	if err != nil {
+		return nil, err
+	}
+	tableNames, err := readFirstColumn(getTableNamesStatementResult)
+	if err != nil {
+		return nil, err
+	}
+	virtualTables, err := getVirtualTables(config)
+	if err != nil {
+		return nil, err
+	}
+	verified := make([]string, 0, len(tableNames))
+	for _, tableName := range tableNames {
+		name := strings.ToLower(tableName)
+		if strings.HasPrefix(name, "sqlite_") || virtualTables.byName[name] != nil || virtualTables.byShadow[name] != nil {
+			continue
+		}
+		verified = append(verified, tableName)
+	}
+	return verified, nil
+}
+
+func getVerifiedTableSource(tableName string, condition string) string {
+	source := db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
+	if condition != "" {
+		source += " WHERE (" + condition + ")"
+	}
+	return source
+}
+
+// readDumpScripts returns the scripts of the dump written, in the order they're restored: the output, the file, or the
+// schema file and the table files listed by the manifest of the split directory
+func readDumpScripts(config *DbCmdConfig, file string, splitDir string, output string) ([]string, error) {
+	if file == "" && splitDir == "" {
+		return []string{output}, nil
+	}
+	if file != "" {
+		path, err := ResolveFilePath(config.FileRoot, file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the dump to verify it: %w", err)
+		}
+		return []string{string(content)}, nil
+	}
+
+	dir, err := ResolveFilePath(config.FileRoot, splitDir)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(dir, splitDumpManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dump to verify it: %w", err)
+	}
+	var manifest splitDumpManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to read the dump to verify it: %s: %w", splitDumpManifestFile, err)
+	}
+	fileNames := []string{manifest.Schema}
+	for _, table := range manifest.Tables {
+		fileNames = append(fileNames, table.File)
+	}
+	scripts := make([]string, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		content, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the dump to verify it: %w", err)
+		}
+		scripts = append(scripts, string(content))
+	}
+	return scripts, nil
+}
+
+// verifyDump restores the scripts of the dump into a temporary database, removed whatever the outcome, and compares
+// the digest of each table with the one of the database, failing with the tables that differ
+func verifyDump(config *DbCmdConfig, options dumpOptions, tableNames []string, scripts []string) error {
+	tempFile, err := os.CreateTemp("", "libsql-shell-verify-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create the database to verify the dump in: %w", err)
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	defer func() {
+		for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+			os.Remove(tempPath + suffix)
+		}
+	}()
+	restoredDb, err := db.NewDb(tempPath, "")
+	if err != nil {
+		return fmt.Errorf("failed to create the database to verify the dump in: %w", err)
+	}
+	defer restoredDb.Close()
+
+	for _, script := range scripts {
+		if err := executeStatementsSilentlyIn(restoredDb, script); err != nil {
+			return fmt.Errorf("the dump failed to restore into an empty database: %w", err)
+		}
+	}
+
+	differences := make([]string, 0)
+	var totalRows int64
+	for _, tableName := range tableNames {
+		columns, err := queryAssertRows(config, fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", db.EscapeSingleQuotes(tableName)))
+		if err != nil {
+			return err
+		}
+		columnNames := make([]string, 0, len(columns))
+		for _, column := range columns {
+			columnNames = append(columnNames, column[0])
+		}
+		expected, err := digestTable(config.Db, tableName, columnNames, options.whereClauses[tableName])
+		if err != nil {
+			return err
+		}
+		totalRows += expected.rows
+		restored, err := digestTable(restoredDb, tableName, columnNames, "")
+		switch {
+		case err != nil && isNoSuchTableError(err, tableName):
+			differences = append(differences, fmt.Sprintf("  %s: missing from the restored dump", tableName))
+		case err != nil:
+			return fmt.Errorf("failed to read table %s of the restored dump: %w", tableName, err)
+		case restored.rows != expected.rows:
+			differences = append(differences, fmt.Sprintf("  %s: %s rows in the database, %s in the restored dump", tableName, db.FormatCount(expected.rows), db.FormatCount(restored.rows)))
+		case restored.hash != expected.hash:
+			differences = append(differences, fmt.Sprintf("  %s: the values of its %s rows differ", tableName, db.FormatCount(expected.rows)))
+		}
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("the restored dump differs from the database in %d of %d tables:\n%s", len(differences), len(tableNames), strings.Join(differences, "\n"))
+	}
+	options.log.Infof("Verified the dump: the %s rows of its %d table(s) restore the same as in the database", db.FormatCount(totalRows), len(tableNames))
+	return nil
+}
+
+// digestTable reads the rows of the table matching the condition, when it's not empty, as the quote() literals of the
+// columns, which tell apart the values of every type. They're ordered by those literals, so both databases read the
+// rows in the same order whatever the order they were inserted in
+func digestTable(database *db.Db, tableName string, columnNames []string, condition string) (tableDigest, error) {
+	literals := make([]string, 0, len(columnNames))
+	positions := make([]string, 0, len(columnNames))
+	for i, columnName := range columnNames {
+		literals = append(literals, "quote("+db.QuoteIdentifier(columnName, enums.DOUBLE_QUOTE_STYLE)+")")
+		positions = append(positions, strconv.Itoa(i+1))
+	}
+	query := "SELECT " + strings.Join(literals, ", ") + " FROM " + getVerifiedTableSource(tableName, condition) + " ORDER BY " + strings.Join(positions, ", ")
+
+	result, err := database.ExecuteStatements(query)
+	if err != nil {
+		return tableDigest{}, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return tableDigest{}, statementResult.Err
+	}
+	hash := sha256.New()
+	var digest tableDigest
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return tableDigest{}, rowResult.Err
+		}
+		for _, value := range rowResult.Row {
+			switch literal := value.(type) {
+			case string:
+				hash.Write([]byte(literal))
+			case []byte:
+				hash.Write(literal)
+			default:
+				fmt.Fprint(hash, literal)
+			}
+			hash.Write([]byte{0})
+		}
+		hash.Write([]byte{'\n'})
+		digest.rows++
+	}
+	digest.hash = hex.EncodeToString(hash.Sum(nil))
+	return digest, nil
+}
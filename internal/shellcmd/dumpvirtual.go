@@ -0,0 +1,225 @@
+package shellcmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// virtualTable is a virtual table of the database, whose module may keep its content in shadow tables
+type virtualTable struct {
+	name        string
+	createTable string
+	// contentInserts is set for the modules whose shadow tables are filled again by inserting the rows of the table:
+	// the full-text ones holding their own content, whose rowids are kept, and the R*Tree ones. The other virtual
+	// tables are restored from a copy of their shadow tables, as the sqlite3 CLI does
+	contentInserts bool
+	withRowid      bool
+	// shadowTableNames are the names of the shadow tables, in the order they were created
+	shadowTableNames []string
+}
+
+// virtualTables holds the virtual tables of the database by their lower case name, and the ones owning each shadow
+// table by the lower case name of the shadow table
+type virtualTables struct {
+	byName   map[string]*virtualTable
+	byShadow map[string]*virtualTable
+}
+
+var virtualTableModuleRegexp = regexp.MustCompile(`(?is)\sUSING\s+(\w+)\s*(?:\((.*)\))?\s*;?\s*$`)
+
+// ftsContentOptionRegexp matches the content option of the full-text tables whose content is kept by another table,
+// or not kept at all
+var ftsContentOptionRegexp = regexp.MustCompile(`(?i)(^|,)\s*content\s*=`)
+
+func getVirtualTables(config *DbCmdConfig) (virtualTables, error) {
+	tables := virtualTables{byName: map[string]*virtualTable{}, byShadow: map[string]*virtualTable{}}
+	rows, err := queryAssertRows(config, "SELECT name, sql || ';' FROM sqlite_master WHERE type='table' AND sql LIKE 'CREATE VIRTUAL TABLE%'")
+	if err != nil || len(rows) == 0 {
+		return tables, err
+	}
+	for _, row := range rows {
+		table := &virtualTable{name: row[0], createTable: row[1]}
+		if match := virtualTableModuleRegexp.FindStringSubmatch(row[1]); match != nil {
+			switch strings.ToLower(match[1]) {
+			case "fts3", "fts4", "fts5":
+				table.contentInserts = !ftsContentOptionRegexp.MatchString(match[2])
+				table.withRowid = true
+			case "rtree", "rtree_i32":
+				table.contentInserts = true
+			}
+		}
+		tables.byName[strings.ToLower(table.name)] = table
+	}
+
+	shadowTableNames, err := getShadowTableNames(config)
+	if err != nil {
+		return tables, err
+	}
+	for _, shadowTableName := range shadowTableNames {
+		// A shadow table is named after its virtual table, and the longest such name is its own
+		var owner *virtualTable
+		for _, table := range tables.byName {
+			if strings.HasPrefix(strings.ToLower(shadowTableName), strings.ToLower(table.name)+"_") && (owner == nil || len(table.name) > len(owner.name)) {
+				owner = table
+			}
+		}
+		if owner != nil {
+			owner.shadowTableNames = append(owner.shadowTableNames, shadowTableName)
+			tables.byShadow[strings.ToLower(shadowTableName)] = owner
+		}
+	}
+	return tables, nil
+}
+
+// getShadowTableNames returns the tables SQLite knows to be shadow tables in the order they were created. Before
+// SQLite 3.37, which lists them in pragma_table_list, they are told apart by their name only, like the sqlite3 CLI does
+func getShadowTableNames(config *DbCmdConfig) ([]string, error) {
+	rows, err := queryAssertRows(config, "SELECT name FROM sqlite_master WHERE type='table' AND sql NOT LIKE 'CREATE VIRTUAL TABLE%' ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	var isShadowTable map[string]bool
+	if shadowRows, err := queryAssertRows(config, "SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'shadow'"); err == nil {
+		isShadowTable = make(map[string]bool, len(shadowRows))
+		for _, row := range shadowRows {
+			isShadowTable[row[0]] = true
+		}
+	}
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if isShadowTable == nil || isShadowTable[row[0]] {
+			names = append(names, row[0])
+		}
+	}
+	return names, nil
+}
+
+func (t virtualTables) get(tableName string) *virtualTable {
+	return t.byName[strings.ToLower(tableName)]
+}
+
+func (t virtualTables) getOwner(shadowTableName string) *virtualTable {
+	return t.byShadow[strings.ToLower(shadowTableName)]
+}
+
+// getFirst returns the name of the first virtual table among the tables, or "" when there's none
+func (t virtualTables) getFirst(tableNames []string) string {
+	for _, tableName := range tableNames {
+		if t.get(tableName) != nil {
+			return tableName
+		}
+	}
+	return ""
+}
+
+func isSQLiteCompat(compat dumpCompat) bool {
+	return compat.formatType == db.SQLITE || compat.formatType == db.SQLITE3
+}
+
+// restoresShadowTables reports whether the virtual table is restored with a copy of its shadow tables rather than by
+// inserting its rows
+func restoresShadowTables(table *virtualTable, options dumpOptions) bool {
+	return options.compat.sqlite3Layout || !table.contentInserts
+}
+
+// selectShadowTables leaves out of the dumped tables the shadow tables filled again by inserting the rows of their
+// virtual table, and all of them in dumps to other engines, which have no virtual tables. The shadow tables of the
+// other virtual tables are dumped right after them, even when they weren't selected themselves
+func selectShadowTables(tableNames []string, options dumpOptions) []string {
+	listed := make(map[string]bool, len(tableNames))
+	for _, tableName := range tableNames {
+		listed[strings.ToLower(tableName)] = true
+	}
+	selected := make([]string, 0, len(tableNames))
+	for _, tableName := range tableNames {
+		if owner := options.virtualTables.getOwner(tableName); owner != nil {
+			if isSQLiteCompat(options.compat) && restoresShadowTables(owner, options) && !listed[strings.ToLower(owner.name)] {
+				selected = append(selected, tableName)
+			}
+			continue
+		}
+		selected = append(selected, tableName)
+		if table := options.virtualTables.get(tableName); table != nil && isSQLiteCompat(options.compat) && restoresShadowTables(table, options) {
+			selected = append(selected, table.shadowTableNames...)
+		}
+	}
+	return selected
+}
+
+// writeVirtualTableCreation writes the statements creating a virtual table. One restored from its shadow tables can't
+// be created with CREATE VIRTUAL TABLE, which would create them too, so it's inserted into sqlite_schema with
+// writable_schema, which SQLITE_DBCONFIG_DEFENSIVE must allow. RESET makes the connection restoring the dump read the
+// schema again, to see the table
+func writeVirtualTableCreation(out io.Writer, table *virtualTable, options dumpOptions) {
+	if !isSQLiteCompat(options.compat) {
+		fmt.Fprintf(out, "-- Skipped virtual table %s, as only SQLite has virtual tables\n", db.QuoteIdentifier(table.name, enums.DOUBLE_QUOTE_STYLE))
+		return
+	}
+	if !restoresShadowTables(table, options) {
+		fmt.Fprintln(out, table.createTable)
+		return
+	}
+	createTable := db.EscapeSingleQuotes(strings.TrimSuffix(table.createTable, ";"))
+	name := db.EscapeSingleQuotes(table.name)
+	if options.compat.sqlite3Layout {
+		// Like the sqlite3 CLI, writable_schema is turned on once, before the first virtual table, and off at the end
+		if table.name == options.firstVirtualTable {
+			fmt.Fprintln(out, "PRAGMA writable_schema=ON;")
+		}
+		fmt.Fprintf(out, "INSERT INTO sqlite_schema(type,name,tbl_name,rootpage,sql)VALUES('table','%s','%s',0,'%s');\n", name, name, createTable)
+		return
+	}
+	fmt.Fprintln(out, "-- Restoring this virtual table requires SQLITE_DBCONFIG_DEFENSIVE to be off")
+	fmt.Fprintln(out, "PRAGMA writable_schema=ON;")
+	fmt.Fprintf(out, "INSERT INTO sqlite_schema (type, name, tbl_name, rootpage, sql) VALUES ('table', '%s', '%s', 0, '%s');\n", name, name, createTable)
+	fmt.Fprintln(out, "PRAGMA writable_schema=RESET;")
+}
+
+// dumpsVirtualTableRecords reports whether the records of a table are dumped: not the ones of the virtual tables
+// restored from their shadow tables, nor of any virtual table in dumps to other engines
+func dumpsVirtualTableRecords(tableName string, options dumpOptions) bool {
+	table := options.virtualTables.get(tableName)
+	return table == nil || (isSQLiteCompat(options.compat) && !restoresShadowTables(table, options))
+}
+
+// getDumpTableColumns returns the columns the records of a table are dumped with. The full-text tables are selected
+// with their rowid, and the shadow tables with the values as stored, as their columns often hold values of another
+// type than declared
+func getDumpTableColumns(config *DbCmdConfig, tableName string, options dumpOptions) (string, error) {
+	if table := options.virtualTables.get(tableName); table != nil && table.withRowid {
+		return "rowid, *", nil
+	}
+	if options.virtualTables.getOwner(tableName) == nil {
+		return "*", nil
+	}
+	columnNames, err := getDescribedColumnNames(config, tableName)
+	if err != nil {
+		return "", err
+	}
+	// An expression has no declared type, so its values aren't converted to it when read
+	columns := make([]string, 0, len(columnNames))
+	for _, columnName := range columnNames {
+		column := db.QuoteIdentifier(columnName, enums.DOUBLE_QUOTE_STYLE)
+		columns = append(columns, "+"+column+" AS "+column)
+	}
+	return strings.Join(columns, ", "), nil
+}
+
+// getVirtualTableInsertInto returns the start of the INSERT statements of the records of a full-text table, with the
+// rowid they're selected with, or "" for other tables
+func getVirtualTableInsertInto(tableName string, columnNames []string, options dumpOptions) string {
+	table := options.virtualTables.get(tableName)
+	if table == nil || !table.withRowid {
+		return ""
+	}
+	quotedColumnNames := make([]string, 0, len(columnNames))
+	for _, columnName := range columnNames {
+		quotedColumnNames = append(quotedColumnNames, quoteIdentifierIfNeeded(columnName, options.quoteStyle))
+	}
+	return "INSERT INTO " + quoteIdentifierIfNeeded(tableName, options.quoteStyle) + " (" + strings.Join(quotedColumnNames, ", ") + ") VALUES ("
+}
@@ -0,0 +1,342 @@
+package shellcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+// queryPlanNameMarker starts a named query in the file of queries given to .eqp baseline save
+const queryPlanNameMarker = "-- name:"
+
+// queryPlanNormalizations replace the parts of plans that change between equivalent schemas, like the numbers SQLite
+// gives to its automatic indexes and to subqueries
+var queryPlanNormalizations = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\b(sqlite_autoindex_\w+?_)\d+\b`), "${1}N"},
+	{regexp.MustCompile(`\bsubquery-\d+\b`), "subquery-N"},
+	{regexp.MustCompile(`\b(SUBQUERY|MATERIALIZE|CO-ROUTINE) \d+\b`), "$1 N"},
+}
+
+var eqpQueriesFile string
+
+var eqpCmd = &cobra.Command{
+	Use:   ".eqp baseline save|check FILE",
+	Short: "Save or check the query plans of named queries",
+	Long: `Detect query plan regressions after changing the schema. .eqp baseline save --queries QUERIES FILE records
+the EXPLAIN QUERY PLAN of each query of QUERIES in FILE, and .eqp baseline check FILE explains them again and shows
+the lines of the plans that changed, like a SCAN where there was a SEARCH. A check finding changes fails, so that a
+script stops with an error, and with --bail=false the script goes on and the shell exits with an error at the end.
+
+QUERIES holds the queries, each after a line naming it:
+  -- name: users_by_email
+  SELECT * FROM users WHERE email = 'a@example.com';
+
+The names SQLite gives to automatic indexes and subqueries are normalized before the plans are compared.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if args[0] != "baseline" {
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are baseline", args[0])
+		}
+		file, err := ResolveFilePath(config.FileRoot, args[2])
+		if err != nil {
+			return err
+		}
+
+		switch args[1] {
+		case "save":
+			if eqpQueriesFile == "" {
+				return fmt.Errorf("--queries is required to save a baseline")
+			}
+			queriesFile, err := ResolveFilePath(config.FileRoot, eqpQueriesFile)
+			if err != nil {
+				return err
+			}
+			return saveQueryPlanBaseline(config, queriesFile, file)
+		case "check":
+			if eqpQueriesFile != "" {
+				return fmt.Errorf("--queries only applies to save, as the baseline holds its queries")
+			}
+			err := checkQueryPlanBaseline(config, file)
+			if err == nil {
+				return nil
+			}
+			// With bail off the changes are counted like the failure of a statement, so the script goes on
+			if errorLog := config.GetErrorLog(); errorLog != nil {
+				errorLog.Record(".eqp "+strings.Join(args, " "), err)
+				return nil
+			}
+			return err
+		}
+		return fmt.Errorf("invalid argument \"%s\". Valid arguments are save and check", args[1])
+	},
+}
+
+func init() {
+	eqpCmd.Flags().StringVar(&eqpQueriesFile, "queries", "", "File of the named queries whose plans are saved")
+}
+
+type queryPlanBaseline struct {
+	Queries []queryPlan `json:"queries"`
+}
+
+type queryPlan struct {
+	Name string   `json:"name"`
+	Sql  string   `json:"sql"`
+	Plan []string `json:"plan"`
+}
+
+func saveQueryPlanBaseline(config *DbCmdConfig, queriesFile string, baselineFile string) error {
+	queries, err := readNamedQueries(queriesFile)
+	if err != nil {
+		return err
+	}
+
+	baseline := queryPlanBaseline{Queries: queries}
+	for i := range baseline.Queries {
+		query := &baseline.Queries[i]
+		query.Plan, err = getQueryPlan(config, query.Sql)
+		if err != nil {
+			return fmt.Errorf("failed to explain query %s: %w", query.Name, err)
+		}
+	}
+
+	err = writeFileAtomically(baselineFile, func(out io.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(baseline)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(config.OutF, "Saved the plans of %d queries\n", len(baseline.Queries))
+	return nil
+}
+
+func checkQueryPlanBaseline(config *DbCmdConfig, baselineFile string) error {
+	content, err := os.ReadFile(baselineFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the baseline: %w", err)
+	}
+	var baseline queryPlanBaseline
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return fmt.Errorf("invalid baseline %s: %w", baselineFile, err)
+	}
+
+	changed := 0
+	for _, query := range baseline.Queries {
+		plan, err := getQueryPlan(config, query.Sql)
+		if err != nil {
+			plan = []string{"error: " + err.Error()}
+		}
+		lineChanges := diffQueryPlans(query.Plan, plan)
+		if len(lineChanges) == 0 {
+			continue
+		}
+		changed++
+		fmt.Fprintf(config.OutF, "changed: %s\n", query.Name)
+		for _, lineChange := range lineChanges {
+			fmt.Fprintf(config.OutF, "  %s\n", lineChange)
+		}
+	}
+
+	if changed > 0 {
+		return fmt.Errorf("the plans of %d of %d queries changed", changed, len(baseline.Queries))
+	}
+	fmt.Fprintf(config.OutF, "The plans of %d queries are unchanged\n", len(baseline.Queries))
+	return nil
+}
+
+// readNamedQueries reads the queries of a file, each one after a line with its name like "-- name: users_by_email"
+func readNamedQueries(fileName string) ([]queryPlan, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the queries: %w", err)
+	}
+	defer file.Close()
+
+	queries := make([]queryPlan, 0)
+	names := make(map[string]bool)
+	var sql strings.Builder
+	finishQuery := func() error {
+		if len(queries) == 0 {
+			return nil
+		}
+		query := &queries[len(queries)-1]
+		splitStatements, err := db.SplitStatements(sql.String())
+		if err != nil {
+			return fmt.Errorf("invalid query %s: %w", query.Name, err)
+		}
+		if len(splitStatements) != 1 {
+			return fmt.Errorf("expected a single statement for query %s, got %d", query.Name, len(splitStatements))
+		}
+		query.Sql = strings.TrimSuffix(strings.TrimSpace(splitStatements[0].Text), ";")
+		sql.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(strings.TrimSpace(line), queryPlanNameMarker) {
+			if len(queries) == 0 && strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("expected a line like \"%s NAME\" before the first query", queryPlanNameMarker)
+			}
+			sql.WriteString(line + "\n")
+			continue
+		}
+		if err := finishQuery(); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), queryPlanNameMarker))
+		if name == "" {
+			return nil, fmt.Errorf("missing the name of a query after \"%s\"", queryPlanNameMarker)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate query name %s", name)
+		}
+		names[name] = true
+		queries = append(queries, queryPlan{Name: name})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the queries: %w", err)
+	}
+	if err := finishQuery(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no query found in %s", fileName)
+	}
+	return queries, nil
+}
+
+// getQueryPlan returns the lines of the EXPLAIN QUERY PLAN of a query, indented by their depth in the plan and
+// normalized
+func getQueryPlan(config *DbCmdConfig, sql string) ([]string, error) {
+	steps, err := getQueryPlanSteps(config, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	depths := make(map[int]int)
+	plan := make([]string, 0, len(steps))
+	for _, step := range steps {
+		depth := 0
+		if parentDepth, ok := depths[step.parent]; ok {
+			depth = parentDepth + 1
+		}
+		depths[step.id] = depth
+		plan = append(plan, strings.Repeat("  ", depth)+normalizeQueryPlanDetail(step.detail))
+	}
+	return plan, nil
+}
+
+type queryPlanStep struct {
+	id     int
+	parent int
+	detail string
+}
+
+// getQueryPlanSteps returns the rows of the EXPLAIN QUERY PLAN of a query
+func getQueryPlanSteps(config *DbCmdConfig, sql string) ([]queryPlanStep, error) {
+	result, err := config.Db.ExecuteStatements("EXPLAIN QUERY PLAN " + sql)
+	if err != nil {
+		return nil, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+
+	steps := make([]queryPlanStep, 0)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return nil, rowResult.Err
+		}
+		values, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) < 4 {
+			return nil, fmt.Errorf("unexpected query plan with %d columns", len(values))
+		}
+		id, _ := strconv.Atoi(values[0])
+		parent, _ := strconv.Atoi(values[1])
+		steps = append(steps, queryPlanStep{id: id, parent: parent, detail: values[3]})
+	}
+	return steps, nil
+}
+
+func normalizeQueryPlanDetail(detail string) string {
+	for _, normalization := range queryPlanNormalizations {
+		detail = normalization.pattern.ReplaceAllString(detail, normalization.replacement)
+	}
+	return detail
+}
+
+// diffQueryPlans returns the lines of the baseline plan missing from the current one prefixed by "- ", followed by the
+// lines of the current plan missing from the baseline prefixed by "+ ", nothing when they're the same
+func diffQueryPlans(baseline []string, current []string) []string {
+	if strings.Join(baseline, "\n") == strings.Join(current, "\n") {
+		return nil
+	}
+
+	removed := countLines(baseline)
+	added := countLines(current)
+	for line, count := range removed {
+		common := count
+		if added[line] < common {
+			common = added[line]
+		}
+		removed[line] -= common
+		added[line] -= common
+	}
+
+	lineChanges := make([]string, 0)
+	for _, line := range baseline {
+		if removed[line] > 0 {
+			removed[line]--
+			lineChanges = append(lineChanges, "- "+line)
+		}
+	}
+	for _, line := range current {
+		if added[line] > 0 {
+			added[line]--
+			lineChanges = append(lineChanges, "+ "+line)
+		}
+	}
+	// Plans with the same lines in another order changed all the same
+	if len(lineChanges) == 0 {
+		for _, line := range baseline {
+			lineChanges = append(lineChanges, "- "+line)
+		}
+		for _, line := range current {
+			lineChanges = append(lineChanges, "+ "+line)
+		}
+	}
+	return lineChanges
+}
+
+func countLines(lines []string) map[string]int {
+	counts := make(map[string]int, len(lines))
+	for _, line := range lines {
+		counts[line]++
+	}
+	return counts
+}
@@ -0,0 +1,55 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var excludeCmd = &cobra.Command{
+	Use:   ".exclude ?PATTERN...?",
+	Short: "Hide tables from .tables and .schema",
+	Long: `Hide the tables whose name matches any of the LIKE patterns from .tables and .schema for the rest of the session.
+In the patterns, % matches any text, _ matches any character and \ escapes them. Without patterns, list the ones set.
+The tables SQLite, Litestream and libSQL keep for themselves are always hidden, unless --include-internal is given to
+the listing command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		clear, err := cmd.Flags().GetBool("clear")
+		if err != nil {
+			return err
+		}
+		if clear {
+			config.SetExcludedTables(nil)
+		}
+
+		if len(args) == 0 && !clear {
+			for _, pattern := range config.GetExcludedTables() {
+				fmt.Fprintln(config.OutF, pattern)
+			}
+			return nil
+		}
+
+		config.SetExcludedTables(append(config.GetExcludedTables(), args...))
+		return nil
+	},
+}
+
+func init() {
+	excludeCmd.Flags().Bool("clear", false, "Remove the patterns set before")
+}
+
+// getListingTableFilter returns the filter of the listing commands, which leave out the tables hidden with .exclude and
+// the internal ones, unless the command was given --include-internal
+func getListingTableFilter(cmd *cobra.Command, config *DbCmdConfig) (db.TableFilter, error) {
+	includeInternal, err := cmd.Flags().GetBool("include-internal")
+	if err != nil {
+		return db.TableFilter{}, err
+	}
+	return db.TableFilter{Exclude: config.GetExcludedTables(), IncludeInternal: includeInternal}, nil
+}
@@ -0,0 +1,40 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var explainFmtCmd = &cobra.Command{
+	Use:   ".explain-fmt ?on|off?",
+	Short: "Show EXPLAIN bytecode aligned and indented",
+	Long: `Show the results of EXPLAIN statements in aligned columns, whatever the output mode. The opcodes inside loops
+are indented, and the jump targets in p2 are marked with "->". Only results with exactly the columns of EXPLAIN
+(addr, opcode, p1, p2, p3, p4, p5 and comment) are shown this way. Without an argument, the current setting is shown.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetExplainFormat() {
+				fmt.Fprintln(config.OutF, "explain-fmt: on")
+			} else {
+				fmt.Fprintln(config.OutF, "explain-fmt: off")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "on", "off":
+			config.SetExplainFormat(args[0] == "on")
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on and off", args[0])
+		}
+		return nil
+	},
+}
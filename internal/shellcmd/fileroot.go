@@ -0,0 +1,82 @@
+package shellcmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+// ResolveFilePath returns the path a dot command accesses for the one it was given. When root is set, relative paths
+// are taken from it, and any path that is outside of it once its symlinks are resolved is refused
+func ResolveFilePath(root string, path string) (string, error) {
+	if root == "" {
+		return path, nil
+	}
+
+	resolvedRoot, err := resolveSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	fullPath := path
+	if !filepath.IsAbs(path) {
+		// Not joined with filepath.Join, which would clean the .. of the path before its symlinks are resolved
+		fullPath = resolvedRoot + string(filepath.Separator) + path
+	}
+	resolvedPath, err := resolveSymlinks(fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	relativePath, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", &shellerrors.FileOutsideRootError{Path: path}
+	}
+	return resolvedPath, nil
+}
+
+// resolveSymlinks returns the absolute path with its symlinks resolved, resolving each part in turn so that a .. after
+// a symlink goes up from its target, as it does when the file is opened. The parts that don't exist yet, like the file
+// a command is about to create, are kept as they are
+func resolveSymlinks(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path = workingDir + string(filepath.Separator) + path
+	}
+
+	volume := filepath.VolumeName(path)
+	resolved := volume + string(filepath.Separator)
+	parts := strings.Split(filepath.ToSlash(path[len(volume):]), "/")
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			return filepath.Join(append([]string{resolved}, parts[i:]...)...), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			next, err = filepath.EvalSymlinks(next)
+			if err != nil {
+				return "", err
+			}
+		}
+		resolved = next
+	}
+	return resolved, nil
+}
@@ -0,0 +1,222 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+var fkcheckCmd = &cobra.Command{
+	Use:   ".fkcheck ?TABLE?",
+	Short: "Find the rows violating foreign key constraints",
+	Long: `Find the rows of a table, or of every table, violating foreign key constraints with PRAGMA foreign_key_check,
+which doesn't need foreign key enforcement to be on. For each foreign key violated, it writes a SELECT statement
+showing the child rows referencing no parent row.
+
+With --fix delete or --fix nullify, it also writes the statements deleting those rows, or setting the columns of their
+foreign key to NULL. They're only executed with --execute, in a single transaction.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		fix, _ := cmd.Flags().GetString("fix")
+		execute, _ := cmd.Flags().GetBool("execute")
+		if fix != "" && fix != "delete" && fix != "nullify" {
+			return fmt.Errorf("invalid --fix \"%s\". Valid values are delete and nullify", fix)
+		}
+		if execute && fix == "" {
+			return fmt.Errorf("--execute needs --fix to tell the statements to execute")
+		}
+
+		violations, err := getForeignKeyViolations(config, args)
+		if err != nil {
+			return err
+		}
+		if len(violations) == 0 {
+			fmt.Fprintln(config.OutF, "-- No foreign key violations")
+			return nil
+		}
+
+		quoteStyle := config.GetIdentifierQuoteStyle()
+		fixStatements := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			foreignKey, err := getViolatedForeignKey(config, violation)
+			if err != nil {
+				return err
+			}
+			if foreignKey.parentExists {
+				fmt.Fprintf(config.OutF, "-- %d rows of %s reference no row of %s (%s)\n", violation.rows, violation.table, foreignKey.parentTable, strings.Join(foreignKey.parentColumns, ", "))
+			} else {
+				fmt.Fprintf(config.OutF, "-- %d rows of %s reference table %s, which doesn't exist\n", violation.rows, violation.table, foreignKey.parentTable)
+			}
+			fmt.Fprintln(config.OutF, foreignKey.getSelectStatement(quoteStyle))
+
+			switch fix {
+			case "delete":
+				fixStatements = append(fixStatements, foreignKey.getDeleteStatement(quoteStyle))
+			case "nullify":
+				if foreignKey.notNullColumn != "" {
+					fmt.Fprintf(config.OutF, "-- Not nullified, as column %s of %s is NOT NULL\n", foreignKey.notNullColumn, violation.table)
+					continue
+				}
+				fixStatements = append(fixStatements, foreignKey.getNullifyStatement(quoteStyle))
+			default:
+				continue
+			}
+			fmt.Fprintln(config.OutF, fixStatements[len(fixStatements)-1])
+		}
+
+		if !execute || len(fixStatements) == 0 {
+			return nil
+		}
+		if err := executeStatementsSilently(config, "BEGIN;"); err != nil {
+			return err
+		}
+		if err := executeStatementsSilently(config, strings.Join(fixStatements, "\n")); err != nil {
+			_ = executeStatementsSilently(config, "ROLLBACK;")
+			return fmt.Errorf("failed to fix the rows, so the changes were rolled back: %w", err)
+		}
+		return executeStatementsSilently(config, "COMMIT;")
+	},
+}
+
+func init() {
+	fkcheckCmd.Flags().String("fix", "", "Write the statements fixing the rows: delete or nullify")
+	fkcheckCmd.Flags().Bool("execute", false, "Execute the statements written by --fix")
+}
+
+// foreignKeyViolation is the number of rows of a table violating one of its foreign keys, identified by its id
+type foreignKeyViolation struct {
+	table string
+	id    string
+	rows  int
+}
+
+// getForeignKeyViolations returns the foreign keys violated by the rows of the table in args, or of every table,
+// in the order PRAGMA foreign_key_check reports them
+func getForeignKeyViolations(config *DbCmdConfig, args []string) ([]foreignKeyViolation, error) {
+	source := "pragma_foreign_key_check"
+	if len(args) == 1 {
+		source = fmt.Sprintf("pragma_foreign_key_check('%s')", db.EscapeSingleQuotes(args[0]))
+	}
+	rows, err := queryAssertRows(config, fmt.Sprintf(`SELECT "table", fkid, count(*) FROM %s GROUP BY "table", fkid ORDER BY "table", fkid`, source))
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]foreignKeyViolation, 0, len(rows))
+	for _, row := range rows {
+		count, err := strconv.Atoi(row[2])
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, foreignKeyViolation{table: row[0], id: row[1], rows: count})
+	}
+	return violations, nil
+}
+
+// violatedForeignKey is a foreign key of a child table, with its columns and the ones of the parent table they
+// reference in the same order
+type violatedForeignKey struct {
+	childTable    string
+	childColumns  []string
+	parentTable   string
+	parentColumns []string
+	// parentExists is unset when the parent table is missing, so every row with a foreign key violates it
+	parentExists bool
+	// notNullColumn is a column of the foreign key that can't be set to NULL, if any
+	notNullColumn string
+}
+
+func getViolatedForeignKey(config *DbCmdConfig, violation foreignKeyViolation) (violatedForeignKey, error) {
+	foreignKey := violatedForeignKey{childTable: violation.table}
+	rows, err := queryAssertRows(config, fmt.Sprintf(`SELECT "from", coalesce("to", ''), "table" FROM pragma_foreign_key_list('%s') WHERE id = %s ORDER BY seq`, db.EscapeSingleQuotes(violation.table), violation.id))
+	if err != nil {
+		return foreignKey, err
+	}
+	if len(rows) == 0 {
+		return foreignKey, fmt.Errorf("foreign key %s of table %s not found", violation.id, violation.table)
+	}
+	foreignKey.parentTable = rows[0][2]
+	for _, row := range rows {
+		foreignKey.childColumns = append(foreignKey.childColumns, row[0])
+		foreignKey.parentColumns = append(foreignKey.parentColumns, row[1])
+	}
+
+	tables, err := queryAssertRows(config, fmt.Sprintf("SELECT name FROM sqlite_master WHERE type = 'table' AND name = '%s' COLLATE NOCASE", db.EscapeSingleQuotes(foreignKey.parentTable)))
+	if err != nil {
+		return foreignKey, err
+	}
+	foreignKey.parentExists = len(tables) == 1
+	// A foreign key naming no parent columns references the primary key of the parent table
+	if foreignKey.parentExists && foreignKey.parentColumns[0] == "" {
+		primaryKey, err := queryAssertRows(config, fmt.Sprintf("SELECT name FROM pragma_table_info('%s') WHERE pk > 0 ORDER BY pk", db.EscapeSingleQuotes(foreignKey.parentTable)))
+		if err != nil {
+			return foreignKey, err
+		}
+		if len(primaryKey) != len(foreignKey.childColumns) {
+			return foreignKey, fmt.Errorf("the primary key of table %s doesn't match foreign key %s of table %s", foreignKey.parentTable, violation.id, violation.table)
+		}
+		for i, row := range primaryKey {
+			foreignKey.parentColumns[i] = row[0]
+		}
+	}
+
+	notNull, err := queryAssertRows(config, fmt.Sprintf(`SELECT name FROM pragma_table_info('%s') WHERE "notnull"`, db.EscapeSingleQuotes(violation.table)))
+	if err != nil {
+		return foreignKey, err
+	}
+	for _, row := range notNull {
+		if containsName(foreignKey.childColumns, row[0]) {
+			foreignKey.notNullColumn = row[0]
+			break
+		}
+	}
+	return foreignKey, nil
+}
+
+// getCondition returns the condition of the child rows violating the foreign key, whose columns are qualified with
+// child: the ones whose columns are all set, and which no row of the parent table matches. The parent table is aliased
+// so that the condition stays right when it's the child table itself
+func (k violatedForeignKey) getCondition(child string, quoteStyle enums.IdentifierQuoteStyle) string {
+	parent := "p"
+	if db.EqualNames(child, parent) {
+		parent = "parent"
+	}
+	conditions := make([]string, 0, len(k.childColumns)+1)
+	matches := make([]string, 0, len(k.childColumns))
+	for i, column := range k.childColumns {
+		childColumn := child + "." + quoteIdentifierIfNeeded(column, quoteStyle)
+		conditions = append(conditions, childColumn+" IS NOT NULL")
+		matches = append(matches, parent+"."+quoteIdentifierIfNeeded(k.parentColumns[i], quoteStyle)+" = "+childColumn)
+	}
+	if k.parentExists {
+		conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s AS %s WHERE %s)", quoteIdentifierIfNeeded(k.parentTable, quoteStyle), parent, strings.Join(matches, " AND ")))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func (k violatedForeignKey) getSelectStatement(quoteStyle enums.IdentifierQuoteStyle) string {
+	return fmt.Sprintf("SELECT c.* FROM %s AS c WHERE %s;", quoteIdentifierIfNeeded(k.childTable, quoteStyle), k.getCondition("c", quoteStyle))
+}
+
+func (k violatedForeignKey) getDeleteStatement(quoteStyle enums.IdentifierQuoteStyle) string {
+	childTable := quoteIdentifierIfNeeded(k.childTable, quoteStyle)
+	return fmt.Sprintf("DELETE FROM %s WHERE %s;", childTable, k.getCondition(childTable, quoteStyle))
+}
+
+func (k violatedForeignKey) getNullifyStatement(quoteStyle enums.IdentifierQuoteStyle) string {
+	childTable := quoteIdentifierIfNeeded(k.childTable, quoteStyle)
+	assignments := make([]string, 0, len(k.childColumns))
+	for _, column := range k.childColumns {
+		assignments = append(assignments, quoteIdentifierIfNeeded(column, quoteStyle)+" = NULL")
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s;", childTable, strings.Join(assignments, ", "), k.getCondition(childTable, quoteStyle))
+}
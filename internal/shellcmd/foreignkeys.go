@@ -0,0 +1,123 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var foreignKeysCmd = &cobra.Command{
+	Use:   ".foreign_keys on|off|show",
+	Short: "Enable, disable or show foreign key enforcement",
+	Long: `Enable, disable or show the enforcement of foreign key constraints on this connection, set by
+PRAGMA foreign_keys. Like the PRAGMA, it has no effect inside a transaction, and it's replayed when the connection is
+reestablished with .open.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off", "show"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		switch args[0] {
+		case "on", "off":
+			return executeStatementsSilently(config, fmt.Sprintf("PRAGMA foreign_keys=%s;", strings.ToUpper(args[0])))
+		case "show":
+			result, err := config.Db.ExecuteStatements("PRAGMA foreign_keys;")
+			if err != nil {
+				return err
+			}
+			statementResult := <-result.StatementResultCh
+			if statementResult.Err != nil {
+				return statementResult.Err
+			}
+			values, err := readFirstColumn(statementResult)
+			if err != nil {
+				return err
+			}
+			enabled := len(values) > 0 && values[0] == "1"
+			if enabled {
+				fmt.Fprintln(config.OutF, "foreign_keys: on")
+			} else {
+				fmt.Fprintln(config.OutF, "foreign_keys: off")
+			}
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on, off and show", args[0])
+		}
+		return nil
+	},
+}
+
+// executeStatementsSilently executes statements returning no rows, stopping at the first one that fails
+func executeStatementsSilently(config *DbCmdConfig, statements string) error {
+	return executeStatementsSilentlyIn(config.Db, statements)
+}
+
+// executeStatementsSilentlyIn is like executeStatementsSilently, on a database other than the one of the shell
+func executeStatementsSilentlyIn(database *db.Db, statements string) error {
+	result, err := database.ExecuteStatements(statements)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for statementResult := range result.StatementResultCh {
+		if statementResult.Err != nil && firstErr == nil {
+			firstErr = statementResult.Err
+		}
+		if statementResult.RowCh != nil {
+			for range statementResult.RowCh {
+			}
+		}
+	}
+	return firstErr
+}
+
+// executeScriptWithDeferredForeignKeys executes a script in a single transaction with PRAGMA defer_foreign_keys set,
+// so foreign key constraints are only checked once the whole script ran. The transaction is rolled back when the
+// script fails or leaves rows violating foreign key constraints, reported with the tables holding them
+func executeScriptWithDeferredForeignKeys(rootCmd *cobra.Command, config *DbCmdConfig, script string, options scriptOptions) error {
+	if err := executeStatementsSilently(config, "BEGIN; PRAGMA defer_foreign_keys=ON;"); err != nil {
+		return fmt.Errorf("failed to begin the transaction of the script: %w", err)
+	}
+
+	options.skipTransactionStatements = true
+	if err := executeScript(rootCmd, config, script, options); err != nil {
+		_ = executeStatementsSilently(config, "ROLLBACK;")
+		return err
+	}
+
+	violatingTables, err := getForeignKeyViolatingTables(config)
+	if err != nil {
+		_ = executeStatementsSilently(config, "ROLLBACK;")
+		return err
+	}
+	if len(violatingTables) > 0 {
+		if err := executeStatementsSilently(config, "ROLLBACK;"); err != nil {
+			return err
+		}
+		return fmt.Errorf("the script left rows violating foreign key constraints in tables %s, so its changes were rolled back. Run PRAGMA foreign_key_check for details", strings.Join(violatingTables, ", "))
+	}
+
+	if err := executeStatementsSilently(config, "COMMIT;"); err != nil {
+		_ = executeStatementsSilently(config, "ROLLBACK;")
+		return fmt.Errorf("failed to commit the transaction of the script: %w", err)
+	}
+	return nil
+}
+
+func getForeignKeyViolatingTables(config *DbCmdConfig) ([]string, error) {
+	result, err := config.Db.ExecuteStatements(`SELECT DISTINCT "table" FROM pragma_foreign_key_check ORDER BY "table";`)
+	if err != nil {
+		return nil, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+	return readFirstColumn(statementResult)
+}
@@ -0,0 +1,40 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var headerCaseCmd = &cobra.Command{
+	Use:   ".headercase ?preserve|upper?",
+	Short: "Show the column names of tables as they are or upper cased",
+	Long: `Show the column names in the header of tables as they are, with preserve, or upper cased, with upper, the
+default. Only table mode upper cases them: CSV, JSON and the other modes always write them as they are. Without an
+argument, the current setting is shown.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"preserve", "upper"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetPreserveHeaderCase() {
+				fmt.Fprintln(config.OutF, "headercase: preserve")
+			} else {
+				fmt.Fprintln(config.OutF, "headercase: upper")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "preserve", "upper":
+			config.SetPreserveHeaderCase(args[0] == "preserve")
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are preserve and upper", args[0])
+		}
+		return nil
+	},
+}
@@ -0,0 +1,39 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var headerIntervalCmd = &cobra.Command{
+	Use:   ".header-interval ?N?",
+	Short: "Show the header of tables again every N rows",
+	Long: `Show the header of tables again every N rows, so it stays in sight while scrolling through long results. It
+only applies to table mode when the output is a terminal, never when it's redirected. An N of 0 turns it off, and
+without N the current interval is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if interval := config.GetHeaderInterval(); interval > 0 {
+				fmt.Fprintf(config.OutF, "header interval: %d\n", interval)
+			} else {
+				fmt.Fprintln(config.OutF, "header interval: off")
+			}
+			return nil
+		}
+
+		interval, err := strconv.Atoi(args[0])
+		if err != nil || interval < 0 {
+			return fmt.Errorf("invalid header interval \"%s\". Give the number of rows between headers, or 0 to turn it off", args[0])
+		}
+		config.SetHeaderInterval(interval)
+		return nil
+	},
+}
@@ -0,0 +1,39 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   ".history ?N?",
+	Short: "List the last N entries of the history",
+	Long: `List the last N entries of the history, or all the entries it keeps without N, from the oldest one. Each is
+numbered by its position in the history. A statement typed over several lines is a single entry, with its lines joined.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		history := config.GetHistory()
+		first := 0
+		if len(args) == 1 {
+			count, err := strconv.Atoi(args[0])
+			if err != nil || count <= 0 {
+				return fmt.Errorf("invalid number of history entries \"%s\"", args[0])
+			}
+			if count < len(history) {
+				first = len(history) - count
+			}
+		}
+
+		for i := first; i < len(history); i++ {
+			fmt.Fprintf(config.OutF, "%5d  %s\n", i+1, history[i])
+		}
+		return nil
+	},
+}
@@ -0,0 +1,391 @@
+package shellcmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   ".import FILE TABLE",
+	Short: "Import CSV data into a table",
+	Long: `Insert the rows of a CSV file into an existing table, mapping the fields of each row to the columns of the
+table in order. Rows with a different number of fields than the table has columns, or with values that aren't numbers
+for columns with INTEGER, REAL or NUMERIC affinity, are skipped and reported with their line number. Empty values are
+always accepted. The fields are separated by commas, or by tabs with --tsv, and --skip N leaves out the first N rows,
+like a header.
+
+The rows are inserted in transactions of 500 rows. When the database rejects a row, its transaction is inserted again
+row by row, skipping the rows rejected. Ctrl-C rolls back the current transaction and stops, keeping the rows of the
+ones before it.
+
+Importing into a table that already has rows must be confirmed at the interactive prompt, or with --yes otherwise.
+With --dry-run the file is read and checked against the table the same way, reporting how many rows would be inserted
+and which lines would be skipped, without writing anything.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		skip, err := cmd.Flags().GetInt("skip")
+		if err != nil {
+			return err
+		}
+		if skip < 0 {
+			return fmt.Errorf("invalid --skip %d. It must be a positive number of rows", skip)
+		}
+		tsv, err := cmd.Flags().GetBool("tsv")
+		if err != nil {
+			return err
+		}
+		if csvFlag, _ := cmd.Flags().GetBool("csv"); csvFlag && tsv {
+			return fmt.Errorf("--csv and --tsv can't be used together")
+		}
+		separator := ','
+		if tsv {
+			separator = '\t'
+		}
+
+		fileName, tableName := args[0], args[1]
+		// The table is imported into under its name as stored, which may differ in case or normal form from the given one
+		if existingName, found, err := findTableName(config, tableName); err != nil {
+			return err
+		} else if found {
+			tableName = existingName
+		}
+		columns, err := getImportColumns(config, tableName)
+		if err != nil {
+			return err
+		}
+		fileName, err = ResolveFilePath(config.FileRoot, fileName)
+		if err != nil {
+			return err
+		}
+		rows, err := readImportFile(fileName, separator)
+		if err != nil {
+			return err
+		}
+		if skip > len(rows) {
+			skip = len(rows)
+		}
+		rows = rows[skip:]
+		validRows, failures := validateImportRows(rows, columns)
+
+		if dryRun {
+			fmt.Fprintf(config.OutF, "%d rows would be inserted into %s\n", len(validRows), tableName)
+			for _, failure := range failures {
+				fmt.Fprintf(config.OutF, "%s would be skipped\n", failure)
+			}
+			return nil
+		}
+
+		existingRows, err := countTableRows(config, tableName)
+		if err != nil {
+			return err
+		}
+		if existingRows > 0 && !yes {
+			question := fmt.Sprintf("Table %s already has %d rows. Import %d more?", tableName, existingRows, len(validRows))
+			if !config.Confirm(question) {
+				return fmt.Errorf("table %s already has %d rows, so nothing was imported. Use --yes to import into it anyway", tableName, existingRows)
+			}
+		}
+
+		imported, insertFailures, interruptedLine, err := insertImportRows(config, tableName, validRows)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, insertFailures...)
+		sort.SliceStable(failures, func(i, j int) bool { return failures[i].line < failures[j].line })
+		for _, failure := range failures {
+			if interruptedLine == 0 || failure.line < interruptedLine {
+				config.Log.Warnf("%s was skipped", failure)
+			}
+		}
+		fmt.Fprintf(config.OutF, "Imported %d rows into %s\n", imported, tableName)
+		if interruptedLine > 0 {
+			return fmt.Errorf("interrupted, so the rows from line %d on weren't imported", interruptedLine)
+		}
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().Bool("yes", false, "Import into a table that already has rows without asking")
+	importCmd.Flags().Int("skip", 0, "Leave out the first N rows of the file, like a header")
+	importCmd.Flags().Bool("csv", false, "Read fields separated by commas, the default")
+	importCmd.Flags().Bool("tsv", false, "Read fields separated by tabs")
+	importCmd.Flags().Bool("dry-run", false, "Check the file against the table and report what would be imported, without writing anything")
+}
+
+type importColumn struct {
+	name     string
+	affinity string
+}
+
+type importRow struct {
+	line   int
+	values []string
+}
+
+type importFailure struct {
+	line   int
+	reason string
+}
+
+func (f importFailure) String() string {
+	return fmt.Sprintf("line %d (%s)", f.line, f.reason)
+}
+
+func getImportColumns(config *DbCmdConfig, tableName string) ([]importColumn, error) {
+	result, err := config.Db.ExecuteStatements(fmt.Sprintf("SELECT name, type FROM pragma_table_info('%s')", db.EscapeSingleQuotes(tableName)))
+	if err != nil {
+		return nil, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return nil, statementResult.Err
+	}
+
+	columns := make([]importColumn, 0)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return nil, rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, importColumn{name: formattedRow[0], affinity: getColumnAffinity(formattedRow[1])})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no such table: %s. Create the table before importing into it", tableName)
+	}
+	return columns, nil
+}
+
+// getColumnAffinity returns the affinity SQLite gives to a column with the declared type
+func getColumnAffinity(declaredType string) string {
+	declaredType = strings.ToUpper(declaredType)
+	switch {
+	case strings.Contains(declaredType, "INT"):
+		return "INTEGER"
+	case strings.Contains(declaredType, "CHAR"), strings.Contains(declaredType, "CLOB"), strings.Contains(declaredType, "TEXT"):
+		return "TEXT"
+	case strings.Contains(declaredType, "BLOB"), declaredType == "":
+		return "BLOB"
+	case strings.Contains(declaredType, "REAL"), strings.Contains(declaredType, "FLOA"), strings.Contains(declaredType, "DOUB"):
+		return "REAL"
+	default:
+		return "NUMERIC"
+	}
+}
+
+// readImportFile reads the rows of a CSV file with fields separated by separator, with the line each one starts on
+func readImportFile(fileName string, separator rune) ([]importRow, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	rows := make([]importRow, 0)
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, importRow{line: line, values: values})
+	}
+}
+
+// validateImportRows returns the rows that can be inserted into the columns, and why the others can't
+func validateImportRows(rows []importRow, columns []importColumn) ([]importRow, []importFailure) {
+	validRows := make([]importRow, 0, len(rows))
+	failures := make([]importFailure, 0)
+	for _, row := range rows {
+		if reason := validateImportRow(row, columns); reason != "" {
+			failures = append(failures, importFailure{line: row.line, reason: reason})
+			continue
+		}
+		validRows = append(validRows, row)
+	}
+	return validRows, failures
+}
+
+func validateImportRow(row importRow, columns []importColumn) string {
+	if len(row.values) != len(columns) {
+		return fmt.Sprintf("expected %d fields, got %d", len(columns), len(row.values))
+	}
+	for i, column := range columns {
+		value := row.values[i]
+		if value == "" {
+			continue
+		}
+		switch column.affinity {
+		case "INTEGER", "REAL", "NUMERIC":
+			if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+				return fmt.Sprintf("%s isn't a number for column %s", strconv.Quote(value), column.name)
+			}
+		}
+	}
+	return ""
+}
+
+func countTableRows(config *DbCmdConfig, tableName string) (int64, error) {
+	result, err := config.Db.ExecuteStatements(fmt.Sprintf("SELECT count(*) FROM %s", db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)))
+	if err != nil {
+		return 0, err
+	}
+	statementResult := <-result.StatementResultCh
+	if statementResult.Err != nil {
+		return 0, statementResult.Err
+	}
+	values, err := readFirstColumn(statementResult)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(values[0], 10, 64)
+}
+
+// importBatchRows is the number of rows inserted by each transaction of .import, so that remote databases are sent a
+// request per batch rather than per row
+const importBatchRows = 500
+
+// insertImportRows inserts the rows in transactions of importBatchRows rows, or fewer when the transaction would be
+// longer than the sql_length limit, as some databases receive it as a single query. The transactions with a row the
+// database rejects are rolled back and inserted again row by row, skipping the rejected ones. When interrupted, the
+// current transaction is rolled back and the rows after it aren't inserted: interruptedLine is then the line of its
+// first row
+func insertImportRows(config *DbCmdConfig, tableName string, rows []importRow) (imported int, failures []importFailure, interruptedLine int, err error) {
+	// A Ctrl-C given before the import started isn't meant for it
+	select {
+	case <-config.Interrupts:
+	default:
+	}
+
+	quotedTableName := db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
+	maxLength := config.Db.StatementLimits().SQLLength
+	noticedCap := false
+	for start := 0; start < len(rows); {
+		batch, inserts, capped := getImportBatch(quotedTableName, rows[start:], maxLength)
+		start += len(batch)
+		if capped && !noticedCap {
+			noticedCap = true
+			config.Log.Notef("Importing in transactions of fewer than %d rows, to keep them under the sql_length limit of %d bytes", importBatchRows, maxLength)
+		}
+		if isImportInterrupted(config) {
+			return imported, failures, batch[0].line, nil
+		}
+
+		if err := executeStatementsSilently(config, "BEGIN;\n"+strings.Join(inserts, "\n")+"\nCOMMIT;"); err == nil {
+			imported += len(batch)
+			continue
+		}
+		_ = executeStatementsSilently(config, "ROLLBACK;")
+		if isImportInterrupted(config) {
+			return imported, failures, batch[0].line, nil
+		}
+
+		batchImported, batchFailures, interrupted, err := insertImportRowsOneByOne(config, batch, inserts)
+		if err != nil {
+			return imported, failures, 0, err
+		}
+		if interrupted {
+			return imported, failures, batch[0].line, nil
+		}
+		imported += batchImported
+		failures = append(failures, batchFailures...)
+	}
+	return imported, failures, 0, nil
+}
+
+// getImportBatch returns the rows the next transaction inserts, from the first ones of rows, with their INSERT
+// statements: importBatchRows of them, or fewer when the transaction would be longer than maxLength, as capped then
+// reports. The first row is always taken, even when it's longer alone
+func getImportBatch(quotedTableName string, rows []importRow, maxLength int64) (batch []importRow, inserts []string, capped bool) {
+	length := int64(len("BEGIN;\n\nCOMMIT;"))
+	for _, row := range rows {
+		if len(inserts) == importBatchRows {
+			break
+		}
+		insert := getImportInsert(quotedTableName, row)
+		if len(inserts) > 0 && maxLength > 0 && length+int64(len(insert))+1 > maxLength {
+			return rows[:len(inserts)], inserts, true
+		}
+		inserts = append(inserts, insert)
+		length += int64(len(insert)) + 1
+	}
+	return rows[:len(inserts)], inserts, false
+}
+
+// insertImportRowsOneByOne inserts the rows of a batch in a transaction, skipping the ones the database rejects
+func insertImportRowsOneByOne(config *DbCmdConfig, rows []importRow, inserts []string) (imported int, failures []importFailure, interrupted bool, err error) {
+	if err := executeStatementsSilently(config, "BEGIN;"); err != nil {
+		return 0, nil, false, err
+	}
+	for i, row := range rows {
+		if isImportInterrupted(config) {
+			_ = executeStatementsSilently(config, "ROLLBACK;")
+			return 0, nil, true, nil
+		}
+		if err := executeStatementsSilently(config, inserts[i]); err != nil {
+			failures = append(failures, importFailure{line: row.line, reason: err.Error()})
+			continue
+		}
+		imported++
+	}
+
+	if err := executeStatementsSilently(config, "COMMIT;"); err != nil {
+		_ = executeStatementsSilently(config, "ROLLBACK;")
+		return 0, nil, false, err
+	}
+	return imported, failures, false, nil
+}
+
+func getImportInsert(quotedTableName string, row importRow) string {
+	literals := make([]string, len(row.values))
+	for i, value := range row.values {
+		literals[i] = "'" + db.EscapeSingleQuotes(value) + "'"
+	}
+	return fmt.Sprintf("INSERT INTO %s VALUES (%s);", quotedTableName, strings.Join(literals, ", "))
+}
+
+// isImportInterrupted reports whether Ctrl-C was given since the import started
+func isImportInterrupted(config *DbCmdConfig) bool {
+	select {
+	case <-config.Interrupts:
+		return true
+	default:
+		return false
+	}
+}
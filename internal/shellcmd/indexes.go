@@ -3,6 +3,7 @@ package shellcmd
 import (
 	"fmt"
 
+	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 	"github.com/spf13/cobra"
 )
@@ -20,11 +21,11 @@ var indexesCmd = &cobra.Command{
 		var schemaStatement string
 
 		if len(args) == 1 {
-			schemaStatement = "SELECT name FROM sqlite_master WHERE type='index' AND tbl_name like '" + args[0] + "'"
+			schemaStatement = "SELECT name FROM sqlite_master WHERE type='index' AND " + db.LikeAnyNormalForm("tbl_name", args[0], "")
 		} else {
 			schemaStatement = "SELECT name FROM sqlite_master WHERE type='index'"
 		}
 
-		return config.Db.ExecuteAndPrintStatements(schemaStatement, config.OutF, true, enums.TABLE_MODE)
+		return config.Db.ExecuteAndPrintStatements(schemaStatement, config.OutF, db.PrintOptions{WithoutHeader: true, Mode: enums.TABLE_MODE})
 	},
 }
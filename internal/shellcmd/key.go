@@ -0,0 +1,44 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/shellerrors"
+)
+
+var keyCmd = &cobra.Command{
+	Use:   ".key",
+	Short: "Enter the key of an encrypted database without echo",
+	Long: `Ask for the key of the encrypted local database without echoing it, and reconnect with it, issuing PRAGMA key
+on each connection. The key never lands in the history, unlike PRAGMA key typed as a statement. It fails unless the
+database can be read with the key, which keeps the current connection, and when the shell is built without cipher
+support, like SQLCipher's. Session statements are replayed like with .open.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if !db.CipherSupported() {
+			return &shellerrors.CipherUnsupportedError{}
+		}
+		key, err := config.ReadSecret("Key: ")
+		if err != nil {
+			return fmt.Errorf("failed to read the key: %w", err)
+		}
+		if key == "" {
+			return fmt.Errorf("no key entered")
+		}
+
+		replayResults, err := config.Db.SetCipherKey(key)
+		if err != nil {
+			return err
+		}
+		PrintSessionReplayResults(config.Log, replayResults)
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var limitCmd = &cobra.Command{
+	Use:   ".limit ?NAME? ?VALUE?",
+	Short: "Show or set the limits generated statements are kept under",
+	Long: `Show the limits of the database on statements, or only the limit NAME, or set it to VALUE. The statements the
+shell generates are kept under them: the multi-row INSERTs of .dump and the transactions of .import take fewer rows,
+with a note, and SQL mode leaves out the rows that don't fit, with a warning.
+
+sql_length is the length in bytes of the longest statement, and compound_select the number of SELECTs a UNION ALL
+joins at most. The limits of local databases are read from SQLite, while remote ones, which can't be asked, are assumed
+to take statements of at most 1000000 bytes.
+
+Setting a limit doesn't change the database: it only keeps the generated statements under it, like to restore a dump
+on a database with lower limits. A VALUE of 0 goes back to the limit of the database.`,
+	Args:      cobra.MaximumNArgs(2),
+	ValidArgs: db.StatementLimitNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 2 {
+			value, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value \"%s\" for limit %s. It must be a number of bytes or SELECTs", args[1], args[0])
+			}
+			return config.Db.SetStatementLimit(args[0], value)
+		}
+
+		limits := config.Db.StatementLimits()
+		names := db.StatementLimitNames
+		if len(args) == 1 {
+			names = args[:1]
+		}
+		data := make([][]string, 0, len(names))
+		for _, name := range names {
+			value, ok := limits.Get(name)
+			if !ok {
+				return fmt.Errorf("unknown limit %s. Valid limits are %s", name, strings.Join(db.StatementLimitNames, " and "))
+			}
+			data = append(data, []string{name, strconv.FormatInt(value, 10)})
+		}
+		db.PrintTable(config.OutF, []string{"limit", "value"}, data)
+		return nil
+	},
+}
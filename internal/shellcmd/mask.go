@@ -0,0 +1,71 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var maskCmd = &cobra.Command{
+	Use:   ".mask ?COLUMN_PATTERN...?",
+	Short: "Hide the values of columns matching the patterns",
+	Long: `Show **** in place of the values of the columns whose name matches any of the glob patterns, ignoring case, like
+*password* or email. In the patterns, * matches any text, ? matches any character and [...] matches any of the
+characters in it. Only table and sparkline modes are masked: csv, json and sql modes, .dump and .cell always show the
+real values, so that exports are never altered. Without patterns, list the ones set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		deleted, err := cmd.Flags().GetStringArray("delete")
+		if err != nil {
+			return err
+		}
+
+		patterns := append([]string{}, config.GetMaskPatterns()...)
+		for _, pattern := range deleted {
+			index := findMaskPattern(patterns, pattern)
+			if index < 0 {
+				return fmt.Errorf("no mask \"%s\" is set", pattern)
+			}
+			patterns = append(patterns[:index], patterns[index+1:]...)
+		}
+
+		if len(args) == 0 && len(deleted) == 0 {
+			for _, pattern := range patterns {
+				fmt.Fprintln(config.OutF, pattern)
+			}
+			return nil
+		}
+
+		for _, pattern := range args {
+			if err := db.ValidateMaskPattern(pattern); err != nil {
+				return fmt.Errorf("invalid mask \"%s\": %w", pattern, err)
+			}
+			if findMaskPattern(patterns, pattern) < 0 {
+				patterns = append(patterns, pattern)
+			}
+		}
+		config.SetMaskPatterns(patterns)
+		return nil
+	},
+}
+
+func init() {
+	maskCmd.Flags().StringArrayP("delete", "d", nil, "Remove a pattern set before. Can be repeated")
+}
+
+// findMaskPattern returns the position of the pattern, compared ignoring case like the column names, or -1
+func findMaskPattern(patterns []string, pattern string) int {
+	for i, existing := range patterns {
+		if strings.EqualFold(existing, pattern) {
+			return i
+		}
+	}
+	return -1
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 	"github.com/spf13/cobra"
 )
@@ -11,11 +12,19 @@ import (
 var modeCmd = &cobra.Command{
 	Use:   ".mode MODE",
 	Short: "Set output mode",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Set output mode. For files opened by Excel, csv mode takes --bom to write a UTF-8 byte order mark before each
+result and --crlf to end rows with \r\n, or --excel-compat for both. NULL is left empty in csv mode. Json mode writes
+an array of objects keyed by column name, with numbers as numbers, NULL as null and blobs as base64 strings, and takes
+--pretty to write each row and value on a line of its own. Table mode computes the widths of the columns from the first
+rows of a result, --width-rows of them, and shows the following ones as they're read with the same widths. --buffered
+reads whole results before showing them instead, aligning every row.`,
+	Args: cobra.MaximumNArgs(1),
 	ValidArgs: []string{
 		string(enums.TABLE_MODE),
 		string(enums.JSON_MODE),
 		string(enums.CSV_MODE),
+		string(enums.SQL_MODE),
+		string(enums.SPARKLINE_MODE),
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		validModes := strings.Join(cmd.ValidArgs, ", ")
@@ -28,6 +37,27 @@ var modeCmd = &cobra.Command{
 			return fmt.Errorf("No mode provided. Current mode is %s. Valid modes are %s", currentMode, validModes)
 		}
 		mode := args[0]
+		csvOptions, err := getCSVOptions(cmd)
+		if err != nil {
+			return err
+		}
+		if csvOptions != (db.CSVOptions{}) && mode != string(enums.CSV_MODE) {
+			return fmt.Errorf("--bom, --crlf and --excel-compat only apply to csv mode")
+		}
+		pretty, err := cmd.Flags().GetBool("pretty")
+		if err != nil {
+			return err
+		}
+		if pretty && mode != string(enums.JSON_MODE) {
+			return fmt.Errorf("--pretty only applies to json mode")
+		}
+		tableOptions, err := getTableOptions(cmd)
+		if err != nil {
+			return err
+		}
+		if tableOptions != (db.TableOptions{}) && mode != string(enums.TABLE_MODE) {
+			return fmt.Errorf("--buffered and --width-rows only apply to table mode")
+		}
 		switch mode {
 		case string(enums.TABLE_MODE):
 			config.SetMode(enums.TABLE_MODE)
@@ -35,9 +65,59 @@ var modeCmd = &cobra.Command{
 			config.SetMode(enums.CSV_MODE)
 		case string(enums.JSON_MODE):
 			config.SetMode(enums.JSON_MODE)
+		case string(enums.SQL_MODE):
+			config.SetMode(enums.SQL_MODE)
+		case string(enums.SPARKLINE_MODE):
+			config.SetMode(enums.SPARKLINE_MODE)
 		default:
 			return fmt.Errorf("Invalid mode. Current mode is %s. Valid modes are %s", currentMode, validModes)
 		}
+		config.SetCSVOptions(csvOptions)
+		config.SetJSONOptions(db.JSONOptions{Pretty: pretty})
+		config.SetTableOptions(tableOptions)
 		return nil
 	},
 }
+
+func init() {
+	modeCmd.Flags().Bool("bom", false, "Write a UTF-8 byte order mark before each CSV result")
+	modeCmd.Flags().Bool("crlf", false, "End CSV rows with \\r\\n")
+	modeCmd.Flags().Bool("excel-compat", false, "Write CSV the way Excel reads it, like --bom --crlf")
+	modeCmd.Flags().Bool("pretty", false, "Write JSON indented, with each row and value on a line of its own")
+	modeCmd.Flags().Bool("buffered", false, "Read whole results before showing them as tables, so that every row is aligned")
+	modeCmd.Flags().Int("width-rows", 0, fmt.Sprintf("Compute the widths of the columns of tables from the first N rows, %d by default", db.DEFAULT_TABLE_WIDTH_ROWS))
+}
+
+func getTableOptions(cmd *cobra.Command) (db.TableOptions, error) {
+	buffered, err := cmd.Flags().GetBool("buffered")
+	if err != nil {
+		return db.TableOptions{}, err
+	}
+	widthRows, err := cmd.Flags().GetInt("width-rows")
+	if err != nil {
+		return db.TableOptions{}, err
+	}
+	if widthRows < 0 {
+		return db.TableOptions{}, fmt.Errorf("invalid --width-rows %d. It must be a positive number of rows", widthRows)
+	}
+	if buffered && widthRows > 0 {
+		return db.TableOptions{}, fmt.Errorf("--buffered and --width-rows can't be used together, as buffered tables are as wide as all their rows")
+	}
+	return db.TableOptions{Buffered: buffered, WidthRows: widthRows}, nil
+}
+
+func getCSVOptions(cmd *cobra.Command) (db.CSVOptions, error) {
+	bom, err := cmd.Flags().GetBool("bom")
+	if err != nil {
+		return db.CSVOptions{}, err
+	}
+	crlf, err := cmd.Flags().GetBool("crlf")
+	if err != nil {
+		return db.CSVOptions{}, err
+	}
+	excelCompat, err := cmd.Flags().GetBool("excel-compat")
+	if err != nil {
+		return db.CSVOptions{}, err
+	}
+	return db.CSVOptions{BOM: bom || excelCompat, CRLF: crlf || excelCompat}, nil
+}
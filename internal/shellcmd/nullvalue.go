@@ -0,0 +1,35 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var nullValueCmd = &cobra.Command{
+	Use:   ".nullvalue ?STRING?",
+	Short: "Show NULL values as STRING",
+	Long: `Show the NULL values of table, CSV and sparkline modes as STRING, so that they can be told apart from text
+values like 'NULL'. STRING may be empty, given as "". Until it's set, tables show NULL and CSV leaves NULL fields empty.
+JSON and SQL modes always write actual NULL literals. Without an argument, the current placeholder is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if nullValue := config.GetNullValue(); nullValue != nil {
+				fmt.Fprintf(config.OutF, "nullvalue: %s\n", strconv.Quote(*nullValue))
+			} else {
+				fmt.Fprintln(config.OutF, "nullvalue: default (NULL in tables, empty in CSV)")
+			}
+			return nil
+		}
+
+		config.SetNullValue(args[0])
+		return nil
+	},
+}
@@ -0,0 +1,60 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var numfmtCmd = &cobra.Command{
+	Use:   ".numfmt ?on|off? ?LOCALE?",
+	Short: "Group the thousands of the numbers shown in tables",
+	Long: `Show the integers and floats of table mode with their thousands grouped, like 1,234,567.5, for presentations.
+LOCALE picks the separators: en, the default, groups with commas and separates decimals with a dot, eu groups with dots
+and separates decimals with a comma, like 1.234.567,5. Locale names like de_DE or en-US are taken by their language.
+Only table mode is affected: csv, json and sql modes, .dump and .cell always show the numbers as they are. Without an
+argument, the current setting is shown.`,
+	Args:      cobra.MaximumNArgs(2),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			fmt.Fprintf(config.OutF, "numfmt: %s\n", formatNumberLocale(config.GetNumberLocale()))
+			return nil
+		}
+
+		switch args[0] {
+		case "on":
+			localeName := "en"
+			if len(args) == 2 {
+				localeName = args[1]
+			}
+			locale, err := db.GetNumberLocale(localeName)
+			if err != nil {
+				return err
+			}
+			config.SetNumberLocale(locale)
+		case "off":
+			if len(args) == 2 {
+				return fmt.Errorf("a locale can only be given with on")
+			}
+			config.SetNumberLocale(db.NumberLocale{})
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on and off", args[0])
+		}
+		return nil
+	},
+}
+
+func formatNumberLocale(locale db.NumberLocale) string {
+	if locale.Name == "" {
+		return "off"
+	}
+	return "on " + locale.Name
+}
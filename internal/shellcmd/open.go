@@ -0,0 +1,52 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var openCmd = &cobra.Command{
+	Use:   ".open ?DB?",
+	Short: "Reconnect to the database or connect to another one",
+	Long: `Connect to DB, or reconnect to the current database if DB is omitted.
+Session statements (ATTACH, DETACH and PRAGMA assignments) executed so far are replayed on the new connection.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		dbUri := ""
+		if len(args) == 1 {
+			dbUri = args[0]
+		}
+		if dbUri != "" && !db.IsUrl(dbUri) {
+			var err error
+			dbUri, err = ResolveFilePath(config.FileRoot, dbUri)
+			if err != nil {
+				return err
+			}
+		}
+
+		authToken, err := cmd.Flags().GetString("auth")
+		if err != nil {
+			return err
+		}
+
+		replayResults, err := config.Db.Open(dbUri, authToken)
+		if err != nil {
+			return err
+		}
+
+		PrintSessionReplayResults(config.Log, replayResults)
+		return nil
+	},
+}
+
+func init() {
+	openCmd.Flags().String("auth", "", "Add a JWT Token.")
+}
@@ -0,0 +1,44 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var outputCmd = &cobra.Command{
+	Use:   ".output ?FILE|stdout?",
+	Short: "Send the results to FILE, or back to the standard output",
+	Long: `Send the results of the following statements and commands, like .dump, to FILE, replacing it, until .output
+is given again. stdout, or no argument, sends them back to the standard output and closes the file. Errors and the
+prompt are never redirected. The current destination is kept when FILE can't be created.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 || args[0] == "stdout" {
+			return config.SetOutputFile("", false)
+		}
+		return config.SetOutputFile(args[0], false)
+	},
+}
+
+var onceCmd = &cobra.Command{
+	Use:   ".once FILE",
+	Short: "Send the results of the next statement or command to FILE",
+	Long: `Send the results of the next statement, or of all the statements of the next line, or of the next command to
+FILE, replacing it. The results after them are sent back to where they were sent before. The current destination is
+kept when FILE can't be created.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		return config.SetOutputFile(args[0], true)
+	},
+}
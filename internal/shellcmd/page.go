@@ -0,0 +1,68 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var pageCmd = &cobra.Command{
+	Use:   ".page ?SIZE?",
+	Short: "Show SELECT results in pages of SIZE rows",
+	Long: `Show the results of SELECT statements in pages of SIZE rows, browsed with .next and .prev. Each page executes
+the statement again with LIMIT and OFFSET clauses, so statements with their own LIMIT clause aren't paged, and
+statements without an ORDER BY clause may show rows in a different order on each page. A SIZE of 0 turns paging off,
+and without SIZE the current page size is shown. A new page size applies from the next statement.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if size := config.GetPageSize(); size > 0 {
+				fmt.Fprintf(config.OutF, "page size: %d\n", size)
+			} else {
+				fmt.Fprintln(config.OutF, "page size: off")
+			}
+			return nil
+		}
+
+		size, err := strconv.Atoi(args[0])
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid page size \"%s\". Give the number of rows of a page, or 0 to turn paging off", args[0])
+		}
+		config.SetPageSize(size)
+		return nil
+	},
+}
+
+var nextCmd = &cobra.Command{
+	Use:   ".next",
+	Short: "Show the next page of the last paged result",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		return config.TurnPage(1)
+	},
+}
+
+var prevCmd = &cobra.Command{
+	Use:   ".prev",
+	Short: "Show the previous page of the last paged result",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		return config.TurnPage(-1)
+	},
+}
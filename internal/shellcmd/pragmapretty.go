@@ -0,0 +1,41 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var pragmaPrettyCmd = &cobra.Command{
+	Use:   ".pragma-pretty ?on|off?",
+	Short: "Show the results of well known PRAGMAs for reading",
+	Long: `Show the results of PRAGMA table_info, index_list, foreign_key_list and database_list in a layout easier to
+read: primary key columns are marked with ✓, flags are shown as YES or NO, foreign keys take a row each, and defaults
+are shown as the SQL they're written in. Only table mode is affected, and only results with exactly the columns of
+these PRAGMAs. Turn it off to see the raw results. Without an argument, the current setting is shown.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetPrettyPragmas() {
+				fmt.Fprintln(config.OutF, "pragma-pretty: on")
+			} else {
+				fmt.Fprintln(config.OutF, "pragma-pretty: off")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "on", "off":
+			config.SetPrettyPragmas(args[0] == "on")
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on and off", args[0])
+		}
+		return nil
+	},
+}
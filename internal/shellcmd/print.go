@@ -0,0 +1,36 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var printEscapesReplacer = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t")
+
+var printCmd = &cobra.Command{
+	Use:   ".print ?-e? ?TEXT...?",
+	Short: "Print the arguments separated by spaces",
+	Long: `Print the arguments separated by spaces. Quoted arguments keep their spaces.
+With -e, the \n, \t and \\ escapes are replaced in every argument, quoted or not.`,
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		interpretEscapes := len(args) > 0 && args[0] == "-e"
+		if interpretEscapes {
+			args = args[1:]
+		}
+
+		text := strings.Join(args, " ")
+		if interpretEscapes {
+			text = printEscapesReplacer.Replace(text)
+		}
+		fmt.Fprintln(config.OutF, text)
+		return nil
+	},
+}
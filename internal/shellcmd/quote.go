@@ -0,0 +1,50 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/spf13/cobra"
+)
+
+var validIdentifierQuoteStyles = []string{
+	string(enums.DOUBLE_QUOTE_STYLE),
+	string(enums.BACKTICK_STYLE),
+	string(enums.BRACKET_STYLE),
+}
+
+var quoteCmd = &cobra.Command{
+	Use:       ".quote STYLE",
+	Short:     "Set quote style for identifiers in generated SQL",
+	Long:      `Set the quote style for table and column names in generated SQL, like the one written by .dump. Valid styles are double ("name"), backtick (` + "`name`" + `) and bracket ([name]).`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: validIdentifierQuoteStyles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			fmt.Fprintln(config.OutF, config.GetIdentifierQuoteStyle())
+			return nil
+		}
+
+		style, err := parseIdentifierQuoteStyle(args[0])
+		if err != nil {
+			return err
+		}
+		config.SetIdentifierQuoteStyle(style)
+		return nil
+	},
+}
+
+func parseIdentifierQuoteStyle(style string) (enums.IdentifierQuoteStyle, error) {
+	for _, validStyle := range validIdentifierQuoteStyles {
+		if style == validStyle {
+			return enums.IdentifierQuoteStyle(style), nil
+		}
+	}
+	return "", fmt.Errorf("invalid quote style \"%s\". Valid styles are %s", style, strings.Join(validIdentifierQuoteStyles, ", "))
+}
@@ -5,37 +5,201 @@ import (
 	"os"
 	"strings"
 
-	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/spf13/cobra"
 )
 
 var readCmd = &cobra.Command{
 	Use:   ".read FILENAME",
 	Short: "Execute commands from a file",
-	Args:  cobra.ExactArgs(1),
+	Long: `Execute the SQL statements and dot commands of a file, or of the standard input with - or /dev/stdin. Dot
+commands this shell doesn't implement, like the ones scripts generated by sqlite3 may contain, are reported and skipped.
+The file stops at its first error, reported with the line of the statement or command that failed.
+
+The standard input can only be read when the shell doesn't read its own statements from it, like with -e ".read -".
+
+With --defer-fk the file is executed in a single transaction with PRAGMA defer_foreign_keys set, so foreign key
+constraints are only checked once at commit, whatever the order the rows are inserted in. The BEGIN, COMMIT, END and
+ROLLBACK statements of the file are skipped, and its changes are rolled back if any row violates a constraint.
+
+With --verify-sha256 HASH, or --verify-manifest with a manifest in the format of sha256sum listing the file, the whole
+file is read and hashed before anything is executed, and nothing is when its SHA-256 hash isn't the expected one. The
+paths of the manifest are relative to its directory.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
 		if !ok {
 			return fmt.Errorf("missing db connection")
 		}
 
-		file, err := os.Open(args[0])
+		content, err := readScriptFile(config, args[0])
 		if err != nil {
 			return err
 		}
-		defer file.Close()
 
-		stat, err := file.Stat()
+		expectedHash, err := cmd.Flags().GetString("verify-sha256")
+		if err != nil {
+			return err
+		}
+		manifest, err := cmd.Flags().GetString("verify-manifest")
+		if err != nil {
+			return err
+		}
+		if err := verifyScriptChecksum(config, args[0], content, expectedHash, manifest); err != nil {
+			return err
+		}
+
+		expandVars, err := cmd.Flags().GetBool("expand-vars")
 		if err != nil {
 			return err
 		}
 
-		content := make([]byte, stat.Size())
-		_, err = file.Read(content)
+		deferForeignKeys, err := cmd.Flags().GetBool("defer-fk")
 		if err != nil {
 			return err
 		}
 
-		return config.Db.ExecuteAndPrintStatements(strings.TrimSpace(string(content)), config.OutF, false, enums.TABLE_MODE)
+		options := scriptOptions{expandVars: expandVars}
+		if deferForeignKeys {
+			return executeScriptWithDeferredForeignKeys(cmd.Root(), config, string(content), options)
+		}
+		return executeScript(cmd.Root(), config, string(content), options)
 	},
 }
+
+func init() {
+	readCmd.Flags().Bool("expand-vars", false, "Replace {{NAME}} references with the variables defined by .set")
+	readCmd.Flags().Bool("defer-fk", false, "Execute the file in a transaction checking foreign key constraints at commit")
+	readCmd.Flags().String("verify-sha256", "", "Execute the file only if its SHA-256 hash is this one")
+	readCmd.Flags().String("verify-manifest", "", "Execute the file only if its SHA-256 hash is the one listed in this manifest")
+}
+
+// readScriptFile returns the content of the file, or of the standard input of the shell for - and /dev/stdin
+func readScriptFile(config *DbCmdConfig, fileName string) ([]byte, error) {
+	if fileName == "-" || fileName == "/dev/stdin" {
+		return config.ReadStdin()
+	}
+	fileName, err := ResolveFilePath(config.FileRoot, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(fileName)
+}
+
+type scriptOptions struct {
+	expandVars bool
+	// skipTransactionStatements leaves out BEGIN, COMMIT, END and ROLLBACK statements, for scripts run in a transaction
+	skipTransactionStatements bool
+}
+
+// executeScript executes the statements of a script as they complete, printing them in the current mode. Lines starting
+// with "." outside of a statement are dot commands, routed to the ones of rootCmd or reported with their line number
+// when there's no such command. It stops at the first statement or command failing, returning its error with its line
+func executeScript(rootCmd *cobra.Command, config *DbCmdConfig, script string, options scriptOptions) error {
+	statementLines := make([]string, 0)
+	// firstLine is the line of the script the first of statementLines is on
+	firstLine := 1
+
+	for i, line := range strings.Split(script, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmedLine, ".") || db.IsInsideStatement(strings.Join(statementLines, "\n")) {
+			if len(statementLines) == 0 {
+				firstLine = i + 1
+			}
+			statementLines = append(statementLines, line)
+			continue
+		}
+
+		if err := executeScriptStatements(config, strings.Join(statementLines, "\n"), firstLine, options); err != nil {
+			return err
+		}
+		statementLines = statementLines[:0]
+
+		commandName := strings.Fields(trimmedLine)[0]
+		if !isSupportedCommand(rootCmd, commandName) {
+			config.Log.Warnf("line %d: unsupported command %s was skipped", i+1, commandName)
+			continue
+		}
+		if err := config.ExecuteCommand(trimmedLine); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	return executeScriptStatements(config, strings.Join(statementLines, "\n"), firstLine, options)
+}
+
+// executeScriptStatements executes statements starting on line firstLine of a script. The error of the statement
+// failing is returned with the line that statement starts on
+func executeScriptStatements(config *DbCmdConfig, statements string, firstLine int, options scriptOptions) error {
+	if strings.TrimSpace(statements) == "" {
+		return nil
+	}
+	defer config.EndOnceOutput()
+
+	// The lines are those of the statements as written, before variables expand to values that may span several lines.
+	// They're unknown when the statements can't be split, like when a string isn't terminated, which the database
+	// reports on its own
+	splitStatements, _ := db.SplitStatements(statements)
+
+	if options.expandVars {
+		var err error
+		statements, err = db.ExpandVariables(statements, config.GetVariables())
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.skipTransactionStatements {
+		var err error
+		splitStatements, err = removeTransactionStatements(config, statements)
+		if err != nil {
+			return err
+		}
+		statements = joinStatements(splitStatements)
+	}
+
+	// The statements before the one failing are the ones that succeeded, as the file stops at its first error
+	summary := &db.ExecutionSummary{}
+	printOptions := config.GetPrintOptions()
+	printOptions.Summary = summary
+	err := config.Db.ExecuteAndPrintStatements(statements, config.OutF, printOptions)
+	if err != nil && summary.Succeeded < len(splitStatements) {
+		return fmt.Errorf("line %d: %w", firstLine+splitStatements[summary.Succeeded].Line-1, err)
+	}
+	return err
+}
+
+// removeTransactionStatements leaves out the statements beginning or ending a transaction, warning about each
+func removeTransactionStatements(config *DbCmdConfig, statements string) ([]db.Statement, error) {
+	splitStatements, err := db.SplitStatements(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]db.Statement, 0, len(splitStatements))
+	for _, statement := range splitStatements {
+		if db.IsTransactionStatement(statement.Text) {
+			config.Log.Warnf("%s was skipped, as the file is executed in a single transaction", statement.Text)
+			continue
+		}
+		kept = append(kept, statement)
+	}
+	return kept, nil
+}
+
+func joinStatements(statements []db.Statement) string {
+	texts := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		texts = append(texts, statement.Text+";")
+	}
+	return strings.Join(texts, "\n")
+}
+
+func isSupportedCommand(rootCmd *cobra.Command, name string) bool {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package shellcmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// verifyScriptChecksum checks the SHA-256 hash of the content read from the file against the one given with
+// --verify-sha256, or the one the manifest given with --verify-manifest lists for the file, when either is set
+func verifyScriptChecksum(config *DbCmdConfig, fileName string, content []byte, expectedHash string, manifest string) error {
+	if expectedHash == "" && manifest == "" {
+		return nil
+	}
+	if expectedHash != "" && manifest != "" {
+		return fmt.Errorf("--verify-sha256 and --verify-manifest can't be used together")
+	}
+
+	if manifest != "" {
+		if fileName == "-" || fileName == "/dev/stdin" {
+			return fmt.Errorf("the standard input isn't listed in a manifest. Use --verify-sha256 to verify it")
+		}
+		var err error
+		expectedHash, err = getManifestHash(config, manifest, fileName)
+		if err != nil {
+			return err
+		}
+	}
+	if !isSha256Hash(expectedHash) {
+		return fmt.Errorf("invalid SHA-256 hash \"%s\". It must be 64 hexadecimal digits", expectedHash)
+	}
+
+	sum := sha256.Sum256(content)
+	actualHash := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actualHash, expectedHash) {
+		return fmt.Errorf("the SHA-256 hash of %s doesn't match, so nothing was executed\n  expected: %s\n  actual:   %s", fileName, strings.ToLower(expectedHash), actualHash)
+	}
+	return nil
+}
+
+// getManifestHash returns the hash the manifest lists for the file. The manifest has the format of sha256sum, a line
+// per file with its hash and its path, relative to the directory of the manifest, separated by two spaces, or by a
+// space and a * for files hashed in binary mode. Blank lines and lines starting with # are skipped
+func getManifestHash(config *DbCmdConfig, manifest string, fileName string) (string, error) {
+	manifestPath, err := ResolveFilePath(config.FileRoot, manifest)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return "", err
+	}
+	filePath, err := ResolveFilePath(config.FileRoot, fileName)
+	if err != nil {
+		return "", err
+	}
+	filePath, err = resolveSymlinks(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, entry, ok := strings.Cut(line, " ")
+		if !ok || len(entry) < 2 || (entry[0] != ' ' && entry[0] != '*') {
+			return "", fmt.Errorf("%s line %d: expected a hash and a file name separated by two spaces", manifest, i+1)
+		}
+		entry = entry[1:]
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(filepath.Dir(manifestPath), entry)
+		}
+		entryPath, err := resolveSymlinks(entry)
+		if err != nil {
+			return "", err
+		}
+		if entryPath == filePath {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("%s isn't listed in the manifest %s, so nothing was executed", fileName, manifest)
+}
+
+func isSha256Hash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
@@ -0,0 +1,60 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+)
+
+var renameTableCmd = &cobra.Command{
+	Use:   ".rename-table OLD NEW",
+	Short: "Rename a table",
+	Long: `Rename the table OLD to NEW with ALTER TABLE, quoting both names as needed. It fails when there's already a
+table, index, view or trigger named NEW. SQLite updates the indexes, triggers, views and foreign keys mentioning the
+table.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		tableName, found, err := findTableName(config, args[0])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no such table: %s", args[0])
+		}
+		if err := checkSchemaNameFree(config, args[1]); err != nil {
+			return err
+		}
+
+		quoteStyle := config.GetIdentifierQuoteStyle()
+		return executeStatementsSilently(config, fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteIdentifierIfNeeded(tableName, quoteStyle), quoteIdentifierIfNeeded(args[1], quoteStyle)))
+	},
+}
+
+// checkSchemaNameFree fails when a table, index, view or trigger is named name already, matching it as db.EqualNames
+// does, as SQLite doesn't take two of them with the same name
+func checkSchemaNameFree(config *DbCmdConfig, name string) error {
+	rows, err := queryAssertRows(config, "SELECT type, name FROM sqlite_master")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if db.EqualNames(row[1], name) {
+			return fmt.Errorf("there is already %s named %s", getSchemaTypeArticle(row[0]), row[1])
+		}
+	}
+	return nil
+}
+
+func getSchemaTypeArticle(schemaType string) string {
+	if schemaType == "index" {
+		return "an index"
+	}
+	return "a " + schemaType
+}
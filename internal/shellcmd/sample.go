@@ -0,0 +1,181 @@
+package shellcmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// sampleHashRange is the number of values the hash of the sampled keys takes, from 0 on
+const sampleHashRange = 1 << 32
+
+var sampleCmd = &cobra.Command{
+	Use:   ".sample TABLE N",
+	Short: "Show about N rows of a table, chosen the same way every time",
+	Long: `Show about N rows of a table, in the current mode or in the one given with --format, or write them to a file
+with --out. Each row is taken when a hash of its rowid, mixed with --seed, falls in a range sized for N rows, so the
+sample is about N rows rather than exactly N, and the same rows are taken every time: running it again, or with rows
+added to the table, takes the rows it took before. Another seed takes other rows. --where restricts the rows sampled.
+
+Tables without rowid have their rows numbered in the order of their primary key instead, so their sample changes when
+rows are added before the ones it took, and numbering them reads the whole table.
+
+--explain-sample shows the statement selecting the sample instead of executing it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		rows, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || rows <= 0 {
+			return fmt.Errorf("invalid number of rows %s. It must be a positive number", args[1])
+		}
+		seed, err := cmd.Flags().GetInt64("seed")
+		if err != nil {
+			return err
+		}
+		condition, err := cmd.Flags().GetString("where")
+		if err != nil {
+			return err
+		}
+		outFile, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		explain, err := cmd.Flags().GetBool("explain-sample")
+		if err != nil {
+			return err
+		}
+		mode := config.GetMode()
+		if format != "" {
+			mode = enums.PrintMode(format)
+			if mode != enums.TABLE_MODE && mode != enums.CSV_MODE && mode != enums.JSON_MODE && mode != enums.SQL_MODE {
+				return fmt.Errorf("invalid --format \"%s\". Valid formats are table, csv, json and sql", format)
+			}
+		}
+
+		tableName, found, err := findTableName(config, args[0])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("no such table: %s", args[0])
+		}
+		statement, err := getSampleStatement(config, tableName, rows, seed, condition)
+		if err != nil {
+			return err
+		}
+		if explain {
+			fmt.Fprintln(config.OutF, statement)
+			return nil
+		}
+
+		options := config.GetPrintOptions()
+		options.Mode = mode
+		if outFile == "" {
+			return config.Db.ExecuteAndPrintStatements(statement, config.OutF, options)
+		}
+
+		outFile, err = ResolveFilePath(config.FileRoot, outFile)
+		if err != nil {
+			return err
+		}
+		file, err := os.Create(outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		// The file only holds the sampled rows, without the lines added for the people reading them
+		options.Summary = &db.ExecutionSummary{}
+		options.Timer, options.Changes, options.HeaderInterval = false, false, 0
+		if err := config.Db.ExecuteAndPrintStatements(statement, file, options); err != nil {
+			return err
+		}
+		config.Log.Infof("Wrote %d sampled rows to %s", options.Summary.RowsReturned, outFile)
+		return file.Close()
+	},
+}
+
+func init() {
+	sampleCmd.Flags().Int64("seed", 0, "Mix this number into the hash choosing the rows, to take another sample")
+	sampleCmd.Flags().String("where", "", "Sample only the rows matching this SQL condition")
+	sampleCmd.Flags().String("out", "", "Write the sample to this file instead of showing it")
+	sampleCmd.Flags().String("format", "", "Write the sample in this format instead of the current mode: table, csv, json or sql")
+	sampleCmd.Flags().Bool("explain-sample", false, "Show the statement selecting the sample instead of executing it")
+}
+
+// getSampleStatement returns the statement selecting about rows rows of the table matching the condition, when it's
+// not empty: the ones whose key hashes below a threshold set from the number of rows matching it
+func getSampleStatement(config *DbCmdConfig, tableName string, rows int64, seed int64, condition string) (string, error) {
+	quoteStyle := config.GetIdentifierQuoteStyle()
+	table := quoteIdentifierIfNeeded(tableName, quoteStyle)
+	where := ""
+	if condition != "" {
+		where = " WHERE " + condition
+	}
+
+	counts, err := queryAssertRows(config, "SELECT count(*) FROM "+table+where)
+	if err != nil {
+		return "", err
+	}
+	total, err := strconv.ParseInt(counts[0][0], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	threshold := int64(sampleHashRange)
+	if rows < total {
+		threshold = int64(math.Ceil(float64(rows) / float64(total) * sampleHashRange))
+	}
+
+	rowidColumn, err := getRowidColumn(config, tableName)
+	if err != nil {
+		return "", err
+	}
+	hashes := getSampleHashes(seed)
+	if rowidColumn != "" {
+		return fmt.Sprintf("WITH sample_keys(k) AS (SELECT %s FROM %s%s),\n%s\nSELECT * FROM %s WHERE %s IN (SELECT k FROM sample_hashes WHERE h < %d) ORDER BY %s;", rowidColumn, table, where, hashes, table, rowidColumn, threshold, rowidColumn), nil
+	}
+
+	columnRows, err := queryAssertRows(config, fmt.Sprintf("SELECT name, pk FROM pragma_table_info('%s') ORDER BY cid", db.EscapeSingleQuotes(tableName)))
+	if err != nil {
+		return "", err
+	}
+	columns := make([]string, 0, len(columnRows))
+	primaryKey := make([]string, len(columnRows))
+	for _, row := range columnRows {
+		column := quoteIdentifierIfNeeded(row[0], quoteStyle)
+		columns = append(columns, column)
+		if position, err := strconv.Atoi(row[1]); err == nil && position > 0 && position <= len(primaryKey) {
+			primaryKey[position-1] = column
+		}
+	}
+	orderBy := strings.Join(strings.Fields(strings.Join(primaryKey, " ")), ", ")
+	return fmt.Sprintf("WITH sample_rows AS (SELECT row_number() OVER (ORDER BY %s) AS sample_key, * FROM %s%s),\nsample_keys(k) AS (SELECT sample_key FROM sample_rows),\n%s\nSELECT %s FROM sample_rows WHERE sample_key IN (SELECT k FROM sample_hashes WHERE h < %d) ORDER BY %s;", orderBy, table, where, hashes, strings.Join(columns, ", "), threshold, orderBy), nil
+}
+
+// getSampleHashes returns the common table expressions hashing each key k of sample_keys into the h of sample_hashes,
+// a number from 0 to sampleHashRange-1. The hash is the integer hash of Thomas Mueller, with the seed added after its
+// first round so that each seed takes unrelated rows. It's computed on 32 bits so it never overflows the 64-bit
+// integers of SQLite, which has no XOR operator: a XOR b is written (a | b) - (a & b)
+func getSampleHashes(seed int64) string {
+	mask := sampleHashRange - 1
+	xorShift := "((h >> 16) | h) - ((h >> 16) & h)"
+	return fmt.Sprintf(`sample_hashes_1(k, h) AS (SELECT k, ((k & %[1]d) + ((k >> 32) & %[1]d)) & %[1]d FROM sample_keys),
+sample_hashes_2(k, h) AS (SELECT k, ((((%[3]s) * 73244475) & %[1]d) + %[2]d) & %[1]d FROM sample_hashes_1),
+sample_hashes_3(k, h) AS (SELECT k, ((%[3]s) * 73244475) & %[1]d FROM sample_hashes_2),
+sample_hashes_4(k, h) AS (SELECT k, ((%[3]s) * 73244475) & %[1]d FROM sample_hashes_3),
+sample_hashes(k, h) AS (SELECT k, %[3]s FROM sample_hashes_4)`, mask, seed&int64(mask), xorShift)
+}
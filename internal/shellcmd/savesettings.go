@@ -0,0 +1,29 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var saveSettingsCmd = &cobra.Command{
+	Use:   ".save-settings --per-db",
+	Short: "Save the output settings of this database",
+	Long: `Save the output mode and the quote style, to be loaded every time the shell connects to this database.
+The settings are saved for the absolute path of the database file or the host and path of its URL, and are loaded after the --init file and before the
+--init-sql statements.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		return config.SaveDatabaseSettings()
+	},
+}
+
+func init() {
+	saveSettingsCmd.Flags().Bool("per-db", false, "Save the settings for this database")
+	_ = saveSettingsCmd.MarkFlagRequired("per-db")
+}
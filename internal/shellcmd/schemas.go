@@ -3,6 +3,7 @@ package shellcmd
 import (
 	"fmt"
 
+	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 	"github.com/spf13/cobra"
 )
@@ -17,18 +18,27 @@ var schemaCmd = &cobra.Command{
 			return fmt.Errorf("missing db connection")
 		}
 
+		tableFilter, err := getListingTableFilter(cmd, config)
+		if err != nil {
+			return err
+		}
+
+		// Filtering on tbl_name too leaves out the indexes and triggers of the tables left out
 		schemaStatement := `select sql || ';' from sqlite_schema
-			where name not like 'sqlite_%'
-			and name != '_litestream_seq'
-			and name != '_litestream_lock'
-			and name != 'libsql_wasm_func_table'`
+			where sql is not null
+			and ` + tableFilter.Condition("name") + `
+			and ` + tableFilter.Condition("tbl_name")
 
 		if len(args) == 1 {
-			schemaStatement += " and name like '" + args[0] + "'"
+			schemaStatement += " and " + db.LikeAnyNormalForm("name", args[0], "")
 		}
 
 		schemaStatement += " order by tbl_name"
 
-		return config.Db.ExecuteAndPrintStatements(schemaStatement, config.OutF, true, enums.TABLE_MODE)
+		return config.Db.ExecuteAndPrintStatements(schemaStatement, config.OutF, db.PrintOptions{WithoutHeader: true, Mode: enums.TABLE_MODE})
 	},
 }
+
+func init() {
+	schemaCmd.Flags().Bool("include-internal", false, "Show the schema of the tables SQLite, Litestream and libSQL keep for themselves too")
+}
@@ -0,0 +1,165 @@
+package shellcmd
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+)
+
+// selftestValue is a value the self test round trips, written as the SQL literal the sqlite dump writes for it
+type selftestValue struct {
+	name     string
+	literal  string
+	typeName string
+}
+
+var selftestValues = []selftestValue{
+	{name: "integer", literal: "9007199254740993", typeName: "integer"},
+	{name: "negative integer", literal: "-42", typeName: "integer"},
+	{name: "real", literal: "1.5", typeName: "real"},
+	{name: "text", literal: "'plain'", typeName: "text"},
+	{name: "text with quotes", literal: `'it''s "quoted"'`, typeName: "text"},
+	{name: "text with newlines", literal: "'first line\nsecond line\r\n'", typeName: "text"},
+	{name: "unicode text", literal: "'ünïcödé 日本語 🎉'", typeName: "text"},
+	{name: "blob", literal: "X'00FF10'", typeName: "blob"},
+	{name: "empty blob", literal: "X''", typeName: "blob"},
+	{name: "null", literal: "NULL", typeName: "null"},
+}
+
+const selftestTablePrefix = "_libsql_shell_selftest_"
+
+var selftestCmd = &cobra.Command{
+	Use:   ".selftest",
+	Short: "Check that values round trip through this database",
+	Long: `Check that the connection and the shell handle every kind of value right: integers, reals, texts with quotes,
+newlines and unicode, blobs and NULL are inserted into a scratch table, selected back, dumped and restored from the
+dump. Each check is reported as PASS or FAIL, and the command fails when any check does. The scratch table is created
+with a name of its own, never touching existing tables, and it's dropped even when a check fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		tableName := fmt.Sprintf("%s%08x", selftestTablePrefix, rand.Uint32())
+		checks := &selftestChecks{config: config}
+		if created := runSelftest(config, checks, tableName); created {
+			checks.report("drop the scratch table", executeStatementsSilently(config, "DROP TABLE IF EXISTS "+db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)+";"))
+		}
+
+		fmt.Fprintf(config.OutF, "%d of %d checks passed\n", checks.count-checks.failed, checks.count)
+		if checks.failed > 0 {
+			return fmt.Errorf("self test failed: %d of %d checks failed", checks.failed, checks.count)
+		}
+		return nil
+	},
+}
+
+type selftestChecks struct {
+	config *DbCmdConfig
+	count  int
+	failed int
+}
+
+// report prints the outcome of a check, returning whether it passed
+func (c *selftestChecks) report(name string, err error) bool {
+	c.count++
+	if err != nil {
+		c.failed++
+		fmt.Fprintf(c.config.OutF, "FAIL %s: %s\n", name, err)
+		return false
+	}
+	fmt.Fprintf(c.config.OutF, "PASS %s\n", name)
+	return true
+}
+
+// runSelftest runs the checks on the scratch table, stopping when a check the following ones depend on fails. It
+// returns whether it created the scratch table, which is then left to drop
+func runSelftest(config *DbCmdConfig, checks *selftestChecks, tableName string) (created bool) {
+	quotedTableName := db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
+
+	// Without IF NOT EXISTS, so that a table that happens to have the name is left alone
+	createTable := fmt.Sprintf("CREATE TABLE %s (id INTEGER PRIMARY KEY, value);", quotedTableName)
+	if !checks.report("create the scratch table", executeStatementsSilently(config, createTable)) {
+		return false
+	}
+
+	inserts := make([]string, 0, len(selftestValues))
+	for i, value := range selftestValues {
+		inserts = append(inserts, fmt.Sprintf("INSERT INTO %s VALUES (%d, %s);", quotedTableName, i, value.literal))
+	}
+	if !checks.report("insert the values", executeStatementsSilently(config, strings.Join(inserts, "\n"))) {
+		return true
+	}
+	if !checkSelftestValues(config, checks, quotedTableName, "select") {
+		return true
+	}
+
+	dump := new(bytes.Buffer)
+	options := dumpOptions{compat: dumpCompats["sqlite"], quoteStyle: enums.DOUBLE_QUOTE_STYLE, progress: func(db.ProgressEvent) {}}
+	_, err := dumpTable(dump, config, tableName, options, true)
+	if !checks.report("dump the scratch table", err) {
+		return true
+	}
+	if !checks.report("drop the scratch table before restoring it", executeStatementsSilently(config, "DROP TABLE "+quotedTableName+";")) {
+		return true
+	}
+	if !checks.report("restore the scratch table from the dump", executeStatementsSilently(config, dump.String())) {
+		return true
+	}
+	checkSelftestValues(config, checks, quotedTableName, "restore")
+	return true
+}
+
+// checkSelftestValues checks each value selected from the scratch table against the one inserted, returning whether
+// the values could be selected at all
+func checkSelftestValues(config *DbCmdConfig, checks *selftestChecks, quotedTableName string, stage string) bool {
+	result, err := config.Db.ExecuteStatements(fmt.Sprintf("SELECT value, typeof(value) FROM %s ORDER BY id;", quotedTableName))
+	if err == nil {
+		statementResult := <-result.StatementResultCh
+		err = statementResult.Err
+		if err == nil {
+			return checkSelftestRows(checks, statementResult, stage)
+		}
+	}
+	return checks.report(stage+" the values", err)
+}
+
+func checkSelftestRows(checks *selftestChecks, statementResult db.StatementResult, stage string) bool {
+	rows := make([][]interface{}, 0, len(selftestValues))
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return checks.report(stage+" the values", rowResult.Err)
+		}
+		rows = append(rows, rowResult.Row)
+	}
+	if len(rows) != len(selftestValues) {
+		return checks.report(stage+" the values", fmt.Errorf("got %d rows, want %d", len(rows), len(selftestValues)))
+	}
+
+	for i, value := range selftestValues {
+		checks.report(fmt.Sprintf("%s %s", stage, value.name), checkSelftestRow(rows[i], value))
+	}
+	return true
+}
+
+func checkSelftestRow(row []interface{}, value selftestValue) error {
+	formattedRow, err := db.FormatData(row, db.SQLITE)
+	if err != nil {
+		return err
+	}
+	if formattedRow[0] != value.literal {
+		return fmt.Errorf("got %s, want %s", formattedRow[0], value.literal)
+	}
+	if typeName := strings.Trim(formattedRow[1], "'"); typeName != value.typeName {
+		return fmt.Errorf("got type %s, want %s", typeName, value.typeName)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   ".session show|clear",
+	Short: "Show or clear the statements replayed after reconnecting",
+	Long: `Show or clear the session statements (ATTACH, DETACH and PRAGMA assignments) that are replayed when the
+connection is reestablished with .open.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"show", "clear"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		switch args[0] {
+		case "show":
+			for _, statement := range config.Db.SessionStatements() {
+				fmt.Fprintln(config.OutF, statement+";")
+			}
+		case "clear":
+			config.Db.ClearSessionStatements()
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are show and clear", args[0])
+		}
+		return nil
+	},
+}
+
+// PrintSessionReplayResults reports which session statements were replayed on a new connection and which failed
+func PrintSessionReplayResults(log *db.Logger, replayResults []db.SessionReplayResult) {
+	for _, replayResult := range replayResults {
+		if replayResult.Err != nil {
+			log.Warnf("failed to replay: %s (%v)", replayResult.Statement, replayResult.Err)
+		} else {
+			log.Infof("Replayed: %s", replayResult.Statement)
+		}
+	}
+}
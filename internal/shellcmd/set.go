@@ -0,0 +1,55 @@
+package shellcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   ".set ?NAME VALUE?",
+	Short: "Set a variable referenced in SQL as {{NAME}}",
+	Long: `Set a variable whose value replaces every {{NAME}} reference in the SQL statements typed afterwards. VALUE is
+the rest of the line as written, quotes included, so .set cond name = 'x' makes {{cond}} the SQL name = 'x'.
+Without arguments, list the variables currently set.`,
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			printVariables(config)
+			return nil
+		}
+
+		name := args[0]
+		if !db.IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name \"%s\". Names must start with a letter or underscore and contain only letters, digits and underscores", name)
+		}
+		if len(args) == 1 {
+			return fmt.Errorf("missing value for variable \"%s\"", name)
+		}
+
+		config.SetVariable(name, strings.Join(args[1:], " "))
+		return nil
+	},
+}
+
+func printVariables(config *DbCmdConfig) {
+	variables := config.GetVariables()
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(config.OutF, "%s = %s\n", name, variables[name])
+	}
+}
@@ -0,0 +1,101 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   ".show",
+	Short: "Show the current settings",
+	Long: `Show the current value of the settings changed by dot commands, like the output mode set by .mode or the number
+format set by .numfmt, each named after the command setting it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		mode := string(config.GetMode())
+		if config.GetCSVOptions().BOM {
+			mode += " --bom"
+		}
+		if config.GetCSVOptions().CRLF {
+			mode += " --crlf"
+		}
+		if config.GetJSONOptions().Pretty {
+			mode += " --pretty"
+		}
+		if options := config.GetTableOptions(); options.Buffered {
+			mode += " --buffered"
+		} else if options.WidthRows > 0 {
+			mode += fmt.Sprintf(" --width-rows %d", options.WidthRows)
+		}
+		slowThreshold, slowLog := "off", "off"
+		if threshold := config.GetSlowThreshold(); threshold > 0 {
+			slowThreshold = threshold.String()
+		}
+		if file := config.GetSlowLogFile(); file != "" {
+			slowLog = file
+		}
+		headerCase := "upper"
+		if config.GetPreserveHeaderCase() {
+			headerCase = "preserve"
+		}
+		settings := [][2]string{
+			{"mode", mode},
+			{"quote", string(config.GetIdentifierQuoteStyle())},
+			{"numfmt", formatNumberLocale(config.GetNumberLocale())},
+			{"nullvalue", formatNullValue(config.GetNullValue())},
+			{"mask", formatSettingList(config.GetMaskPatterns())},
+			{"exclude", formatSettingList(config.GetExcludedTables())},
+			{"header-interval", formatSettingCount(config.GetHeaderInterval())},
+			{"headercase", headerCase},
+			{"page", formatSettingCount(config.GetPageSize())},
+			{"timer", formatSettingSwitch(config.GetTimer())},
+			{"changes", formatSettingSwitch(config.GetChanges())},
+			{"delta", formatSettingSwitch(config.GetDelta())},
+			{"explain-fmt", formatSettingSwitch(config.GetExplainFormat())},
+			{"pragma-pretty", formatSettingSwitch(config.GetPrettyPragmas())},
+			{"slow-threshold", slowThreshold},
+			{"slow-log", slowLog},
+		}
+
+		for _, setting := range settings {
+			fmt.Fprintf(config.OutF, "%16s: %s\n", setting[0], setting[1])
+		}
+		return nil
+	},
+}
+
+func formatNullValue(nullValue *string) string {
+	if nullValue == nil {
+		return "default"
+	}
+	return strconv.Quote(*nullValue)
+}
+
+func formatSettingList(values []string) string {
+	if len(values) == 0 {
+		return "off"
+	}
+	return strings.Join(values, " ")
+}
+
+func formatSettingCount(count int) string {
+	if count == 0 {
+		return "off"
+	}
+	return fmt.Sprint(count)
+}
+
+func formatSettingSwitch(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,24 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var sleepCmd = &cobra.Command{
+	Use:   ".sleep MS",
+	Short: "Pause for MS milliseconds",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		milliseconds, err := strconv.Atoi(args[0])
+		if err != nil || milliseconds < 0 {
+			return fmt.Errorf("invalid time \"%s\". Give the time to sleep as a number of milliseconds", args[0])
+		}
+
+		time.Sleep(time.Duration(milliseconds) * time.Millisecond)
+		return nil
+	},
+}
@@ -0,0 +1,72 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var slowThresholdCmd = &cobra.Command{
+	Use:   ".slow-threshold ?DURATION|off?",
+	Short: "Flag statements taking longer than DURATION",
+	Long: `Flag the statements that take longer than DURATION to run and show their results, with a note after they
+complete, and with a line in the file set by .slow-log. The statements aren't stopped. DURATION is a number of seconds
+or a duration like 500ms or 2s, and off stops flagging statements. Without an argument, the current threshold is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if threshold := config.GetSlowThreshold(); threshold > 0 {
+				fmt.Fprintf(config.OutF, "slow threshold: %s\n", threshold)
+			} else {
+				fmt.Fprintln(config.OutF, "slow threshold: off")
+			}
+			return nil
+		}
+
+		if args[0] == "off" {
+			config.SetSlowThreshold(0)
+			return nil
+		}
+		threshold, ok := parseWatchInterval(args[0])
+		if !ok {
+			return fmt.Errorf("invalid slow threshold \"%s\". Give a number of seconds or a duration like 500ms, or off", args[0])
+		}
+		config.SetSlowThreshold(threshold)
+		return nil
+	},
+}
+
+var slowLogCmd = &cobra.Command{
+	Use:   ".slow-log ?FILE|off?",
+	Short: "Append the statements flagged as slow to FILE",
+	Long: `Append a line to FILE for each statement taking longer than the threshold set by .slow-threshold, with the
+time it completed, its duration, the rows it returned and its text. off stops logging them. Without an argument, the
+current file is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if file := config.GetSlowLogFile(); file != "" {
+				fmt.Fprintf(config.OutF, "slow log: %s\n", file)
+			} else {
+				fmt.Fprintln(config.OutF, "slow log: off")
+			}
+			return nil
+		}
+
+		if args[0] == "off" {
+			config.SetSlowLogFile("")
+			return nil
+		}
+		return config.SetSlowLogFile(args[0])
+	},
+}
@@ -0,0 +1,26 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   ".stats session",
+	Short: "Show the statistics of this session",
+	Long: `Show the statistics of this session without exiting: the statements executed and how many failed, the rows
+returned and changed, the bytes written to the output, how long the database was connected to and the slowest
+statement. They're shown when the shell exits too, if it's interactive or --session-stats is given.`,
+	ValidArgs: []string{"session"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		fmt.Fprintln(config.OutF, config.GetSessionStats())
+		return nil
+	},
+}
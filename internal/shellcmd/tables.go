@@ -1,9 +1,11 @@
 package shellcmd
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 
-	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
+	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -17,14 +19,45 @@ var tableCmd = &cobra.Command{
 			return fmt.Errorf("missing db connection")
 		}
 
+		tableFilter, err := getListingTableFilter(cmd, config)
+		if err != nil {
+			return err
+		}
+
 		tableStatement := `select name from sqlite_schema
 			where type = 'table'
-			and name not like 'sqlite_%'
-			and name != '_litestream_seq'
-			and name != '_litestream_lock'
-			and name != 'libsql_wasm_func_table'
+			and ` + tableFilter.Condition("name") + `
 			order by name`
 
-		return config.Db.ExecuteAndPrintStatements(tableStatement, config.OutF, true, enums.TABLE_MODE)
+		result, err := config.Db.ExecuteStatements(tableStatement)
+		if err != nil {
+			return err
+		}
+		statementResult := <-result.StatementResultCh
+		if statementResult.Err != nil {
+			return statementResult.Err
+		}
+		return printTableNames(config.OutF, statementResult)
 	},
 }
+
+// printTableNames writes the table names a line each as they're read, so that databases with many thousands of tables
+// are listed without holding them all to align them
+func printTableNames(outF io.Writer, statementResult db.StatementResult) error {
+	out := bufio.NewWriter(outF)
+	for rowResult := range statementResult.RowCh {
+		if rowResult.Err != nil {
+			return rowResult.Err
+		}
+		formattedRow, err := db.FormatData(rowResult.Row, db.TABLE)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, formattedRow[0])
+	}
+	return out.Flush()
+}
+
+func init() {
+	tableCmd.Flags().Bool("include-internal", false, "List the tables SQLite, Litestream and libSQL keep for themselves too")
+}
@@ -0,0 +1,41 @@
+package shellcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var timerCmd = &cobra.Command{
+	Use:   ".timer ?on|off?",
+	Short: "Show how long each statement takes",
+	Long: `Show how long each statement takes with a line like "Run Time: real 0.042s" after its result, like the
+sqlite3 CLI. A statement is timed until the last row of its result is read, so the time of remote databases includes
+streaming their rows, and each of the statements of a line is timed on its own. Without an argument, the current
+setting is shown.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		if len(args) == 0 {
+			if config.GetTimer() {
+				fmt.Fprintln(config.OutF, "timer: on")
+			} else {
+				fmt.Fprintln(config.OutF, "timer: off")
+			}
+			return nil
+		}
+
+		switch args[0] {
+		case "on", "off":
+			config.SetTimer(args[0] == "on")
+		default:
+			return fmt.Errorf("invalid argument \"%s\". Valid arguments are on and off", args[0])
+		}
+		return nil
+	},
+}
@@ -0,0 +1,120 @@
+package shellcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libsql/libsql-shell-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+const clearScreenSequence = "\033[H\033[2J"
+
+var watchCmd = &cobra.Command{
+	Use:   ".watch ?INTERVAL? SQL...",
+	Short: "Execute a statement every INTERVAL until interrupted",
+	Long: `Execute SQL again and again, every INTERVAL, until Ctrl-C returns to the prompt. INTERVAL is a number of
+seconds or a duration like 500ms or 1m, and defaults to 2s. Each result is shown with the time it was taken and the
+iteration count, on a cleared screen when the output is a terminal. Errors are shown and the statement is still
+executed at the next iteration.
+
+The quotes around arguments are removed, so quote SQL when it holds quotes of its own, like
+.watch "SELECT * FROM t WHERE name = 'x'". Only queries can be watched, unless --allow-writes is given. Flags must come
+before INTERVAL.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, ok := cmd.Context().Value(dbCtx{}).(*DbCmdConfig)
+		if !ok {
+			return fmt.Errorf("missing db connection")
+		}
+
+		allowWrites, err := cmd.Flags().GetBool("allow-writes")
+		if err != nil {
+			return err
+		}
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			return err
+		}
+
+		interval := defaultWatchInterval
+		if len(args) > 1 {
+			if parsedInterval, ok := parseWatchInterval(args[0]); ok {
+				interval = parsedInterval
+				args = args[1:]
+			}
+		}
+		statements := strings.Join(args, " ")
+
+		if !allowWrites {
+			if err := checkReadOnlyStatements(statements); err != nil {
+				return err
+			}
+		}
+
+		return watch(config, statements, interval, count)
+	},
+}
+
+func init() {
+	watchCmd.Flags().SetInterspersed(false)
+	watchCmd.Flags().Bool("allow-writes", false, "Watch statements that aren't queries")
+	watchCmd.Flags().Int("count", 0, "Stop after this many iterations instead of waiting for Ctrl-C")
+}
+
+func parseWatchInterval(arg string) (time.Duration, bool) {
+	if seconds, err := strconv.ParseFloat(arg, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), seconds > 0
+	}
+	interval, err := time.ParseDuration(arg)
+	return interval, err == nil && interval > 0
+}
+
+func checkReadOnlyStatements(statements string) error {
+	splitStatements, err := db.SplitStatements(statements)
+	if err != nil {
+		return err
+	}
+	for _, statement := range splitStatements {
+		if !db.IsReadOnlyStatement(statement.Text) {
+			return fmt.Errorf("only queries can be watched, and %s isn't one. Use --allow-writes to watch it anyway", statement.Text)
+		}
+	}
+	return nil
+}
+
+func watch(config *DbCmdConfig, statements string, interval time.Duration, count int) error {
+	// A Ctrl-C given before the watch started isn't meant for it
+	select {
+	case <-config.Interrupts:
+	default:
+	}
+
+	for iteration := 1; ; iteration++ {
+		if config.IsOutTerminal() {
+			fmt.Fprint(config.OutF, clearScreenSequence)
+		}
+		fmt.Fprintf(config.OutF, "Every %s: %s\n%s, iteration %d\n", interval, statements, time.Now().Format("2006-01-02 15:04:05"), iteration)
+
+		err := config.Db.ExecuteAndPrintStatements(statements, config.OutF, config.GetPrintOptions())
+		if db.IsOutputClosed(config.OutF) {
+			return nil
+		}
+		if err != nil {
+			db.PrintError(err, config.ErrF)
+		}
+
+		if iteration == count {
+			return nil
+		}
+		select {
+		case <-config.Interrupts:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
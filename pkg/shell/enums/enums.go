@@ -3,9 +3,11 @@ package enums
 type PrintMode string
 
 const (
-	TABLE_MODE PrintMode = "table"
-	CSV_MODE   PrintMode = "csv"
-	JSON_MODE  PrintMode = "json"
+	TABLE_MODE     PrintMode = "table"
+	CSV_MODE       PrintMode = "csv"
+	JSON_MODE      PrintMode = "json"
+	SQL_MODE       PrintMode = "sql"
+	SPARKLINE_MODE PrintMode = "sparkline"
 )
 
 type HistoryMode int
@@ -15,3 +17,26 @@ const (
 	PerDatabaseHistory
 	LocalHistory
 )
+
+type IdentifierQuoteStyle string
+
+const (
+	DOUBLE_QUOTE_STYLE IdentifierQuoteStyle = "double"
+	BACKTICK_STYLE     IdentifierQuoteStyle = "backtick"
+	BRACKET_STYLE      IdentifierQuoteStyle = "bracket"
+)
+
+type IdleAction string
+
+const (
+	IDLE_EXIT       IdleAction = "exit"
+	IDLE_DISCONNECT IdleAction = "disconnect"
+)
+
+type LogLevel string
+
+const (
+	LOG_INFO    LogLevel = "info"
+	LOG_NOTE    LogLevel = "note"
+	LOG_WARNING LogLevel = "warning"
+)
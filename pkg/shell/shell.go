@@ -1,13 +1,18 @@
 package shell
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/libsql/libsql-shell-go/internal/db"
 	"github.com/libsql/libsql-shell-go/internal/shell"
+	"github.com/libsql/libsql-shell-go/internal/shellcmd"
 	"github.com/libsql/libsql-shell-go/pkg/shell/enums"
 )
 
@@ -23,74 +28,404 @@ type ShellConfig struct {
 	WelcomeMessage            *string
 	AfterDbConnectionCallback func()
 	DisableAutoCompletion     bool
+	// ResultCacheSize is the maximum size in bytes of the last result retained for .cell. Zero means
+	// DEFAULT_RESULT_CACHE_SIZE
+	ResultCacheSize int
+	// InitFile and then InitStatements are executed after connecting and before any other input
+	InitFile       string
+	InitStatements []string
+	// Progress receives the progress of long operations like .dump instead of the progress printed to ErrF
+	Progress ProgressFunc
+	// IdleTimeout is how long the interactive prompt waits for input before IdleAction is taken. Zero disables it
+	IdleTimeout time.Duration
+	IdleAction  enums.IdleAction
+	// ContinueOnError turns bail off, so the statements after a failing one still run. The failures are reported as
+	// they happen, as JSON lines with JSONErrors, and summarized at the end with an error returned
+	ContinueOnError bool
+	JSONErrors      bool
+	// ExecutionSummary prints to ErrF a summary of each input holding more than one statement, like
+	// "20 statements: 18 ok, 2 errors, 1,204 rows returned, 350 rows changed, 1.2s total"
+	ExecutionSummary bool
+	// FailOnEmpty fails the queries returning no rows, like an error would, once their header is printed. Statements
+	// without columns, like INSERT, never fail for it
+	FailOnEmpty bool
+	// SessionStats prints to ErrF the statistics of the session on exit, like the statements executed, the rows returned
+	// and the slowest statement. They're always printed when the interactive shell reads from a terminal
+	SessionStats bool
+	// PerDatabaseSettings loads the settings saved for the database when the shell starts, after InitFile and before
+	// InitStatements, and saves them on exit when they were changed at an interactive prompt. Settings are saved next to
+	// the per database history, keyed by the absolute path of the database file or the host and path of its URL
+	PerDatabaseSettings bool
+	// LockCheck warns on ErrF when another shell has the same local database file open, as long sessions holding write
+	// transactions make the other fail with SQLITE_BUSY. The file is marked open by this shell with a lock file next to
+	// it, removed on exit
+	LockCheck bool
+	// FileRoot, when set, confines the files read and written by dot commands like .read, .import, .cell --out,
+	// .dump --split-dir, .slow-log and .open to this directory. Relative paths are taken from it, and the paths outside
+	// of it once their symlinks are resolved are refused. SQL statements like ATTACH aren't confined
+	FileRoot string
+	// OnConnect and OnDisconnect are called after each connection to the database is opened or closed, including the
+	// reconnections after idle disconnects and the connections made by .open. OnConnect is given the live connection,
+	// shared with the shell, to run queries on the same connection, like ones reading the temp tables of the session
+	OnConnect    func(event ConnectionEvent)
+	OnDisconnect func(event ConnectionEvent)
+	// CipherKey is the key of an encrypted local database, issued with PRAGMA key on each connection. Connecting fails
+	// with InvalidCipherKeyError when the database can't be read with it, and with CipherUnsupportedError when
+	// CipherSupported is false
+	CipherKey string
+	// HistoryFile, when set, is the file the history of the interactive shell is saved to instead of the one chosen by
+	// HistoryMode. HistoryLimit is the number of entries it keeps, the most recent ones, DEFAULT_HISTORY_LIMIT when 0
+	HistoryFile  string
+	HistoryLimit int
+	// ColumnTransform, when set, is called with each value of the results printed by the shell, in every mode, before
+	// it's formatted, and replaces it with the value it returns along with true, like to mask a column or show the name
+	// of an enum. The values are the raw ones, like an int64, a string, a []byte or nil. The SQL written by .dump isn't
+	// transformed unless ColumnTransformInDumps is set, so that dumps restore the data as it is stored
+	ColumnTransform        ColumnTransform
+	ColumnTransformInDumps bool
+	// LogFile, when set, is the file the diagnostics of the shell are appended to instead of ErrF, like progress,
+	// reconnection notices, slow statement notes and warnings, each line with its time and level. LogToErrF writes them
+	// to ErrF too. Errors are always written to ErrF only, wherever the diagnostics go. LogLevel is the lowest level of
+	// the diagnostics written, LOG_INFO when empty
+	LogFile   string
+	LogToErrF bool
+	LogLevel  enums.LogLevel
 }
 
-func RunShell(config ShellConfig) error {
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-	db, err := db.NewDb(config.DbUri, config.AuthToken)
+const DEFAULT_RESULT_CACHE_SIZE = db.DEFAULT_RESULT_CACHE_SIZE
+
+const DEFAULT_HISTORY_LIMIT = shell.DEFAULT_HISTORY_LIMIT
+
+type ConnectionEvent = db.ConnectionEvent
+
+type ProgressEvent = db.ProgressEvent
+type ProgressEventKind = db.ProgressEventKind
+type ProgressFunc = db.ProgressFunc
+
+type ColumnTransform = db.ColumnTransform
+
+const (
+	TABLE_STARTED      = db.TABLE_STARTED
+	ROWS_PROCESSED     = db.ROWS_PROCESSED
+	TABLE_FINISHED     = db.TABLE_FINISHED
+	OPERATION_FINISHED = db.OPERATION_FINISHED
+)
+
+// Shell is a shell connected to a database, for programs embedding it. It executes the statements and dot commands of
+// the CLI, like .dump, .tables and .schema, writing their results to OutF and their errors to ErrF
+type Shell struct {
+	config ShellConfig
+	shell  *shell.Shell
+	db     *db.Db
+	// closers are called by Close in reverse order, like the lock of the database file and the log file
+	closers []func()
+	// loadedDatabaseSettings are the settings of the database once loaded for PerDatabaseSettings, saved by Run when
+	// they changed. It's nil when they weren't loaded
+	loadedDatabaseSettings *string
+}
+
+// New connects to the database of the config and runs InitFile and InitStatements. The statements and dot commands are
+// then executed with ExecuteCommandOrStatements, or read from InF by Run. Unlike RunShell, it doesn't handle signals:
+// CancelQuery interrupts the statement running. The Shell must be closed with Close
+func New(config ShellConfig) (*Shell, error) {
+	return newShell(config, true, config.PerDatabaseSettings)
+}
+
+func newShell(config ShellConfig, cancelableInput bool, loadDatabaseSettings bool) (*Shell, error) {
+	log, closeLog, err := openLog(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := db.TestConnection(); err != nil {
-		return err
+	s := &Shell{config: config, closers: []func(){closeLog}}
+	s.db, err = db.NewDb(config.DbUri, config.AuthToken)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.db.SetConnectionHooks(connectionHooks(config))
+	if err := testConnection(s.db, config); err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.closers = append(s.closers, func() { s.db.Close() })
+	if config.LockCheck {
+		lock := lockDbFile(config, log)
+		s.closers = append(s.closers, func() { lock.Release() })
 	}
-	defer db.Close()
 
 	if config.AfterDbConnectionCallback != nil {
 		config.AfterDbConnectionCallback()
 	}
 
 	internalConfig := publicToInternalConfig(config)
-	shellInstance, err := shell.NewShell(internalConfig, db)
+	internalConfig.Log = log
+	internalConfig.CancelableInput = cancelableInput
+	s.shell, err = shell.NewShell(internalConfig, s.db)
 	if err != nil {
-		return err
+		s.Close()
+		return nil, err
 	}
+	s.closers = append(s.closers, s.shell.Close)
 
+	if err := runInit(s.shell, config, loadDatabaseSettings); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if loadDatabaseSettings {
+		settings := s.shell.DatabaseSettings()
+		s.loadedDatabaseSettings = &settings
+	}
+	return s, nil
+}
+
+// Run reads and executes the statements and dot commands of InF until it ends, .quit is entered or the context is
+// done, which cancels the statement running and returns the error of the context. The settings of the database are
+// saved when PerDatabaseSettings is set and they were changed at an interactive prompt. The error returned summarizes
+// the failures of the statements when ContinueOnError is set. It's called at most once per Shell
+func (s *Shell) Run(ctx context.Context) error {
+	stopped := make(chan struct{})
+	defer close(stopped)
 	go func() {
-		for range signals {
-			shellInstance.CancelQuery()
+		select {
+		case <-ctx.Done():
+			s.shell.Stop()
+		case <-stopped:
 		}
 	}()
-	return shellInstance.Run()
+
+	if err := s.shell.Run(); err != nil {
+		return err
+	}
+	// Scripts piped to the shell only change the settings of their run, unless they save them with .save-settings
+	if s.loadedDatabaseSettings != nil && s.shell.IsInteractive() && s.shell.DatabaseSettings() != *s.loadedDatabaseSettings {
+		if err := s.shell.SaveDatabaseSettings(); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.shell.PrintErrorSummary()
 }
 
-func RunShellLine(config ShellConfig, line string) error {
+// ExecuteCommandOrStatements executes a dot command, like ".dump" or ".schema users", or SQL statements, printing their
+// results to OutF. It's safe to call from multiple goroutines, and the calls on the same Shell run one at a time
+func (s *Shell) ExecuteCommandOrStatements(commandOrStatements string) error {
+	return s.shell.ExecuteCommandOrStatements(commandOrStatements)
+}
+
+// CancelQuery interrupts the statement running, like Ctrl-C does in the CLI, and the dot commands running until
+// interrupted, like .watch
+func (s *Shell) CancelQuery() {
+	s.shell.CancelQuery()
+}
+
+// PrintErrorSummary returns an error summarizing the failures of the statements executed so far when ContinueOnError
+// is set, writing them to ErrF
+func (s *Shell) PrintErrorSummary() error {
+	return s.shell.PrintErrorSummary()
+}
+
+// PrintSessionStats writes to ErrF the statistics of the statements executed so far
+func (s *Shell) PrintSessionStats() {
+	s.shell.PrintSessionStats()
+}
+
+// Close closes the files opened by the shell and the connection to the database
+func (s *Shell) Close() error {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+	return nil
+}
+
+func RunShell(config ShellConfig) error {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-	db, err := db.NewDb(config.DbUri, config.AuthToken)
+	ignoreBrokenPipeSignal()
+	shellInstance, err := newShell(config, false, config.PerDatabaseSettings)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
+	defer shellInstance.Close()
 
-	if config.AfterDbConnectionCallback != nil {
-		config.AfterDbConnectionCallback()
-	}
+	go func() {
+		for range signals {
+			shellInstance.CancelQuery()
+		}
+	}()
 
-	internalConfig := publicToInternalConfig(config)
-	shellInstance, err := shell.NewShell(internalConfig, db)
+	return shellInstance.Run(context.Background())
+}
+
+// ShellInput is a piece of the input of RunShellInputs: statements and dot commands, or a script file executed like
+// .read
+type ShellInput struct {
+	Statements string
+	File       string
+	// Name identifies the input in the error it fails with, when there are several. The file name when empty
+	Name string
+}
+
+func RunShellLine(config ShellConfig, line string) error {
+	return RunShellInputs(config, []ShellInput{{Statements: line}})
+}
+
+// RunShellInputs executes the inputs one after the other, in their order. When there are several, the error an input
+// fails with is prefixed with its name
+func RunShellInputs(config ShellConfig, inputs []ShellInput) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ignoreBrokenPipeSignal()
+	shellInstance, err := newShell(config, false, false)
 	if err != nil {
 		return err
 	}
+	defer shellInstance.Close()
 
 	go func() {
 		<-signals
 		shellInstance.CancelQuery()
 	}()
 
-	return shellInstance.ExecuteCommandOrStatements(line)
+	if config.SessionStats {
+		defer shellInstance.PrintSessionStats()
+	}
+	for _, input := range inputs {
+		commandOrStatements := input.Statements
+		if input.File != "" {
+			commandOrStatements = ".read " + shellcmd.QuoteArgument(input.File)
+		}
+		if err := shellInstance.ExecuteCommandOrStatements(commandOrStatements); err != nil {
+			if len(inputs) == 1 {
+				return err
+			}
+			name := input.Name
+			if name == "" {
+				name = input.File
+			}
+			return fmt.Errorf("%s failed: %w", name, err)
+		}
+	}
+	return shellInstance.PrintErrorSummary()
+}
+
+// testConnection connects to the database, with the cipher key when there's one
+func testConnection(shellDb *db.Db, config ShellConfig) error {
+	if config.CipherKey != "" {
+		_, err := shellDb.SetCipherKey(config.CipherKey)
+		return err
+	}
+	return shellDb.TestConnection()
+}
+
+// CipherSupported reports whether encrypted local databases can be opened with CipherKey, which needs the shell to be
+// built with a SQLite driver linking SQLCipher or a compatible library
+func CipherSupported() bool {
+	return db.CipherSupported()
+}
+
+// runInit executes the init file and then each init statement, stopping at the first one that fails even with bail
+// off. The settings saved for the database are loaded in between when loadDatabaseSettings is set, so they override
+// the init file and are overridden by the init statements
+func runInit(shellInstance *shell.Shell, config ShellConfig, loadDatabaseSettings bool) error {
+	if config.InitFile != "" {
+		content, err := os.ReadFile(config.InitFile)
+		if err != nil {
+			return fmt.Errorf("failed to read init file: %w", err)
+		}
+		if statements := strings.TrimSpace(string(content)); statements != "" {
+			if err := shellInstance.ExecuteCommandOrStatementsWithBail(statements); err != nil {
+				return fmt.Errorf("init file %s failed: %w", config.InitFile, err)
+			}
+		}
+	}
+
+	if loadDatabaseSettings {
+		if err := shellInstance.LoadDatabaseSettings(); err != nil {
+			return err
+		}
+	}
+
+	for i, statement := range config.InitStatements {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		if err := shellInstance.ExecuteCommandOrStatementsWithBail(statement); err != nil {
+			return fmt.Errorf("init statement %d (%s) failed: %w", i+1, statement, err)
+		}
+	}
+	return nil
+}
+
+func connectionHooks(config ShellConfig) db.ConnectionHooks {
+	return db.ConnectionHooks{OnConnect: config.OnConnect, OnDisconnect: config.OnDisconnect}
+}
+
+// lockDbFile marks the local database file open by this shell, warning when another shell already has it open. The
+// lock is advisory, so failing to create it doesn't stop the shell
+func lockDbFile(config ShellConfig, log *db.Logger) *db.FileLock {
+	lock, otherPid, err := db.AcquireFileLock(config.DbUri)
+	if err != nil {
+		return nil
+	}
+	if otherPid != 0 {
+		log.Warnf("another shell (process %d) has %s open, so long write transactions in either one make the other fail with SQLITE_BUSY. Use --no-lock-check to skip this check", otherPid, config.DbUri)
+	}
+	return lock
+}
+
+// openLog returns the Logger of the diagnostics of the shell, writing to LogFile when set, and the function closing it
+func openLog(config ShellConfig) (*db.Logger, func(), error) {
+	if config.LogLevel != "" && !db.IsLogLevel(config.LogLevel) {
+		return nil, nil, fmt.Errorf("invalid log level %q. Valid levels are info, note and warning", config.LogLevel)
+	}
+	if config.LogFile == "" {
+		return db.NewLogger(config.ErrF, nil, config.LogLevel), func() {}, nil
+	}
+
+	logFile, err := os.OpenFile(config.LogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the log file: %w", err)
+	}
+	var errF io.Writer
+	if config.LogToErrF {
+		errF = config.ErrF
+	}
+	return db.NewLogger(errF, logFile, config.LogLevel), func() { logFile.Close() }, nil
+}
+
+// ignoreBrokenPipeSignal makes writes to a closed stdout fail with EPIPE, which the shell handles by stopping quietly,
+// instead of killing the process with SIGPIPE
+func ignoreBrokenPipeSignal() {
+	signal.Ignore(syscall.SIGPIPE)
 }
 
 func publicToInternalConfig(publicConfig ShellConfig) shell.ShellConfig {
 	return shell.ShellConfig{
-		InF:                   publicConfig.InF,
-		OutF:                  publicConfig.OutF,
-		ErrF:                  publicConfig.ErrF,
-		HistoryMode:           publicConfig.HistoryMode,
-		HistoryName:           publicConfig.HistoryName,
-		QuietMode:             publicConfig.QuietMode,
-		WelcomeMessage:        publicConfig.WelcomeMessage,
-		DisableAutoCompletion: publicConfig.DisableAutoCompletion,
+		InF:                    publicConfig.InF,
+		OutF:                   publicConfig.OutF,
+		ErrF:                   publicConfig.ErrF,
+		HistoryMode:            publicConfig.HistoryMode,
+		HistoryName:            publicConfig.HistoryName,
+		QuietMode:              publicConfig.QuietMode,
+		WelcomeMessage:         publicConfig.WelcomeMessage,
+		DisableAutoCompletion:  publicConfig.DisableAutoCompletion,
+		ResultCacheSize:        publicConfig.ResultCacheSize,
+		Progress:               publicConfig.Progress,
+		IdleTimeout:            publicConfig.IdleTimeout,
+		IdleAction:             publicConfig.IdleAction,
+		ContinueOnError:        publicConfig.ContinueOnError,
+		JSONErrors:             publicConfig.JSONErrors,
+		ExecutionSummary:       publicConfig.ExecutionSummary,
+		FailOnEmpty:            publicConfig.FailOnEmpty,
+		FileRoot:               publicConfig.FileRoot,
+		SessionStats:           publicConfig.SessionStats,
+		HistoryFile:            publicConfig.HistoryFile,
+		HistoryLimit:           publicConfig.HistoryLimit,
+		ColumnTransform:        publicConfig.ColumnTransform,
+		ColumnTransformInDumps: publicConfig.ColumnTransformInDumps,
 	}
 }
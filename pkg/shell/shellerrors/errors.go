@@ -1,5 +1,7 @@
 package shellerrors
 
+import "fmt"
+
 type InternalError interface {
 	error
 	internalError() string
@@ -37,11 +39,145 @@ func (e *UrlDoesNotContainHostError) userError() string {
 	return "url does not contain host"
 }
 
-type ProtocolError struct{}
+type ProtocolError struct {
+	Scheme string
+}
 
 func (e *ProtocolError) Error() string {
 	return e.userError()
 }
 func (e *ProtocolError) userError() string {
-	return "invalid sqld protocol. valid protocols are libsql://, wss://, ws://, https:// and http://"
+	if e.Scheme == "" {
+		return "invalid sqld protocol. valid protocols are libsql://, wss://, ws://, https:// and http://"
+	}
+	return fmt.Sprintf("invalid sqld protocol %s://. valid protocols are libsql://, wss://, ws://, https:// and http://", e.Scheme)
+}
+
+type MissingProtocolError struct {
+	Host string
+}
+
+func (e *MissingProtocolError) Error() string {
+	return e.userError()
+}
+func (e *MissingProtocolError) userError() string {
+	return fmt.Sprintf("%s looks like the host of a database, but it has no protocol. Use libsql://%s to connect to it, or one of wss://, ws://, https:// and http://", e.Host, e.Host)
+}
+
+type UndefinedVariableError struct {
+	Name   string
+	Line   int
+	Column int
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return e.userError()
+}
+func (e *UndefinedVariableError) userError() string {
+	return fmt.Sprintf("undefined variable \"%s\" at line %d, column %d. Use \".set %s VALUE\" to define it", e.Name, e.Line, e.Column, e.Name)
+}
+
+type OutputClosedError struct{}
+
+func (e *OutputClosedError) Error() string {
+	return e.internalError()
+}
+func (e *OutputClosedError) internalError() string {
+	return "output closed by its reader"
+}
+
+type StatementsFailedError struct {
+	Count int64
+}
+
+func (e *StatementsFailedError) Error() string {
+	return e.userError()
+}
+func (e *StatementsFailedError) userError() string {
+	return fmt.Sprintf("%d statement(s) failed", e.Count)
+}
+
+type AuthenticationError struct {
+	StatusCode int
+}
+
+func (e *AuthenticationError) Error() string {
+	return e.userError()
+}
+func (e *AuthenticationError) userError() string {
+	return fmt.Sprintf("authentication failed (%d): check the token given with --auth", e.StatusCode)
+}
+
+type NetworkError struct {
+	Host string
+	Err  error
+}
+
+func (e *NetworkError) Error() string {
+	return e.userError()
+}
+func (e *NetworkError) userError() string {
+	return fmt.Sprintf("unable to reach %s: %v. Retry later, or check the database URL", e.Host, e.Err)
+}
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+type DatabaseNotFoundError struct {
+	Database string
+	// StatusCode is the HTTP status returned by the server, or zero for local databases
+	StatusCode int
+}
+
+func (e *DatabaseNotFoundError) Error() string {
+	return e.userError()
+}
+func (e *DatabaseNotFoundError) userError() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("database not found (%d): check the database URL %s", e.StatusCode, e.Database)
+	}
+	return fmt.Sprintf("database not found: unable to open %s", e.Database)
+}
+
+type FileOutsideRootError struct {
+	Path string
+}
+
+func (e *FileOutsideRootError) Error() string {
+	return e.userError()
+}
+func (e *FileOutsideRootError) userError() string {
+	return fmt.Sprintf("access to %s is refused, as it's outside the directory file access is confined to", e.Path)
+}
+
+type CipherUnsupportedError struct{}
+
+func (e *CipherUnsupportedError) Error() string {
+	return e.userError()
+}
+func (e *CipherUnsupportedError) userError() string {
+	return "encrypted databases aren't supported: this build's SQLite driver has no cipher support, like SQLCipher's"
+}
+
+type InvalidCipherKeyError struct {
+	Err error
+}
+
+func (e *InvalidCipherKeyError) Error() string {
+	return e.userError()
+}
+func (e *InvalidCipherKeyError) userError() string {
+	return "invalid key or not an encrypted database"
+}
+func (e *InvalidCipherKeyError) Unwrap() error {
+	return e.Err
+}
+
+type EmptyResultError struct{}
+
+func (e *EmptyResultError) Error() string {
+	return e.userError()
+}
+func (e *EmptyResultError) userError() string {
+	return "the query returned no rows, which fails with --fail-on-empty"
 }
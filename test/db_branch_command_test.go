@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/internal/cmd"
+	"github.com/libsql/libsql-shell-go/test/utils"
+)
+
+// newBranchServer serves the databases of organization acme on the platform API, and answers the statements it
+// receives with a single value naming the database, so that the one a shell is connected to shows in its results
+func newBranchServer(t *testing.T, databases func() string, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/organizations/acme/databases":
+			if r.Header.Get("Authorization") != "Bearer api-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, databases())
+		case r.Method == http.MethodPost && r.URL.Path == "/":
+			fmt.Fprintf(w, `[{"results":{"columns":["db"],"rows":[["%s"]]}}]`, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDotBranch_GivenPlatformCredentials_WhenListAndSwitch_ExpectBranchesListedAndConnectionToTheBranch(t *testing.T) {
+	c := qt.New(t)
+
+	var databases string
+	getDatabases := func() string { return databases }
+	mainServer := newBranchServer(t, getDatabases, "main")
+	devServer := newBranchServer(t, getDatabases, "dev")
+	mainHost := strings.TrimPrefix(mainServer.URL, "http://")
+	devHost := strings.TrimPrefix(devServer.URL, "http://")
+	databases = fmt.Sprintf(`{"databases":[
+		{"Name":"main","Hostname":"%s"},
+		{"Name":"other","Hostname":"other.example.com"},
+		{"Name":"dev","Hostname":"%s","parent":{"name":"main"}},
+		{"Name":"dev-fix","Hostname":"dev-fix.example.com","parent":{"name":"dev"}}
+	]}`, mainHost, devHost)
+	t.Setenv("TURSO_API_URL", mainServer.URL)
+	t.Setenv("TURSO_API_TOKEN", "api-token")
+	t.Setenv("TURSO_ORG", "acme")
+
+	outS, errS, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv\nSELECT 1;\n.branch list\n.branch switch dev\nSELECT 1;\n", "--quiet", "--auth", "token", mainServer.URL)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+	c.Assert(strings.HasPrefix(outS, "db\nmain\n"), qt.IsTrue)
+	c.Assert(outS, qt.Contains, utils.GetPrintTableOutput([]string{"name", "branched from", "url"}, [][]string{
+		{"main (current)", "", "libsql://" + mainHost},
+		{"dev", "main", "libsql://" + devHost},
+		{"dev-fix", "dev", "libsql://dev-fix.example.com"},
+	}))
+	c.Assert(strings.HasSuffix(outS, "\ndb\ndev"), qt.IsTrue)
+
+	_, errS, _ = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".branch switch other\n", "--quiet", "--auth", "token", mainServer.URL)
+	c.Assert(errS, qt.Equals, "Error: no branch other of database main. List them with .branch list")
+}
+
+func TestDotBranch_GivenMissingPlatformCredentials_ExpectTheMissingOnesExplained(t *testing.T) {
+	c := qt.New(t)
+
+	server := newBranchServer(t, func() string { return `{"databases":[]}` }, "main")
+	t.Setenv("TURSO_API_URL", server.URL)
+	t.Setenv("TURSO_API_TOKEN", "")
+	t.Setenv("TURSO_ORG", "acme")
+
+	_, errS, _ := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".branch list\n", "--quiet", server.URL)
+	c.Assert(errS, qt.Equals, `Error: branches are found with the Turso platform API, which needs credentials. Set TURSO_API_TOKEN to an API token, created with "turso auth api-tokens mint NAME"`)
+
+	t.Setenv("TURSO_API_TOKEN", "wrong-token")
+	_, errS, _ = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".branch list\n", "--quiet", server.URL)
+	c.Assert(errS, qt.Equals, "Error: the Turso platform API refused TURSO_API_TOKEN (HTTP 401). Check that it's an API token of organization acme")
+
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+	_, errS, _ = tc.ExecuteShell([]string{".branch list"})
+	c.Assert(errS, qt.Equals, "Error: branches belong to Turso databases, and the current database is a local file")
+}
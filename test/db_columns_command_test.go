@@ -0,0 +1,54 @@
+package main_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/test/utils"
+)
+
+func TestDotColumns_GivenTableAndView_WhenListColumns_ExpectMatchingNamesQuotedAsNeeded(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, user_name TEXT, "order" INTEGER, Org_Id INTEGER, total AS (id * 2));`,
+		`CREATE VIEW "active users" AS SELECT id, user_name AS "display name" FROM users;`,
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".columns users", ".columns USERS *_ID --join", ".columns main.users --select", `.columns "active users" --select`, ".quote backtick", ".columns users ord* --join"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `id
+user_name
+"order"
+Org_Id
+total
+Org_Id
+SELECT id, user_name, "order", Org_Id, total FROM main.users;
+SELECT id, "display name" FROM "active users";
+`+"`order`")
+
+	_, errS, _ = tc.ExecuteShell([]string{".columns users x*", ".columns missing", ".columns nope.users", ".columns users [", ".columns users --join --select"})
+	tc.Assert(errS, qt.Equals, "Error: no column of users matches x*\nError: no such table or view: missing\nError: unknown database nope\n"+
+		"Error: invalid pattern \"[\": syntax error in pattern\nError: --join and --select can't be used together")
+}
+
+func TestDotColumns_GivenTableOfAttachedDatabase_WhenListColumns_ExpectSchemaQualifiedName(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	attachedDbPath := filepath.Join(t.TempDir(), "archive.sqlite")
+	_, errS, err := tc.ExecuteShell([]string{"ATTACH '" + attachedDbPath + "' AS archive;", `CREATE TABLE archive."users.2023" (id INTEGER, deleted_at TEXT);`, "CREATE TABLE users (id INTEGER);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.columns 'ARCHIVE."users.2023"' --select`, `.columns 'archive."users.2023"' deleted*`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "SELECT id, deleted_at FROM archive.\"users.2023\";\ndeleted_at")
+}
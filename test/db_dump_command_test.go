@@ -0,0 +1,947 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/test/utils"
+)
+
+func TestDotDump_GivenTextWithNulAndInvalidUtf8_WhenDump_ExpectHexCastAndConversionCountReported(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (value TEXT);", "INSERT INTO t VALUES (CAST(X'610062' AS TEXT)), (CAST(X'FF' AS TEXT)), ('plain');"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Note: 2 TEXT value(s) containing NUL bytes or invalid UTF-8 were written as CAST(X'...' AS TEXT)")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (value TEXT);
+INSERT INTO t VALUES (CAST(X'610062' AS TEXT));
+INSERT INTO t VALUES (CAST(X'FF' AS TEXT));
+INSERT INTO t VALUES ('plain');
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT hex(value), typeof(value) FROM t;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "hex(value),typeof(value)\n610062,text\nFF,text\n706C61696E,text")
+}
+
+func TestDotDump_GivenProgressFlag_WhenDump_ExpectProgressOfEachTableReported(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE a (id INTEGER);", "INSERT INTO a VALUES (1), (2);", "CREATE TABLE b (id INTEGER);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	_, errS, err = tc.ExecuteShell([]string{".dump --progress"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Matches, "Dumping table a\nDumped table a: 2 rows\nDumping table b\nDumped table b: 0 rows\nDumped 2 tables, 2 rows in .+")
+}
+
+func TestDotDump_GivenExcludePatterns_WhenDump_ExpectMatchingTablesLeftOut(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE schema_migrations (version TEXT);", "CREATE TABLE schemaXmigrations (id INTEGER);", "CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT);", "INSERT INTO t VALUES (1);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.dump --exclude schema\_migrations --exclude t`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE schemaXmigrations (id INTEGER);\nCOMMIT;")
+
+	outS, errS, err = tc.ExecuteShell([]string{".exclude schema%", `.dump --include-internal --exclude schema\_migrations --exclude schemaXmigrations`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT);
+INSERT INTO t VALUES (1);
+DELETE FROM sqlite_sequence;
+INSERT INTO sqlite_sequence VALUES ('t', 1);
+COMMIT;`)
+}
+
+func TestDotDump_GivenAutoincrementTableWithDeletedRows_WhenDumpRestored_ExpectRowidsNotReused(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, v TEXT);", "INSERT INTO t (v) VALUES ('a'), ('b'), ('c');", "DELETE FROM t WHERE id > 1;", "CREATE TABLE u (id INTEGER PRIMARY KEY AUTOINCREMENT);", "INSERT INTO u VALUES (7);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump --exclude u"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, v TEXT);
+INSERT INTO t VALUES (1, 'a');
+DELETE FROM sqlite_sequence;
+INSERT INTO sqlite_sequence VALUES ('t', 3);
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "INSERT INTO t (v) VALUES ('d');", "SELECT id, v FROM t;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "id,v\n1,a\n4,d")
+}
+
+// The golden dumps were recorded by running testdata/sqlite3_dump_fixture.sql with the sqlite3 CLI 3.50.2, then its
+// .dump, .dump --data-only and .dump users
+func TestDotDump_GivenCompatSQLite3_WhenDump_ExpectOutputOfTheSQLite3CLI(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	fixture, err := os.ReadFile("testdata/sqlite3_dump_fixture.sql")
+	tc.Assert(err, qt.IsNil)
+	_, errS, err := tc.ExecuteShell([]string{string(fixture)})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	for golden, command := range map[string]string{
+		"sqlite3_dump_fixture.golden":           ".dump --compat sqlite3",
+		"sqlite3_dump_fixture_data_only.golden": ".dump --compat sqlite3 --data-only",
+		"sqlite3_dump_fixture_users.golden":     ".dump --compat sqlite3 users",
+	} {
+		expected, err := os.ReadFile(filepath.Join("testdata", golden))
+		tc.Assert(err, qt.IsNil)
+
+		outS, errS, err := tc.ExecuteShell([]string{command})
+		tc.Assert(err, qt.IsNil)
+		tc.Assert(errS, qt.Equals, "")
+		tc.Assert(outS, qt.Equals, strings.TrimSpace(string(expected)), qt.Commentf(command))
+	}
+}
+
+func TestDotDump_GivenCompatSQLite3_WhenDumpValuesWithoutShortLiterals_ExpectShortestRealsAndUnistrTexts(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (v);", `INSERT INTO t VALUES (0.1), (1e300), (1e-7), (-0.0), (1e300 * 1e300), (-1e300 * 1e300), (1e19), ('a\b' || char(10)), ('a\b'), (char(27) || 'it''s');`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump --compat sqlite3 --data-only"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	// The sqlite3 CLI writes 0.1000000000000000055 for 0.1, whose digits differ between its versions
+	tc.Assert(outS, qt.Equals, `INSERT INTO t VALUES(0.1);
+INSERT INTO t VALUES(1.0e+300);
+INSERT INTO t VALUES(1.0e-07);
+INSERT INTO t VALUES(0.0);
+INSERT INTO t VALUES(9.0e+999);
+INSERT INTO t VALUES(-9.0e+999);
+INSERT INTO t VALUES(10000000000000000000.0);
+INSERT INTO t VALUES(unistr('a\\b\u000a'));
+INSERT INTO t VALUES('a\b');
+INSERT INTO t VALUES(unistr('\u001bit''s'));`)
+}
+
+func TestDotDump_GivenViewAndTriggerOverTablesCreatedInTheOtherOrder_WhenDumpRestored_ExpectThemCreatedAfterBothTables(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER);",
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT);",
+		"CREATE VIEW order_customers AS SELECT o.id, c.name FROM orders AS o JOIN customers AS c ON c.id = o.customer_id;",
+		"CREATE TRIGGER orders_customer AFTER INSERT ON orders BEGIN INSERT OR IGNORE INTO customers (id, name) VALUES (new.customer_id, 'unknown'); END;",
+		"INSERT INTO customers VALUES (1, 'ann');",
+		"INSERT INTO orders VALUES (10, 1), (11, 2);",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER);
+INSERT INTO orders VALUES (10, 1);
+INSERT INTO orders VALUES (11, 2);
+CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO customers VALUES (1, 'ann');
+INSERT INTO customers VALUES (2, 'unknown');
+CREATE VIEW order_customers AS SELECT o.id, c.name FROM orders AS o JOIN customers AS c ON c.id = o.customer_id;
+CREATE TRIGGER orders_customer AFTER INSERT ON orders BEGIN INSERT OR IGNORE INTO customers (id, name) VALUES (new.customer_id, 'unknown'); END;
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "INSERT INTO orders VALUES (12, 3);", "SELECT * FROM order_customers;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "id,name\n10,ann\n11,unknown\n12,unknown")
+
+	// The schema file of a split dump creates all the tables first, so the view follows them there
+	dir := t.TempDir()
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + dir + " --force"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	schema, err := os.ReadFile(filepath.Join(dir, "000_schema.sql"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(string(schema), qt.Equals, `PRAGMA foreign_keys=OFF;
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER);
+CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT);
+CREATE VIEW order_customers AS SELECT o.id, c.name FROM orders AS o JOIN customers AS c ON c.id = o.customer_id;
+`)
+}
+
+func TestDotDump_GivenTableNamesNeedingQuotes_WhenDumpRestored_ExpectNamesDoubleQuoted(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{`CREATE TABLE "my table" (v);`, `CREATE TABLE "it's" (v);`, `CREATE TABLE "say ""hi""" (v);`, `CREATE TABLE "order" (v);`, `INSERT INTO "my table" VALUES (1);`, `INSERT INTO "it's" VALUES (2);`, `INSERT INTO "say ""hi""" VALUES (3);`, `INSERT INTO "order" VALUES (4);`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE "my table" (v);
+INSERT INTO "my table" VALUES (1);
+CREATE TABLE "it's" (v);
+INSERT INTO "it's" VALUES (2);
+CREATE TABLE "say ""hi""" (v);
+INSERT INTO "say ""hi""" VALUES (3);
+CREATE TABLE "order" (v);
+INSERT INTO "order" VALUES (4);
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", `SELECT (SELECT v FROM "my table") + (SELECT v FROM "it's") + (SELECT v FROM "say ""hi""") + (SELECT v FROM "order") AS total;`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "total\n10")
+}
+
+func TestDotDump_GivenFTS4Table_WhenDumpRestored_ExpectShadowTablesLeftOutAndMatchesFound(t *testing.T) {
+	testFullTextTableDumpRestored(t, "fts4")
+}
+
+// FTS5 is only built into go-sqlite3 with the sqlite_fts5 tag
+func TestDotDump_GivenFTS5Table_WhenDumpRestored_ExpectShadowTablesLeftOutAndMatchesFound(t *testing.T) {
+	testFullTextTableDumpRestored(t, "fts5")
+}
+
+func testFullTextTableDumpRestored(t *testing.T, module string) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE VIRTUAL TABLE docs USING " + module + "(title, body);", "INSERT INTO docs (rowid, title, body) VALUES (3, 'a', 'hello world'), (8, 'b', 'it''s me');"})
+	if strings.Contains(errS, "no such module: "+module) {
+		t.Skipf("%s isn't built into this SQLite", module)
+	}
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE VIRTUAL TABLE docs USING `+module+`(title, body);
+INSERT INTO docs (rowid, title, body) VALUES (3, 'a', 'hello world');
+INSERT INTO docs (rowid, title, body) VALUES (8, 'b', 'it''s me');
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT rowid, title FROM docs WHERE docs MATCH 'hello';"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "rowid,title\n3,a")
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump --compat postgres"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `BEGIN;
+-- Skipped virtual table "docs", as only SQLite has virtual tables
+COMMIT;`)
+}
+
+func TestDotDump_GivenContentlessFTS4AndRtreeTables_WhenDumpRestored_ExpectContentlessOneRestoredFromItsShadowTables(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE VIRTUAL TABLE c USING fts4(body, content='');", "INSERT INTO c (docid, body) VALUES (7, 'hello there');", "CREATE VIRTUAL TABLE r USING rtree(id, x0, x1);", "INSERT INTO r VALUES (1, 0.5, 2.5);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+-- Restoring this virtual table requires SQLITE_DBCONFIG_DEFENSIVE to be off
+PRAGMA writable_schema=ON;
+INSERT INTO sqlite_schema (type, name, tbl_name, rootpage, sql) VALUES ('table', 'c', 'c', 0, 'CREATE VIRTUAL TABLE c USING fts4(body, content='''')');
+PRAGMA writable_schema=RESET;
+CREATE TABLE 'c_segments'(blockid INTEGER PRIMARY KEY, block BLOB);
+CREATE TABLE 'c_segdir'(level INTEGER,idx INTEGER,start_block INTEGER,leaves_end_block INTEGER,end_block INTEGER,root BLOB,PRIMARY KEY(level, idx));
+INSERT INTO c_segdir VALUES (0, 0, 0, 0, '0 22', X'000568656C6C6F030702000005746865726503070300');
+CREATE TABLE 'c_docsize'(docid INTEGER PRIMARY KEY, size BLOB);
+INSERT INTO c_docsize VALUES (7, X'02');
+CREATE TABLE 'c_stat'(id INTEGER PRIMARY KEY, value BLOB);
+INSERT INTO c_stat VALUES (0, X'01020B');
+CREATE VIRTUAL TABLE r USING rtree(id, x0, x1);
+INSERT INTO r VALUES (1, 0.5, 2.5);
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT docid FROM c WHERE c MATCH 'hello';", "SELECT * FROM r WHERE x0 < 1;", "PRAGMA integrity_check;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "docid\n7\nid,x0,x1\n1,0.5,2.5\nintegrity_check\nok")
+}
+
+func TestDotDump_GivenTableNamesAndPatterns_WhenDump_ExpectOnlyThoseTablesWithTheirIndexesAndTriggers(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE users (id INTEGER);", "CREATE INDEX idx_users_id ON users (id);", "INSERT INTO users VALUES (1);",
+		"CREATE TABLE audit_login (id INTEGER);", "CREATE TABLE audit_logout (id INTEGER);", "CREATE TABLE auditXreport (id INTEGER);",
+		"CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN INSERT INTO audit_login VALUES (new.id); END;",
+		"CREATE VIEW user_ids AS SELECT id FROM users;",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.dump USERS audit\_%`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE users (id INTEGER);
+INSERT INTO users VALUES (1);
+CREATE INDEX idx_users_id ON users (id);
+CREATE TABLE audit_login (id INTEGER);
+CREATE TABLE audit_logout (id INTEGER);
+CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN INSERT INTO audit_login VALUES (new.id); END;
+COMMIT;`)
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump users missing"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: no such table: missing")
+	tc.Assert(outS, qt.Equals, "")
+}
+
+func TestDotDump_GivenSchemaOnlyOrDataOnly_WhenDump_ExpectOnlyTheSchemaOrTheRecords(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);", "CREATE INDEX idx_users_name ON users (name);",
+		"INSERT INTO users (name) VALUES ('a');", "CREATE VIEW user_names AS SELECT name FROM users;",
+		"CREATE VIEW first_user_names AS SELECT name FROM user_names LIMIT 1;",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);
+INSERT INTO users VALUES (1, 'a');
+CREATE INDEX idx_users_name ON users (name);
+DELETE FROM sqlite_sequence;
+INSERT INTO sqlite_sequence VALUES ('users', 1);
+CREATE VIEW user_names AS SELECT name FROM users;
+CREATE VIEW first_user_names AS SELECT name FROM user_names LIMIT 1;
+COMMIT;`)
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump --schema-only"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE VIEW user_names AS SELECT name FROM users;
+CREATE VIEW first_user_names AS SELECT name FROM user_names LIMIT 1;
+COMMIT;`)
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump --data-only"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+INSERT INTO users VALUES (1, 'a');
+DELETE FROM sqlite_sequence;
+INSERT INTO sqlite_sequence VALUES ('users', 1);
+COMMIT;`)
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump --schema-only --data-only", ".dump --data-only --split-dir " + t.TempDir()})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: --schema-only and --data-only can't be used together. Give neither to dump both\n"+
+		"Error: --schema-only and --data-only can't be used with --split-dir, whose schema file already holds the schema alone")
+	tc.Assert(outS, qt.Equals, "")
+}
+
+func TestDotDump_GivenSplitDir_WhenDump_ExpectFilePerTableInDependencyOrderAndManifest(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE child (id INTEGER, parent_id INTEGER REFERENCES PARENT (id));",
+		"CREATE TABLE parent (id INTEGER PRIMARY KEY);",
+		`CREATE TABLE "weird/name: *" (value TEXT);`,
+		"CREATE INDEX idx_child ON child (parent_id);",
+		"INSERT INTO parent VALUES (1), (2);",
+		"INSERT INTO child VALUES (10, 1);",
+		`INSERT INTO "weird/name: *" VALUES ('a');`,
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	dir := t.TempDir() + "/dump"
+	outS, errS, err := tc.ExecuteShell([]string{".dump --split-dir " + dir})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "")
+
+	entries, err := os.ReadDir(dir)
+	tc.Assert(err, qt.IsNil)
+	fileNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fileNames = append(fileNames, entry.Name())
+	}
+	tc.Assert(fileNames, qt.DeepEquals, []string{"000_schema.sql", "001_parent.sql", "002_child.sql", "003_weird_name___.sql", "manifest.json"})
+
+	readFile := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		tc.Assert(err, qt.IsNil)
+		return string(content)
+	}
+	tc.Assert(readFile("000_schema.sql"), qt.Equals, `PRAGMA foreign_keys=OFF;
+CREATE TABLE parent (id INTEGER PRIMARY KEY);
+CREATE TABLE child (id INTEGER, parent_id INTEGER REFERENCES PARENT (id));
+CREATE TABLE "weird/name: *" (value TEXT);
+`)
+	tc.Assert(readFile("002_child.sql"), qt.Equals, `PRAGMA foreign_keys=OFF;
+INSERT INTO child VALUES (10, 1);
+CREATE INDEX idx_child ON child (parent_id);
+`)
+
+	var manifest struct {
+		Schema string
+		Tables []struct {
+			Table string
+			File  string
+			Rows  int64
+		}
+	}
+	tc.Assert(json.Unmarshal([]byte(readFile("manifest.json")), &manifest), qt.IsNil)
+	tc.Assert(manifest.Schema, qt.Equals, "000_schema.sql")
+	tc.Assert(manifest.Tables, qt.HasLen, 3)
+	tc.Assert(manifest.Tables[2].Table, qt.Equals, "weird/name: *")
+	tc.Assert(manifest.Tables[2].File, qt.Equals, "003_weird_name___.sql")
+	tc.Assert(manifest.Tables[0].Rows, qt.Equals, int64(2))
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	for _, fileName := range []string{"000_schema.sql", "003_weird_name___.sql", "002_child.sql", "001_parent.sql"} {
+		_, errS, err = restoredTc.ExecuteShell([]string{".read " + filepath.Join(dir, fileName)})
+		tc.Assert(err, qt.IsNil)
+		tc.Assert(errS, qt.Equals, "")
+	}
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", `SELECT (SELECT count(*) FROM parent), (SELECT count(*) FROM child), (SELECT value FROM "weird/name: *");`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(strings.Split(outS, "\n")[1], qt.Equals, "2,1,a")
+}
+
+func TestDotDump_GivenNonEmptySplitDir_WhenDump_ExpectRejectedUnlessForced(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	dir := t.TempDir()
+	tc.Assert(os.WriteFile(filepath.Join(dir, "other.txt"), []byte("keep"), 0644), qt.IsNil)
+
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + dir})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: dump directory "+dir+" is not empty. Use --force to write into it anyway")
+
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + dir + " --force"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	entries, err := os.ReadDir(dir)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(entries, qt.HasLen, 4)
+}
+
+func TestDotDump_GivenTableWithUniqueColumn_WhenDump_ExpectAutoindexLeftOutAndDumpRestored(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY, email TEXT UNIQUE);", "CREATE INDEX idx_t_id ON t (id);", "INSERT INTO t VALUES (1, 'a@example.com');"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (id INTEGER PRIMARY KEY, email TEXT UNIQUE);
+INSERT INTO t VALUES (1, 'a@example.com');
+CREATE INDEX idx_t_id ON t (id);
+COMMIT;`)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell(strings.Split(outS, "\n"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT name FROM sqlite_master WHERE type = 'index' ORDER BY name;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "name\nidx_t_id\nsqlite_autoindex_t_1")
+}
+
+func TestDotDump_GivenWhereClauses_WhenDump_ExpectOnlyMatchingRecordsOfThoseTables(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE orders (id INTEGER, created_at TEXT);", "INSERT INTO orders VALUES (1, '2023-12-31'), (2, '2024-02-01');", "CREATE TABLE events (id INTEGER REFERENCES orders(id));", "INSERT INTO events VALUES (1);", "CREATE TABLE t (id INTEGER);", "INSERT INTO t VALUES (1);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.dump --where "orders:created_at > '2024-01-01'" --where "EVENTS:1=0"`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Warning: records left out with --where may still be referenced by foreign keys, which aren't checked by the dump")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE orders (id INTEGER, created_at TEXT);
+INSERT INTO orders VALUES (2, '2024-02-01');
+CREATE TABLE events (id INTEGER REFERENCES orders(id));
+CREATE TABLE t (id INTEGER);
+INSERT INTO t VALUES (1);
+COMMIT;`)
+}
+
+func TestDotDump_GivenChunkedSelect_WhenDump_ExpectSameDumpAsUnchunkedAndTablesWithoutKeyWarnedAbout(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT);",
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 11) INSERT INTO t SELECT i * 3, 'v' || i FROM n;",
+		"CREATE TABLE w (k TEXT PRIMARY KEY, v) WITHOUT ROWID;",
+		"INSERT INTO w VALUES ('b', 1), ('a''x', 2), ('c', 3), ('d', 4);",
+		"CREATE TABLE p (a, b, PRIMARY KEY (a, b)) WITHOUT ROWID;",
+		"INSERT INTO p VALUES (1, 2), (0, 5);",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	unchunkedS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	for _, chunkSize := range []string{"1", "2", "4", "11", "100"} {
+		chunkedS, errS, err := tc.ExecuteShell([]string{".dump --chunked-select " + chunkSize})
+		tc.Assert(err, qt.IsNil)
+		tc.Assert(errS, qt.Equals, "Warning: table p has neither a rowid nor a single-column primary key to select its records in chunks by, so they're selected at once")
+		tc.Assert(chunkedS, qt.Equals, unchunkedS, qt.Commentf("--chunked-select %s", chunkSize))
+	}
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump --chunked-select 2 --data-only --where 't:id > 25' t"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+INSERT INTO t VALUES (27, 'v9');
+INSERT INTO t VALUES (30, 'v10');
+INSERT INTO t VALUES (33, 'v11');
+COMMIT;`)
+}
+
+func TestDotDump_GivenMultiRowInsert_WhenDump_ExpectRecordsCoalescedIntoStatementsOfAtMostNRowsAndBytes(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT);",
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) INSERT INTO t SELECT i, 'v' || i FROM n;",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump --multi-row-insert=2"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT);
+INSERT INTO t VALUES (1, 'v1'),(2, 'v2');
+INSERT INTO t VALUES (3, 'v3'),(4, 'v4');
+INSERT INTO t VALUES (5, 'v5');
+COMMIT;`)
+
+	outS, errS, err = tc.ExecuteShell([]string{".dump --compat mysql --data-only --multi-row-insert"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "SET FOREIGN_KEY_CHECKS=0;\nSTART TRANSACTION;\nINSERT INTO `t` (`id`, `v`) VALUES (1, 'v1'),(2, 'v2'),(3, 'v3'),(4, 'v4'),(5, 'v5');\nCOMMIT;")
+
+	// Rows of 400 KB are coalesced by two, as a third one would make the statement larger than 1 MB
+	_, errS, err = tc.ExecuteShell([]string{"DELETE FROM t;", "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) INSERT INTO t SELECT i, hex(zeroblob(200000)) FROM n;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	outS, errS, err = tc.ExecuteShell([]string{".dump --multi-row-insert"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(strings.Count(outS, "INSERT INTO t VALUES"), qt.Equals, 3)
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	file, filePath := restoredTc.CreateTempFile(outS)
+	defer file.Close()
+	_, errS, err = restoredTc.ExecuteShell([]string{".read " + filePath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	outS, _, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT count(*), sum(length(v)) FROM t;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(outS, qt.Equals, "count(*),sum(length(v))\n5,2000000")
+
+	_, errS, _ = tc.ExecuteShell([]string{".dump --compat sqlite3 --multi-row-insert"})
+	tc.Assert(errS, qt.Equals, "Error: --multi-row-insert can't be used with --compat sqlite3, whose dump has an INSERT statement per record")
+}
+
+func TestDotDump_GivenWideTableAndSQLLengthLimit_WhenMultiRowInsert_ExpectFewerRecordsPerStatementUnderTheLimit(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	columns := make([]string, 0, 150)
+	values := make([]string, 0, 150)
+	for i := 1; i <= 150; i++ {
+		columns = append(columns, fmt.Sprintf("c%d INTEGER", i))
+		values = append(values, fmt.Sprintf("i * %d", i))
+	}
+	_, errS, err := tc.ExecuteShell([]string{
+		fmt.Sprintf("CREATE TABLE wide (%s);", strings.Join(columns, ", ")),
+		fmt.Sprintf("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 20) INSERT INTO wide SELECT %s FROM n;", strings.Join(values, ", ")),
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	// Without a lower limit, the 20 records fit in 2 statements of 10
+	outS, errS, err := tc.ExecuteShell([]string{".dump --data-only --multi-row-insert=10"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(strings.Count(outS, "INSERT INTO wide VALUES"), qt.Equals, 2)
+
+	outS, errS, err = tc.ExecuteShell([]string{".limit sql_length 4000", ".dump --data-only --multi-row-insert=10"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Note: the INSERT statements of table wide hold fewer than 10 records, to stay under the sql_length limit of 4000 bytes")
+	inserts := 0
+	for _, line := range strings.Split(outS, "\n") {
+		if strings.HasPrefix(line, "INSERT INTO wide VALUES") {
+			inserts++
+			tc.Assert(len(line) <= 4000, qt.IsTrue, qt.Commentf("statement of %d bytes", len(line)))
+		}
+	}
+	tc.Assert(inserts > 2, qt.IsTrue, qt.Commentf("%d statements", inserts))
+
+	restoredTc := utils.NewTestContext(t, t.TempDir()+"/restored.sqlite", "")
+	defer restoredTc.Close()
+	_, errS, err = restoredTc.ExecuteShell([]string{fmt.Sprintf("CREATE TABLE wide (%s);", strings.Join(columns, ", "))})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	file, filePath := restoredTc.CreateTempFile(outS)
+	defer file.Close()
+	_, errS, err = restoredTc.ExecuteShell([]string{".read " + filePath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	outS, _, err = restoredTc.ExecuteShell([]string{".mode csv", "SELECT count(*), sum(c150) FROM wide;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(outS, qt.Equals, "count(*),sum(c150)\n20,31500")
+}
+
+func TestDotDump_GivenInvalidWhereClauses_WhenDump_ExpectErrorNamingTheTableAndNoOutput(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);", "INSERT INTO t VALUES (1);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.dump --where "t:id >"`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Matches, "Error: invalid --where condition for table t: .*")
+	tc.Assert(outS, qt.Equals, "")
+
+	outS, errS, err = tc.ExecuteShell([]string{`.dump --where "t:1; DROP TABLE t"`, `.dump --where "missing:1=1"`, `.dump --where "id > 1"`, ".tables"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, `Error: invalid --where condition for table t: it must be a single SQL expression
+Error: no such table: missing, given with --where
+Error: invalid --where "id > 1". Give it as TABLE:CONDITION, like "orders:created_at > '2024-01-01'"`)
+	tc.Assert(outS, qt.Equals, "t")
+}
+
+func TestDotDump_GivenIndexAndTriggerNamingTheTableInAnotherCase_WhenDump_ExpectEachStatementOnce(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE Users (name TEXT);", "CREATE TABLE users_log (name TEXT);", "CREATE INDEX users_name ON USERS (name);", "CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN INSERT INTO users_log VALUES (new.name); END;", "INSERT INTO Users VALUES ('a');"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	// SQLite table names are case insensitive, so a table can't be named like another one in another case
+	_, errS, err = tc.ExecuteShell([]string{"CREATE TABLE users (name TEXT);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: table users already exists")
+
+	outS, errS, err := tc.ExecuteShell([]string{".dump"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE Users (name TEXT);
+INSERT INTO Users VALUES ('a');
+CREATE INDEX users_name ON USERS (name);
+CREATE TABLE users_log (name TEXT);
+INSERT INTO users_log VALUES ('a');
+CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN INSERT INTO users_log VALUES (new.name); END;
+COMMIT;`)
+}
+
+func TestDotDump_GivenFile_WhenDumpSucceedsOrFails_ExpectFileReplacedOnlyOnSuccess(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);", "INSERT INTO t VALUES (1);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "backup.sql")
+	outS, errS, err := tc.ExecuteShell([]string{".dump --file " + file})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "")
+	content, err := os.ReadFile(file)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(string(content), qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE t (id INTEGER);\nINSERT INTO t VALUES (1);\nCOMMIT;\n")
+
+	// The condition only fails once the records of the second table are read, in the middle of the dump
+	_, errS, err = tc.ExecuteShell([]string{"CREATE TABLE u (a INTEGER);", "INSERT INTO u VALUES (-9223372036854775808);", ".dump --file " + file + " --where 'u:abs(a) > 0'"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: integer overflow")
+	content, err = os.ReadFile(file)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(string(content), qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE t (id INTEGER);\nINSERT INTO t VALUES (1);\nCOMMIT;\n")
+	entries, err := os.ReadDir(dir)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(entries, qt.HasLen, 1)
+
+	// Written to the screen, the output of a failed dump is left without its COMMIT, so restoring it changes nothing
+	outS, errS, err = tc.ExecuteShell([]string{".dump --where 'u:abs(a) > 0'"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: integer overflow")
+	tc.Assert(outS, qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE t (id INTEGER);\nINSERT INTO t VALUES (1);\nCREATE TABLE u (a INTEGER);")
+
+	_, errS, _ = tc.ExecuteShell([]string{".dump --file " + file + " --split-dir " + dir})
+	tc.Assert(errS, qt.Equals, "Error: --file and --split-dir can't be used together")
+}
+
+func TestDotDump_GivenPreAndPostSQL_WhenDump_ExpectHooksExecutedAroundTheDumpWithoutTheirOutput(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{"CREATE TABLE audit (event TEXT);"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := tc.ExecuteShell([]string{`.dump --pre-sql "PRAGMA wal_checkpoint(TRUNCATE); INSERT INTO audit VALUES ('started')" --post-sql "INSERT INTO audit VALUES ('done')" --post-sql "SELECT count(*) FROM audit"`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE audit (event TEXT);\nINSERT INTO audit VALUES ('started');\nCOMMIT;")
+
+	outS, errS, err = tc.ExecuteShell([]string{`.dump --pre-sql "SELECT * FROM missing"`, `.dump --post-sql "INSERT INTO missing VALUES (1)"`})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: --pre-sql failed, nothing was dumped: no such table: missing\nError: the dump was written, but --post-sql failed: no such table: missing")
+	tc.Assert(outS, qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE audit (event TEXT);\nINSERT INTO audit VALUES ('started');\nINSERT INTO audit VALUES ('done');\nCOMMIT;")
+}
+
+func TestDotDump_GivenResumeManifest_WhenDumpInterruptedAndResumed_ExpectSameFilesAsSplitDump(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE a (id INTEGER PRIMARY KEY, v TEXT);",
+		"CREATE TABLE b (rowid TEXT, v INTEGER);",
+		"CREATE INDEX idx_b ON b (v);",
+		"CREATE TABLE w (k TEXT PRIMARY KEY) WITHOUT ROWID;",
+		"INSERT INTO a VALUES (1, 'x'), (2, 'y');",
+		"INSERT INTO b VALUES ('r1', 1), ('r2', 2), ('r3', 3), ('r4', 4);",
+		"INSERT INTO w VALUES ('k1'), ('k2');",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	splitDir := t.TempDir() + "/split"
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + splitDir})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	resumedDir := t.TempDir() + "/resumed"
+	manifestPath := t.TempDir() + "/resume.json"
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + resumedDir + " --resume-manifest " + manifestPath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	readFile := func(dir string, name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		tc.Assert(err, qt.IsNil)
+		return string(content)
+	}
+	fileNames := []string{"000_schema.sql", "001_a.sql", "002_b.sql", "003_w.sql", "manifest.json"}
+	for _, name := range fileNames {
+		tc.Assert(readFile(resumedDir, name), qt.Equals, readFile(splitDir, name))
+	}
+
+	// Interrupt the dump after the second record of b was checkpointed, with more records written after it, and while
+	// w was in progress. The file of a, done, is marked to check it isn't written again
+	var manifest struct {
+		Fingerprint string                   `json:"fingerprint"`
+		Tables      []map[string]interface{} `json:"tables"`
+	}
+	tc.Assert(json.Unmarshal([]byte(readFile(manifestPath, "")), &manifest), qt.IsNil)
+	tc.Assert(manifest.Tables, qt.HasLen, 3)
+	bFile := readFile(resumedDir, "002_b.sql")
+	checkpointSize := strings.Index(bFile, "INSERT INTO b VALUES ('r3'")
+	manifest.Tables[1] = map[string]interface{}{"table": "b", "file": "002_b.sql", "started": true, "rows": 2, "last_rowid": 2, "size": checkpointSize}
+	manifest.Tables[2] = map[string]interface{}{"table": "w", "file": "003_w.sql", "started": true, "rows": 1, "size": 30}
+	content, err := json.Marshal(manifest)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(os.WriteFile(manifestPath, content, 0644), qt.IsNil)
+	tc.Assert(os.WriteFile(filepath.Join(resumedDir, "002_b.sql"), []byte(bFile[:checkpointSize]+"INSERT INTO b VALUES ('r3', 3);\nINSERT INTO b VA"), 0644), qt.IsNil)
+	tc.Assert(os.WriteFile(filepath.Join(resumedDir, "001_a.sql"), []byte(readFile(splitDir, "001_a.sql")+"-- done\n"), 0644), qt.IsNil)
+	tc.Assert(os.Remove(filepath.Join(resumedDir, "manifest.json")), qt.IsNil)
+
+	_, errS, err = tc.ExecuteShell([]string{".dump --split-dir " + resumedDir + " --resume-manifest " + manifestPath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Warning: table w has no rowid to continue its dump from, so it's dumped again from scratch")
+	tc.Assert(readFile(resumedDir, "001_a.sql"), qt.Equals, readFile(splitDir, "001_a.sql")+"-- done\n")
+	for _, name := range []string{"000_schema.sql", "002_b.sql", "003_w.sql", "manifest.json"} {
+		tc.Assert(readFile(resumedDir, name), qt.Equals, readFile(splitDir, name))
+	}
+
+	_, errS, err = tc.ExecuteShell([]string{"CREATE TABLE c (id INTEGER);", ".dump --split-dir " + resumedDir + " --resume-manifest " + manifestPath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: the schema or the options of the dump changed since "+manifestPath+" was written, so the dump can't be resumed. Remove it to dump from scratch")
+
+	_, errS, _ = tc.ExecuteShell([]string{".dump --resume-manifest " + manifestPath})
+	tc.Assert(errS, qt.Equals, "Error: --resume-manifest only applies with --split-dir")
+}
+
+func TestDotDump_GivenVerify_WhenDump_ExpectRestoredTablesComparedAndTemporaryDatabaseRemoved(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	_, errS, err := tc.ExecuteShell([]string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, score REAL, data BLOB);",
+		"INSERT INTO users VALUES (1, 'a', 1.5, X'00FF'), (2, NULL, 2, NULL), (3, 'NULL', 1e300, '');",
+		"CREATE TABLE posts (a, b);",
+		"INSERT INTO posts VALUES (1, 'x'), (1, 'x');",
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+
+	dir := t.TempDir()
+	splitDir := filepath.Join(dir, "split")
+	manifestPath := filepath.Join(dir, "progress.json")
+	outS, errS, err := tc.ExecuteShell([]string{
+		".dump --verify posts",
+		".dump --verify --file " + filepath.Join(dir, "backup.sql"),
+		".dump --verify --compat sqlite3 --where 'users:id > 1'",
+		".dump --verify --split-dir " + splitDir + " --resume-manifest " + manifestPath,
+		".dump --verify --verify-max-rows 4 --file " + filepath.Join(dir, "large.sql"),
+	})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, `Verified the dump: the 2 rows of its 1 table(s) restore the same as in the database
+Verified the dump: the 5 rows of its 2 table(s) restore the same as in the database
+Verified the dump: the 4 rows of its 2 table(s) restore the same as in the database
+Verified the dump: the 5 rows of its 2 table(s) restore the same as in the database
+Warning: the dump isn't verified, as its 5 rows are more than the 4 of --verify-max-rows`)
+	tc.Assert(outS, qt.Equals, "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE posts (a, b);\nINSERT INTO posts VALUES (1, 'x');\nINSERT INTO posts VALUES (1, 'x');\nCOMMIT;\n"+
+		"PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, score REAL, data BLOB);\nINSERT INTO users VALUES(2,NULL,2.0,NULL);\nINSERT INTO users VALUES(3,'NULL',1.0e+300,'');\nCREATE TABLE posts (a, b);\nINSERT INTO posts VALUES(1,'x');\nINSERT INTO posts VALUES(1,'x');\nCOMMIT;")
+
+	// The files of the tables done aren't written again when the dump is resumed, so the ones changed since are restored
+	usersFile, err := os.ReadFile(filepath.Join(splitDir, "001_users.sql"))
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(os.WriteFile(filepath.Join(splitDir, "001_users.sql"), []byte(strings.Replace(string(usersFile), "'a'", "'b'", 1)), 0644), qt.IsNil)
+	tc.Assert(os.WriteFile(filepath.Join(splitDir, "002_posts.sql"), []byte("INSERT INTO posts VALUES (1, 'x');\n"), 0644), qt.IsNil)
+	_, errS, err = tc.ExecuteShell([]string{".dump --verify --split-dir " + splitDir + " --resume-manifest " + manifestPath})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Error: the restored dump differs from the database in 2 of 2 tables:\n  users: the values of its 3 rows differ\n  posts: 2 rows in the database, 1 in the restored dump")
+
+	entries, err := os.ReadDir(tempDir)
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(entries, qt.HasLen, 0)
+
+	_, errS, _ = tc.ExecuteShell([]string{".dump --verify --compat postgres", ".dump --verify --schema-only", ".dump --verify --verify-max-rows -1"})
+	tc.Assert(errS, qt.Equals, "Error: --verify only applies to the dumps for sqlite and sqlite3, which it restores into SQLite\n"+
+		"Error: --verify can't be used with --schema-only or --data-only, whose dumps don't restore the tables with their records\n"+
+		"Error: invalid --verify-max-rows -1. It must be a positive number of rows, or 0 for no limit")
+}
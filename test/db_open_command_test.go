@@ -0,0 +1,67 @@
+package main_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/test/utils"
+)
+
+func TestDotOpen_GivenAttachedDatabaseAndForeignKeysOn_WhenReconnect_ExpectSessionStateReplayed(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+	attachedDbPath := t.TempDir() + "/attached.sqlite"
+
+	_, errS, err := tc.ExecuteShell([]string{"ATTACH '" + attachedDbPath + "' AS ref;", "PRAGMA foreign_keys=ON;", ".open"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "Replayed: ATTACH '"+attachedDbPath+"' AS ref\nReplayed: PRAGMA foreign_keys=ON")
+
+	outS, errS, err := tc.ExecuteShell([]string{".mode csv", "SELECT name FROM pragma_database_list WHERE name = 'ref';", "PRAGMA foreign_keys;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, "name\nref\nforeign_keys\n1")
+}
+
+func TestDotOpen_GivenStatementThatFailsToReplay_WhenReconnect_ExpectFailureReported(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+	attachedDbDir := t.TempDir() + "/attached"
+	tc.Assert(os.Mkdir(attachedDbDir, 0755), qt.IsNil)
+	attachedDbPath := attachedDbDir + "/attached.sqlite"
+
+	_, errS, err := tc.ExecuteShell([]string{"ATTACH '" + attachedDbPath + "' AS ref;", "PRAGMA foreign_keys=ON;"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(os.RemoveAll(attachedDbDir), qt.IsNil)
+
+	_, errS, err = tc.ExecuteShell([]string{".open"})
+	tc.Assert(err, qt.IsNil)
+	errLines := strings.Split(errS, "\n")
+	tc.Assert(errLines, qt.HasLen, 2)
+	tc.Assert(errLines[0], qt.Matches, "Warning: failed to replay: ATTACH '"+attachedDbPath+"' AS ref \\(unable to open database.*\\)")
+	tc.Assert(errLines[1], qt.Equals, "Replayed: PRAGMA foreign_keys=ON")
+}
+
+func TestDotOpen_GivenAnotherDatabase_WhenOpen_ExpectStatementsToRunOnIt(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+	otherDbPath := t.TempDir() + "/other.sqlite"
+
+	outS, errS, err := tc.ExecuteShell([]string{"CREATE TABLE main_table (id INTEGER);", ".open " + otherDbPath, "CREATE TABLE other_table (id INTEGER);", ".tables"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Equals, "")
+	tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"other_table"}}))
+}
+
+func TestDotOpen_GivenInvalidDatabase_WhenOpen_ExpectCurrentConnectionKept(t *testing.T) {
+	tc := utils.NewTestContext(t, t.TempDir()+"/main.sqlite", "")
+	defer tc.Close()
+
+	outS, errS, err := tc.ExecuteShell([]string{"CREATE TABLE main_table (id INTEGER);", ".open ftp://invalid", ".tables"})
+	tc.Assert(err, qt.IsNil)
+	tc.Assert(errS, qt.Contains, "Error: invalid sqld protocol")
+	tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"main_table"}}))
+}
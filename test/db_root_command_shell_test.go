@@ -1,11 +1,20 @@
 package main_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/libsql/libsql-shell-go/pkg/shell"
 	"github.com/libsql/libsql-shell-go/test/utils"
 )
 
@@ -37,7 +46,7 @@ func (s *DBRootCommandShellSuite) Test_GivenADBWithTwoTables_WhenCallDotTablesCo
 	outTables, errS, err := s.tc.ExecuteShell([]string{".tables"})
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
-	s.tc.Assert(outTables, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"another_simple_table\nsimple_table"}}))
+	s.tc.Assert(outTables, qt.Equals, "another_simple_table\nsimple_table")
 }
 
 func (s *DBRootCommandShellSuite) Test_GivenADBWithTwoTables_WhenCallDotSchemaCommand_ExpectAListContainingTheSchemas() {
@@ -87,17 +96,180 @@ func (s *DBRootCommandShellSuite) Test_WhenCallDotHelpCommand_ExpectAListWithAll
 	s.tc.Assert(errS, qt.Equals, "")
 
 	expectedHelp :=
-		`.dump       Render database content as SQL
-  .help       List of all available commands.
-  .indexes    List indexes in a table or database
-  .mode       Set output mode
-  .quit       Exit this program
-  .read       Execute commands from a file
-  .schema     Show table schemas.
-  .tables     List all existing tables in the database.`
+		`.advise          Suggest indexes for the full table scans of a query
+  .assert          Fail unless a statement returns the expected result
+  .branch          List the branches of this database or switch to one
+  .cell            Show the full value of a cell from the last result
+  .changes         Show how many rows each statement changes
+  .columns         List the column names of a table or view
+  .copy-table      Create a copy of a table, with its rows
+  .delta           Show how each statement changes the rows of its table
+  .describe        Describe the columns of a table
+  .dump            Render database content as SQL
+  .eqp             Save or check the query plans of named queries
+  .exclude         Hide tables from .tables and .schema
+  .explain-fmt     Show EXPLAIN bytecode aligned and indented
+  .fkcheck         Find the rows violating foreign key constraints
+  .foreign_keys    Enable, disable or show foreign key enforcement
+  .header-interval Show the header of tables again every N rows
+  .headercase      Show the column names of tables as they are or upper cased
+  .help            List of all available commands.
+  .history         List the last N entries of the history
+  .import          Import CSV data into a table
+  .indexes         List indexes in a table or database
+  .key             Enter the key of an encrypted database without echo
+  .limit           Show or set the limits generated statements are kept under
+  .mask            Hide the values of columns matching the patterns
+  .mode            Set output mode
+  .next            Show the next page of the last paged result
+  .nullvalue       Show NULL values as STRING
+  .numfmt          Group the thousands of the numbers shown in tables
+  .once            Send the results of the next statement or command to FILE
+  .open            Reconnect to the database or connect to another one
+  .output          Send the results to FILE, or back to the standard output
+  .page            Show SELECT results in pages of SIZE rows
+  .pragma-pretty   Show the results of well known PRAGMAs for reading
+  .prev            Show the previous page of the last paged result
+  .print           Print the arguments separated by spaces
+  .quit            Exit this program
+  .quote           Set quote style for identifiers in generated SQL
+  .read            Execute commands from a file
+  .rename-table    Rename a table
+  .sample          Show about N rows of a table, chosen the same way every time
+  .save-settings   Save the output settings of this database
+  .schema          Show table schemas.
+  .selftest        Check that values round trip through this database
+  .session         Show or clear the statements replayed after reconnecting
+  .set             Set a variable referenced in SQL as {{NAME}}
+  .show            Show the current settings
+  .sleep           Pause for MS milliseconds
+  .slow-log        Append the statements flagged as slow to FILE
+  .slow-threshold  Flag statements taking longer than DURATION
+  .stats           Show the statistics of this session
+  .tables          List all existing tables in the database.
+  .timer           Show how long each statement takes
+  .watch           Execute a statement every INTERVAL until interrupted`
 	s.tc.Assert(outS, qt.Equals, expectedHelp)
 }
 
+func (s *DBRootCommandShellSuite) Test_GivenATable_WhenCallDotSample_ExpectAboutNRowsTheSameEveryTimeForASeed() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT);", "WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM c WHERE i < 2000) INSERT INTO t SELECT i, 'v' || i FROM c;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	sample := func(command string) []string {
+		outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", command})
+		s.tc.Assert(err, qt.IsNil)
+		s.tc.Assert(errS, qt.Equals, "")
+		lines := strings.Split(outS, "\n")
+		s.tc.Assert(lines[0], qt.Equals, "id,v")
+		return lines[1:]
+	}
+	rows := sample(".sample t 200")
+	s.tc.Assert(len(rows) > 150 && len(rows) < 250, qt.IsTrue, qt.Commentf("%d rows", len(rows)))
+	s.tc.Assert(sample(".sample t 200"), qt.DeepEquals, rows)
+	s.tc.Assert(sample(".sample --seed 42 t 200"), qt.Not(qt.DeepEquals), rows)
+	s.tc.Assert(sample(".sample t 5000"), qt.HasLen, 2000)
+	for _, row := range sample(".sample --where 'id <= 100' t 1000") {
+		id, err := strconv.Atoi(strings.Split(row, ",")[0])
+		s.tc.Assert(err, qt.IsNil)
+		s.tc.Assert(id <= 100, qt.IsTrue)
+	}
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".sample --explain-sample --seed 42 t 200"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Contains, "WITH sample_keys(k) AS (SELECT rowid FROM t),")
+	s.tc.Assert(outS, qt.Contains, " + 42) & 4294967295 FROM sample_hashes_1),")
+	s.tc.Assert(strings.HasSuffix(outS, "SELECT * FROM t WHERE rowid IN (SELECT k FROM sample_hashes WHERE h < 429496730) ORDER BY rowid;"), qt.IsTrue)
+
+	outFile := filepath.Join(s.T().TempDir(), "sample.json")
+	_, errS, err = s.tc.ExecuteShell([]string{fmt.Sprintf(".sample --out %s --format json t 10", outFile)})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Matches, `Wrote \d+ sampled rows to .*sample.json`)
+	content, err := os.ReadFile(outFile)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(string(content), qt.Matches, `(?s)\[\{"id":\d+,"v":"v\d+"\}.*\]\n`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATableWithoutRowid_WhenCallDotSample_ExpectRowsNumberedInPrimaryKeyOrder() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE w (a TEXT, b INTEGER, PRIMARY KEY (b, a)) WITHOUT ROWID;", "WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM c WHERE i < 500) INSERT INTO w SELECT 'x' || i, i % 7 FROM c;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".sample w 50", ".sample --explain-sample w 50"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasPrefix(outS, "a,b\n"), qt.IsTrue)
+	s.tc.Assert(outS, qt.Contains, "WITH sample_rows AS (SELECT row_number() OVER (ORDER BY b, a) AS sample_key, * FROM w),")
+	s.tc.Assert(outS, qt.Contains, "SELECT a, b FROM sample_rows WHERE sample_key IN (SELECT k FROM sample_hashes WHERE h < 429496730) ORDER BY b, a;")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATable_WhenCallDotRenameTable_ExpectItRenamedUnlessTheNameIsTaken() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY);", "CREATE INDEX idx ON t (id);", "INSERT INTO t VALUES (1);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	_, errS, err = s.tc.ExecuteShell([]string{".rename-table t idx"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: there is already an index named idx")
+
+	_, errS, err = s.tc.ExecuteShell([]string{".rename-table nope x"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: no such table: nope")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".rename-table T 'my table'", ".tables", ".mode csv", `SELECT id FROM "my table";`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "my table\nid\n1")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATable_WhenCallDotCopyTable_ExpectItsSchemaRenamedAndItsRowsCopiedInBatches() {
+	_, errS, err := s.tc.ExecuteShell([]string{
+		"CREATE TABLE users (id INTEGER CONSTRAINT pk_users PRIMARY KEY, name TEXT, parent INTEGER REFERENCES users(id), upper_name TEXT GENERATED ALWAYS AS (upper(name)));",
+		"CREATE INDEX idx_users_name ON users (name);",
+		"CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN UPDATE users SET parent = new.id WHERE id = new.id AND parent IS NULL; END;",
+		"WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM c WHERE i < 2500) INSERT INTO users (id, name) SELECT i, 'user' || i FROM c;",
+	})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".copy-table users 'users copy' --with-indexes"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "1000 of 2500 rows\n  2000 of 2500 rows\n  2500 of 2500 rows")
+	s.tc.Assert(outS, qt.Equals, "Copied 2500 rows from users to users copy")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".mode csv", `SELECT type, name, sql FROM sqlite_master WHERE tbl_name = 'users copy' ORDER BY rowid;`, `SELECT count(*), sum(parent = id), max(upper_name) FROM "users copy";`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `type,name,sql
+table,users copy,"CREATE TABLE ""users copy"" (id INTEGER CONSTRAINT ""pk_users copy"" PRIMARY KEY, name TEXT, parent INTEGER REFERENCES ""users copy""(id), upper_name TEXT GENERATED ALWAYS AS (upper(name)))"
+index,idx_users_name_users copy,"CREATE INDEX ""idx_users_name_users copy"" ON ""users copy"" (name)"
+trigger,users_insert_users copy,"CREATE TRIGGER ""users_insert_users copy"" AFTER INSERT ON ""users copy"" BEGIN UPDATE ""users copy"" SET parent = new.id WHERE id = new.id AND parent IS NULL; END"
+count(*),sum(parent = id),max(upper_name)
+2500,2500,USER999`)
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".mode csv", ".copy-table users even --where 'id % 2 = 0'", ".copy-table users empty --no-data", `SELECT count(*), min(id) FROM even;`, `SELECT count(*) FROM empty;`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "1000 of 1250 rows\n  1250 of 1250 rows")
+	s.tc.Assert(outS, qt.Equals, "Copied 1250 rows from users to even\ncount(*),min(id)\n1250,2\ncount(*)\n0")
+
+	_, errS, err = s.tc.ExecuteShell([]string{".copy-table users even"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: there is already a table named even")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATableWithoutRowid_WhenCallDotCopyTable_ExpectRowsCopiedInPrimaryKeyOrder() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE w (a TEXT, b INTEGER, PRIMARY KEY (b, a)) WITHOUT ROWID;", "WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM c WHERE i < 1500) INSERT INTO w SELECT 'x' || i, i % 7 FROM c;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".copy-table w w2", "SELECT count(*) AS missing FROM (SELECT * FROM w EXCEPT SELECT * FROM w2);", "SELECT count(*) AS copied FROM w2;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "1000 of 1500 rows\n  1500 of 1500 rows")
+	s.tc.Assert(outS, qt.Equals, "Copied 1500 rows from w to w2\nmissing\n0\ncopied\n1500")
+}
+
 func (s *DBRootCommandShellSuite) Test_GivenAEmptyDb_WhenCallDotReadCommand_ExpectToSeeATableWithOneEntry() {
 	content := `CREATE TABLE IF NOT EXISTS testread (name TEXT);
 		/* Comment in the middle of the file.*/
@@ -114,6 +286,92 @@ func (s *DBRootCommandShellSuite) Test_GivenAEmptyDb_WhenCallDotReadCommand_Expe
 	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"NAME"}, [][]string{{"test"}}))
 }
 
+func (s *DBRootCommandShellSuite) Test_GivenAChecksum_WhenCallDotReadCommand_ExpectTheFileExecutedOnlyIfItsHashMatches() {
+	dir := s.T().TempDir()
+	content := "CREATE TABLE IF NOT EXISTS migrated (v TEXT);\nINSERT INTO migrated VALUES ('done');\n"
+	s.tc.Assert(os.MkdirAll(filepath.Join(dir, "migrations"), 0o755), qt.IsNil)
+	filePath := filepath.Join(dir, "migrations", "001.sql")
+	s.tc.Assert(os.WriteFile(filePath, []byte(content), 0o644), qt.IsNil)
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	wrongHash := strings.Repeat("0", 64)
+	manifest := filepath.Join(dir, "manifest.txt")
+	s.tc.Assert(os.WriteFile(manifest, []byte("# migrations\n"+wrongHash+"  migrations/000.sql\n"+strings.ToUpper(hash)+" *migrations/001.sql\n"), 0o644), qt.IsNil)
+
+	_, errS, err := s.tc.ExecuteShell([]string{".read --verify-sha256 " + wrongHash + " " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, fmt.Sprintf("Error: the SHA-256 hash of %s doesn't match, so nothing was executed\n  expected: %s\n  actual:   %s", filePath, wrongHash, hash))
+
+	_, errS, err = s.tc.ExecuteShell([]string{".read --verify-manifest " + manifest + " " + manifest})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, fmt.Sprintf("Error: %s isn't listed in the manifest %s, so nothing was executed", manifest, manifest))
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".read --verify-manifest " + manifest + " " + filePath, ".read --verify-sha256 " + hash + " " + filePath, "SELECT count(*) AS runs FROM migrated;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"RUNS"}, [][]string{{"2"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSemicolonsInsideTextAndComments_WhenEnteredOrRead_ExpectStatementsNotSplitThere() {
+	statements := []string{
+		"INSERT INTO t VALUES ('a;b'), ('it''s; -- here'), ('/* c; */'); -- don't; split",
+		`SELECT v AS "v;w" FROM t /* it's; */ ORDER BY v;`,
+		`SELECT 'e;f' AS "x;" -- "here;`,
+		";",
+	}
+	expected := "v;w\n/* c; */\na;b\nit's; -- here\nx;\ne;f"
+
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (v TEXT);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	outS, errS, err := s.tc.ExecuteShell(append([]string{".mode csv"}, statements...))
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, expected)
+
+	file, filePath := s.tc.CreateTempFile(strings.Join(statements, "\n"))
+	defer file.Close()
+	outS, errS, err = s.tc.ExecuteShell([]string{"DELETE FROM t;", ".mode csv", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, expected)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptFailingAtAStatement_WhenCallDotRead_ExpectItStoppedThereNamingItsLine() {
+	content := `CREATE TABLE t (text TEXT);
+CREATE TRIGGER t_copy AFTER INSERT ON t BEGIN
+  INSERT INTO t SELECT 'copy; of ' || new.text WHERE new.text NOT LIKE 'copy%';
+END;
+INSERT INTO t VALUES ('a;b');
+SELECT text FROM t ORDER BY text;
+
+SELECT
+  missing FROM t;
+SELECT 'not executed';`
+	file, filePath := s.tc.CreateTempFile(content)
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: line 8: no such column: missing")
+	s.tc.Assert(outS, qt.Equals, "text\na;b\ncopy; of a;b")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptFailingAtADotCommand_WhenCallDotRead_ExpectItStoppedThereNamingItsLine() {
+	file, filePath := s.tc.CreateTempFile("SELECT 1;\n\n.mode unknown\nSELECT 2;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: line 3: Invalid mode. Current mode is csv. Valid modes are table, json, csv, sql, sparkline")
+	s.tc.Assert(outS, qt.Equals, "1\n1")
+}
+
 func (s *DBRootCommandShellSuite) Test_GivenAEmptyDb_WhenCallDotReadCommandPassingANonExistingFile_ExpectToReturnAnErrorMessage() {
 	outS, errS, err := s.tc.ExecuteShell([]string{".read nonExistingFile.txt"})
 	s.tc.Assert(err, qt.IsNil)
@@ -184,7 +442,7 @@ func (s *DBRootCommandShellSuite) Test_GivenAEmptyTable_WhenCallDotDumpCommand_E
 	outS, errS, err := s.tc.ExecuteShell([]string{".dump"})
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
-	expected := "PRAGMA foreign_keys=OFF;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');"
+	expected := "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');\nCOMMIT;"
 	s.tc.AssertSqlEquals(outS, expected)
 }
 
@@ -198,7 +456,7 @@ func (s *DBRootCommandShellSuite) Test_GivenATableConainingRandomFields_WhenInse
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "PRAGMA foreign_keys=OFF;\nCREATE TABLE alltypes (t text, i integer, r real, b blob);\nINSERT INTO alltypes VALUES ('text', 99, 3.14, X'0123456789ABCDEF');"
+	expected := "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE alltypes (t text, i integer, r real, b blob);\nINSERT INTO alltypes VALUES ('text', 99, 3.14, X'0123456789ABCDEF');\nCOMMIT;"
 
 	s.tc.AssertSqlEquals(outS, expected)
 }
@@ -212,7 +470,7 @@ func (s *DBRootCommandShellSuite) Test_GivenATableConainingFieldsWithALLTypes_Wh
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "PRAGMA foreign_keys=OFF;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');\nINSERT INTO alltypes VALUES (NULL, 'text2', 'defaultValue', NULL, 0, 0, NULL, 1.5, 0, NULL, 0, 0, NULL, X'0123456789ABCDEF', 'x\"0\"');"
+	expected := "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');\nINSERT INTO alltypes VALUES (NULL, 'text2', 'defaultValue', NULL, 0, 0, NULL, 1.5, 0, NULL, 0, 0, NULL, X'0123456789ABCDEF', 'x\"0\"');\nCOMMIT;"
 
 	s.tc.AssertSqlEquals(outS, expected)
 }
@@ -227,7 +485,7 @@ func (s *DBRootCommandShellSuite) Test_GivenATableWithRecords_WhenCreateIndexAnd
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "PRAGMA foreign_keys=OFF;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');\nCREATE INDEX idx_textNullable on alltypes (textNullable);\nCREATE INDEX idx_intNotNullable on alltypes (intNotNullable) WHERE intNotNullable > 1;"
+	expected := "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE alltypes (textNullable text, textNotNullable text NOT NULL, textWithDefault text DEFAULT 'defaultValue', \n\tintNullable INTEGER, intNotNullable INTEGER NOT NULL, intWithDefault INTEGER DEFAULT '0', \n\tfloatNullable REAL, floatNotNullable REAL NOT NULL, floatWithDefault REAL DEFAULT '0.0', \n\tunknownNullable NUMERIC, unknownNotNullable NUMERIC NOT NULL, unknownWithDefault NUMERIC DEFAULT 0.0, \n\tblobNullable BLOB, blobNotNullable BLOB NOT NULL, blobWithDefault BLOB DEFAULT 'x\"0\"');\nCREATE INDEX idx_textNullable on alltypes (textNullable);\nCREATE INDEX idx_intNotNullable on alltypes (intNotNullable) WHERE intNotNullable > 1;\nCOMMIT;"
 
 	s.tc.AssertSqlEquals(outS, expected)
 }
@@ -242,7 +500,7 @@ func (s *DBRootCommandShellSuite) Test_GivenATableWithRecordsWithSingleQuote_Whe
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "pragma foreign_keys=off;\ncreate table t (id integer primary key, textfield text, intfield integer);\ninsert into t values (0, 'x''x', 0);"
+	expected := "pragma foreign_keys=off;\nbegin transaction;\ncreate table t (id integer primary key, textfield text, intfield integer);\ninsert into t values (0, 'x''x', 0);\ncommit;"
 
 	s.tc.AssertSqlEquals(outS, expected)
 }
@@ -254,7 +512,7 @@ func (s *DBRootCommandShellSuite) Test_GivenATableNameStartingWithNumber_WhenCal
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "pragma foreign_keys=off;\ncreate table '8test' (id integer primary key, textfield text, intfield integer);\ninsert into '8test' values (1, 'value', 1);"
+	expected := "pragma foreign_keys=off;\nbegin transaction;\ncreate table '8test' (id integer primary key, textfield text, intfield integer);\ninsert into \"8test\" values (1, 'value', 1);\ncommit;"
 
 	s.tc.AssertSqlEquals(outS, expected)
 }
@@ -266,11 +524,100 @@ func (s *DBRootCommandShellSuite) Test_GivenATableNameWithSpecialCharacters_When
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errS, qt.Equals, "")
 
-	expected := "pragma foreign_keys=off;\ncreate table 't+e(s!t?' (id integer primary key, textfield text, intfield integer);\ninsert into 't+e(s!t?' values (1, 'value', 1);"
+	expected := "pragma foreign_keys=off;\nbegin transaction;\ncreate table 't+e(s!t?' (id integer primary key, textfield text, intfield integer);\ninsert into \"t+e(s!t?\" values (1, 'value', 1);\ncommit;"
+
+	s.tc.AssertSqlEquals(outS, expected)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPreviousResult_WhenCallDotCellCommand_ExpectFullValueInLineMode() {
+	outS, errS, err := s.tc.ExecuteShell([]string{"SELECT 1 AS id, 'a long text value' AS description UNION ALL SELECT 2, 'another value';", ".cell 2 description", ".cell 1 2"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasSuffix(outS, "\ndescription = another value\ndescription = a long text value"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenBlobInPreviousResult_WhenCallDotCellCommandWithOut_ExpectRawBytesWrittenToFile() {
+	filePath := s.T().TempDir() + "/cell.bin"
+
+	_, errS, err := s.tc.ExecuteShell([]string{"SELECT x'00FF10' AS data;", ".cell --out " + filePath + " 1 data"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	content, err := os.ReadFile(filePath)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(content, qt.DeepEquals, []byte{0x00, 0xFF, 0x10})
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPreviousResult_WhenCallDotCellCommandOutOfRange_ExpectError() {
+	_, errS, err := s.tc.ExecuteShell([]string{"SELECT 1 AS id;", ".cell 2 id", ".cell 1 missing"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: no cell at row 2, column id in the last result\nError: no column \"missing\" in the last result")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenCompatPostgres_WhenCallDotDumpCommand_ExpectColumnListsAndPostgresLiterals() {
+	_, errS, err := s.tc.Execute(`CREATE TABLE "my table" (id INTEGER PRIMARY KEY AUTOINCREMENT, t text, b blob);
+	INSERT INTO "my table" (t, b) VALUES ('it''s a \path', x'0123456789ABCDEF')`)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".dump --compat postgres"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	expected := "BEGIN;\n-- Omitted SQLite-only clauses: AUTOINCREMENT\nCREATE TABLE \"my table\" (id INTEGER PRIMARY KEY, t text, b blob);\nINSERT INTO \"my table\" (\"id\", \"t\", \"b\") VALUES (1, 'it''s a \\path', '\\x0123456789abcdef');\nCOMMIT;"
+	s.tc.Assert(outS, qt.Equals, expected)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenCompatMySQL_WhenCallDotDumpCommand_ExpectColumnListsAndMySQLLiterals() {
+	_, errS, err := s.tc.Execute(`CREATE TABLE t (id INTEGER PRIMARY KEY, t text, b blob) WITHOUT ROWID;
+	INSERT INTO t VALUES (1, 'it''s a \path', x'0123456789ABCDEF')`)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".dump --compat mysql"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	expected := "SET FOREIGN_KEY_CHECKS=0;\nSTART TRANSACTION;\n-- Omitted SQLite-only clauses: WITHOUT ROWID\nCREATE TABLE t (id INTEGER PRIMARY KEY, t text, b blob);\nINSERT INTO `t` (`id`, `t`, `b`) VALUES (1, 'it''s a \\\\path', 0x0123456789ABCDEF);\nCOMMIT;"
+	s.tc.Assert(outS, qt.Equals, expected)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenQuoteStyleSetInShell_WhenCallDotDumpCommand_ExpectIdentifiersQuotedWithIt() {
+	s.tc.CreateSimpleTable("'my`table'", []utils.SimpleTableEntry{{TextField: "Value", IntField: 1}})
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".quote backtick", ".dump"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	expected := "pragma foreign_keys=off;\nbegin transaction;\ncreate table 'my`table' (id integer primary key, textfield text, intfield integer);\ninsert into `my``table` values (1, 'value', 1);\ncommit;"
+	s.tc.AssertSqlEquals(outS, expected)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenQuoteFlag_WhenCallDotDumpCommandWithCompat_ExpectFlagStyleUsed() {
+	s.tc.CreateSimpleTable("t", []utils.SimpleTableEntry{{TextField: "Value", IntField: 1}})
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".quote backtick", ".dump --compat postgres --quote bracket"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
 
+	expected := "begin;\ncreate table t (id integer primary key, textfield text, intfield integer);\ninsert into [t] ([id], [textfield], [intfield]) values (1, 'value', 1);\ncommit;"
 	s.tc.AssertSqlEquals(outS, expected)
 }
 
+func (s *DBRootCommandShellSuite) Test_GivenInvalidQuoteStyle_WhenCallDotQuoteCommand_ExpectError() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".quote single", ".quote"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, "double")
+	s.tc.Assert(errS, qt.Equals, `Error: invalid quote style "single". Valid styles are double, backtick, bracket`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenUnsupportedCompat_WhenCallDotDumpCommand_ExpectError() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".dump --compat oracle"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, "")
+	s.tc.Assert(errS, qt.Equals, `Error: unsupported compat target "oracle". Use postgres, mysql, sqlite or sqlite3`)
+}
+
 func (s *DBRootCommandShellSuite) Test_GivenATableWithRecordsWithSingleQuote_WhenCalllSelectAllFromTable_ExpectSingleQuoteScape() {
 	s.tc.CreateEmptySimpleTable("t")
 	_, errS, err := s.tc.Execute("INSERT INTO t VALUES (0, \"x'x\", 0)")
@@ -310,16 +657,80 @@ func (s *DBRootCommandShellSuite) Test_GivenATableWithRecords_WhenCallDotModeJSO
 	outS, errSMode, err := s.tc.ExecuteShell([]string{".mode json", "SELECT * from simple_table;"})
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errSMode, qt.Equals, "")
-	s.tc.Assert(outS, qt.Equals, `[{"id":"1","intField":"1","textField":"value"},{"id":"2","intField":"2","textField":"value2"}]`)
+	s.tc.Assert(outS, qt.Equals, `[{"id":1,"intField":1,"textField":"value"},{"id":2,"intField":2,"textField":"value2"}]`)
 }
 
-func (s *DBRootCommandShellSuite) Test_GivenAnEmptyTable_WhenCallDotModeJSONAndSelect_ExpectEmptyReturn() {
+func (s *DBRootCommandShellSuite) Test_GivenAnEmptyTable_WhenCallDotModeJSONAndSelect_ExpectEmptyArray() {
 	s.tc.CreateEmptySimpleTable("simple_table")
 
 	outS, errSMode, err := s.tc.ExecuteShell([]string{".mode json", "SELECT * from simple_table;"})
 	s.tc.Assert(err, qt.IsNil)
 	s.tc.Assert(errSMode, qt.Equals, "")
-	s.tc.Assert(outS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "[]")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATableWithRecords_WhenCallDotModeSQLAndSelect_ExpectStatementReproducingTheResult() {
+	query := "SELECT 1 AS id, 'it''s' AS \"text value\", X'AB01' AS data, NULL AS missing, 1.5 AS real UNION ALL SELECT 2, 'b', X'', NULL, -2;"
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode sql", query})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `-- columns: id, text value, data, missing, real
+SELECT 1 AS "id", 'it''s' AS "text value", X'AB01' AS "data", NULL AS "missing", 1.5 AS "real"
+UNION ALL SELECT 2, 'b', X'', NULL, -2;`)
+
+	replayedOutS, errS, err := s.tc.ExecuteShell([]string{outS})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	originalOutS, _, err := s.tc.ExecuteShell([]string{query})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(replayedOutS, qt.Equals, originalOutS)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenQuoteStyleSet_WhenCallDotModeSQLAndSelect_ExpectAliasesQuotedWithIt() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".quote backtick", ".mode sql", "SELECT 1 AS id;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "-- columns: id\nSELECT 1 AS `id`;")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAnEmptyTable_WhenCallDotModeSQLAndSelect_ExpectSelectWithoutRows() {
+	s.tc.CreateEmptySimpleTable("simple_table")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode sql", "SELECT * FROM simple_table;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "-- columns: id, textField, intField\nSELECT NULL AS \"id\", NULL AS \"textField\", NULL AS \"intField\" WHERE 0;")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenMoreRowsThanTheLimit_WhenCallDotModeSQLAndSelect_ExpectRowsOmittedWithWarning() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode sql", "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 502) SELECT i FROM n;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasSuffix(outS, "UNION ALL SELECT 500;\n-- Warning: 2 more rows were omitted, as only the first 500 rows can be written as a single SELECT"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenLoweredLimits_WhenCallDotModeSQLAndSelect_ExpectRowsOmittedToStayUnderThem() {
+	defer s.tc.ExecuteShell([]string{".limit compound_select 0", ".limit sql_length 0"})
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 20) SELECT i FROM n;"
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".limit compound_select 3", ".mode sql", query})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "-- columns: i\nSELECT 1 AS \"i\"\nUNION ALL SELECT 2\nUNION ALL SELECT 3;\n-- Warning: 17 more rows were omitted, as only the first 3 rows can be written as a single SELECT")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".limit compound_select 0", ".limit sql_length 60", ".mode sql", query})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "-- columns: i\nSELECT 1 AS \"i\"\nUNION ALL SELECT 2\nUNION ALL SELECT 3;\n-- Warning: 17 more rows were omitted, as only the first 3 rows fit in a single SELECT under the sql_length limit of 60 bytes")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".limit sql_length"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"limit", "value"}, [][]string{{"sql_length", "60"}}))
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".limit unknown", ".limit sql_length many"})
+	s.tc.Assert(errS, qt.Equals, "Error: unknown limit unknown. Valid limits are compound_select and sql_length\nError: invalid value \"many\" for limit sql_length. It must be a number of bytes or SELECTs")
 }
 
 func (s *DBRootCommandShellSuite) Test_WhenCallACommandThatDoesNotExist_ExpectToReturnAnErrorMessage() {
@@ -329,6 +740,143 @@ func (s *DBRootCommandShellSuite) Test_WhenCallACommandThatDoesNotExist_ExpectTo
 	s.tc.Assert(outS, qt.Equals, "")
 }
 
+func (s *DBRootCommandShellSuite) Test_GivenAVariableSet_WhenReferencedInStatement_ExpectItToBeSubstituted() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".set suffix _prod", "CREATE TABLE orders{{suffix}} (id INTEGER);", ".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"orders_prod"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenUndefinedVariable_WhenReferencedInStatement_ExpectErrorWithNameAndPosition() {
+	outS, errS, err := s.tc.ExecuteShell([]string{"SELECT *\nFROM t{{ missing }};"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, "")
+	s.tc.Assert(errS, qt.Equals, `Error: undefined variable "missing" at line 2, column 7. Use ".set missing VALUE" to define it`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenVariablesSet_WhenCallDotSetWithoutArguments_ExpectVariablesListed() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".set table_name users", ".set condition id > 1", ".set"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "condition = id > 1\ntable_name = users")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenVariableSetToSQLWithQuotes_WhenReferenced_ExpectTheQuotesKept() {
+	file, filePath := s.tc.CreateTempFile(".set cond name = 'it''s'\nSELECT count(*) AS n FROM t WHERE {{cond}};")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{
+		"CREATE TABLE t (name TEXT); INSERT INTO t VALUES ('x'), ('x  y'), ('it''s');",
+		".set cond name = 'x'", ".set", ".mode csv", "SELECT count(*) AS n FROM t WHERE {{cond}};",
+		".set cond   name = 'x  y'  ", "SELECT count(*) AS n FROM t WHERE {{cond}};",
+		".read --expand-vars " + filePath,
+	})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "cond = name = 'x'\nn\n1\nn\n1\nn\n1")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotSetWithInvalidName_ExpectError() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".set 1name value"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, "")
+	s.tc.Assert(errS, qt.Equals, `Error: invalid variable name "1name". Names must start with a letter or underscore and contain only letters, digits and underscores`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAVariableSet_WhenCallDotReadWithoutExpandVars_ExpectReferenceNotSubstituted() {
+	file, filePath := s.tc.CreateTempFile("SELECT '{{name}}' AS value;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".set name expanded", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"value"}, [][]string{{"{{name}}"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAVariableSet_WhenCallDotReadWithExpandVars_ExpectReferenceSubstituted() {
+	file, filePath := s.tc.CreateTempFile("SELECT '{{name}}' AS value;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".set name expanded", ".read --expand-vars " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"value"}, [][]string{{"expanded"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenDotReadCalledWithExpandVars_WhenCallDotReadAgainWithoutIt_ExpectFlagNotKept() {
+	file, filePath := s.tc.CreateTempFile("SELECT '{{name}}' AS value;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".set name expanded", ".read --expand-vars " + filePath, ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasSuffix(outS, "{{name}}"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptWithDotCommands_WhenCallDotRead_ExpectSupportedOnesExecutedAndOthersSkipped() {
+	content := `-- Generated by sqlite3
+.bail on
+CREATE TABLE t (name TEXT);
+INSERT INTO t VALUES ('a'),
+('.b');
+.print "rows:"
+.mode csv
+SELECT * FROM t;
+.headers off`
+	file, filePath := s.tc.CreateTempFile(content)
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: line 2: unsupported command .bail was skipped\nWarning: line 9: unsupported command .headers was skipped")
+	s.tc.Assert(outS, qt.Equals, "rows:\nname\na\n.b")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotPrintWithQuotedArguments_ExpectQuotesRemoved() {
+	outS, errS, err := s.tc.ExecuteShell([]string{`.print plain 'single  quoted' "tab\there" 'it''s'`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "plain single  quoted tab\there it s")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotPrintWithEscapesFlag_ExpectEscapesInterpreted() {
+	outS, errS, err := s.tc.ExecuteShell([]string{`.print -e a\tb 'c\nd' "e\\\\n"`, `.print a\tb -e`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "a\tb c\nd e\\n\na\\tb -e")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotPrintWithUnterminatedQuote_ExpectError() {
+	outS, errS, err := s.tc.ExecuteShell([]string{`.print "unterminated`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, `Error: unterminated quoted argument: "unterminated`)
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSessionStatementsExecuted_WhenCallDotSessionShow_ExpectOnlySessionStatementsListed() {
+	outS, errS, err := s.tc.ExecuteShell([]string{"PRAGMA foreign_keys=ON;", "SELECT 1;", "PRAGMA case_sensitive_like = 1;", "PRAGMA foreign_keys;", ".mode csv", ".session show"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasSuffix(outS, "PRAGMA foreign_keys=ON;\nPRAGMA case_sensitive_like = 1;"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSessionStatementsExecuted_WhenCallDotOpen_ExpectStatementsReplayed() {
+	outS, errS, err := s.tc.ExecuteShell([]string{"PRAGMA foreign_keys=ON;", ".open", "CREATE TABLE t (id INTEGER);", ".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Replayed: PRAGMA foreign_keys=ON")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"t"}}))
+
+	_, _, err = s.tc.ExecuteShell([]string{".session clear"})
+	s.tc.Assert(err, qt.IsNil)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSessionStatementsCleared_WhenCallDotOpen_ExpectNothingReplayed() {
+	outS, errS, err := s.tc.ExecuteShell([]string{"PRAGMA foreign_keys=ON;", ".session clear", ".open", ".session show"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
 func TestDBRootCommandShellSuite_WhenDbIsSQLite(t *testing.T) {
 	suite.Run(t, NewDBRootCommandShellSuite(t.TempDir()+"test.sqlite"))
 }
@@ -341,3 +889,837 @@ func TestDBRootCommandShellSuite_WhenDbIsSqld(t *testing.T) {
 
 	suite.Run(t, NewDBRootCommandShellSuite(testConfig.SqldDbUri))
 }
+
+func (s *DBRootCommandShellSuite) Test_WhenCallSaveSettingsWithoutPerDb_ExpectToReturnAnErrorMessage() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".save-settings"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, `Error: required flag(s) "per-db" not set`)
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotSleep_ExpectPausedForTheGivenMilliseconds() {
+	start := time.Now()
+	outS, errS, err := s.tc.ExecuteShell([]string{".sleep 50"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "")
+	s.tc.Assert(time.Since(start) >= 50*time.Millisecond, qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotSleepWithInvalidTime_ExpectToReturnAnErrorMessage() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".sleep soon", ".sleep 1.5"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: invalid time \"soon\". Give the time to sleep as a number of milliseconds\nError: invalid time \"1.5\". Give the time to sleep as a number of milliseconds")
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptWithDotPrintAndDotSleep_WhenCallDotRead_ExpectBothExecuted() {
+	file, filePath := s.tc.CreateTempFile(".print -e 'before\\nsleeping'\n.sleep 1\n.print after")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "before\nsleeping\nafter")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenExcludedTables_WhenCallDotTablesAndDotSchema_ExpectMatchingTablesHidden() {
+	s.tc.CreateEmptySimpleTable("simple_table")
+	s.tc.CreateEmptySimpleTable("schema_migrations")
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE INDEX idx_migrations ON schema_migrations (textField);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{`.exclude schema\_migrations`, ".exclude", ".tables", ".schema"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Matches, `schema\\_migrations\nsimple_table *\nCREATE TABLE simple_table \(id INTEGER PRIMARY KEY, textField TEXT, intField INTEGER\);`)
+
+	outS, errS, err = s.tc.ExecuteShell([]string{`.exclude schema\_migrations`, ".exclude --clear", ".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "schema_migrations\nsimple_table")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAutoincrementTable_WhenCallDotTablesWithIncludeInternal_ExpectInternalTablesListed() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{""}, [][]string{{"t"}}))
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".tables --include-internal"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "sqlite_sequence\nt")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotForeignKeys_ExpectEnforcementChangedAndShown() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".foreign_keys on", ".foreign_keys show", ".foreign_keys off", ".foreign_keys show"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "foreign_keys: on\nforeign_keys: off")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptInsertingChildRowsFirst_WhenCallDotReadWithDeferFk_ExpectScriptRestored() {
+	content := `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id));
+CREATE TABLE parent (id INTEGER PRIMARY KEY);
+INSERT INTO child VALUES (1, 1);
+INSERT INTO parent VALUES (1);
+COMMIT;`
+	file, filePath := s.tc.CreateTempFile(content)
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".foreign_keys on", ".read --defer-fk " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: BEGIN TRANSACTION was skipped, as the file is executed in a single transaction\nWarning: COMMIT was skipped, as the file is executed in a single transaction")
+	s.tc.Assert(outS, qt.Equals, "")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{"SELECT count(*) FROM child;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"1"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAScriptViolatingForeignKeys_WhenCallDotReadWithDeferFk_ExpectViolatingTablesReportedAndRolledBack() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE parent (id INTEGER PRIMARY KEY);", "CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id));"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	file, filePath := s.tc.CreateTempFile("INSERT INTO child VALUES (1, 2);\nINSERT INTO parent VALUES (1);")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".foreign_keys on", ".read --defer-fk " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: the script left rows violating foreign key constraints in tables child, so its changes were rolled back. Run PRAGMA foreign_key_check for details")
+	s.tc.Assert(outS, qt.Equals, "")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{"SELECT count(*) FROM parent;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"0"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPageSizeSet_WhenCallDotNextAndDotPrev_ExpectPagesShown() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);", "INSERT INTO t VALUES (1), (2), (3), (4), (5);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".page 2", "SELECT id FROM t ORDER BY id;", ".next", ".next", ".next", ".prev"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "page 1 (rows 1–2)\npage 2 (rows 3–4)\npage 3 (rows 5–5)\nError: already at the last page\npage 2 (rows 3–4)")
+	s.tc.Assert(outS, qt.Equals, "id\n1\n2\nid\n3\n4\nid\n5\nid\n3\n4")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPageSizeSet_WhenExecuteStatementsNotPageable_ExpectThemExecutedWhole() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".page 1", "SELECT 1 AS v UNION ALL SELECT 2 LIMIT 2;", "SELECT 3 AS v; SELECT 4 AS v;", ".prev"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: the statement isn't shown in pages, as it has a LIMIT clause\nError: no result to page through. Set a page size with .page and execute a SELECT statement first")
+	s.tc.Assert(outS, qt.Equals, "v\n1\n2\nv\n3\nv\n4")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPageSizeSet_WhenExecuteQueryWithoutOrderBy_ExpectWarning() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".page 10", ".page", "SELECT 1 AS v;", ".prev", ".page 0", ".page"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: the statement has no ORDER BY clause, so its rows may change pages between executions\npage 1 (rows 1–1)\nError: already at the first page")
+	s.tc.Assert(outS, qt.Equals, "page size: 10\nv\n1\npage size: off")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSparklineMode_WhenQueryLabelsAndNumbers_ExpectBarsScaledToTheLargestValue() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode sparkline", "SELECT * FROM (VALUES ('2024-01', 40), ('2024-02', 10.1), ('b', NULL), ('2024-03', -3));"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "2024-01 "+strings.Repeat("█", 40)+" 40\n2024-02 "+strings.Repeat("█", 10)+"▏ 10.1\nb       NULL\n2024-03 -3")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSparklineMode_WhenQueryOtherShape_ExpectTableWithWarning() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode sparkline", "SELECT 'a' AS label, 'x' AS value;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: 1 result(s) were shown as tables, as sparkline mode needs a label and a numeric column")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"label", "value"}, [][]string{{"a", "x"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenCsvModeWithExcelCompat_WhenQuery_ExpectBomAndCrlf() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv --excel-compat", "SELECT 'é' AS a UNION ALL SELECT 'b';"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "\ufeffa\r\né\r\nb")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".mode csv --crlf", "SELECT 1 AS a;", ".mode csv", "SELECT 2 AS a;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "a\r\n1\r\na\n2")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotModeWithCsvFlagsForOtherMode_ExpectToReturnAnErrorMessage() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode json --bom"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: --bom, --crlf and --excel-compat only apply to csv mode")
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAnEmptyTable_WhenCallDotImport_ExpectValidRowsImportedAndOthersReported() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER, name TEXT UNIQUE);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	file, filePath := s.tc.CreateTempFile("1,a\n2,\"b, c\"\nthree,d\n4\n5,a\n")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import " + filePath + " t"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, `Warning: line 3 ("three" isn't a number for column id) was skipped
+Warning: line 4 (expected 2 fields, got 1) was skipped
+Warning: line 5 (UNIQUE constraint failed: t.name) was skipped`)
+	s.tc.Assert(outS, qt.Equals, "Imported 2 rows into t")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".mode csv", "SELECT id, typeof(id), name FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "id,typeof(id),name\n1,integer,a\n2,integer,\"b, c\"")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATableWithRows_WhenCallDotImportWithoutPrompt_ExpectYesRequired() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);", "INSERT INTO t VALUES (1);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	file, filePath := s.tc.CreateTempFile("2\n3\n")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import " + filePath + " t", ".import --yes " + filePath + " t", ".mode csv", "SELECT count(*) FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: table t already has 1 rows, so nothing was imported. Use --yes to import into it anyway")
+	s.tc.Assert(outS, qt.Equals, "Imported 2 rows into t\ncount(*)\n3")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotImportWithDryRun_ExpectReportAndNothingWritten() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER, price REAL);", "INSERT INTO t VALUES (1, 1.5);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	file, filePath := s.tc.CreateTempFile("2,2.5\n3,cheap\n4,\n5,6,7\n")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import --dry-run " + filePath + " t"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `2 rows would be inserted into t
+line 2 ("cheap" isn't a number for column price) would be skipped
+line 4 (expected 2 fields, got 3) would be skipped`)
+
+	outS, errS, err = s.tc.ExecuteShell([]string{"SELECT count(*) FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"1"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenATsvFileWithAHeader_WhenCallDotImportWithSkip_ExpectTheOtherRowsImported() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER, name TEXT);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	file, filePath := s.tc.CreateTempFile("id\tname\n1\ta, b\n2\tc\nthree\td\n")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import --tsv --skip 1 " + filePath + " t", ".mode csv", "SELECT * FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, `Warning: line 4 ("three" isn't a number for column id) was skipped`)
+	s.tc.Assert(outS, qt.Equals, "Imported 2 rows into t\nid,name\n1,\"a, b\"\n2,c")
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".import --csv --tsv " + filePath + " t", ".import --skip -1 " + filePath + " t"})
+	s.tc.Assert(errS, qt.Equals, "Error: --csv and --tsv can't be used together\nError: invalid --skip -1. It must be a positive number of rows")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenMoreRowsThanABatch_WhenCallDotImport_ExpectOnlyTheRejectedRowsSkipped() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	var content strings.Builder
+	for id := 1; id <= 1200; id++ {
+		content.WriteString(strconv.Itoa(id) + "\n")
+		if id == 700 {
+			content.WriteString("700\n")
+		}
+	}
+	file, filePath := s.tc.CreateTempFile(content.String())
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import " + filePath + " t", ".mode csv", "SELECT count(*), max(id) FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Warning: line 701 (UNIQUE constraint failed: t.id) was skipped")
+	s.tc.Assert(outS, qt.Equals, "Imported 1200 rows into t\ncount(*),max(id)\n1200,1200")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenWideRowsAndSQLLengthLimit_WhenCallDotImport_ExpectSmallerTransactionsNoted() {
+	defer s.tc.ExecuteShell([]string{".limit sql_length 0"})
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b TEXT);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	var content strings.Builder
+	for id := 1; id <= 600; id++ {
+		content.WriteString(fmt.Sprintf("%d,%s,%s\n", id, strings.Repeat("a", 100), strings.Repeat("b", 100)))
+	}
+	file, filePath := s.tc.CreateTempFile(content.String())
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".limit sql_length 20000", ".import " + filePath + " t", ".mode csv", "SELECT count(*), max(id) FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Note: Importing in transactions of fewer than 500 rows, to keep them under the sql_length limit of 20000 bytes")
+	s.tc.Assert(outS, qt.Equals, "Imported 600 rows into t\ncount(*),max(id)\n600,600")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotImportIntoMissingTable_ExpectToReturnAnErrorMessage() {
+	file, filePath := s.tc.CreateTempFile("1\n")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".import " + filePath + " missing"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: no such table: missing. Create the table before importing into it")
+	s.tc.Assert(outS, qt.Equals, "")
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotWatchWithCount_ExpectResultOfEachIterationWithTimestamp() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", `.watch --count 2 0.01 "SELECT 'x' AS v"`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Matches, `Every 10ms: SELECT 'x' AS v\n\d{4}-\d\d-\d\d \d\d:\d\d:\d\d, iteration 1\nv\nx\nEvery 10ms: SELECT 'x' AS v\n\d{4}-\d\d-\d\d \d\d:\d\d:\d\d, iteration 2\nv\nx`)
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotWatchWithFailingStatement_ExpectErrorsShownAndIterationsKept() {
+	outS, errS, err := s.tc.ExecuteShell([]string{`.watch --count 2 1ms SELECT * FROM missing`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Matches, `Error: .*no such table: missing.*\nError: .*no such table: missing.*`)
+	s.tc.Assert(outS, qt.Matches, `Every 1ms: SELECT \* FROM missing\n.*, iteration 1\nEvery 1ms: SELECT \* FROM missing\n.*, iteration 2`)
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenCallDotWatchWithWrite_ExpectAllowWritesRequired() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".watch --count 1 INSERT INTO t VALUES (1)", ".watch --count 2 --allow-writes 1ms INSERT INTO t VALUES (1)", ".mode csv", "SELECT count(*) FROM t;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: only queries can be watched, and INSERT INTO t VALUES (1) isn't one. Use --allow-writes to watch it anyway")
+	s.tc.Assert(outS, qt.Matches, `(?s)Every 1ms: .*iteration 2\ncount\(\*\)\n2`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenExplainFmtOn_WhenExplainQuery_ExpectAlignedBytecodeWithJumpTargets() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".explain-fmt on", ".explain-fmt", "EXPLAIN SELECT * FROM (VALUES (1), (2));"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	lines := strings.Split(outS, "\n")
+	s.tc.Assert(lines[0], qt.Equals, "explain-fmt: on")
+	s.tc.Assert(lines[1], qt.Matches, `addr +opcode +p1 +p2 +p3 +p4 +p5 +comment`)
+	s.tc.Assert(lines[2], qt.Matches, `0 +Init +0 +-> \d+ .*`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenExplainFmtOn_WhenQueryColumnsDifferFromExplain_ExpectTable() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".explain-fmt on", "SELECT 0 AS addr, 'Init' AS opcode, 0 AS p1, 1 AS p2, 0 AS p3, NULL AS p4, 0 AS p5, NULL AS Comment;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"addr", "opcode", "p1", "p2", "p3", "p4", "p5", "Comment"}, [][]string{{"0", "Init", "0", "1", "0", "NULL", "0", "NULL"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenExplainFmtOn_WhenCallDotRead_ExpectAlignedBytecodeInTheScriptOutput() {
+	file, filePath := s.tc.CreateTempFile("EXPLAIN SELECT 1;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".explain-fmt on", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	lines := strings.Split(outS, "\n")
+	s.tc.Assert(lines[0], qt.Matches, `addr +opcode +p1 +p2 +p3 +p4 +p5 +comment`)
+	s.tc.Assert(lines[1], qt.Matches, `0 +Init +0 +-> \d+ .*`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenHeaderInterval_WhenOutputIsRedirected_ExpectHeaderShownOnce() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".header-interval 1", ".header-interval", "SELECT * FROM (VALUES (1), (2));"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "header interval: 1\n"+utils.GetPrintTableOutput([]string{"column1"}, [][]string{{"1"}, {"2"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_WhenSelftest_ExpectEveryCheckPassedAndNoTableLeft() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".selftest", ".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.Contains(outS, "FAIL"), qt.IsFalse)
+	s.tc.Assert(outS, qt.Contains, "PASS restore text with quotes\n")
+	s.tc.Assert(strings.HasSuffix(outS, "PASS drop the scratch table\n26 of 26 checks passed"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPragmaPrettyOn_WhenTableInfo_ExpectReadableLayoutUntilTurnedOff() {
+	outS, errS, err := s.tc.ExecuteShell([]string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT 'none', note);",
+		".pragma-pretty on",
+		"PRAGMA table_info(t);",
+	})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"column", "type", "nullable", "default", "primary key"}, [][]string{
+		{"id", "INTEGER", "YES", "", "✓"},
+		{"name", "TEXT", "NO", "'none'", ""},
+		{"note", "", "YES", "", ""},
+	}))
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".pragma-pretty on", ".pragma-pretty off", ".pragma-pretty", ".mode csv", "PRAGMA table_info(t);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "pragma-pretty: off\ncid,name,type,notnull,dflt_value,pk\n0,id,INTEGER,0,,1\n1,name,TEXT,1,'none',0\n2,note,,0,,0")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPragmaPrettyOn_WhenColumnsDifferFromKnownPragmas_ExpectNormalTable() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".pragma-pretty on", "SELECT 0 AS seq, 'main' AS name, '' AS File;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"seq", "name", "File"}, [][]string{{"0", "main", ""}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenPragmaPrettyOn_WhenCallDotRead_ExpectReadableLayoutInTheScriptOutput() {
+	file, filePath := s.tc.CreateTempFile("CREATE TABLE t (id INTEGER PRIMARY KEY);\nPRAGMA table_info(t);")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".pragma-pretty on", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"column", "type", "nullable", "default", "primary key"}, [][]string{{"id", "INTEGER", "YES", "", "✓"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSlowThresholdAndLog_WhenStatementExceedsIt_ExpectNoteAndLogLine() {
+	logPath := s.T().TempDir() + "/slow.log"
+	outS, errS, err := s.tc.ExecuteShell([]string{".slow-threshold 1ns", ".slow-log " + logPath, ".slow-threshold", ".mode csv", "SELECT 1 AS v UNION SELECT 2;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Matches, `Note: statement took \S+, exceeding slow threshold of 1ns`)
+	s.tc.Assert(outS, qt.Equals, "slow threshold: 1ns\nv\n1\n2")
+
+	log, err := os.ReadFile(logPath)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(string(log), qt.Matches, "\\S+\t\\S+\t2 rows\tSELECT 1 AS v UNION SELECT 2\n")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".slow-threshold 1h", ".mode csv", "SELECT 1 AS v;", ".slow-threshold off", ".slow-threshold", ".slow-log"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "v\n1\nslow threshold: off\nslow log: off")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenSlowThreshold_WhenCallDotRead_ExpectNoteForTheScriptStatements() {
+	file, filePath := s.tc.CreateTempFile("SELECT 1 AS v;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".slow-threshold 1ns", ".mode csv", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Matches, `Note: statement took \S+, exceeding slow threshold of 1ns`)
+	s.tc.Assert(outS, qt.Equals, "v\n1")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenNumberFormat_WhenSelecting_ExpectThousandsGroupedInTableModeOnly() {
+	_, _, err := s.tc.ExecuteShell([]string{"CREATE TABLE sales (region TEXT, units INTEGER, revenue REAL); INSERT INTO sales VALUES ('north', 1234567, -9876.5), ('south', 12, 1000);"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".numfmt on", ".numfmt", "SELECT * FROM sales;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "numfmt: on en\n"+utils.GetPrintTableOutput([]string{"region", "units", "revenue"}, [][]string{{"north", "1,234,567", "-9,876.5"}, {"south", "12", "1,000"}}))
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".numfmt on de_DE", "SELECT * FROM sales;", ".mode csv", "SELECT * FROM sales;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Contains, utils.GetPrintTableOutput([]string{"region", "units", "revenue"}, [][]string{{"north", "1.234.567", "-9.876,5"}, {"south", "12", "1.000"}}))
+	s.tc.Assert(outS, qt.Contains, "\nregion,units,revenue\nnorth,1234567,-9876.5\nsouth,12,1000")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".numfmt on eu", ".mode csv", ".show"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `mode: csv
+           quote: double
+          numfmt: on eu
+       nullvalue: default
+            mask: off
+         exclude: off
+ header-interval: off
+      headercase: upper
+            page: off
+           timer: off
+         changes: off
+           delta: off
+     explain-fmt: off
+   pragma-pretty: off
+  slow-threshold: off
+        slow-log: off`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".numfmt on xx"})
+	s.tc.Assert(errS, qt.Equals, `Error: unknown locale "xx". Use en for 1,234.5 or eu for 1.234,5`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenNumberFormat_WhenCallDotRead_ExpectThousandsGroupedInTheScriptOutput() {
+	file, filePath := s.tc.CreateTempFile("SELECT 1234567 AS units;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".numfmt on", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"units"}, [][]string{{"1,234,567"}}))
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenHeaderCasePreserve_WhenSelecting_ExpectTableHeaderWithTheExactAliases() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".headercase", `SELECT 1 AS "userId";`, ".headercase preserve", ".headercase", `SELECT 1 AS "userId", 2 AS "créé le";`, ".mode csv", `SELECT 1 AS "userId";`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `headercase: upper
+USERID 
+1          
+headercase: preserve
+userId     créé le 
+1          2           
+userId
+1`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".headercase lower"})
+	s.tc.Assert(errS, qt.Equals, `Error: invalid argument "lower". Valid arguments are preserve and upper`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenHeaderCasePreserve_WhenCallDotRead_ExpectTheExactAliasesInTheScriptOutput() {
+	file, filePath := s.tc.CreateTempFile(`SELECT 1 AS "userId";`)
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".headercase preserve", ".read " + filePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "userId \n1")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenNullValue_WhenSelecting_ExpectNullsToDifferFromTextNull() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".nullvalue", ".mode csv", ".nullvalue <null>", ".nullvalue", "SELECT 'NULL' AS a, NULL AS b;", `.nullvalue ""`, ".nullvalue", "SELECT 'NULL' AS a, NULL AS b;", ".mode json", "SELECT NULL AS b;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `nullvalue: default (NULL in tables, empty in CSV)
+nullvalue: "<null>"
+a,b
+NULL,<null>
+nullvalue: ""
+a,b
+NULL,
+[{"b":null}]`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenTimerOn_WhenExecutingStatements_ExpectARunTimeLineAfterEachOne() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".timer", ".timer on", ".timer", ".mode csv", "SELECT 1 AS a; SELECT 2 AS b;", ".timer off", "SELECT 3 AS c;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Matches, `timer: off
+timer: on
+a
+1
+Run Time: real \d+\.\d{3}s
+b
+2
+Run Time: real \d+\.\d{3}s
+c
+3`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".timer yes"})
+	s.tc.Assert(errS, qt.Equals, `Error: invalid argument "yes". Valid arguments are on and off`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenChangesOn_WhenExecutingStatements_ExpectRowsAffectedAfterChangesAndRowCountAfterTables() {
+	outS, errS, err := s.tc.ExecuteShell([]string{
+		".changes", ".changes on", ".changes", "CREATE TABLE t (a INTEGER);", "INSERT INTO t VALUES (1), (2);",
+		"UPDATE t SET a = 3 WHERE a = 2;", "DELETE FROM t WHERE a = 9;", "SELECT a FROM t;", ".changes off", "DELETE FROM t;",
+	})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "changes: off\nchanges: on\n2 rows affected, last insert rowid 2\n1 row affected\n0 rows affected\nA \n1     \n3     \n(2 rows)")
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".changes yes"})
+	s.tc.Assert(errS, qt.Equals, `Error: invalid argument "yes". Valid arguments are on and off`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenDeltaForced_WhenWritingToATable_ExpectItsRowCountsBeforeAndAfter() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE users (id INTEGER); INSERT INTO users VALUES (1), (2), (3);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{
+		".delta", ".delta force", ".delta", ".mode csv",
+		"SELECT 1 AS a; DELETE FROM users WHERE id > 1; SELECT 2 AS b;", "UPDATE users SET id = 1 FROM (SELECT 1) AS x;", "DELETE FROM nope;",
+		".delta off", "DELETE FROM users;",
+	})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: no such table: nope")
+	s.tc.Assert(outS, qt.Equals, "delta: off\ndelta: on\na\n1\n\nusers: 3 → 1 (-2)\nb\n2")
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".delta yes"})
+	s.tc.Assert(errS, qt.Equals, `Error: invalid argument "yes". Valid arguments are on, off and force`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenMasks_WhenSelecting_ExpectMatchingColumnsMaskedInTableModeOnly() {
+	_, _, err := s.tc.ExecuteShell([]string{"CREATE TABLE users (id INTEGER, Email TEXT, password_hash TEXT); INSERT INTO users VALUES (1, 'a@b.c', 'x1');"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mask email *PASSWORD*", ".mask", "SELECT * FROM users;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "email\n*PASSWORD*\n"+utils.GetPrintTableOutput([]string{"id", "Email", "password_hash"}, [][]string{{"1", "****", "****"}}))
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".mask email *PASSWORD*", ".mode csv", "SELECT * FROM users;", ".mode table", ".mask -d EMAIL", ".mask", "SELECT * FROM users;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "id,Email,password_hash\n1,a@b.c,x1\n*PASSWORD*\n"+utils.GetPrintTableOutput([]string{"id", "Email", "password_hash"}, [][]string{{"1", "a@b.c", "****"}}))
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".mask -d phone"})
+	s.tc.Assert(errS, qt.Equals, `Error: no mask "phone" is set`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenMasks_WhenCallDotRead_ExpectMatchingColumnsMaskedInTheScriptOutput() {
+	file, filePath := s.tc.CreateTempFile("SELECT 1 AS id, 'alice' AS username;")
+	defer file.Close()
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mask *name*", ".read " + filePath, ".watch --count 1 \"SELECT 'bob' AS username\""})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.HasPrefix(outS, utils.GetPrintTableOutput([]string{"id", "username"}, [][]string{{"1", "****"}})), qt.IsTrue)
+	s.tc.Assert(strings.HasSuffix(outS, "iteration 1\nUSERNAME \n****"), qt.IsTrue)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenJSONModePretty_WhenSelect_ExpectIndentedRowsAndValues() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode json --pretty", "SELECT 1 AS id, 'a\"b' AS name UNION ALL SELECT 2, NULL;", "SELECT 1 AS id WHERE 0;", ".mode table --pretty"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: --pretty only applies to json mode")
+	s.tc.Assert(outS, qt.Equals, `[
+  {
+    "id": 1,
+    "name": "a\"b"
+  },
+  {
+    "id": 2,
+    "name": null
+  }
+]
+[]`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenJSONMode_WhenRowFailsMidResult_ExpectArrayClosedAndErrorReported() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode json", "WITH RECURSIVE r(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM r WHERE x < 5) SELECT CASE WHEN x > 2 THEN abs(-9223372036854775808) ELSE x END AS v FROM r;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: integer overflow")
+	s.tc.Assert(outS, qt.Equals, `[{"v":1},{"v":2}]`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenAssertions_WhenResultsMatchOrNot_ExpectFailuresShowingExpectedAndActual() {
+	_, _, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER, name TEXT); INSERT INTO t VALUES (1, 'a b'), (2, NULL);"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".assert ROWS 2 SELECT * FROM t", ".assert equals 'a b' \"SELECT name FROM t WHERE id = 1\"", ".assert EQUALS NULL SELECT name FROM t WHERE id = 2"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "")
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".assert ROWS 3 SELECT * FROM t"})
+	s.tc.Assert(errS, qt.Equals, "Error: assertion failed: expected 3 row(s), got 2")
+	_, errS, _ = s.tc.ExecuteShell([]string{".assert EQUALS x SELECT name FROM t WHERE id = 1"})
+	s.tc.Assert(errS, qt.Equals, "Error: assertion failed: expected 'x', got 'a b'")
+	_, errS, _ = s.tc.ExecuteShell([]string{".assert EQUALS 1 SELECT * FROM t"})
+	s.tc.Assert(errS, qt.Equals, "Error: assertion failed: expected a single value '1', got 2 row(s) of 2 column(s)")
+	_, errS, _ = s.tc.ExecuteShell([]string{".assert COLUMNS 2 SELECT * FROM t"})
+	s.tc.Assert(errS, qt.Equals, `Error: unknown assertion "COLUMNS". Use ROWS or EQUALS`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenStatementsExecuted_WhenStatsSession_ExpectCumulativeCounts() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", "CREATE TABLE t (id INTEGER); INSERT INTO t VALUES (1), (2), (3);", "SELECT * FROM t;", "SELECT * FROM missing;", ".stats session"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: no such table: missing")
+	s.tc.Assert(outS, qt.Matches, `(?s)id\n1\n2\n3\nsession: 4 statements, 1 errors, 3 rows returned, 3 rows changed, 11 bytes written, \S+ connected\nslowest statement: .+ \(\S+\)`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".stats"})
+	s.tc.Assert(errS, qt.Equals, "Error: accepts 1 arg(s), received 0")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenCSVMode_WhenValuesNeedQuotingOrAreNull_ExpectRFC4180FieldsAndEmptyNulls() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", "SELECT 'a,b' AS text, 'say \"hi\"' AS quoted, 'two' || char(10) || 'lines' AS multiline, NULL AS missing, X'00FF' AS data;", "SELECT 1;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "text,quoted,multiline,missing,data\n\"a,b\",\"say \"\"hi\"\"\",\"two\nlines\",,0x00FF\n1\n1")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenNamesInEitherUnicodeNormalForm_WhenLookedUpInTheOtherForm_ExpectFoundWithOriginalBytes() {
+	composed, decomposed := "caf\u00e9", "cafe\u0301"
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE \"" + decomposed + "\" (id INTEGER);", "CREATE INDEX \"idx_" + decomposed + "\" ON \"" + decomposed + "\" (id);", "CREATE TABLE \"na\u00efve\" (id INTEGER);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".schema " + composed})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.TrimSpace(outS), qt.Equals, "CREATE TABLE \""+decomposed+"\" (id INTEGER);")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".indexes " + composed})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.TrimSpace(outS), qt.Equals, "idx_"+decomposed)
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".schema nai\u0308ve"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(strings.TrimSpace(outS), qt.Equals, "CREATE TABLE \"na\u00efve\" (id INTEGER);")
+
+	file, filePath := s.tc.CreateTempFile("1\n2\n")
+	defer file.Close()
+	outS, errS, err = s.tc.ExecuteShell([]string{".import " + filePath + " " + composed, ".dump --where '" + composed + ":id > 1' --exclude na%"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "Imported 2 rows into "+decomposed+"\nPRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE \""+decomposed+"\" (id INTEGER);\nINSERT INTO \""+decomposed+"\" VALUES (2);\nCREATE INDEX \"idx_"+decomposed+"\" ON \""+decomposed+"\" (id);\nCOMMIT;")
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".exclude " + composed, ".tables"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "na\u00efve")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenJSONMode_WhenSelectingEachType_ExpectTypedValues() {
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode json", "SELECT -9007199254740993 AS i, 1.5 AS f, 'it''s' AS t, NULL AS n, X'00FF10' AS b, 1e999 AS inf;", "CREATE TABLE t (id INTEGER);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `[{"b":"AP8Q","f":1.5,"i":-9007199254740993,"inf":"+Inf","n":null,"t":"it's"}]`)
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenQueryPlanBaseline_WhenIndexDropped_ExpectChangedPlansReported() {
+	dir := s.T().TempDir()
+	queriesPath := filepath.Join(dir, "queries.sql")
+	baselinePath := filepath.Join(dir, "baseline.json")
+	err := os.WriteFile(queriesPath, []byte("-- name: by_email\nSELECT * FROM users\n  WHERE email = 'a@example.com';\n\n-- name: all\nSELECT * FROM users;\n"), 0644)
+	s.tc.Assert(err, qt.IsNil)
+	_, _, err = s.tc.ExecuteShell([]string{"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); CREATE INDEX users_email ON users (email);"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".eqp baseline save --queries " + queriesPath + " " + baselinePath, ".eqp baseline check " + baselinePath})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, "Saved the plans of 2 queries\nThe plans of 2 queries are unchanged")
+
+	outS, errS, _ = s.tc.ExecuteShell([]string{"DROP INDEX users_email;", ".eqp baseline check " + baselinePath})
+	s.tc.Assert(errS, qt.Equals, "Error: the plans of 1 of 2 queries changed")
+	s.tc.Assert(outS, qt.Matches, `changed: by_email\n  - SEARCH users USING (COVERING )?INDEX users_email \(email=\?\)\n  \+ SCAN users`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".eqp baseline save " + baselinePath})
+	s.tc.Assert(errS, qt.Equals, "Error: --queries is required to save a baseline")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenFullTableScans_WhenAdvising_ExpectIndexesOnTheirFilteredColumns() {
+	_, _, err := s.tc.ExecuteShell([]string{"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, org INT); CREATE TABLE orgs (id INTEGER PRIMARY KEY, name TEXT);"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{`.advise "SELECT * FROM users AS u JOIN orgs o ON o.name = u.email WHERE u.email = 'a' AND u.org > 3"`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `-- Heuristic suggestions from the query plan and the conditions of the query, not from SQLite's expert extension. Check them with EXPLAIN QUERY PLAN
+CREATE INDEX users_email_org ON users (email, org);
+CREATE INDEX orgs_name ON orgs (name);`)
+
+	outS, _, err = s.tc.ExecuteShell([]string{".advise SELECT * FROM users WHERE id = 1", ".advise SELECT * FROM users"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, `-- Heuristic suggestions from the query plan and the conditions of the query, not from SQLite's expert extension. Check them with EXPLAIN QUERY PLAN
+-- No index suggested, as the query plan scans no table in full
+-- Heuristic suggestions from the query plan and the conditions of the query, not from SQLite's expert extension. Check them with EXPLAIN QUERY PLAN
+-- No index suggested for the scan of users, which no condition of the WHERE clause or of a join filters`)
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".advise SELECT * FROM users WHERE email = 1 OR org = 1"})
+	s.tc.Assert(errS, qt.Equals, "Error: unable to analyze: OR conditions aren't supported")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenOrphanedRows_WhenCheckingForeignKeys_ExpectStatementsSelectingAndFixingThem() {
+	_, errS, err := s.tc.ExecuteShell([]string{".foreign_keys off", `CREATE TABLE parent (a, b, PRIMARY KEY (a, b));
+CREATE TABLE child (id INTEGER PRIMARY KEY, x, y NOT NULL, FOREIGN KEY (x, y) REFERENCES parent);
+CREATE TABLE tree (id INTEGER PRIMARY KEY, up REFERENCES tree(id));
+INSERT INTO parent VALUES (1, 1);
+INSERT INTO child VALUES (1, 1, 1), (2, 1, 2), (3, NULL, 3);
+INSERT INTO tree VALUES (1, NULL), (2, 1), (3, 9);`})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".fkcheck --fix nullify"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, `-- 1 rows of child reference no row of parent (a, b)
+SELECT c.* FROM child AS c WHERE c.x IS NOT NULL AND c.y IS NOT NULL AND NOT EXISTS (SELECT 1 FROM parent AS p WHERE p.a = c.x AND p.b = c.y);
+-- Not nullified, as column y of child is NOT NULL
+-- 1 rows of tree reference no row of tree (id)
+SELECT c.* FROM tree AS c WHERE c.up IS NOT NULL AND NOT EXISTS (SELECT 1 FROM tree AS p WHERE p.id = c.up);
+UPDATE tree SET up = NULL WHERE tree.up IS NOT NULL AND NOT EXISTS (SELECT 1 FROM tree AS p WHERE p.id = tree.up);`)
+
+	outS, _, err = s.tc.ExecuteShell([]string{"SELECT c.* FROM child AS c WHERE c.x IS NOT NULL AND c.y IS NOT NULL AND NOT EXISTS (SELECT 1 FROM parent AS p WHERE p.a = c.x AND p.b = c.y);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"id", "x", "y"}, [][]string{{"2", "1", "2"}}))
+
+	_, _, err = s.tc.ExecuteShell([]string{".fkcheck tree --fix nullify --execute", ".fkcheck child --fix delete --execute"})
+	s.tc.Assert(err, qt.IsNil)
+	outS, _, err = s.tc.ExecuteShell([]string{".fkcheck", "SELECT (SELECT group_concat(id) FROM child), (SELECT group_concat(coalesce(up, '-')) FROM tree);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(outS, qt.Equals, "-- No foreign key violations\n"+utils.GetPrintTableOutput([]string{"(SELECT group_concat(id) FROM child)", "(SELECT group_concat(coalesce(up, '-')) FROM tree)"}, [][]string{{"1,3", "-,1,-"}}))
+
+	_, errS, _ = s.tc.ExecuteShell([]string{".fkcheck --execute"})
+	s.tc.Assert(errS, qt.Equals, "Error: --execute needs --fix to tell the statements to execute")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenOutputAndOnce_WhenStatementsAndDumpRun_ExpectTheirResultsInTheFiles() {
+	dir := s.T().TempDir()
+	outputPath := filepath.Join(dir, "output.txt")
+	oncePath := filepath.Join(dir, "once.txt")
+	_, _, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER);"})
+	s.tc.Assert(err, qt.IsNil)
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".mode csv", ".output " + outputPath, "SELECT 1 AS v;", ".dump", ".once " + oncePath, "SELECT 2 AS v;", "SELECT 3 AS v;", ".output missing/output.txt", "SELECT 4 AS v;", ".output stdout", "SELECT 5 AS v;"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Matches, "Error: failed to open the output file: .*")
+	s.tc.Assert(outS, qt.Equals, "v\n5")
+
+	output, err := os.ReadFile(outputPath)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(string(output), qt.Equals, "v\n1\nPRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\nCREATE TABLE t (id INTEGER);\nCOMMIT;\nv\n3\nv\n4\n")
+	once, err := os.ReadFile(oncePath)
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(string(once), qt.Equals, "v\n2\n")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenBuildWithoutCipherSupport_WhenKey_ExpectClearError() {
+	if shell.CipherSupported() {
+		s.T().Skip("the SQLite driver of this build supports encryption")
+	}
+
+	_, errS, _ := s.tc.ExecuteShell([]string{".key"})
+	s.tc.Assert(errS, qt.Equals, "Error: encrypted databases aren't supported: this build's SQLite driver has no cipher support, like SQLCipher's")
+}
+
+func (s *DBRootCommandShellSuite) Test_GivenTable_WhenCallDotDescribeWithStats_ExpectCountsLabeledWithTheirSource() {
+	_, errS, err := s.tc.ExecuteShell([]string{"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT 'x', city);", "INSERT INTO t (name, city) VALUES ('a', 'p'), ('b', NULL), ('a', 'q'), ('c', NULL);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+
+	outS, errS, err := s.tc.ExecuteShell([]string{".describe T"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"name", "type", "not_null", "default", "pk"}, [][]string{{"id", "INTEGER", "0", "NULL", "1"}, {"name", "TEXT", "1", "'x'", "0"}, {"city", "", "0", "NULL", "0"}}))
+
+	header := []string{"name", "type", "not_null", "default", "pk", "distinct_values", "nulls", "source"}
+	outS, errS, err = s.tc.ExecuteShell([]string{".describe --stats t"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput(header, [][]string{{"id", "INTEGER", "0", "NULL", "1", "4", "0.0%", "exact"}, {"name", "TEXT", "1", "'x'", "0", "3", "0.0%", "exact"}, {"city", "", "0", "NULL", "0", "2", "50.0%", "exact"}}))
+
+	outS, errS, err = s.tc.ExecuteShell([]string{".describe --stats --sample 2 t"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	source := "sample of the first 2 rows"
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput(header, [][]string{{"id", "INTEGER", "0", "NULL", "1", "2", "0.0%", source}, {"name", "TEXT", "1", "'x'", "0", "2", "0.0%", source}, {"city", "", "0", "NULL", "0", "1", "50.0%", source}}))
+
+	// Tables too large to be read whole need a sample, or the statistics of ANALYZE
+	_, errS, err = s.tc.ExecuteShell([]string{"WITH RECURSIVE r (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM r WHERE i < 100000) INSERT INTO t (name) SELECT 'n' || (i % 46) FROM r;", "CREATE INDEX t_name ON t (name);"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "")
+	outS, errS, err = s.tc.ExecuteShell([]string{".describe --stats t", "ANALYZE;", ".describe --stats t", ".describe --sample 2 t", ".describe missing"})
+	s.tc.Assert(err, qt.IsNil)
+	s.tc.Assert(errS, qt.Equals, "Error: table t has more than 100000 rows. Give --sample N to count the values of its first N rows, or run ANALYZE to estimate them from sqlite_stat1\n"+
+		"Error: --sample only applies with --stats\nError: no such table: missing")
+	source = "sqlite_stat1, as of the last ANALYZE"
+	s.tc.Assert(outS, qt.Equals, utils.GetPrintTableOutput(header, [][]string{{"id", "INTEGER", "0", "NULL", "1", "NULL", "NULL", source}, {"name", "TEXT", "1", "'x'", "0", "48", "NULL", source}, {"city", "", "0", "NULL", "0", "NULL", "NULL", source}}))
+}
@@ -1,11 +1,18 @@
 package main_test
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
 
 	"github.com/libsql/libsql-shell-go/internal/cmd"
+	"github.com/libsql/libsql-shell-go/pkg/shell"
 	"github.com/libsql/libsql-shell-go/test/utils"
 )
 
@@ -27,7 +34,7 @@ func TestRootCommandFlags_WhenDbIsMissing_ExpectErrorReturned(t *testing.T) {
 
 	_, _, err := utils.ExecuteCobraCommand(t, rootCmd, "--exec", "CREATE TABLE test (id INTEGER PRIMARY KEY, value TEXT);")
 
-	c.Assert(err.Error(), qt.Equals, `accepts 1 arg(s), received 0`)
+	c.Assert(err.Error(), qt.Equals, `requires at least 1 arg(s), only received 0`)
 }
 
 func TestRootCommandFlags_GivenEmptyStatements_ExpectErrorReturned(t *testing.T) {
@@ -40,3 +47,481 @@ func TestRootCommandFlags_GivenEmptyStatements_ExpectErrorReturned(t *testing.T)
 
 	c.Assert(err.Error(), qt.IsNotNil)
 }
+
+func TestRootCommandFlags_GivenInitFileAndInitSql_WhenExec_ExpectInitExecutedFirstInOrder(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	initFile := c.TempDir() + "/init.sql"
+	c.Assert(os.WriteFile(initFile, []byte("CREATE TABLE t (id INTEGER);\n"), 0644), qt.IsNil)
+	rootCmd := cmd.NewRootCmd()
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, rootCmd, "--init", initFile, "--init-sql", "INSERT INTO t VALUES (1);", "--init-sql", "INSERT INTO t VALUES (2);", "--exec", "SELECT count(*) FROM t;", dbPath)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"2"}}))
+}
+
+func TestRootCommandFlags_GivenInitSql_WhenInteractive_ExpectInitExecutedBeforeInput(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	outS, _, err := utils.ExecuteCobraCommandWithInitialInput(t, rootCmd, "SELECT count(*) FROM t;", "--quiet", "--init-sql", "CREATE TABLE t (id INTEGER);", dbPath)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"0"}}))
+}
+
+func TestRootCommandFlags_GivenFailingInitSql_WhenExec_ExpectStartupAbortedNamingTheStatement(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	_, _, err := utils.ExecuteCobraCommand(t, rootCmd, "--init-sql", "CREATE TABLE t (id INTEGER);", "--init-sql", "INSERT INTO missing VALUES (1);", "--exec", "CREATE TABLE never (id INTEGER);", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, `init statement 2 \(INSERT INTO missing VALUES \(1\);\) failed: .*no such table: missing.*`)
+
+	outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--exec", "SELECT name FROM sqlite_master;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"name"}, [][]string{{"t"}}))
+}
+
+func TestRootCommandFlags_GivenInvalidIdleAction_ExpectErrorReturned(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	_, _, err := utils.ExecuteCobraCommand(t, rootCmd, "--idle-timeout", "1m", "--idle-action", "sleep", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, `invalid idle action "sleep". Valid actions are exit and disconnect`)
+}
+
+func TestRootCommandFlags_GivenQuietExec_WhenStatementFails_ExpectResultsSoFarErrorOnStderrAndExitCode1(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "-q", "-e", "SELECT 1 AS v; SELECT * FROM missing; SELECT 2 AS v;", dbPath)
+
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(cmd.ExitCode(err), qt.Equals, cmd.EXIT_FAILURE)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"v"}, [][]string{{"1"}}))
+	c.Assert(errS, qt.Contains, "Error: no such table: missing")
+}
+
+func TestRootCommandFlags_GivenBailOff_WhenExec_ExpectFollowingStatementsRunAndFailuresSummarized(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, rootCmd, "--bail=false", "--exec", "SELECT * FROM missing; CREATE TABLE t (id INTEGER); SELECT count(*) FROM t;", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, `1 statement\(s\) failed`)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"count(*)"}, [][]string{{"0"}}))
+	c.Assert(errS, qt.Contains, "Error: no such table: missing\n1 statement(s) failed:\n  SELECT * FROM missing: no such table: missing")
+}
+
+func TestRootCommandFlags_GivenFailOnEmpty_WhenQueryReturnsNoRows_ExpectHeaderPrintedAndExitCode1(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--fail-on-empty", "-e", ".mode csv", "-e", "CREATE TABLE t (id INTEGER, name TEXT); SELECT * FROM t; SELECT 1;", dbPath)
+	c.Assert(cmd.ExitCode(err), qt.Equals, cmd.EXIT_FAILURE)
+	c.Assert(outS, qt.Equals, "id,name")
+	c.Assert(errS, qt.Contains, "the query returned no rows, which fails with --fail-on-empty")
+
+	outS, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--fail-on-empty", "-e", ".mode csv", "-e", "INSERT INTO t VALUES (1, 'a'); SELECT * FROM t;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "id,name\n1,a")
+}
+
+func TestRootCommandFlags_GivenFailOnEmpty_WhenScriptQueryReturnsNoRows_ExpectExitCode1(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	dbPath := dir + "/test.sqlite"
+	scriptPath := dir + "/test.sql"
+	c.Assert(os.WriteFile(scriptPath, []byte(".mode csv\nSELECT 1 AS v WHERE 0;\n"), 0644), qt.IsNil)
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--fail-on-empty", dbPath, scriptPath)
+	c.Assert(cmd.ExitCode(err), qt.Equals, cmd.EXIT_FAILURE)
+	c.Assert(outS, qt.Equals, "v")
+	c.Assert(errS, qt.Contains, "the query returned no rows, which fails with --fail-on-empty")
+
+	outS, errS, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--fail-on-empty", "-e", ".read "+scriptPath, dbPath)
+	c.Assert(cmd.ExitCode(err), qt.Equals, cmd.EXIT_FAILURE)
+	c.Assert(outS, qt.Equals, "v")
+	c.Assert(errS, qt.Contains, "the query returned no rows, which fails with --fail-on-empty")
+}
+
+func TestRootCommandFlags_GivenScriptOnStdin_WhenDotReadDash_ExpectItExecutedUnlessTheShellReadsStdin(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	for _, fileName := range []string{"-", "/dev/stdin"} {
+		outS, errS, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 'from'\n  || ' stdin' AS v;\nSELECT 2 AS v;", "-q", "-e", ".mode csv", "-e", ".read "+fileName, dbPath)
+		c.Assert(err, qt.IsNil)
+		c.Assert(errS, qt.Equals, "")
+		c.Assert(outS, qt.Equals, "v\nfrom stdin\nv\n2")
+	}
+
+	outS, errS, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".read -\nSELECT 3 AS v;\n", "-q", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, `Error: the standard input is read by the shell for its statements. Pass the file with .read FILENAME instead, or run the shell with -e ".read -"`)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"v"}, [][]string{{"3"}}))
+}
+
+func TestRootCommandFlags_GivenLogFile_ExpectDiagnosticsInTheFileAndErrorsOnStderr(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	dbPath := dir + "/test.sqlite"
+	logPath := dir + "/shell.log"
+	scriptPath := dir + "/test.sql"
+	c.Assert(os.WriteFile(scriptPath, []byte(".bail on\nSELECT 1 AS v;\nSELECT * FROM missing;\n"), 0644), qt.IsNil)
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--log-file", logPath, dbPath, scriptPath)
+	c.Assert(err, qt.ErrorMatches, "line 3: no such table: missing")
+	c.Assert(errS, qt.Equals, "Error: line 3: no such table: missing")
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"v"}, [][]string{{"1"}}))
+	logContent, err := os.ReadFile(logPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(logContent), qt.Matches, `\S+ WARNING line 1: unsupported command .bail was skipped\n`)
+
+	_, errS, _ = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--log-file", logPath, "--log-stderr", dbPath, scriptPath)
+	c.Assert(errS, qt.Equals, "Warning: line 1: unsupported command .bail was skipped\nError: line 3: no such table: missing")
+	logContent, err = os.ReadFile(logPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(strings.Count(string(logContent), "WARNING"), qt.Equals, 2)
+
+	_, errS, _ = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "-e", ".slow-threshold 1ns", "-e", "SELECT 1;", dbPath)
+	c.Assert(errS, qt.Matches, `Note: statement took \S+, exceeding slow threshold of 1ns`)
+	_, errS, _ = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--log-level", "warning", "-e", ".slow-threshold 1ns", "-e", "SELECT 1;", dbPath)
+	c.Assert(errS, qt.Equals, "")
+
+	_, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--log-level", "debug", dbPath, scriptPath)
+	c.Assert(err, qt.ErrorMatches, `invalid log level "debug". Valid levels are info, note and warning`)
+}
+
+func TestRootCommandFlags_GivenBailOff_WhenScriptAssertionFails_ExpectScriptGoesOnAndExitFails(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	dbPath := dir + "/test.sqlite"
+	scriptPath := dir + "/test.sql"
+	err := os.WriteFile(scriptPath, []byte("CREATE TABLE t (id INTEGER);\n.assert ROWS 1 SELECT * FROM t\nINSERT INTO t VALUES (1);\n.assert ROWS 1 SELECT * FROM t\n"), 0644)
+	c.Assert(err, qt.IsNil)
+
+	_, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--bail=false", dbPath, scriptPath)
+	c.Assert(err, qt.ErrorMatches, `1 statement\(s\) failed`)
+	c.Assert(errS, qt.Contains, "Error: assertion failed: expected 1 row(s), got 0\n1 statement(s) failed:\n  .assert ROWS 1 SELECT * FROM t: assertion failed: expected 1 row(s), got 0")
+
+	_, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), dir+"/bail.sqlite", scriptPath)
+	c.Assert(err, qt.ErrorMatches, `line 2: assertion failed: expected 1 row\(s\), got 0`)
+}
+
+func TestRootCommandFlags_GivenSessionStats_WhenBatchRuns_ExpectStatisticsPrintedOnExit(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	_, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--session-stats", "-e", "CREATE TABLE t (id INTEGER); INSERT INTO t VALUES (1), (2);", "-e", "SELECT * FROM t;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Matches, `session: 3 statements, 0 errors, 2 rows returned, 2 rows changed, \d+ bytes written, \S+ connected\nslowest statement: .+ \(\S+\)`)
+
+	_, errS, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "-e", "SELECT * FROM t;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+}
+
+func TestRootCommandFlags_GivenSummary_WhenExecStopsAtAnError_ExpectCountsIncludingStatementsNotExecuted(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	_, errS, err := utils.ExecuteCobraCommand(t, rootCmd, "--summary", "--exec", "CREATE TABLE t (id INTEGER); INSERT INTO t VALUES (1), (2); UPDATE t SET id = id + 1; SELECT * FROM t; SELECT * FROM missing; SELECT 1;", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, ".*no such table: missing.*")
+	c.Assert(errS, qt.Matches, `(?s)6 statements: 4 ok, 1 errors, 1 not executed, 2 rows returned, 4 rows changed, \S+ total.*`)
+}
+
+func TestRootCommandFlags_WhenExecWithoutSummary_ExpectNoSummary(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	_, errS, err := utils.ExecuteCobraCommand(t, rootCmd, "--exec", "CREATE TABLE t (id INTEGER); SELECT * FROM t;", dbPath)
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+}
+
+func TestRootCommandFlags_GivenBailOffAndFailingInitSql_WhenExec_ExpectStartupAborted(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	rootCmd := cmd.NewRootCmd()
+
+	outS, _, err := utils.ExecuteCobraCommand(t, rootCmd, "--bail=false", "--init-sql", "SELECT * FROM nope;", "--exec", "SELECT 42;", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, `init statement 1 \(SELECT \* FROM nope;\) failed: .*no such table: nope.*`)
+	c.Assert(outS, qt.Equals, "")
+}
+
+func TestRootCommandFlags_GivenSettingsChanged_WhenReconnecting_ExpectOnlySettingsSavedForTheDatabaseLoaded(t *testing.T) {
+	c := qt.New(t)
+	t.Setenv("HOME", c.TempDir())
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	sameNameDbPath := c.TempDir() + "/test.sqlite"
+	otherDbPath := c.TempDir() + "/other.sqlite"
+
+	// Piped input isn't an interactive session, whose changes only are saved on exit
+	_, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	outS, errS, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"a"}, [][]string{{"1"}}))
+	c.Assert(errS, qt.Equals, "")
+
+	_, _, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv\n.save-settings --per-db", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	outS, errS, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "a\n1")
+	c.Assert(errS, qt.Matches, `Loaded the settings saved for this database from .*\.test_[0-9a-f]{8}_shell_settings`)
+
+	for _, path := range []string{sameNameDbPath, otherDbPath} {
+		outS, errS, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", path)
+		c.Assert(err, qt.IsNil)
+		c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"a"}, [][]string{{"1"}}))
+		c.Assert(errS, qt.Equals, "")
+	}
+}
+
+func TestRootCommandFlags_GivenSavedSettings_ExpectInitFileOverriddenAndInitSqlOverriding(t *testing.T) {
+	c := qt.New(t)
+	t.Setenv("HOME", c.TempDir())
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	initFile := c.TempDir() + "/init.sql"
+	c.Assert(os.WriteFile(initFile, []byte(".mode json\n"), 0644), qt.IsNil)
+
+	_, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv\n.save-settings --per-db\n.mode table", "--quiet", "--no-per-db-settings", dbPath)
+	c.Assert(err, qt.IsNil)
+
+	outS, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", "--init", initFile, dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "a\n1")
+
+	outS, _, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", "--init", initFile, "--init-sql", ".mode table", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"a"}, [][]string{{"1"}}))
+
+	// The settings given by flags only last for their session
+	outS, _, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "a\n1")
+}
+
+func TestRootCommandFlags_GivenNoPerDbSettings_ExpectSettingsNeitherLoadedNorSaved(t *testing.T) {
+	c := qt.New(t)
+	t.Setenv("HOME", c.TempDir())
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	_, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv\n.save-settings --per-db", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+
+	outS, errS, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;\n.mode json", "--quiet", "--no-per-db-settings", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, utils.GetPrintTableOutput([]string{"a"}, [][]string{{"1"}}))
+	c.Assert(errS, qt.Equals, "")
+
+	outS, _, err = utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "a\n1")
+}
+
+func TestRootCommandFlags_GivenCsvOptionsSaved_ExpectThemLoaded(t *testing.T) {
+	c := qt.New(t)
+	t.Setenv("HOME", c.TempDir())
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	_, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), ".mode csv --excel-compat\n.save-settings --per-db", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+
+	outS, _, err := utils.ExecuteCobraCommandWithInitialInput(t, cmd.NewRootCmd(), "SELECT 1 AS a;", "--quiet", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "\ufeffa\r\n1")
+}
+
+func TestRootCommandFlags_GivenConnectionFailures_ExpectDistinctExitCodesAndMessages(t *testing.T) {
+	c := qt.New(t)
+
+	unauthorizedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorizedServer.Close()
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+	notFoundServer := httptest.NewServer(http.NotFoundHandler())
+	defer notFoundServer.Close()
+
+	tests := []struct {
+		dbUri    string
+		exitCode int
+		stderr   string
+	}{
+		{unauthorizedServer.URL, cmd.EXIT_AUTHENTICATION_FAILED, `Error: authentication failed \(401\): check the token given with --auth`},
+		{closedServer.URL, cmd.EXIT_NETWORK_FAILURE, `Error: unable to reach .*: connection refused\. Retry later, or check the database URL`},
+		{notFoundServer.URL, cmd.EXIT_DATABASE_NOT_FOUND, `Error: database not found \(404\): check the database URL .*`},
+		{"ftp://example.com", cmd.EXIT_FAILURE, `Error: invalid sqld protocol.*`},
+		{"mydb-org.turso.io", cmd.EXIT_FAILURE, `Error: mydb-org.turso.io looks like the host of a database, but it has no protocol\. Use libsql://mydb-org.turso.io .*`},
+	}
+	for _, test := range tests {
+		_, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--auth", "token", "--exec", "SELECT 1;", test.dbUri)
+		c.Assert(err, qt.IsNotNil)
+		c.Assert(cmd.ExitCode(err), qt.Equals, test.exitCode)
+		c.Assert(errS, qt.Matches, test.stderr)
+	}
+}
+
+func TestRootCommandFlags_WhenHelp_ExpectExitCodesDocumented(t *testing.T) {
+	c := qt.New(t)
+
+	outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--help")
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Contains, "Exit codes:\n  0  Success\n  1  Any other failure\n  2  Authentication failed")
+}
+
+func TestRootCommandFlags_GivenLockFileOfRunningProcess_ExpectWarningUnlessNoLockCheck(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	lockPath := dbPath + "-shell.lock"
+	c.Assert(os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644), qt.IsNil)
+
+	_, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--exec", "SELECT 1;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, fmt.Sprintf("Warning: another shell (process %d) has %s open, so long write transactions in either one make the other fail with SQLITE_BUSY. Use --no-lock-check to skip this check", os.Getpid(), dbPath))
+	_, err = os.Stat(lockPath)
+	c.Assert(err, qt.IsNil)
+
+	_, errS, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--no-lock-check", "--exec", "SELECT 1;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+}
+
+func TestRootCommandFlags_GivenStaleLockFile_ExpectItReplacedAndRemovedOnExit(t *testing.T) {
+	c := qt.New(t)
+
+	// The process ID of a process that already exited
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	c.Assert(exited.Run(), qt.IsNil)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	lockPath := dbPath + "-shell.lock"
+	c.Assert(os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", exited.ProcessState.Pid())), 0644), qt.IsNil)
+
+	_, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--exec", "SELECT 1;", dbPath)
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+	_, err = os.Stat(lockPath)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+}
+
+func TestRootCommandFlags_WhenGeneratingCompletion_ExpectScriptsCallingDynamicCompletion(t *testing.T) {
+	c := qt.New(t)
+
+	for _, shellName := range []string{"bash", "zsh", "fish", "powershell"} {
+		outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "completion", shellName)
+
+		c.Assert(err, qt.IsNil)
+		c.Assert(outS, qt.Contains, "__complete")
+	}
+
+	_, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "completion", "tcsh")
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestRootCommandFlags_WhenCompletingIdleAction_ExpectItsValues(t *testing.T) {
+	c := qt.New(t)
+
+	outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "__complete", "--idle-action", "")
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "exit\tExit the shell\ndisconnect\tDisconnect, and reconnect on the next statement\n:4")
+}
+
+func TestRootCommandFlags_WhenCompletingDatabase_ExpectDatabaseFileExtensions(t *testing.T) {
+	c := qt.New(t)
+
+	outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "__complete", "test")
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, "db\nsqlite\nsqlite3\ndb3\n:8")
+
+	outS, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "__complete", "test.db", "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(outS, qt.Equals, ":4")
+}
+
+func TestRootCommandFlags_GivenSeveralExecAndScriptFiles_ExpectRunInCommandLineOrder(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	scriptFile := c.TempDir() + "/migrate.sql"
+	c.Assert(os.WriteFile(scriptFile, []byte(".mode csv\nCREATE TABLE t (id INTEGER);\nINSERT INTO t VALUES (1), (2);\n"), 0644), qt.IsNil)
+
+	outS, errS, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "-e", "PRAGMA foreign_keys=ON;", dbPath, scriptFile, "-e", "SELECT count(*) AS n FROM t;", "-e", "PRAGMA foreign_keys;")
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(errS, qt.Equals, "")
+	c.Assert(outS, qt.Equals, "n\n2\nforeign_keys\n1")
+}
+
+func TestRootCommandFlags_GivenSeveralInputs_WhenOneFails_ExpectErrorNamingItAndNextOnesSkipped(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	scriptFile := c.TempDir() + "/broken.sql"
+	c.Assert(os.WriteFile(scriptFile, []byte("SELECT * FROM missing;\n"), 0644), qt.IsNil)
+
+	outS, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "-e", ".mode csv", dbPath, scriptFile, "-e", "SELECT 1 AS skipped;")
+	c.Assert(err, qt.ErrorMatches, "script .*/broken.sql failed: line 1: no such table: missing")
+	c.Assert(outS, qt.Equals, "")
+
+	_, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), dbPath, "-e", "SELECT 1;", "-e", "SELECT * FROM missing;")
+	c.Assert(err, qt.ErrorMatches, `-e 2 \(SELECT \* FROM missing;\) failed: no such table: missing`)
+
+	// A single input fails with its error alone, as before
+	_, _, err = utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), dbPath, "-e", "SELECT * FROM missing;")
+	c.Assert(err, qt.ErrorMatches, "no such table: missing")
+}
+
+func TestRootCommandFlags_GivenBuildWithoutCipherSupport_WhenCipherKey_ExpectClearErrorBeforeConnecting(t *testing.T) {
+	c := qt.New(t)
+	if shell.CipherSupported() {
+		c.Skip("the SQLite driver of this build supports encryption")
+	}
+
+	dbPath := c.TempDir() + "/test.sqlite"
+
+	_, _, err := utils.ExecuteCobraCommand(t, cmd.NewRootCmd(), "--cipher-key", "secret", "-e", "SELECT 1;", dbPath)
+
+	c.Assert(err, qt.ErrorMatches, "encrypted databases aren't supported: .*")
+	_, statErr := os.Stat(dbPath)
+	c.Assert(os.IsNotExist(statErr), qt.IsTrue)
+}
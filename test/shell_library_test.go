@@ -0,0 +1,88 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/libsql/libsql-shell-go/pkg/shell"
+)
+
+func TestShellLibrary_GivenTempTableCreatedOnConnectionHandle_ExpectVisibleToShellStatements(t *testing.T) {
+	c := qt.New(t)
+
+	dbPath := c.TempDir() + "/test.sqlite"
+	outF := new(bytes.Buffer)
+	var connects, disconnects []shell.ConnectionEvent
+	config := shell.ShellConfig{
+		DbUri: dbPath,
+		OutF:  outF,
+		ErrF:  new(bytes.Buffer),
+		OnConnect: func(event shell.ConnectionEvent) {
+			connects = append(connects, event)
+			_, err := event.DB.Exec("CREATE TEMP TABLE embedder (value TEXT); INSERT INTO embedder VALUES ('from the handle');")
+			c.Assert(err, qt.IsNil)
+		},
+		OnDisconnect: func(event shell.ConnectionEvent) { disconnects = append(disconnects, event) },
+	}
+
+	err := shell.RunShellLine(config, "SELECT value FROM temp.embedder;")
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(outF.String(), qt.Contains, "from the handle")
+	c.Assert(connects, qt.HasLen, 1)
+	c.Assert(connects[0].Url, qt.Equals, dbPath)
+	c.Assert(disconnects, qt.HasLen, 1)
+	c.Assert(disconnects[0].Url, qt.Equals, dbPath)
+	c.Assert(disconnects[0].DB, qt.IsNil)
+	c.Assert(disconnects[0].Duration > 0, qt.IsTrue)
+}
+
+func TestShellLibrary_GivenNewShell_ExpectStatementsAndDotCommandsExecuted(t *testing.T) {
+	c := qt.New(t)
+
+	outF := new(bytes.Buffer)
+	sh, err := shell.New(shell.ShellConfig{DbUri: c.TempDir() + "/test.sqlite", OutF: outF, ErrF: new(bytes.Buffer), QuietMode: true, InitStatements: []string{".mode csv"}})
+	c.Assert(err, qt.IsNil)
+	defer sh.Close()
+
+	c.Assert(sh.ExecuteCommandOrStatements("CREATE TABLE t (v TEXT); INSERT INTO t VALUES ('a');"), qt.IsNil)
+	outF.Reset()
+	c.Assert(sh.ExecuteCommandOrStatements(".tables"), qt.IsNil)
+	c.Assert(sh.ExecuteCommandOrStatements("SELECT v FROM t;"), qt.IsNil)
+	c.Assert(outF.String(), qt.Equals, "t\nv\na\n")
+
+	outF.Reset()
+	c.Assert(sh.ExecuteCommandOrStatements(".dump"), qt.IsNil)
+	c.Assert(outF.String(), qt.Contains, "CREATE TABLE t (v TEXT);\nINSERT INTO t VALUES ('a');\n")
+	c.Assert(sh.ExecuteCommandOrStatements("SELECT nope FROM t;"), qt.ErrorMatches, ".*no such column: nope.*")
+}
+
+func TestShellLibrary_GivenContextCanceledWhileRunWaitsForInput_ExpectRunToReturn(t *testing.T) {
+	c := qt.New(t)
+
+	inR, inW := io.Pipe()
+	defer inW.Close()
+	outF := new(bytes.Buffer)
+	sh, err := shell.New(shell.ShellConfig{DbUri: c.TempDir() + "/test.sqlite", InF: inR, OutF: outF, ErrF: new(bytes.Buffer), QuietMode: true, HistoryFile: c.TempDir() + "/history"})
+	c.Assert(err, qt.IsNil)
+	defer sh.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sh.Run(ctx) }()
+	_, err = inW.Write([]byte("SELECT 42 AS answer;\n"))
+	c.Assert(err, qt.IsNil)
+	cancel()
+
+	select {
+	case err := <-done:
+		c.Assert(err, qt.Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("Run didn't return once its context was canceled")
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, score REAL, avatar BLOB);
+CREATE TABLE "order" (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), note TEXT);
+CREATE TABLE "line items"(order_id INTEGER, sku TEXT, quantity INTEGER DEFAULT 1, UNIQUE (order_id, sku));
+CREATE INDEX order_user ON "order" (user_id);
+CREATE VIEW named AS SELECT name FROM users;
+CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN UPDATE users SET score = coalesce(score, 0) WHERE id = new.id; END;
+INSERT INTO users (name, score, avatar) VALUES ('ann', 1.5, X'00ff10'), ('bob''s', 0.125, NULL), ('line
+break', 3.0, X''), ('big', 1e20, NULL), ('neg', -2.25, NULL), ('back\slash', 35000000000.0, NULL);
+INSERT INTO "order" VALUES (1, 1, 'first'), (2, 2, NULL), (3, 1, 'tab' || char(9) || 'and "quotes"');
+INSERT INTO "line items" VALUES (1, 'A-1', 2), (3, 'B''2', 1);
+DELETE FROM users WHERE name = 'neg';
+ANALYZE users;
+ANALYZE "order";
@@ -147,7 +147,7 @@ func (tc *DbTestContext) CreateAllTypesTable(tableName string, initialValues []A
 func (tc *DbTestContext) DropTable(tableName string) {
 	name := tableName
 	if db.NeedsEscaping(tableName) {
-		name = fmt.Sprintf("'%s'", db.EscapeSingleQuotes(tableName))
+		name = db.QuoteIdentifier(tableName, enums.DOUBLE_QUOTE_STYLE)
 	}
 	_, _, err := tc.Execute("DROP TABLE " + name)
 	tc.Assert(err, qt.IsNil)